@@ -20,7 +20,10 @@ package web3ext
 var Modules = map[string]string{
 	"admin":      Admin_JS,
 	"chequebook": Chequebook_JS,
+	"clique":     Clique_JS,
+	"dapp":       DApp_JS,
 	"debug":      Debug_JS,
+	"dpos":       DPoS_JS,
 	"block":      Block_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -110,6 +113,23 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'attachDApp',
+			call: 'admin_attachDApp',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'detachDApp',
+			call: 'admin_detachDApp',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setDAppQuota',
+			call: 'admin_setDAppQuota',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -124,6 +144,10 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'dapps',
+			getter: 'admin_listDApps'
+		}),
 	]
 });
 `
@@ -322,6 +346,184 @@ web3._extend({
 });
 `
 
+const Clique_JS = `
+web3._extend({
+	property: 'clique',
+	methods: [
+		new web3._extend.Method({
+			name: 'getSnapshot',
+			call: 'clique_getSnapshot',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSnapshotAtHash',
+			call: 'clique_getSnapshotAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSigners',
+			call: 'clique_getSigners',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignersAtHash',
+			call: 'clique_getSignersAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'propose',
+			call: 'clique_propose',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discard',
+			call: 'clique_discard',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'proposals',
+			getter: 'clique_proposals'
+		}),
+	]
+});
+`
+
+const DPoS_JS = `
+web3._extend({
+	property: 'dpos',
+	methods: [
+		new web3._extend.Method({
+			name: 'getDelegators',
+			call: 'dpos_getDelegators',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSchedule',
+			call: 'dpos_getSchedule',
+			params: 1,
+			inputFormatter: [function(epoch) {
+				return (epoch === undefined || epoch === null) ? epoch : web3._extend.utils.toHex(epoch);
+			}]
+		}),
+		new web3._extend.Method({
+			name: 'getCandidate',
+			call: 'dpos_getCandidate',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getVotes',
+			call: 'dpos_getVotes',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEpochInfo',
+			call: 'dpos_getEpochInfo',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getProducerOfBlock',
+			call: 'dpos_getProducerOfBlock',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getFinalityCertificate',
+			call: 'dpos_getFinalityCertificate',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getRewards',
+			call: 'dpos_getRewards',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEmission',
+			call: 'dpos_getEmission',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
+		new web3._extend.Method({
+			name: 'getLiveness',
+			call: 'dpos_getLiveness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDelegatorLiveness',
+			call: 'dpos_getDelegatorLiveness',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEvidence',
+			call: 'dpos_getEvidence',
+			params: 0
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'delegators',
+			getter: 'dpos_getDelegators'
+		}),
+		new web3._extend.Property({
+			name: 'schedule',
+			getter: 'dpos_getSchedule'
+		}),
+		new web3._extend.Property({
+			name: 'epochInfo',
+			getter: 'dpos_getEpochInfo'
+		}),
+		new web3._extend.Property({
+			name: 'finalityCertificate',
+			getter: 'dpos_getFinalityCertificate'
+		}),
+		new web3._extend.Property({
+			name: 'evidence',
+			getter: 'dpos_getEvidence'
+		}),
+	]
+});
+`
+
+const DApp_JS = `
+web3._extend({
+	property: 'dapp',
+	methods: [
+		new web3._extend.Method({
+			name: 'getAnchorProof',
+			call: 'dapp_getAnchorProof',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getDeposit',
+			call: 'dapp_getDeposit',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getWithdrawal',
+			call: 'dapp_getWithdrawal',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getReleaseProof',
+			call: 'dapp_getReleaseProof',
+			params: 2
+		}),
+	]
+});
+`
+
 const Block_JS = `
 web3._extend({
 	property: 'block',
@@ -363,6 +565,76 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getDAppBlockNumber',
+			call: 'block_blockNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getDAppBlock',
+			call: function(args) {
+				return (web3._extend.utils.isString(args[0]) && args[0].indexOf('0x') === 0) ? 'block_getBlockByHash' : 'block_getBlockByNumber';
+			},
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, function (val) { return !!val; }, web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.formatters.outputBlockFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getDAppBalance',
+			call: 'block_getBalance',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.formatters.outputBigNumberFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getDAppCode',
+			call: 'block_getCode',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDAppStorageAt',
+			call: 'block_getStorageAt',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex, web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDAppTransactionCount',
+			call: 'block_getTransactionCount',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getDAppTransaction',
+			call: 'block_getTransactionByHash',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.formatters.outputTransactionFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getDAppTransactionReceipt',
+			call: 'block_getTransactionReceipt',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.formatters.outputTransactionReceiptFormatter
+		}),
+		new web3._extend.Method({
+			name: 'dappCall',
+			call: 'block_call',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getLogs',
+			call: 'block_getLogs',
+			params: 1,
+			outputFormatter: function(logs) {
+				return logs.map(web3._extend.formatters.outputLogFormatter);
+			}
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -424,12 +696,57 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'updateDAppStorageNode',
+			call: 'personal_updateDAppStorageNode',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'listDAppStorageNodeInfo',
+			call: 'personal_listDAppStorageNodeInfo',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'newDAppAccount',
+			call: 'personal_newDAppAccount',
+			params: 7
+		}),
+		new web3._extend.Method({
+			name: 'getDAppInfo',
+			call: 'personal_getDAppInfo',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'listAllDAppIds',
+			call: 'personal_listAllDAppIds',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'listAllDApps',
+			call: 'personal_listAllDApps',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'listDAppContracts',
+			call: 'personal_listDAppContracts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'listWallets',
 			getter: 'personal_listWallets'
 		}),
+		new web3._extend.Property({
+			name: 'totalDApps',
+			getter: 'personal_totalDApps'
+		}),
 	]
 })
 `
@@ -0,0 +1,197 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/juchain/go-juchain/cmd/utils"
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/p2p/node"
+	"github.com/juchain/go-juchain/p2p/protocol"
+	"github.com/juchain/go-juchain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dappCommandAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Value: node.DefaultIPCEndpoint(clientIdentifier),
+		Usage: "API endpoint of the running node",
+	}
+	dappCommandPruneFlag = cli.BoolFlag{
+		Name:  "prune",
+		Usage: "Remove the database of the detached DApp chain",
+	}
+	dappCommand = cli.Command{
+		Name:      "dapp",
+		Usage:     "Manage the DApp chains of a running node",
+		ArgsUsage: "",
+		Category:  "DAPP COMMANDS",
+		Description: `
+Attach DApp chains to a running node, detach them, limit their disk usage or list
+the attached ones. The changes are persisted and apply on the next start of the
+node as well.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "attach",
+				Usage:     "Attach the chain of a DApp to the running node",
+				ArgsUsage: "<dappId>",
+				Action:    utils.MigrateFlags(dappAttach),
+				Flags: []cli.Flag{
+					dappCommandAttachFlag,
+				},
+				Description: `
+    juchain dapp attach <dappId>
+
+Opens the chain of the DApp, bootstrapping its database from the genesis on
+first use, and follows it from the head of the main chain on.`,
+			},
+			{
+				Name:      "detach",
+				Usage:     "Detach the chain of a DApp from the running node",
+				ArgsUsage: "<dappId>",
+				Action:    utils.MigrateFlags(dappDetach),
+				Flags: []cli.Flag{
+					dappCommandAttachFlag,
+					dappCommandPruneFlag,
+				},
+				Description: `
+    juchain dapp detach [--prune] <dappId>
+
+Stops following the chain of the DApp and drops its pending transactions. The
+database of the chain is removed with --prune.`,
+			},
+			{
+				Name:      "quota",
+				Usage:     "Limit the disk space the chain of a DApp may use",
+				ArgsUsage: "<dappId> <megabytes>",
+				Action:    utils.MigrateFlags(dappQuota),
+				Flags: []cli.Flag{
+					dappCommandAttachFlag,
+				},
+				Description: `
+    juchain dapp quota <dappId> <megabytes>
+
+Limits the size of the database of an attached DApp chain. Transactions of the
+DApp are rejected once its database exceeds the quota. A quota of 0 falls back
+to the default quota set by --dapp.quota.`,
+			},
+			{
+				Name:   "list",
+				Usage:  "Print the DApp chains attached to the running node",
+				Action: utils.MigrateFlags(dappList),
+				Flags: []cli.Flag{
+					dappCommandAttachFlag,
+				},
+				Description: `
+Print the DApp chains attached to the running node along with their heads.`,
+			},
+		},
+	}
+)
+
+// dappClient attaches to the running node to manage its DApp chains.
+func dappClient(ctx *cli.Context) *rpc.Client {
+	client, err := dialRPC(ctx.String(dappCommandAttachFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to attach to node: %v", err)
+	}
+	return client
+}
+
+// dappArgument parses the DApp id given as the only argument.
+func dappArgument(ctx *cli.Context) common.Address {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a DApp id as argument.")
+	}
+	if !common.IsHexAddress(ctx.Args().First()) {
+		utils.Fatalf("Invalid DApp id: %s", ctx.Args().First())
+	}
+	return common.HexToAddress(ctx.Args().First())
+}
+
+func dappAttach(ctx *cli.Context) error {
+	dappId := dappArgument(ctx)
+
+	client := dappClient(ctx)
+	defer client.Close()
+
+	var ok bool
+	if err := client.Call(&ok, "admin_attachDApp", dappId); err != nil {
+		utils.Fatalf("Failed to attach DApp chain: %v", err)
+	}
+	fmt.Printf("Attached DApp chain %x\n", dappId)
+	return nil
+}
+
+func dappDetach(ctx *cli.Context) error {
+	dappId := dappArgument(ctx)
+
+	client := dappClient(ctx)
+	defer client.Close()
+
+	var ok bool
+	if err := client.Call(&ok, "admin_detachDApp", dappId, ctx.Bool(dappCommandPruneFlag.Name)); err != nil {
+		utils.Fatalf("Failed to detach DApp chain: %v", err)
+	}
+	fmt.Printf("Detached DApp chain %x\n", dappId)
+	return nil
+}
+
+func dappQuota(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires a DApp id and a quota in megabytes as arguments.")
+	}
+	if !common.IsHexAddress(ctx.Args().First()) {
+		utils.Fatalf("Invalid DApp id: %s", ctx.Args().First())
+	}
+	dappId := common.HexToAddress(ctx.Args().First())
+	quota, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid quota: %v", err)
+	}
+	client := dappClient(ctx)
+	defer client.Close()
+
+	var ok bool
+	if err := client.Call(&ok, "admin_setDAppQuota", dappId, hexutil.Uint64(quota*1024*1024)); err != nil {
+		utils.Fatalf("Failed to set DApp quota: %v", err)
+	}
+	fmt.Printf("Limited DApp chain %x to %d MB\n", dappId, quota)
+	return nil
+}
+
+func dappList(ctx *cli.Context) error {
+	client := dappClient(ctx)
+	defer client.Close()
+
+	var dapps []*protocol.DAppChainInfo
+	if err := client.Call(&dapps, "admin_listDApps"); err != nil {
+		utils.Fatalf("Failed to list DApp chains: %v", err)
+	}
+	for _, dapp := range dapps {
+		fmt.Printf("DApp %x: #%d [%x] anchored to %x, disk %s", dapp.DAppId, dapp.Number, dapp.Hash, dapp.Anchor, common.StorageSize(dapp.DiskUsage))
+		if dapp.DiskQuota > 0 {
+			fmt.Printf(" of %s", common.StorageSize(dapp.DiskQuota))
+		}
+		fmt.Println()
+	}
+	return nil
+}
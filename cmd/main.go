@@ -64,6 +64,7 @@ var (
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolDAppSlotsFlag,
 		utils.TxPoolLifetimeFlag,
 		utils.FastSyncFlag,
 		utils.SyncModeFlag,
@@ -78,6 +79,7 @@ var (
 		utils.EtherbaseFlag,
 		utils.DAppAddressFlag,
 		utils.DAppMaximumFlag,
+		utils.DAppQuotaFlag,
 		utils.GasPriceFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
@@ -86,6 +88,8 @@ var (
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.TestnetFlag,
+		utils.DeveloperFlag,
+		utils.DeveloperPeriodFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
@@ -140,6 +144,8 @@ func init() {
 		bugCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See dappcmd.go
+		dappCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
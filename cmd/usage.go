@@ -76,6 +76,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IdentityFlag,
 		},
 	},
+	{
+		Name: "DEVELOPER CHAIN",
+		Flags: []cli.Flag{
+			utils.DeveloperFlag,
+			utils.DeveloperPeriodFlag,
+		},
+	},
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
@@ -88,6 +95,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolDAppSlotsFlag,
 			utils.TxPoolLifetimeFlag,
 		},
 	},
@@ -112,6 +120,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.DAppAddressFlag,
 			utils.DAppMaximumFlag,
+			utils.DAppQuotaFlag,
 		},
 	},
 	{
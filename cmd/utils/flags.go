@@ -20,6 +20,7 @@ package utils
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"math/big"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -47,7 +48,6 @@ import (
 	"github.com/juchain/go-juchain/p2p/netutil"
 	"github.com/juchain/go-juchain/config"
 	"gopkg.in/urfave/cli.v1"
-	"github.com/juchain/go-juchain/consensus/dpos"
 )
 
 var (
@@ -126,6 +126,14 @@ var (
 		Name:  "testnet",
 		Usage: "Test network: pre-configured delegated proof-of-stake test network",
 	}
+	DeveloperFlag = cli.BoolFlag{
+		Name:  "dev",
+		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, sealing instantly",
+	}
+	DeveloperPeriodFlag = cli.IntFlag{
+		Name:  "dev.period",
+		Usage: "Block period to use in developer mode (0 = seal on every transaction)",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -196,6 +204,11 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: protocol.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolDAppSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.dappslots",
+		Usage: "Maximum number of DApp transaction slots waiting for their anchors per DApp",
+		Value: protocol.DefaultConfig.TxPool.DAppSlots,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
@@ -211,6 +224,11 @@ var (
 		Usage: "Specify how many DApp addresses are supported in this node. By default has limited in 50 ledgers",
 		Value: "50",
 	}
+	DAppQuotaFlag = cli.Uint64Flag{
+		Name:  "dapp.quota",
+		Usage: "Default disk quota of a DApp chain database in megabytes (0 = unlimited)",
+		Value: 0,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -628,23 +646,36 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *protocol.Config)
 }
 
 func setDAppStorage(ctx *cli.Context, stack *node.Node) {
-	persistAddresses := stack.DAppAddresses()
+	maximum, err := strconv.Atoi(ctx.GlobalString(DAppMaximumFlag.Name))
+	if err != nil || maximum < 0 {
+		Fatalf("Invalid maximum number of DApps: %s", ctx.GlobalString(DAppMaximumFlag.Name))
+	}
+	dapps := &config.DAppAddress{
+		Maximum: maximum,
+		Quota:   ctx.GlobalUint64(DAppQuotaFlag.Name) * 1024 * 1024,
+	}
+	// DApps attached at runtime are persisted, the ones on the command line aren't
+	for _, addr := range stack.DAppAddresses() {
+		dapps.Add(addr)
+	}
 	if ctx.GlobalIsSet(DAppAddressFlag.Name) {
-		addresses := strings.Split(ctx.GlobalString(DAppAddressFlag.Name), ",");
-		dappAddresses := make([]common.Address, len(addresses));
-		for i := range addresses {
-			dappAddresses[i] = common.HexToAddress(addresses[i])
+		for _, addr := range strings.Split(ctx.GlobalString(DAppAddressFlag.Name), ",") {
+			dapps.AddStatic(common.HexToAddress(addr))
 		}
-		dappAddresses = append(dappAddresses, persistAddresses...)
-		config.DAppAddresses = &config.DAppAddress{dappAddresses};
-	} else {
-		config.DAppAddresses = &config.DAppAddress{persistAddresses};
-
-		// TODO: load all current assigned dapp chain addresses from DB.
-		// append(cfg.DAppAddresses, db.DAppAddresses)
 	}
-	for i := range config.DAppAddresses.Addresse {
-		log.Debug("Detected DApp Address: " + config.DAppAddresses.Addresse[i].String())
+	if maximum > 0 && len(dapps.List()) > maximum {
+		Fatalf("Too many DApps assigned: %d > %d", len(dapps.List()), maximum)
+	}
+	for addr, quota := range stack.DAppQuotas() {
+		dapps.SetQuota(addr, quota)
+	}
+	for addr, nodes := range stack.DAppStorageNodes() {
+		dapps.AssignNodes(addr, nodes)
+	}
+	config.DAppAddresses = dapps
+
+	for _, addr := range config.DAppAddresses.List() {
+		log.Debug("Detected DApp Address: " + addr.String(), "quota", config.DAppAddresses.DiskQuota(addr))
 	}
 }
 
@@ -682,7 +713,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
-	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
+	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || ctx.GlobalBool(DeveloperFlag.Name) {
 		cfg.NoDiscovery = true
 	}
 
@@ -693,6 +724,11 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalBool(DeveloperFlag.Name) {
+		// --dev mode runs a single node, without p2p networking.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ":0"
+	}
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -706,6 +742,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
+	case ctx.GlobalBool(DeveloperFlag.Name):
+		cfg.DataDir = "" // unless set explicitly, the developer chain is in memory
 	case ctx.GlobalBool(TestnetFlag.Name):
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "testnet")
 	}
@@ -755,6 +793,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolDAppSlotsFlag.Name) {
+		cfg.DAppSlots = ctx.GlobalUint64(TxPoolDAppSlotsFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
@@ -824,6 +865,7 @@ func checkExclusive(ctx *cli.Context, args ...interface{}) {
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *protocol.Config) {
 	// Avoid conflicting network flags
+	checkExclusive(ctx, DeveloperFlag, TestnetFlag)
 	checkExclusive(ctx, FastSyncFlag, SyncModeFlag)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
@@ -877,6 +919,31 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *protocol.Config) {
 			cfg.NetworkId = 3
 		}
 		cfg.Genesis = core.DefaultTestnetGenesisBlock()
+	case ctx.GlobalBool(DeveloperFlag.Name):
+		// Create a new developer account or reuse the existing one, unlocked to
+		// seal the blocks and to fund the transactions of the developer.
+		var developer account.Account
+		if accounts := ks.Accounts(); len(accounts) > 0 {
+			developer = accounts[0]
+		} else {
+			var err error
+			if developer, err = ks.NewAccount(""); err != nil {
+				Fatalf("Failed to create developer account: %v", err)
+			}
+		}
+		if err := ks.Unlock(developer, ""); err != nil {
+			Fatalf("Failed to unlock developer account: %v", err)
+		}
+		log.Info("Using developer account", "address", developer.Address)
+
+		cfg.Etherbase = developer.Address
+		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkId = cfg.Genesis.Config.ChainId.Uint64()
+		}
+		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
+			cfg.GasPrice = big.NewInt(1)
+		}
 	}
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
@@ -939,6 +1006,8 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	switch {
 	case ctx.GlobalBool(TestnetFlag.Name):
 		genesis = core.DefaultTestnetGenesisBlock()
+	case ctx.GlobalBool(DeveloperFlag.Name):
+		Fatalf("Developer chains are ephemeral")
 	}
 	return genesis
 }
@@ -963,7 +1032,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	engine := dpos.New(config.DPoS, chainDb)
+	engine := protocol.CreateConsensusEngine(nil, config, chainDb)
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
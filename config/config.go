@@ -19,6 +19,7 @@ package config
 import (
 	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/juchain/go-juchain/common"
 	"time"
@@ -53,15 +54,23 @@ var (
 		big.NewInt(1337),
 		big.NewInt(0),
 		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
 		&CliqueConfig{Period: 0, Epoch: 30000},
-		nil, nil}
+		nil, nil, nil, nil}
 
 	TestChainConfig = &ChainConfig{
 		big.NewInt(1),
 		big.NewInt(0),
 		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
+		big.NewInt(0),
 		nil ,
-		new(DPoSConfig), nil}
+		new(DPoSConfig), nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -74,21 +83,179 @@ type ChainConfig struct {
 	ChainId      *big.Int `json:"chainId"` // Chain id identifies the current chain and is used for replay protection
 	EIP158Block  *big.Int `json:"eip158Block,omitempty"` // EIP158 HF block
 	ByzantiumBlock  *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	SystemContractsBlock *big.Int `json:"systemContractsBlock,omitempty"` // Switch block activating the delegator registry and the DApp bridge (nil = no fork, 0 = already activated)
+	DAppSignBlock       *big.Int `json:"dappSignBlock,omitempty"`       // Switch block signing the DApp and anchor of DApp transactions (nil = no fork, 0 = already activated)
+	DAppContextBlock    *big.Int `json:"dappContextBlock,omitempty"`    // Switch block exposing the DApp context contract (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	DPoS   *DPoSConfig   `json:"dpos,omitempty"`
 	DAppId *common.Address `json:"dappid,omitempty"`
+
+	// DApp chain configurations
+	DApps map[common.Address]*DAppConfig `json:"dapps,omitempty"` // Configurations of the DApp chains anchored to the main chain
+	DApp  *DAppConfig                    `json:"dapp,omitempty"`  // Configuration of a DApp chain, nil on the main chain
 }
 
 type DPoSConfig struct{
-	Period   uint64 `json:"period"` // Number of seconds between blocks to enforce
-	Epoch    uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+	Period   uint64 `json:"period"`          // Number of seconds between blocks to enforce
+	Epoch    uint64 `json:"epoch"`           // Epoch length to reset votes and checkpoint
+	Grace    uint64 `json:"grace,omitempty"` // Number of seconds a delegator may still seal its slot after it began
+
+	Unbonding       uint64 `json:"unbonding,omitempty"`       // Number of epochs withdrawn votes stay bonded
+	DoubleSignSlash uint64 `json:"doubleSignSlash,omitempty"` // Per mille of the bonded votes slashed for double signing
+	MissedSlots     uint64 `json:"missedSlots,omitempty"`     // Number of missed slots within an epoch a delegator is slashed for
+	MissedSlotSlash uint64 `json:"missedSlotSlash,omitempty"` // Per mille of the bonded votes slashed for missing slots
+	MissRatio       uint64 `json:"missRatio,omitempty"`       // Per mille of missed slots within an epoch a delegator is jailed above
+	Jail            uint64 `json:"jail,omitempty"`            // Number of epochs a delegator jailed for missing slots is not scheduled
+
+	Reward         *big.Int        `json:"reward,omitempty"`         // Emission of a block in wei before any decay
+	EmissionPeriod uint64          `json:"emissionPeriod,omitempty"` // Number of blocks after which the emission decays, zero to never decay
+	EmissionDecay  uint64          `json:"emissionDecay,omitempty"`  // Per mille the emission decays by every period, 500 halves it
+	Commission     uint64          `json:"commission,omitempty"`     // Per mille of the block reward kept by the producing delegator
+	Treasury       *common.Address `json:"treasury,omitempty"`       // Account receiving a share of every block reward, if any
+	TreasuryShare  uint64          `json:"treasuryShare,omitempty"`  // Per mille of the block reward paid to the treasury
+
 	PoSMode  Mode
 	FakeFail  uint64        // Block number which fails PoS check even in fake mode
 	FakeDelay time.Duration // Time delay to sleep for before returning from verify
 }
 
+// BlockPeriod returns the number of seconds between the slots of the delegators.
+func (c *DPoSConfig) BlockPeriod() uint64 {
+	if c == nil || c.Period == 0 {
+		return DefaultDPoSPeriod
+	}
+	return c.Period
+}
+
+// GraceWindow returns the number of seconds a delegator may still seal its slot
+// after it began, half a period by default. The window ends before the next slot.
+func (c *DPoSConfig) GraceWindow() uint64 {
+	period := c.BlockPeriod()
+	if c == nil || c.Grace == 0 {
+		return period / 2
+	}
+	if c.Grace >= period {
+		return period - 1
+	}
+	return c.Grace
+}
+
+// EpochLength returns the number of blocks of an epoch.
+func (c *DPoSConfig) EpochLength() uint64 {
+	if c == nil || c.Epoch == 0 {
+		return DefaultDPoSEpoch
+	}
+	return c.Epoch
+}
+
+// UnbondingEpochs returns the number of epochs withdrawn votes stay bonded.
+func (c *DPoSConfig) UnbondingEpochs() uint64 {
+	if c == nil || c.Unbonding == 0 {
+		return DefaultUnbondingEpochs
+	}
+	return c.Unbonding
+}
+
+// DoubleSignPenalty returns the per mille of the bonded votes slashed for double signing.
+func (c *DPoSConfig) DoubleSignPenalty() uint64 {
+	if c == nil || c.DoubleSignSlash == 0 {
+		return DefaultDoubleSignSlash
+	}
+	return c.DoubleSignSlash
+}
+
+// MissedSlotsLimit returns the number of missed slots within an epoch a delegator is slashed for.
+func (c *DPoSConfig) MissedSlotsLimit() uint64 {
+	if c == nil || c.MissedSlots == 0 {
+		return DefaultMissedSlots
+	}
+	return c.MissedSlots
+}
+
+// BlockReward returns the emission of the block of the given number, decayed
+// once for every emission period passed.
+func (c *DPoSConfig) BlockReward(number uint64) *big.Int {
+	reward := new(big.Int).Set(DefaultBlockReward)
+	if c == nil {
+		return reward
+	}
+	if c.Reward != nil {
+		reward.Set(c.Reward)
+	}
+	if c.EmissionPeriod == 0 || c.EmissionDecay == 0 {
+		return reward
+	}
+	keep := uint64(0)
+	if c.EmissionDecay < 1000 {
+		keep = 1000 - c.EmissionDecay
+	}
+	return decayedEmission(reward, keep, number/c.EmissionPeriod)
+}
+
+// emissionSchedule identifies a decaying emission by its initial emission and
+// the per mille kept every period.
+type emissionSchedule struct {
+	reward string
+	keep   uint64
+}
+
+var (
+	emissionTables     = make(map[emissionSchedule][]*big.Int) // Emissions of every period, until decayed to zero
+	emissionTablesLock sync.Mutex
+)
+
+// decayedEmission returns the emission after the given number of periods, each
+// keeping the given per mille of the previous one rounded down. The emissions
+// are computed once per schedule and looked up afterwards, ending with the first
+// period emitting nothing.
+func decayedEmission(reward *big.Int, keep uint64, periods uint64) *big.Int {
+	emissionTablesLock.Lock()
+	defer emissionTablesLock.Unlock()
+
+	schedule := emissionSchedule{reward: reward.String(), keep: keep}
+	table := emissionTables[schedule]
+	if table == nil {
+		table = []*big.Int{new(big.Int).Set(reward)}
+	}
+	for uint64(len(table)) <= periods && table[len(table)-1].Sign() > 0 {
+		next := new(big.Int).Mul(table[len(table)-1], new(big.Int).SetUint64(keep))
+		table = append(table, next.Div(next, big.NewInt(1000)))
+	}
+	emissionTables[schedule] = table
+
+	if periods >= uint64(len(table)) {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(table[periods])
+}
+
+// MissedSlotPenalty returns the per mille of the bonded votes slashed for missing slots.
+func (c *DPoSConfig) MissedSlotPenalty() uint64 {
+	if c == nil || c.MissedSlotSlash == 0 {
+		return DefaultMissedSlotSlash
+	}
+	return c.MissedSlotSlash
+}
+
+// MaxMissRatio returns the per mille of missed slots within an epoch a delegator is jailed above.
+func (c *DPoSConfig) MaxMissRatio() uint64 {
+	if c == nil || c.MissRatio == 0 {
+		return DefaultMissRatio
+	}
+	return c.MissRatio
+}
+
+// JailEpochs returns the number of epochs a delegator jailed for missing slots is not scheduled.
+func (c *DPoSConfig) JailEpochs() uint64 {
+	if c == nil || c.Jail == 0 {
+		return DefaultJailEpochs
+	}
+	return c.Jail
+}
+
 // only for test purpose
 type Mode uint
 const (
@@ -111,6 +278,72 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// DAppConfig is the configuration of a DApp chain, setting the economics of its
+// transactions independently of the main chain and the other DApps.
+type DAppConfig struct {
+	GasLimit    uint64          `json:"gasLimit,omitempty"`    // Gas limit of the DApp blocks, zero to follow the gas usage
+	MinGasPrice *big.Int        `json:"minGasPrice,omitempty"` // Minimum gas price of the DApp transactions, nil for none
+	Period      uint64          `json:"period,omitempty"`      // Minimum number of seconds between the DApp blocks
+	FeePayer    *common.Address `json:"feePayer,omitempty"`    // Account paying the fees of the sponsored DApp transactions
+	Sponsored   []common.Address `json:"sponsored,omitempty"`  // Senders whose fees the fee payer covers, the others paying their own
+}
+
+// PaidBy returns the account paying the fees of a DApp transaction, the fee
+// payer if it sponsors the sender, otherwise the sender itself.
+func (c *DAppConfig) PaidBy(sender common.Address) common.Address {
+	if c == nil || c.FeePayer == nil {
+		return sender
+	}
+	for _, addr := range c.Sponsored {
+		if addr == sender {
+			return *c.FeePayer
+		}
+	}
+	return sender
+}
+
+// equal returns whether two DApp configurations set the same rules.
+func (c *DAppConfig) equal(other *DAppConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	if c.GasLimit != other.GasLimit || c.Period != other.Period || !configNumEqual(c.MinGasPrice, other.MinGasPrice) {
+		return false
+	}
+	if (c.FeePayer == nil) != (other.FeePayer == nil) || (c.FeePayer != nil && *c.FeePayer != *other.FeePayer) {
+		return false
+	}
+	if len(c.Sponsored) != len(other.Sponsored) {
+		return false
+	}
+	for i := range c.Sponsored {
+		if c.Sponsored[i] != other.Sponsored[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DAppChainConfig derives the configuration of the chain of a DApp from the main
+// chain configuration.
+func (c *ChainConfig) DAppChainConfig(dappId common.Address) *ChainConfig {
+	cpy := *c
+	cpy.DAppId = &dappId
+	cpy.DApp = c.DApps[dappId]
+	cpy.DApps = nil
+	// DApp chains have no history before the DApp rules of the main chain
+	if c.SystemContractsBlock != nil {
+		cpy.SystemContractsBlock = new(big.Int)
+	}
+	if c.DAppSignBlock != nil {
+		cpy.DAppSignBlock = new(big.Int)
+	}
+	if c.DAppContextBlock != nil {
+		cpy.DAppContextBlock = new(big.Int)
+	}
+	return &cpy
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -132,13 +365,79 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
-// GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
+// IsConstantinople returns whether num is either equal to the Constantinople fork block or greater.
+func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
+	return isForked(c.ConstantinopleBlock, num)
+}
+
+// IsSystemContracts returns whether num is either equal to the system contracts
+// fork block or greater. The native delegator registry and DApp bridge run from
+// then on.
+func (c *ChainConfig) IsSystemContracts(num *big.Int) bool {
+	return isForked(c.SystemContractsBlock, num)
+}
+
+// IsDAppSign returns whether num is either equal to the DApp signing fork block or
+// greater. Transactions of a DApp sign their DApp id and anchor reference from then
+// on.
+func (c *ChainConfig) IsDAppSign(num *big.Int) bool {
+	return isForked(c.DAppSignBlock, num)
+}
+
+// IsDAppContext returns whether num is either equal to the DApp context fork block
+// or greater. Contracts may read the DApp context of their block from then on.
+func (c *ChainConfig) IsDAppContext(num *big.Int) bool {
+	return isForked(c.DAppContextBlock, num)
+}
+
+// GasTable returns the gas table corresponding to the current phase (EIP158 or Constantinople).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
 func (c *ChainConfig) GasTable(num *big.Int) GasTable {
+	if c.IsConstantinople(num) {
+		return GasTableConstantinople
+	}
 	return GasTableEIP158;
 }
 
+// fork is a protocol upgrade scheduled in the chain configuration.
+type fork struct {
+	name  string
+	block *big.Int // Activation block, nil if not scheduled
+}
+
+// forks returns the scheduled protocol upgrades in their activation order. New
+// forks are appended here, which enrolls them in the compatibility and ordering
+// checks of the configuration.
+func (c *ChainConfig) forks() []fork {
+	return []fork{
+		{"EIP158", c.EIP158Block},
+		{"Byzantium", c.ByzantiumBlock},
+		{"Constantinople", c.ConstantinopleBlock},
+		{"SystemContracts", c.SystemContractsBlock},
+		{"DAppSign", c.DAppSignBlock},
+		{"DAppContext", c.DAppContextBlock},
+	}
+}
+
+// CheckForkOrder checks that the forks of the configuration are scheduled in
+// their activation order, and that no fork is scheduled without its predecessors.
+func (c *ChainConfig) CheckForkOrder() error {
+	var last fork
+	for i, cur := range c.forks() {
+		if i > 0 && cur.block != nil {
+			if last.block == nil {
+				return fmt.Errorf("unsupported fork ordering: %s not enabled, but %s enabled at %v", last.name, cur.name, cur.block)
+			}
+			if last.block.Cmp(cur.block) > 0 {
+				return fmt.Errorf("unsupported fork ordering: %s enabled at %v, but %s enabled at %v", last.name, last.block, cur.name, cur.block)
+			}
+		}
+		last = cur
+	}
+	return nil
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -158,36 +457,294 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
-		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
+	stored, scheduled := c.forks(), newcfg.forks()
+	for i := range stored {
+		if isForkIncompatible(stored[i].block, scheduled[i].block, head) {
+			return newCompatError(stored[i].name+" fork block", stored[i].block, scheduled[i].block)
+		}
+	}
+	// The rules of a DApp chain apply from its genesis, blocks past it can't be
+	// kept once they change
+	if head.Sign() > 0 && !c.DApp.equal(newcfg.DApp) {
+		return newCompatError("DApp configuration", new(big.Int), new(big.Int))
 	}
 	return nil
 }
 
 type DAppAddress struct {
 	Addresse []common.Address;
+	Maximum  int    // Maximum number of DApps replicated by this node, unlimited if zero
+	Quota    uint64 // Default disk quota of a DApp chain database in bytes, unlimited if zero
+
+	quotas map[common.Address]uint64   // Disk quotas overriding the default one per DApp
+	usage  map[common.Address]uint64   // Last measured disk usage of the DApp chain databases
+	nodes  map[common.Address][]string // Storage nodes assigned to the DApps
+	static map[common.Address]struct{} // DApps assigned on the command line, never persisted
+
+	lock sync.RWMutex // Protects the addresses of DApp chains attached at runtime
+}
+
+// Add assigns a DApp to this node, returning false if it was assigned already.
+func (c *DAppAddress) Add(dappAddr common.Address) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, addr := range c.Addresse {
+		if addr == dappAddr {
+			return false
+		}
+	}
+	c.Addresse = append(c.Addresse, dappAddr)
+	return true
+}
+
+// AddStatic assigns a DApp given on the command line to this node, returning
+// false if it was assigned already. Static DApps are neither persisted nor
+// removed at runtime.
+func (c *DAppAddress) AddStatic(dappAddr common.Address) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.has(dappAddr) {
+		return false
+	}
+	c.Addresse = append(c.Addresse, dappAddr)
+	if c.static == nil {
+		c.static = make(map[common.Address]struct{})
+	}
+	c.static[dappAddr] = struct{}{}
+	return true
+}
+
+// Static returns whether the DApp was assigned on the command line.
+func (c *DAppAddress) Static(dappAddr common.Address) bool {
+	if c == nil {
+		return false
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	_, ok := c.static[dappAddr]
+	return ok
+}
+
+// Remove unassigns a DApp from this node, returning false if it wasn't assigned
+// or was assigned on the command line. The quota, disk usage and storage nodes
+// of the DApp are forgotten as well.
+func (c *DAppAddress) Remove(dappAddr common.Address) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.static[dappAddr]; ok {
+		return false
+	}
+	for i, addr := range c.Addresse {
+		if addr == dappAddr {
+			c.Addresse = append(c.Addresse[:i:i], c.Addresse[i+1:]...)
+			delete(c.quotas, dappAddr)
+			delete(c.usage, dappAddr)
+			delete(c.nodes, dappAddr)
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the DApps assigned to this node.
+func (c *DAppAddress) List() []common.Address {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]common.Address(nil), c.Addresse...)
 }
 
+// Persisted returns the DApps assigned to this node apart from the static ones,
+// to be reattached on the next start.
+func (c *DAppAddress) Persisted() []common.Address {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	addresses := make([]common.Address, 0, len(c.Addresse))
+	for _, addr := range c.Addresse {
+		if _, ok := c.static[addr]; !ok {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// Full returns whether this node replicates the maximum number of DApps already.
+func (c *DAppAddress) Full() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.Maximum > 0 && len(c.Addresse) >= c.Maximum
+}
+
+// Has returns whether the DApp is assigned to this node.
 func (c *DAppAddress) Has(dappAddr *common.Address) bool {
-	return true;
+	if c == nil || dappAddr == nil {
+		return false
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.has(*dappAddr)
+}
+
+func (c *DAppAddress) has(dappAddr common.Address) bool {
+	for _, addr := range c.Addresse {
+		if addr == dappAddr {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQuota limits the disk space the chain database of a DApp may use, zero
+// falling back to the default quota.
+func (c *DAppAddress) SetQuota(dappAddr common.Address, quota uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if quota == 0 {
+		delete(c.quotas, dappAddr)
+		return
+	}
+	if c.quotas == nil {
+		c.quotas = make(map[common.Address]uint64)
+	}
+	c.quotas[dappAddr] = quota
+}
+
+// Quotas returns the disk quotas set explicitly per DApp.
+func (c *DAppAddress) Quotas() map[common.Address]uint64 {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	quotas := make(map[common.Address]uint64, len(c.quotas))
+	for addr, quota := range c.quotas {
+		quotas[addr] = quota
+	}
+	return quotas
+}
+
+// DiskQuota returns the disk space the chain database of a DApp may use, zero
+// if unlimited.
+func (c *DAppAddress) DiskQuota(dappAddr common.Address) uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if quota, ok := c.quotas[dappAddr]; ok {
+		return quota
+	}
+	return c.Quota
+}
+
+// SetDiskUsage records the measured size of the chain database of a DApp.
+func (c *DAppAddress) SetDiskUsage(dappAddr common.Address, usage uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.usage == nil {
+		c.usage = make(map[common.Address]uint64)
+	}
+	c.usage[dappAddr] = usage
 }
 
+// DiskUsage returns the last measured size of the chain database of a DApp.
+func (c *DAppAddress) DiskUsage(dappAddr common.Address) uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.usage[dappAddr]
+}
+
+// HasDiskSpace returns whether the chain database of an assigned DApp is still
+// below its disk quota.
 func (c *DAppAddress) HasDiskSpace(dappAddr *common.Address) bool {
-	return true;
+	if c == nil || dappAddr == nil {
+		return false
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !c.has(*dappAddr) {
+		return false
+	}
+	quota, ok := c.quotas[*dappAddr]
+	if !ok {
+		quota = c.Quota
+	}
+	return quota == 0 || c.usage[*dappAddr] < quota
 }
 
-func (c *DAppAddress) GetAssignedNodes(dappAddr *common.Address) bool {
-	return false;
+// AssignNodes sets the storage nodes replicating the chain of a DApp.
+func (c *DAppAddress) AssignNodes(dappAddr common.Address, nodes []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(nodes) == 0 {
+		delete(c.nodes, dappAddr)
+		return
+	}
+	if c.nodes == nil {
+		c.nodes = make(map[common.Address][]string)
+	}
+	c.nodes[dappAddr] = append([]string(nil), nodes...)
 }
 
-func (c *DAppAddress) HasAssignedNodes(nodeId string) bool {
-	return false;
+// GetAssignedNodes returns the storage nodes replicating the chain of a DApp.
+func (c *DAppAddress) GetAssignedNodes(dappAddr *common.Address) []string {
+	if c == nil || dappAddr == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]string(nil), c.nodes[*dappAddr]...)
+}
+
+// AssignedNodes returns the storage nodes assigned to the DApps.
+func (c *DAppAddress) AssignedNodes() map[common.Address][]string {
+	if c == nil {
+		return nil
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	nodes := make(map[common.Address][]string, len(c.nodes))
+	for addr, assigned := range c.nodes {
+		nodes[addr] = append([]string(nil), assigned...)
+	}
+	return nodes
+}
+
+// HasAssignedNodes returns whether the node is assigned to store the chain of
+// a DApp.
+func (c *DAppAddress) HasAssignedNodes(dappAddr *common.Address, nodeId string) bool {
+	for _, node := range c.GetAssignedNodes(dappAddr) {
+		if node == nodeId {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *DAppAddress) ToString() []string {
-	array := make([]string, len(c.Addresse))
-	for i := range c.Addresse {
-		array[i] = c.Addresse[i].String()
+	addresses := c.List()
+	array := make([]string, len(addresses))
+	for i := range addresses {
+		array[i] = addresses[i].String()
 	}
 	return array;
 }
@@ -256,6 +813,9 @@ type Rules struct {
 	ChainId     *big.Int
 	IsEIP158     bool
 	IsByzantium  bool
+	IsConstantinople bool
+	IsSystemContracts bool
+	IsDAppContext    bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -263,5 +823,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsEIP158: true, IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsEIP158: true, IsByzantium: c.IsByzantium(num), IsConstantinople: c.IsConstantinople(num), IsSystemContracts: c.IsSystemContracts(num), IsDAppContext: c.IsDAppContext(num)}
 }
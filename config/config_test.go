@@ -17,8 +17,11 @@
 package config
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/juchain/go-juchain/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -30,6 +33,55 @@ func TestCheckCompatible(t *testing.T) {
 	tests := []test{
 		{stored: MainnetChainConfig, new: MainnetChainConfig, head: 0, wantErr: nil},
 		{stored: MainnetChainConfig, new: MainnetChainConfig, head: 100, wantErr: nil},
+		{
+			stored:  &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0)},
+			new:     &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(20)},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0)},
+			new:    &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(20)},
+			head:   25,
+			wantErr: &ConfigCompatError{
+				What:         "Constantinople fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(30), ConstantinopleBlock: big.NewInt(40)},
+			new:    &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(10), ConstantinopleBlock: big.NewInt(10)},
+			head:   50,
+			wantErr: &ConfigCompatError{
+				What:         "Byzantium fork block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{DApp: &DAppConfig{GasLimit: 1000000}},
+			new:    &ChainConfig{DApp: &DAppConfig{GasLimit: 1000000}},
+			head:   10,
+		},
+		{
+			stored: &ChainConfig{DApp: &DAppConfig{GasLimit: 1000000}},
+			new:    &ChainConfig{DApp: &DAppConfig{GasLimit: 2000000}},
+			head:   0,
+		},
+		{
+			stored: &ChainConfig{DApp: &DAppConfig{GasLimit: 1000000}},
+			new:    &ChainConfig{DApp: &DAppConfig{GasLimit: 1000000, Sponsored: []common.Address{{0x01}}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "DApp configuration",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(0),
+				RewindTo:     0,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -39,3 +91,119 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckForkOrder(t *testing.T) {
+	tests := []struct {
+		config *ChainConfig
+		valid  bool
+	}{
+		{config: MainnetChainConfig, valid: true},
+		{config: TestChainConfig, valid: true},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(10)}, valid: true},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ConstantinopleBlock: big.NewInt(10)}, valid: false},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(20), ConstantinopleBlock: big.NewInt(10)}, valid: false},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(0), DAppContextBlock: big.NewInt(10)}, valid: false},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(0), DAppSignBlock: big.NewInt(10)}, valid: false},
+		{config: &ChainConfig{EIP158Block: big.NewInt(0), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(0), SystemContractsBlock: big.NewInt(5), DAppSignBlock: big.NewInt(10)}, valid: true},
+	}
+	for i, test := range tests {
+		if err := test.config.CheckForkOrder(); (err == nil) != test.valid {
+			t.Errorf("test %d: fork order check mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
+
+func TestDPoSBlockReward(t *testing.T) {
+	tests := []struct {
+		config *DPoSConfig
+		number uint64
+		want   *big.Int
+	}{
+		{config: nil, number: 1000000, want: DefaultBlockReward},
+		{config: &DPoSConfig{Reward: big.NewInt(1000)}, number: 1000000, want: big.NewInt(1000)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 500}, number: 9, want: big.NewInt(1000)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 500}, number: 10, want: big.NewInt(500)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 500}, number: 35, want: big.NewInt(125)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 100}, number: 20, want: big.NewInt(810)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 1000}, number: 10, want: new(big.Int)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 1, EmissionDecay: 500}, number: 1<<64 - 1, want: new(big.Int)},
+		{config: &DPoSConfig{Reward: big.NewInt(1000), EmissionPeriod: 10, EmissionDecay: 500}, number: 25, want: big.NewInt(250)},
+	}
+	for i, test := range tests {
+		if have := test.config.BlockReward(test.number); have.Cmp(test.want) != 0 {
+			t.Errorf("test %d: block reward mismatch: have %v, want %v", i, have, test.want)
+		}
+	}
+}
+
+// Tests that only attached DApps are replicated, and only while their chain
+// database stays below its disk quota.
+func TestDAppAddressQuota(t *testing.T) {
+	var (
+		dappA = common.Address{0x0a}
+		dappB = common.Address{0x0b}
+	)
+	dapps := &DAppAddress{Maximum: 2, Quota: 100}
+	if dapps.Has(&dappA) || dapps.HasDiskSpace(&dappA) {
+		t.Fatalf("unattached DApp replicated")
+	}
+	dapps.Add(dappA)
+	if !dapps.Has(&dappA) || dapps.Has(&dappB) || dapps.Full() {
+		t.Fatalf("membership mismatch after attaching")
+	}
+	dapps.Add(dappB)
+	if !dapps.Full() {
+		t.Errorf("maximum number of DApps not enforced")
+	}
+	// The default quota applies unless overridden per DApp
+	dapps.SetDiskUsage(dappA, 150)
+	if dapps.HasDiskSpace(&dappA) {
+		t.Errorf("default quota exceeded but disk space left")
+	}
+	dapps.SetQuota(dappA, 200)
+	if !dapps.HasDiskSpace(&dappA) || dapps.DiskQuota(dappA) != 200 {
+		t.Errorf("overridden quota not applied")
+	}
+	dapps.SetQuota(dappA, 0)
+	if dapps.HasDiskSpace(&dappA) || len(dapps.Quotas()) != 0 {
+		t.Errorf("reset quota not falling back to the default")
+	}
+	// Storage nodes are forgotten along with the DApp
+	dapps.AssignNodes(dappB, []string{"node1", "node2"})
+	if !dapps.HasAssignedNodes(&dappB, "node2") || dapps.HasAssignedNodes(&dappA, "node2") {
+		t.Errorf("storage node assignment mismatch")
+	}
+	dapps.Remove(dappB)
+	if dapps.Has(&dappB) || len(dapps.GetAssignedNodes(&dappB)) != 0 {
+		t.Errorf("detached DApp still replicated")
+	}
+}
+
+// Tests that DApps assigned on the command line are kept apart from the ones
+// attached at runtime, and neither persisted nor removed.
+func TestDAppAddressStatic(t *testing.T) {
+	var (
+		dappA = common.Address{0x0a}
+		dappB = common.Address{0x0b}
+	)
+	dapps := new(DAppAddress)
+	dapps.Add(dappA)
+	if dapps.AddStatic(dappA) || dapps.Static(dappA) {
+		t.Fatalf("attached DApp turned static")
+	}
+	if !dapps.AddStatic(dappB) || !dapps.Static(dappB) || !dapps.Has(&dappB) {
+		t.Fatalf("static DApp not assigned")
+	}
+	if dapps.Add(dappB) {
+		t.Errorf("static DApp attached twice")
+	}
+	if have := dapps.Persisted(); !reflect.DeepEqual(have, []common.Address{dappA}) {
+		t.Errorf("persisted DApps mismatch: have %v, want %v", have, []common.Address{dappA})
+	}
+	if dapps.Remove(dappB) || !dapps.Has(&dappB) {
+		t.Errorf("static DApp removed")
+	}
+	if !dapps.Remove(dappA) || len(dapps.Persisted()) != 0 {
+		t.Errorf("attached DApp not removed")
+	}
+}
@@ -57,10 +57,21 @@ const (
 	TierStepGas      uint64 = 0     // Once per operation, for a selection of them.
 	LogTopicGas      uint64 = 375   // Multiplied by the * of the LOG*, per LOG transaction. e.g. LOG0 incurs 0 * c_txLogTopicGas, LOG4 incurs 4 * c_txLogTopicGas.
 	CreateGas        uint64 = 32000 // Once per CREATE operation & contract-creation transaction.
+	Create2Gas       uint64 = 32000 // Once per CREATE2 operation
 	SuicideRefundGas uint64 = 24000 // Refunded following a suicide operation.
 	MemoryGas        uint64 = 3     // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
+	NetSstoreNoopGas  uint64 = 200   // Once per SSTORE operation if the value doesn't change.
+	NetSstoreInitGas  uint64 = 20000 // Once per SSTORE operation from clean zero.
+	NetSstoreCleanGas uint64 = 5000  // Once per SSTORE operation from clean non-zero.
+	NetSstoreDirtyGas uint64 = 200   // Once per SSTORE operation from dirty.
+	SstoreSentryGas   uint64 = 2300  // Minimum gas left for an SSTORE operation, not consumed.
+
+	NetSstoreClearRefund      uint64 = 15000 // Once per SSTORE operation for clearing an originally existing storage slot
+	NetSstoreResetRefund      uint64 = 4800  // Once per SSTORE operation for resetting to the original non-zero value
+	NetSstoreResetClearRefund uint64 = 19800 // Once per SSTORE operation for resetting to the original zero value
+
 	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
 
 	// Precompiled contract gas prices
@@ -77,6 +88,35 @@ const (
 	Bn256ScalarMulGas       uint64 = 40000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
+
+	// System contract gas prices
+
+	DelegatorRegisterGas    uint64 = 60000 // Price for registering a delegator candidate
+	DelegatorVoteGas        uint64 = 30000 // Price for bonding votes for a delegator candidate
+	DelegatorUnvoteGas      uint64 = 20000 // Price for unbonding the votes of a delegator candidate
+	DelegatorQueryGas       uint64 = 200   // Base price for reading the delegator registry
+	DelegatorListPerItemGas uint64 = 400   // Per-candidate price for listing the top delegators
+	DelegatorWithdrawGas    uint64 = 10000 // Price for releasing unbonded votes
+	DelegatorSlashGas       uint64 = 40000 // Price for verifying and applying double signing evidence
+	DelegatorUnjailGas      uint64 = 20000 // Price for releasing a jailed delegator candidate
+
+	BridgeLockGas    uint64 = 40000 // Price for locking value for a DApp chain
+	BridgeBurnGas    uint64 = 20000 // Price for burning value on a DApp chain
+	BridgeReleaseGas uint64 = 80000 // Price for verifying a burn and releasing its value
+	BridgeQueryGas   uint64 = 200   // Price for reading the DApp bridge
+
+	DAppContextGas uint64 = 200 // Price for reading the DApp context of the block
+
+	// Delegated proof-of-stake defaults
+
+	DefaultDPoSPeriod      uint64 = 5   // Default number of seconds between the slots of the delegators
+	DefaultDPoSEpoch       uint64 = 310 // Default number of blocks after which the delegator set is checkpointed
+	DefaultUnbondingEpochs uint64 = 3   // Default number of epochs withdrawn votes stay bonded
+	DefaultDoubleSignSlash uint64 = 50  // Default per mille of the bonded votes slashed for double signing
+	DefaultMissedSlots     uint64 = 10  // Default number of missed slots within an epoch a delegator is slashed for
+	DefaultMissedSlotSlash uint64 = 10  // Default per mille of the bonded votes slashed for missing slots
+	DefaultMissRatio       uint64 = 500 // Default per mille of missed slots within an epoch a delegator is jailed above
+	DefaultJailEpochs      uint64 = 1   // Default number of epochs a delegator jailed for missing slots is not scheduled
 )
 
 var (
@@ -84,4 +124,6 @@ var (
 	GenesisDifficulty      = big.NewInt(131072) // Difficulty of the Genesis block.
 	MinimumDifficulty      = big.NewInt(131072) // The minimum that the difficulty may ever be.
 	DurationLimit          = big.NewInt(13)     // The decision boundary on the blocktime duration used to determine whether difficulty should go up or not.
+
+	DefaultBlockReward = new(big.Int).Mul(big.NewInt(3), big.NewInt(Ether)) // Default emission of a delegated proof-of-stake block
 )
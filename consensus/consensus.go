@@ -105,3 +105,36 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// Slasher is a consensus engine able to prove the misbehaviour of block producers.
+type Slasher interface {
+	Engine
+
+	// VerifyDoubleSign checks whether both headers were sealed by the same block
+	// producer for the same slot, returning the id of the producer.
+	VerifyDoubleSign(first, second *types.Header) (string, error)
+
+	// VerifyDoublePreCommit checks whether both finality votes were cast by the
+	// same block producer for different blocks of the same height, returning the
+	// id of the producer.
+	VerifyDoublePreCommit(first, second *types.PreCommit) (string, error)
+}
+
+// DAppSealer is a consensus engine sealing the blocks of the DApp chains
+// anchored to its chain.
+type DAppSealer interface {
+	Engine
+
+	// VerifyDAppSeal checks whether the DApp block header was sealed by the block
+	// producer of the main chain block anchoring it.
+	VerifyDAppSeal(anchor, header *types.Header) error
+}
+
+// Finalizer is a consensus engine able to prove the finality of blocks.
+type Finalizer interface {
+	Engine
+
+	// VerifyFinality checks whether the certificate holds the votes of a qualified
+	// majority of the block producers for its block to become final.
+	VerifyFinality(chain ChainReader, cert *types.FinalityCertificate) error
+}
@@ -274,9 +274,10 @@ func (dpos *DElection) verifyHeader(chain ChainReader, header, parent *types.Hea
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), config.MaximumExtraDataSize)
 	}
 	if !bytes.Equal(header.DAppID.Bytes(),types.EmptyDAppIdHash.Bytes()) {
-		//todo dapp block verification
+		// DApp blocks must refer to the main chain block anchoring them, the anchor
+		// itself is verified against the main chain on import
 		if bytes.Equal(header.DAppMainHash.Bytes(), types.EmptyHash.Bytes()) {
-
+			return ErrMissingAnchor
 		}
 	}
 	// Verify the header's timestamp
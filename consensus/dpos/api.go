@@ -0,0 +1,352 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+	"github.com/juchain/go-juchain/rpc"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+var (
+	// errStateUnavailable is returned when the chain the API was created with is
+	// not able to provide the state of a block.
+	errStateUnavailable = errors.New("state unavailable")
+
+	// errUnknownDelegator is returned when the requested delegator is not
+	// registered in the delegator registry.
+	errUnknownDelegator = errors.New("unknown delegator")
+
+	// errFinalityUnavailable is returned when the chain the API was created with
+	// does not track the finality of blocks.
+	errFinalityUnavailable = errors.New("finality unavailable")
+)
+
+// stateReader is implemented by chains which are able to open the state of a
+// block, as core.BlockChain does.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// finalityReader is implemented by chains tracking the finality of blocks, as
+// core.BlockChain does.
+type finalityReader interface {
+	CurrentFinalizedHeader() *types.Header
+	GetFinalityCertificate(hash common.Hash) *types.FinalityCertificate
+}
+
+// EpochInfo is the position of a block within its epoch.
+type EpochInfo struct {
+	Number     uint64      `json:"number"`     // Block the information was retrieved for
+	Epoch      uint64      `json:"epoch"`      // Epoch the block belongs to
+	Length     uint64      `json:"length"`     // Number of blocks of an epoch
+	FirstBlock uint64      `json:"firstBlock"` // First block of the epoch
+	LastBlock  uint64      `json:"lastBlock"`  // Checkpoint block closing the epoch
+	Checkpoint common.Hash `json:"checkpoint"` // Hash of the block the delegators of the epoch were recorded in
+	Delegators []string    `json:"delegators"` // Delegators of the epoch in their packaging order
+}
+
+// BlockProducer is the delegator which produced a block.
+type BlockProducer struct {
+	Number    uint64         `json:"number"`    // Number of the block
+	Hash      common.Hash    `json:"hash"`      // Hash of the block
+	Round     uint64         `json:"round"`     // Round the block was produced in
+	Delegator string         `json:"delegator"` // Delegator which sealed the block
+	Scheduled bool           `json:"scheduled"` // Whether the delegator was scheduled for the round
+	Coinbase  common.Address `json:"coinbase"`  // Account receiving the transaction fees of the block
+}
+
+// DPoSAPI is a user facing RPC API to inspect the delegated proof-of-stake
+// scheme.
+type DPoSAPI struct {
+	chain consensus.ChainReader
+	dpos  *DElection
+}
+
+func (api *DPoSAPI) Test() string {
+	return "test"
+}
+
+// header retrieves the header of the requested block number, or the current
+// header if none requested.
+func (api *DPoSAPI) header(number *rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	switch {
+	case number == nil || *number == rpc.LatestBlockNumber:
+		header = api.chain.CurrentHeader()
+	case *number == rpc.FinalizedBlockNumber:
+		reader, ok := api.chain.(finalityReader)
+		if !ok {
+			return nil, errFinalityUnavailable
+		}
+		header = reader.CurrentFinalizedHeader()
+	default:
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+// state opens the state of the requested block number.
+func (api *DPoSAPI) state(number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, ok := api.chain.(stateReader)
+	if !ok {
+		return nil, nil, errStateUnavailable
+	}
+	statedb, err := reader.StateAt(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, statedb, nil
+}
+
+// schedule retrieves the delegator schedule the given block was produced under.
+func (api *DPoSAPI) schedule(header *types.Header) (*Schedule, error) {
+	if header.Number.Sign() == 0 {
+		return api.dpos.Schedule(api.chain, header)
+	}
+	return api.dpos.schedule(api.chain, header.Number.Uint64(), header.ParentHash, nil)
+}
+
+// GetRewards retrieves how the emission of the given block was distributed
+// between the treasury, the producing delegator and its voters.
+func (api *DPoSAPI) GetRewards(number *rpc.BlockNumber) (map[string]interface{}, error) {
+	header, statedb, err := api.state(number)
+	if err != nil {
+		return nil, err
+	}
+	reward := vm.GetBlockReward(statedb)
+	return map[string]interface{}{
+		"number":     (*hexutil.Big)(header.Number),
+		"delegator":  reward.Delegator,
+		"coinbase":   header.Coinbase,
+		"emission":   (*hexutil.Big)(reward.Emission),
+		"treasury":   (*hexutil.Big)(reward.Treasury),
+		"commission": (*hexutil.Big)(reward.Commission),
+		"voters":     (*hexutil.Big)(reward.Voters),
+	}, nil
+}
+
+// GetEmission retrieves the emission of the given block according to the
+// emission schedule of the chain.
+func (api *DPoSAPI) GetEmission(number *rpc.BlockNumber) (*hexutil.Big, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(api.dpos.config.BlockReward(header.Number.Uint64())), nil
+}
+
+// GetLiveness retrieves the slots produced and missed by the delegators scheduled
+// in the epoch of the given block, within that epoch and ever.
+func (api *DPoSAPI) GetLiveness(number *rpc.BlockNumber) (map[string]*vm.DelegatorLiveness, error) {
+	header, statedb, err := api.state(number)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := api.schedule(header)
+	if err != nil {
+		return nil, err
+	}
+	liveness := make(map[string]*vm.DelegatorLiveness)
+	for _, delegator := range schedule.Delegators {
+		if record := vm.GetDelegatorLiveness(statedb, delegator, schedule.Epoch); record != nil {
+			liveness[delegator] = record
+		}
+	}
+	return liveness, nil
+}
+
+// GetDelegatorLiveness retrieves the slots produced and missed by a registered
+// delegator, within the epoch of the given block and ever.
+func (api *DPoSAPI) GetDelegatorLiveness(id string, number *rpc.BlockNumber) (*vm.DelegatorLiveness, error) {
+	header, statedb, err := api.state(number)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := api.schedule(header)
+	if err != nil {
+		return nil, err
+	}
+	liveness := vm.GetDelegatorLiveness(statedb, id, schedule.Epoch)
+	if liveness == nil {
+		return nil, errUnknownDelegator
+	}
+	return liveness, nil
+}
+
+// delegatorId converts a node id into the short form the delegators are known
+// by. Short ids are returned as they are.
+func delegatorId(nodeId string) string {
+	if id, err := discover.HexID(nodeId); err == nil {
+		return id.TerminalString()
+	}
+	return nodeId
+}
+
+// GetDelegators retrieves the delegators scheduled in the epoch of the given
+// block, in their packaging order.
+func (api *DPoSAPI) GetDelegators(number *rpc.BlockNumber) ([]string, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := api.schedule(header)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.Delegators, nil
+}
+
+// GetSchedule retrieves the delegator schedule of the given epoch, or of the
+// current one if none requested. Only epochs whose delegators were already
+// recorded in a checkpoint block are known.
+func (api *DPoSAPI) GetSchedule(epoch *hexutil.Uint64) (*Schedule, error) {
+	if epoch == nil {
+		header, err := api.header(nil)
+		if err != nil {
+			return nil, err
+		}
+		return api.schedule(header)
+	}
+	checkpoint := api.chain.GetHeaderByNumber(uint64(*epoch) * api.dpos.epochLength())
+	if checkpoint == nil {
+		return nil, errUnknownBlock
+	}
+	return api.dpos.Schedule(api.chain, checkpoint)
+}
+
+// GetCandidate retrieves a candidate of the delegator registry by its full or
+// short node id, at the given block.
+func (api *DPoSAPI) GetCandidate(nodeId string, number *rpc.BlockNumber) (*vm.DelegatorCandidate, error) {
+	_, statedb, err := api.state(number)
+	if err != nil {
+		return nil, err
+	}
+	candidate := vm.GetDelegatorCandidate(statedb, delegatorId(nodeId))
+	if candidate == nil {
+		return nil, errUnknownDelegator
+	}
+	return candidate, nil
+}
+
+// GetVotes retrieves the votes an account bonded in the delegator registry, at
+// the given block.
+func (api *DPoSAPI) GetVotes(address common.Address, number *rpc.BlockNumber) (*vm.DelegatorStake, error) {
+	_, statedb, err := api.state(number)
+	if err != nil {
+		return nil, err
+	}
+	return vm.GetDelegatorStake(statedb, address), nil
+}
+
+// GetEpochInfo retrieves the epoch of the given block, its bounds and the
+// delegators scheduled in it.
+func (api *DPoSAPI) GetEpochInfo(number *rpc.BlockNumber) (*EpochInfo, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := api.schedule(header)
+	if err != nil {
+		return nil, err
+	}
+	length := api.dpos.epochLength()
+	return &EpochInfo{
+		Number:     header.Number.Uint64(),
+		Epoch:      schedule.Epoch,
+		Length:     length,
+		FirstBlock: schedule.Epoch*length + 1,
+		LastBlock:  (schedule.Epoch + 1) * length,
+		Checkpoint: schedule.Checkpoint,
+		Delegators: schedule.Delegators,
+	}, nil
+}
+
+// GetProducerOfBlock retrieves the delegator which produced the given block and
+// whether it was scheduled for the round of the block.
+func (api *DPoSAPI) GetProducerOfBlock(number *rpc.BlockNumber) (*BlockProducer, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	producer := &BlockProducer{
+		Number:    header.Number.Uint64(),
+		Hash:      header.Hash(),
+		Round:     header.Round,
+		Delegator: header.PresidentId,
+		Coinbase:  header.Coinbase,
+	}
+	if header.Number.Sign() > 0 {
+		schedule, err := api.schedule(header)
+		if err != nil {
+			return nil, err
+		}
+		producer.Scheduled = schedule.Authorizes(header.Round, header.PresidentId)
+	}
+	return producer, nil
+}
+
+// GetFinalityCertificate retrieves the pre-commit votes proving the finality of
+// the given block, or of the last finalized block if none requested. Blocks below
+// the last finalized one are final as well, but only carry a certificate if they
+// were finalized on their own.
+func (api *DPoSAPI) GetFinalityCertificate(number *rpc.BlockNumber) (*types.FinalityCertificate, error) {
+	reader, ok := api.chain.(finalityReader)
+	if !ok {
+		return nil, errFinalityUnavailable
+	}
+	if number == nil {
+		finalized := rpc.FinalizedBlockNumber
+		number = &finalized
+	}
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return reader.GetFinalityCertificate(header.Hash()), nil
+}
+
+// EvidenceAPI is a user facing RPC API to inspect the double signing detected by
+// the evidence pool.
+type EvidenceAPI struct {
+	pool *EvidencePool
+}
+
+// NewEvidenceAPI creates the RPC API of the given evidence pool.
+func NewEvidenceAPI(pool *EvidencePool) *EvidenceAPI {
+	return &EvidenceAPI{pool: pool}
+}
+
+// GetEvidence retrieves the proofs of double signing delegators which were not
+// yet used to slash them, ordered by their hash.
+func (api *EvidenceAPI) GetEvidence() []*Evidence {
+	return api.pool.Pending(nil)
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/rpc"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// testerCanonicalChain is a chain reader over a canonical list of headers, all
+// sharing the same state.
+type testerCanonicalChain struct {
+	*testerChainReader
+	canonical []*types.Header
+	state     *state.StateDB
+}
+
+func newTesterCanonicalChain(headers []*types.Header, statedb *state.StateDB) *testerCanonicalChain {
+	chain := &testerCanonicalChain{
+		testerChainReader: &testerChainReader{headers: make(map[common.Hash]*types.Header)},
+		canonical:         headers,
+		state:             statedb,
+	}
+	for _, header := range headers {
+		chain.headers[header.Hash()] = header
+	}
+	return chain
+}
+
+func (c *testerCanonicalChain) CurrentHeader() *types.Header { return c.canonical[len(c.canonical)-1] }
+func (c *testerCanonicalChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.canonical)) {
+		return nil
+	}
+	return c.canonical[number]
+}
+func (c *testerCanonicalChain) StateAt(root common.Hash) (*state.StateDB, error) { return c.state, nil }
+
+// Tests that the dpos RPC API reports the schedule, the epochs, the producers of
+// the blocks and the registry contents of the chain.
+func TestDPoSAPI(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// Register a delegator with some votes and create two epochs of three blocks
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, config.TestChainConfig, vm.Config{})
+
+	key, id := testerDelegator(1)
+	voter := common.BytesToAddress([]byte{1})
+
+	registry, _ := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
+	register := testerRegistration(registry, voter, key)
+	vote, _ := registry.Pack("vote", id)
+	if _, _, err := evm.Call(vm.AccountRef(voter), vm.DelegatorRegistryAddress, register, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if _, _, err := evm.Call(vm.AccountRef(voter), vm.DelegatorRegistryAddress, vote, 1000000, big.NewInt(1000)); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	headers := newTesterHeaderChain(6, 3)
+	for _, header := range headers[1:] {
+		header.PresidentId = testerCheckpointDelegators(0)[0]
+	}
+	for i := 1; i < len(headers); i++ {
+		headers[i].ParentHash = headers[i-1].Hash()
+	}
+	api := &DPoSAPI{chain: newTesterCanonicalChain(headers, statedb), dpos: New(&config.DPoSConfig{Epoch: 3}, nil)}
+
+	// Block 4 belongs to the second epoch, scheduled by the checkpoint of block 3
+	number := rpc.BlockNumber(4)
+	info, err := api.GetEpochInfo(&number)
+	if err != nil {
+		t.Fatalf("failed to retrieve epoch info: %v", err)
+	}
+	if info.Epoch != 1 || info.FirstBlock != 4 || info.LastBlock != 6 || info.Checkpoint != headers[3].Hash() {
+		t.Errorf("epoch info mismatch: have %+v", info)
+	}
+	delegators, err := api.GetDelegators(&number)
+	if err != nil {
+		t.Fatalf("failed to retrieve delegators: %v", err)
+	}
+	if !reflect.DeepEqual(delegators, info.Delegators) {
+		t.Errorf("delegators mismatch: have %v, want %v", delegators, info.Delegators)
+	}
+	epoch := hexutil.Uint64(1)
+	schedule, err := api.GetSchedule(&epoch)
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule: %v", err)
+	}
+	if schedule.Epoch != 1 || !reflect.DeepEqual(schedule.Delegators, info.Delegators) {
+		t.Errorf("schedule mismatch: have %+v, want delegators %v", schedule, info.Delegators)
+	}
+	epoch = 2
+	if _, err := api.GetSchedule(&epoch); err != errUnknownBlock {
+		t.Errorf("unrecorded schedule: have %v, want %v", err, errUnknownBlock)
+	}
+	// Only the producers of the rounds scheduled for them are reported as such
+	number = rpc.BlockNumber(1)
+	producer, err := api.GetProducerOfBlock(&number)
+	if err != nil {
+		t.Fatalf("failed to retrieve producer: %v", err)
+	}
+	first, _ := api.dpos.Schedule(api.chain, headers[0])
+	if want := first.Producer(1) == headers[1].PresidentId; producer.Delegator != headers[1].PresidentId || producer.Scheduled != want {
+		t.Errorf("producer mismatch: have %+v, want scheduled %v", producer, want)
+	}
+	// The registry contents are read from the state of the block
+	if candidate, err := api.GetCandidate(id, nil); err != nil || candidate.Votes.Int64() != 1000 {
+		t.Errorf("candidate mismatch: have %+v, %v", candidate, err)
+	}
+	if _, err := api.GetCandidate("bbbbbbbbbbbbbbbb", nil); err != errUnknownDelegator {
+		t.Errorf("unknown candidate: have %v, want %v", err, errUnknownDelegator)
+	}
+	if stake, err := api.GetVotes(voter, nil); err != nil || stake.Candidate != id || stake.Bonded.Int64() != 1000 {
+		t.Errorf("votes mismatch: have %+v, %v", stake, err)
+	}
+}
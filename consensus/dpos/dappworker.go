@@ -0,0 +1,327 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// maxAnchorGap is the maximum number of main chain blocks a DApp chain catches up
+// on at once, older anchors are skipped.
+const maxAnchorGap = 1024
+
+// DAppPackager packages the DApp transactions anchored by every new main chain
+// block into a block of their DApp chain, executed against the state of that
+// chain and pointing back to the anchoring main chain block. Only the delegator
+// which produced the anchor packages and seals the DApp block, the other
+// replicas of the DApp chain import it from the peer group of the DApp.
+type DAppPackager struct {
+	config *config.ChainConfig
+	engine *DElection       // engine sealing the DApp blocks with the key of the local delegator
+	chain  *core.BlockChain // main chain anchoring the DApp blocks
+	txPool *core.TxPool
+	mux    *event.TypeMux
+
+	mu       sync.Mutex
+	chains   map[common.Address]*core.BlockChain // DApp chains to package, by DApp id
+	anchored map[common.Address]uint64           // Number of the last main block packaged per DApp chain
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+}
+
+// NewDAppPackager creates a packager of the given DApp chains, following the
+// head of the main chain. Packaged blocks are posted to the mux to be gossiped
+// to the peer group of their DApp.
+func NewDAppPackager(config *config.ChainConfig, engine *DElection, chain *core.BlockChain, chains map[common.Address]*core.BlockChain, txPool *core.TxPool, mux *event.TypeMux) *DAppPackager {
+	packager := &DAppPackager{
+		config:      config,
+		engine:      engine,
+		chain:       chain,
+		txPool:      txPool,
+		mux:         mux,
+		chains:      make(map[common.Address]*core.BlockChain),
+		anchored:    make(map[common.Address]uint64),
+		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+	}
+	head := chain.CurrentBlock().NumberU64()
+	for dappId, dappChain := range chains {
+		packager.chains[dappId] = dappChain
+		packager.anchored[dappId] = head
+	}
+	packager.chainHeadSub = chain.SubscribeChainHeadEvent(packager.chainHeadCh)
+
+	go packager.loop()
+	return packager
+}
+
+// Stop stops following the main chain.
+func (self *DAppPackager) Stop() {
+	self.chainHeadSub.Unsubscribe()
+}
+
+// Attach starts packaging a DApp chain from the current head of the main chain.
+func (self *DAppPackager) Attach(dappId common.Address, dappChain *core.BlockChain) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.chains[dappId] = dappChain
+	self.anchored[dappId] = self.chain.CurrentBlock().NumberU64()
+}
+
+// Detach stops packaging a DApp chain.
+func (self *DAppPackager) Detach(dappId common.Address) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	delete(self.chains, dappId)
+	delete(self.anchored, dappId)
+}
+
+func (self *DAppPackager) loop() {
+	for {
+		select {
+		case ev := <-self.chainHeadCh:
+			self.commitAnchored(ev.Block)
+
+		case <-self.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// commitAnchored packages the DApp transactions anchored by the main chain blocks
+// up to the given head which were not packaged yet.
+func (self *DAppPackager) commitAnchored(head *types.Block) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	number := head.NumberU64()
+	for dappId, dappChain := range self.chains {
+		from := self.anchored[dappId] + 1
+		if number >= maxAnchorGap && from <= number-maxAnchorGap {
+			from = number - maxAnchorGap + 1
+		}
+		for n := from; n <= number; n++ {
+			anchor := head
+			if n < number {
+				if anchor = self.chain.GetBlockByNumber(n); anchor == nil {
+					continue
+				}
+			}
+			if _, err := self.commit(dappId, dappChain, anchor); err != nil {
+				log.Error("Failed to package DApp block", "dapp", dappId, "anchor", anchor.Number(), "err", err)
+			}
+		}
+		self.anchored[dappId] = number
+	}
+}
+
+// Commit packages the DApp transactions anchored by a main chain block into a
+// new block of the DApp chain, minting the value locked for the DApp first. No
+// block is created if the local delegator did not produce the anchor, nothing
+// was minted and none of the transactions could be executed.
+func (self *DAppPackager) Commit(dappId common.Address, anchor *types.Block) (*types.Block, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	dappChain, ok := self.chains[dappId]
+	if !ok {
+		return nil, nil
+	}
+	return self.commit(dappId, dappChain, anchor)
+}
+
+func (self *DAppPackager) commit(dappId common.Address, dappChain *core.BlockChain, anchor *types.Block) (*types.Block, error) {
+	if !self.engine.authorized(anchor.Header().PresidentId) {
+		return nil, nil
+	}
+	txs := self.txPool.DAppTransactions(dappId, anchor.Transactions())
+
+	// The block anchored by the main block may have been imported from the peer
+	// group of the DApp already
+	parent := dappChain.CurrentBlock()
+	if parent.Header().DAppMainHash == anchor.Hash() {
+		self.txPool.RemoveDAppTransactions(dappId, parent.Transactions())
+		return nil, nil
+	}
+	statedb, err := dappChain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	// The DApp chain sets its own gas limit and block period
+	dappConfig := dappChain.Config()
+	header := &types.Header{
+		ParentHash:   parent.Hash(),
+		Number:       new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:     core.CalcDAppGasLimit(dappConfig, parent),
+		Time:         core.CalcDAppTime(dappConfig, parent, anchor.Header()),
+		Difficulty:   big.NewInt(1),
+		Coinbase:     anchor.Coinbase(),
+		Extra:        make([]byte, extraSeal),
+		Round:        anchor.Round(),
+		PresidentId:  anchor.Header().PresidentId,
+		DAppID:       dappId,
+		DAppMainHash: anchor.Hash(),
+	}
+	// Value locked for the DApp on the main chain is minted before the transactions,
+	// once the block locking it is buried deep enough below the anchor
+	if source := core.DAppDepositSource(self.chain, anchor.Header()); source != nil {
+		header.MixDigest = source.Hash()
+	}
+	minted, err := core.ApplyDAppDeposits(self.chain, header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 && minted == 0 {
+		return nil, nil
+	}
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		included []*types.Transaction
+		invalid  []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for _, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+
+		snap := statedb.Snapshot()
+		receipt, _, err := core.ApplyTransaction(dappConfig, dappChain, &header.Coinbase, gp, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			statedb.RevertToSnapshot(snap)
+			log.Debug("Skipping failed DApp transaction", "dapp", dappId, "hash", tx.Hash(), "err", err)
+			if invalidDAppTx(err) {
+				invalid = append(invalid, tx)
+			}
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+	// Transactions skipped for a temporary reason, like a full block, stay pending
+	// in case their anchor is packaged again, the invalid ones never execute
+	if len(invalid) > 0 {
+		self.txPool.RemoveDAppTransactions(dappId, invalid)
+	}
+	if len(included) == 0 && minted == 0 {
+		return nil, nil
+	}
+	header.Root = statedb.IntermediateRoot(true)
+	block, err := self.engine.sealDApp(types.NewBlock(header, included, nil, receipts))
+	if err != nil {
+		return nil, err
+	}
+
+	// The block hash is only known now, update the logs created on execution
+	for _, r := range receipts {
+		for _, l := range r.Logs {
+			l.BlockHash = block.Hash()
+		}
+	}
+	for _, l := range statedb.Logs() {
+		l.BlockHash = block.Hash()
+	}
+	stat, err := dappChain.WriteBlockWithState(block, receipts, statedb)
+	if err != nil {
+		return nil, err
+	}
+	self.txPool.RemoveDAppTransactions(dappId, included)
+
+	var (
+		events []interface{}
+		logs   = statedb.Logs()
+	)
+	events = append(events, core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+	if stat == core.CanonStatTy {
+		events = append(events, core.ChainHeadEvent{Block: block})
+	}
+	dappChain.PostChainEvents(events, logs)
+	self.mux.Post(core.NewMinedBlockEvent{Block: block})
+
+	log.Info("Packaged DApp block", "dapp", dappId, "number", block.Number(), "txs", len(included), "deposits", minted, "anchor", anchor.Number(), "hash", block.Hash())
+	return block, nil
+}
+
+// invalidDAppTx returns whether a DApp transaction failed to execute for a
+// reason packaging it again can't fix: its nonce or its signature.
+func invalidDAppTx(err error) bool {
+	switch err {
+	case core.ErrNonceTooLow, core.ErrNonceTooHigh, core.ErrInvalidSender, types.ErrInvalidSig, types.ErrInvalidChainId:
+		return true
+	}
+	return false
+}
+
+// authorized returns whether the local delegator is the given president.
+func (dpos *DElection) authorized(presidentId string) bool {
+	dpos.lock.RLock()
+	defer dpos.lock.RUnlock()
+
+	return dpos.signFn != nil && dpos.presidentId == presidentId
+}
+
+// sealDApp seals a DApp block with the node key of the local delegator, which
+// must have produced the main chain block anchoring it.
+func (dpos *DElection) sealDApp(block *types.Block) (*types.Block, error) {
+	header := block.Header()
+	if len(header.Extra) < extraSeal {
+		return nil, errMissingSignature
+	}
+	dpos.lock.RLock()
+	presidentId, signFn := dpos.presidentId, dpos.signFn
+	dpos.lock.RUnlock()
+
+	if signFn == nil || presidentId != header.PresidentId {
+		return nil, errUnauthorizedSealer
+	}
+	sighash, err := signFn(sigHash(header).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+
+	return block.WithSeal(header), nil
+}
+
+// VerifyDAppSeal implements consensus.DAppSealer, checking whether a DApp block
+// header was sealed by the delegator which produced the main chain block it is
+// anchored to, for the round of that block.
+func (dpos *DElection) VerifyDAppSeal(anchor, header *types.Header) error {
+	if dpos.config.PoSMode == config.ModeFullFake {
+		return nil
+	}
+	if header.Round != anchor.Round || header.PresidentId != anchor.PresidentId {
+		return errUnauthorizedDelegator
+	}
+	signer, err := ecrecover(header, dpos.signatures)
+	if err != nil {
+		return err
+	}
+	if signer != header.PresidentId {
+		return errInvalidSigner
+	}
+	return nil
+}
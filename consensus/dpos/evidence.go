@@ -0,0 +1,299 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+const (
+	inmemorySlots = 4096 // Number of recent delegator slots to remember the sealed header of
+
+	evidenceChanSize = 10 // Size of the channels listening to imported blocks
+)
+
+// evidenceKey is the database key the pending double signing evidence is stored under.
+var evidenceKey = []byte("dpos-evidence")
+
+// Evidence proves that a delegator sealed two different blocks for the same slot,
+// or voted for two different blocks of the same height to become final.
+type Evidence struct {
+	Hash      common.Hash        `json:"hash"`                       // Hash the evidence is recorded under once used for slashing
+	Delegator string             `json:"delegator"`                  // Delegator which sealed both blocks or cast both votes
+	Round     uint64             `json:"round"`                      // Round both blocks were sealed for
+	First     *types.Header      `json:"first" rlp:"nil"`            // Header of the block seen first, nil for conflicting votes
+	Second    *types.Header      `json:"second" rlp:"nil"`           // Header of the conflicting block, nil for conflicting votes
+	Votes     []*types.PreCommit `json:"votes,omitempty" rlp:"tail"` // Conflicting pre-commit votes, empty for double sealing
+}
+
+// used returns whether the evidence was already used to slash its delegator in
+// the given state.
+func (evidence *Evidence) used(statedb vm.StateDB) bool {
+	if len(evidence.Votes) == 2 {
+		return vm.PreCommitEvidenceUsed(statedb, evidence.Votes[0], evidence.Votes[1])
+	}
+	return vm.EvidenceUsed(statedb, evidence.First, evidence.Second)
+}
+
+// pack assembles the input of the registry call slashing the delegator with
+// the evidence.
+func (evidence *Evidence) pack() ([]byte, error) {
+	if len(evidence.Votes) == 2 {
+		return vm.PackSlashPreCommit(evidence.Votes[0], evidence.Votes[1])
+	}
+	return vm.PackSlash(evidence.First, evidence.Second)
+}
+
+// DoubleSignEvent is posted when double signing of a delegator was detected.
+type DoubleSignEvent struct{ Evidence *Evidence }
+
+// evidenceSlot identifies the slot a delegator sealed a header for.
+type evidenceSlot struct {
+	delegator string
+	dappId    common.Address
+	round     uint64
+}
+
+// EvidencePool detects delegators sealing conflicting headers, whether gossiped
+// by peers or imported into the chain, and keeps the proofs until they are used
+// to slash the offenders.
+type EvidencePool struct {
+	engine *DElection
+	chain  *core.BlockChain
+	db     store.Database
+	mux    *event.TypeMux
+
+	headers  *lru.ARCCache             // Sealed headers of recent slots
+	evidence map[common.Hash]*Evidence // Proofs not yet used to slash their delegator
+	lock     sync.RWMutex
+
+	chainCh  chan core.ChainEvent
+	chainSub event.Subscription
+	sideCh   chan core.ChainSideEvent
+	sideSub  event.Subscription
+}
+
+// NewEvidencePool creates a pool watching the blocks imported into the chain,
+// restoring the evidence persisted in the database.
+func NewEvidencePool(engine *DElection, chain *core.BlockChain, db store.Database, mux *event.TypeMux) *EvidencePool {
+	headers, _ := lru.NewARC(inmemorySlots)
+	pool := &EvidencePool{
+		engine:   engine,
+		chain:    chain,
+		db:       db,
+		mux:      mux,
+		headers:  headers,
+		evidence: make(map[common.Hash]*Evidence),
+		chainCh:  make(chan core.ChainEvent, evidenceChanSize),
+		sideCh:   make(chan core.ChainSideEvent, evidenceChanSize),
+	}
+	if blob, _ := db.Get(evidenceKey); len(blob) > 0 {
+		var stored []*Evidence
+		if err := rlp.DecodeBytes(blob, &stored); err != nil {
+			log.Error("Invalid double signing evidence RLP", "err", err)
+		}
+		for _, evidence := range stored {
+			pool.evidence[evidence.Hash] = evidence
+		}
+	}
+	pool.chainSub = chain.SubscribeChainEvent(pool.chainCh)
+	pool.sideSub = chain.SubscribeChainSideEvent(pool.sideCh)
+
+	go pool.loop()
+	return pool
+}
+
+func (pool *EvidencePool) loop() {
+	defer pool.chainSub.Unsubscribe()
+	defer pool.sideSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-pool.chainCh:
+			pool.Observe(ev.Block.Header())
+			if statedb, err := pool.chain.StateAt(ev.Block.Root()); err == nil {
+				pool.Prune(statedb)
+			}
+		case ev := <-pool.sideCh:
+			pool.Observe(ev.Block.Header())
+
+		case <-pool.chainSub.Err():
+			return
+		case <-pool.sideSub.Err():
+			return
+		}
+	}
+}
+
+// Stop stops watching the imported blocks.
+func (pool *EvidencePool) Stop() {
+	pool.chainSub.Unsubscribe()
+	pool.sideSub.Unsubscribe()
+}
+
+// Observe checks a sealed header against the header the same delegator sealed
+// for the same slot before. If they differ, the proof of the double signing is
+// recorded, persisted and announced. Headers are only remembered once their seal
+// was verified, headers with an invalid seal or an unknown parent are ignored.
+func (pool *EvidencePool) Observe(header *types.Header) *Evidence {
+	if header.Number.Sign() == 0 || len(header.Extra) < extraSeal {
+		return nil
+	}
+	if err := pool.engine.VerifySeal(pool.chain, header); err != nil {
+		return nil
+	}
+	signer, err := pool.engine.Signer(header)
+	if err != nil || signer != header.PresidentId {
+		return nil
+	}
+	slot := evidenceSlot{delegator: signer, dappId: header.DAppID, round: header.Round}
+
+	pool.lock.Lock()
+	known, ok := pool.headers.Get(slot)
+	if !ok {
+		pool.headers.Add(slot, header)
+		pool.lock.Unlock()
+		return nil
+	}
+	first := known.(*types.Header)
+	if _, err := pool.engine.VerifyDoubleSign(first, header); err != nil {
+		pool.lock.Unlock()
+		return nil
+	}
+	evidence := &Evidence{
+		Hash:      vm.EvidenceHash(first, header),
+		Delegator: signer,
+		Round:     header.Round,
+		First:     first,
+		Second:    header,
+	}
+	if _, ok := pool.evidence[evidence.Hash]; ok {
+		pool.lock.Unlock()
+		return nil
+	}
+	pool.evidence[evidence.Hash] = evidence
+	pool.persist()
+	pool.lock.Unlock()
+
+	log.Warn("Detected double signing delegator", "delegator", signer, "round", header.Round, "first", first.Hash(), "second", header.Hash())
+	// Delivery blocks until subscribers read, don't stall the peer handling
+	if pool.mux != nil {
+		go pool.mux.Post(DoubleSignEvent{Evidence: evidence})
+	}
+	return evidence
+}
+
+// ObservePreCommits records the proof of a delegator voting for two different
+// blocks of the same height, persisting and announcing it. Votes which are no
+// such proof are ignored.
+func (pool *EvidencePool) ObservePreCommits(first, second *types.PreCommit) *Evidence {
+	delegator, err := pool.engine.VerifyDoublePreCommit(first, second)
+	if err != nil {
+		return nil
+	}
+	evidence := &Evidence{
+		Hash:      vm.PreCommitEvidenceHash(first, second),
+		Delegator: delegator,
+		Votes:     []*types.PreCommit{first, second},
+	}
+	pool.lock.Lock()
+	if _, ok := pool.evidence[evidence.Hash]; ok {
+		pool.lock.Unlock()
+		return nil
+	}
+	pool.evidence[evidence.Hash] = evidence
+	pool.persist()
+	pool.lock.Unlock()
+
+	log.Warn("Detected double voting delegator", "delegator", delegator, "number", first.Number, "first", first.Hash, "second", second.Hash)
+	if pool.mux != nil {
+		go pool.mux.Post(DoubleSignEvent{Evidence: evidence})
+	}
+	return evidence
+}
+
+// Evidence retrieves the proof recorded under the given hash, nil if unknown or
+// already used.
+func (pool *EvidencePool) Evidence(hash common.Hash) *Evidence {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return pool.evidence[hash]
+}
+
+// Pending retrieves the proofs not yet used to slash their delegator in the
+// given state, ordered by their hash. All proofs are returned without a state.
+func (pool *EvidencePool) Pending(statedb vm.StateDB) []*Evidence {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	pending := make([]*Evidence, 0, len(pool.evidence))
+	for _, evidence := range pool.evidence {
+		if statedb == nil || !evidence.used(statedb) {
+			pending = append(pending, evidence)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return bytes.Compare(pending[i].Hash[:], pending[j].Hash[:]) < 0 })
+	return pending
+}
+
+// Prune drops the proofs which were used to slash their delegator in the given
+// state.
+func (pool *EvidencePool) Prune(statedb vm.StateDB) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pruned := false
+	for hash, evidence := range pool.evidence {
+		if evidence.used(statedb) {
+			delete(pool.evidence, hash)
+			pruned = true
+		}
+	}
+	if pruned {
+		pool.persist()
+	}
+}
+
+// persist stores the recorded proofs in the database. The pool lock is assumed
+// to be held.
+func (pool *EvidencePool) persist() {
+	stored := make([]*Evidence, 0, len(pool.evidence))
+	for _, evidence := range pool.evidence {
+		stored = append(stored, evidence)
+	}
+	blob, err := rlp.EncodeToBytes(stored)
+	if err != nil {
+		log.Error("Failed to encode double signing evidence", "err", err)
+		return
+	}
+	if err := pool.db.Put(evidenceKey, blob); err != nil {
+		log.Error("Failed to store double signing evidence", "err", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// Tests that conflicting headers sealed by a delegator for the same round are
+// recorded and announced once, survive restarts and are dropped once used to
+// slash the delegator.
+func TestEvidencePool(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	presidentId := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	engine := New(&config.DPoSConfig{}, nil)
+	db, _ := store.NewMemDatabase()
+	genesis := (&core.Genesis{Config: config.TestChainConfig, ExtraData: testerCheckpointExtra(presidentId)}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Headers of the same round differ by their gas limit
+	seal := func(gasLimit uint64, round uint64) *types.Header {
+		header := &types.Header{
+			ParentHash:  genesis.Hash(),
+			Number:      big.NewInt(1),
+			Time:        new(big.Int).SetUint64(engine.SlotTime(genesis.Header(), round)),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    gasLimit,
+			Round:       round,
+			PresidentId: presidentId,
+			Extra:       make([]byte, extraSeal),
+		}
+		sighash, _ := crypto.Sign(sigHash(header).Bytes(), key)
+		copy(header.Extra, sighash)
+		return header
+	}
+
+	mux := new(event.TypeMux)
+	sub := mux.Subscribe(DoubleSignEvent{})
+	defer sub.Unsubscribe()
+
+	pool := NewEvidencePool(engine, chain, db, mux)
+	defer pool.Stop()
+
+	// Headers of different rounds, unsealed and orphaned headers are no evidence
+	first := seal(1, 3)
+	if evidence := pool.Observe(first); evidence != nil {
+		t.Fatalf("first header: have evidence %x", evidence.Hash)
+	}
+	if evidence := pool.Observe(seal(2, 4)); evidence != nil {
+		t.Fatalf("next round: have evidence %x", evidence.Hash)
+	}
+	forged := seal(2, 3)
+	forged.Extra = make([]byte, extraSeal)
+	if evidence := pool.Observe(forged); evidence != nil {
+		t.Fatalf("unsealed header: have evidence %x", evidence.Hash)
+	}
+	orphan := seal(2, 3)
+	orphan.ParentHash = common.Hash{1}
+	sighash, _ := crypto.Sign(sigHash(orphan).Bytes(), key)
+	copy(orphan.Extra, sighash)
+	if evidence := pool.Observe(orphan); evidence != nil {
+		t.Fatalf("orphaned header: have evidence %x", evidence.Hash)
+	}
+	// A second header of the same round is evidence, announced once
+	second := seal(2, 3)
+	evidence := pool.Observe(second)
+	if evidence == nil {
+		t.Fatalf("conflicting header: no evidence")
+	}
+	if evidence.Delegator != presidentId || evidence.Round != 3 || evidence.Hash != vm.EvidenceHash(first, second) {
+		t.Errorf("evidence mismatch: have %s round %d [%x]", evidence.Delegator, evidence.Round, evidence.Hash)
+	}
+	select {
+	case ev := <-sub.Chan():
+		if ev.Data.(DoubleSignEvent).Evidence != evidence {
+			t.Errorf("announced evidence mismatch")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("double signing not announced")
+	}
+	if again := pool.Observe(second); again != nil {
+		t.Errorf("repeated header: have evidence %x", again.Hash)
+	}
+	// The evidence is restored by a new pool and dropped once used
+	restored := NewEvidencePool(engine, chain, db, nil)
+	defer restored.Stop()
+
+	if pending := restored.Pending(nil); len(pending) != 1 || pending[0].Hash != evidence.Hash {
+		t.Fatalf("restored evidence: have %d proofs, want [%x]", len(pending), evidence.Hash)
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer:      func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:         func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber:      new(big.Int),
+		VerifyDoubleSign: engine.VerifyDoubleSign,
+	}, statedb, config.TestChainConfig, vm.Config{})
+
+	input, err := vm.PackSlash(second, first)
+	if err != nil {
+		t.Fatalf("failed to pack evidence: %v", err)
+	}
+	if _, _, err := evm.Call(vm.AccountRef(common.Address{1}), vm.DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to slash: %v", err)
+	}
+	if pending := restored.Pending(statedb); len(pending) != 0 {
+		t.Errorf("used evidence pending: have %d proofs", len(pending))
+	}
+	restored.Prune(statedb)
+	if restored.Evidence(evidence.Hash) != nil {
+		t.Errorf("used evidence not pruned")
+	}
+}
+
+// Tests that conflicting pre-commit votes are recorded as evidence, restored by
+// a new pool and dropped once used to slash their delegator.
+func TestEvidencePoolPreCommits(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	delegator := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(delegator, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	db, _ := store.NewMemDatabase()
+	(&core.Genesis{Config: config.TestChainConfig}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := NewEvidencePool(engine, chain, db, nil)
+	defer pool.Stop()
+
+	first, _ := engine.SignPreCommit(&types.Header{Number: big.NewInt(2), Round: 2})
+	second, _ := engine.SignPreCommit(&types.Header{Number: big.NewInt(2), Round: 3})
+	if evidence := pool.ObservePreCommits(first, first); evidence != nil {
+		t.Fatalf("identical votes: have evidence %x", evidence.Hash)
+	}
+	evidence := pool.ObservePreCommits(first, second)
+	if evidence == nil {
+		t.Fatalf("conflicting votes: no evidence")
+	}
+	if evidence.Delegator != delegator || evidence.Hash != vm.PreCommitEvidenceHash(first, second) {
+		t.Errorf("evidence mismatch: have %s [%x]", evidence.Delegator, evidence.Hash)
+	}
+	restored := NewEvidencePool(engine, chain, db, nil)
+	defer restored.Stop()
+
+	pending := restored.Pending(nil)
+	if len(pending) != 1 || pending[0].Hash != evidence.Hash || len(pending[0].Votes) != 2 || pending[0].First != nil {
+		t.Fatalf("restored evidence: have %d proofs, want [%x]", len(pending), evidence.Hash)
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer:           func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:              func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber:           new(big.Int),
+		VerifyDoublePreCommit: engine.VerifyDoublePreCommit,
+	}, statedb, config.TestChainConfig, vm.Config{})
+
+	input, err := pending[0].pack()
+	if err != nil {
+		t.Fatalf("failed to pack evidence: %v", err)
+	}
+	if _, _, err := evm.Call(vm.AccountRef(common.Address{1}), vm.DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to slash: %v", err)
+	}
+	if pending := restored.Pending(statedb); len(pending) != 0 {
+		t.Errorf("used evidence pending: have %d proofs", len(pending))
+	}
+}
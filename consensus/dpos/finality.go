@@ -0,0 +1,307 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+)
+
+// pendingFinality is the number of blocks below the newest voted one which keep
+// collecting pre-commit votes before being given up on.
+const pendingFinality = 1024
+
+var (
+	// errInvalidPreCommit is returned if a pre-commit vote is malformed or its
+	// signature was not made by the delegator it claims to be from.
+	errInvalidPreCommit = errors.New("invalid pre-commit vote")
+
+	// errUnscheduledVoter is returned if a pre-commit vote is cast by a delegator
+	// which is not scheduled in the epoch of the voted block.
+	errUnscheduledVoter = errors.New("pre-commit voter not scheduled in epoch")
+
+	// errConflictingPreCommit is returned if a delegator votes for a block while it
+	// already voted for another block of the same height.
+	errConflictingPreCommit = errors.New("conflicting pre-commit vote")
+
+	// errUncertifiedPreCommit is returned if a pre-commit vote is cast for a block
+	// which does not extend the last block certified final.
+	errUncertifiedPreCommit = errors.New("pre-commit vote not extending the last certified block")
+
+	// errInsufficientVotes is returned if a finality certificate does not hold the
+	// votes of a qualified majority of the delegators of the epoch.
+	errInsufficientVotes = errors.New("insufficient pre-commit votes")
+)
+
+// finalityThreshold returns the number of pre-commit votes needed for a block to
+// become final within an epoch of the given number of delegators.
+func finalityThreshold(delegators int) int {
+	return delegators*2/3 + 1
+}
+
+// SignPreCommit votes for the block of the given header to become final, signing
+// the vote with the node key of the local delegator.
+func (dpos *DElection) SignPreCommit(header *types.Header) (*types.PreCommit, error) {
+	if header.Number.Sign() == 0 {
+		return nil, errUnknownBlock
+	}
+	dpos.lock.RLock()
+	presidentId, signFn := dpos.presidentId, dpos.signFn
+	dpos.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errUnauthorizedSealer
+	}
+	vote := &types.PreCommit{
+		Number:    header.Number.Uint64(),
+		Hash:      header.Hash(),
+		Delegator: presidentId,
+	}
+	signature, err := signFn(vote.SigHash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	vote.Signature = signature
+	return vote, nil
+}
+
+// VerifyPreCommit checks whether the vote was signed by the delegator it claims
+// to be from and whether that delegator is scheduled in the epoch of the voted
+// block. The schedule of the epoch is returned.
+func (dpos *DElection) VerifyPreCommit(chain consensus.ChainReader, vote *types.PreCommit) (*Schedule, error) {
+	if vote.Number == 0 {
+		return nil, errInvalidPreCommit
+	}
+	header := chain.GetHeader(vote.Hash, vote.Number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if err := verifyPreCommitSigner(vote); err != nil {
+		return nil, err
+	}
+	schedule, err := dpos.schedule(chain, vote.Number, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.Includes(vote.Delegator) {
+		return nil, errUnscheduledVoter
+	}
+	return schedule, nil
+}
+
+// verifyPreCommitSigner checks whether the vote was signed by the delegator it
+// claims to be from.
+func verifyPreCommitSigner(vote *types.PreCommit) error {
+	pubkey, err := crypto.SigToPub(vote.SigHash().Bytes(), vote.Signature)
+	if err != nil {
+		return errInvalidPreCommit
+	}
+	if discover.PubkeyID(pubkey).TerminalString() != vote.Delegator {
+		return errInvalidPreCommit
+	}
+	return nil
+}
+
+// VerifyFinality implements consensus.Finalizer, checking whether the certificate
+// holds valid votes of more than two thirds of the delegators of the epoch of
+// the finalized block.
+func (dpos *DElection) VerifyFinality(chain consensus.ChainReader, cert *types.FinalityCertificate) error {
+	var (
+		schedule *Schedule
+		voters   = make(map[string]struct{})
+	)
+	for _, vote := range cert.Votes {
+		if vote.Number != cert.Number || vote.Hash != cert.Hash {
+			return errInvalidPreCommit
+		}
+		if _, ok := voters[vote.Delegator]; ok {
+			return errInvalidPreCommit
+		}
+		var err error
+		if schedule, err = dpos.VerifyPreCommit(chain, vote); err != nil {
+			return err
+		}
+		voters[vote.Delegator] = struct{}{}
+	}
+	if schedule == nil || len(voters) < finalityThreshold(len(schedule.Delegators)) {
+		return errInsufficientVotes
+	}
+	return nil
+}
+
+// preCommits are the votes collected for a single block.
+type preCommits struct {
+	number uint64
+	votes  map[string]*types.PreCommit
+}
+
+// preCommitSlot identifies the height a delegator voted at.
+type preCommitSlot struct {
+	delegator string
+	number    uint64
+}
+
+// FinalityTally collects the pre-commit votes of the delegators until a block
+// gathers a qualified majority of its epoch. Only votes for blocks extending the
+// last certified block are counted, so no block conflicting with a final one can
+// become final as long as the delegators don't vote for such blocks either.
+type FinalityTally struct {
+	engine    *DElection
+	evidence  *EvidencePool                      // Pool recording conflicting votes, nil to just drop them
+	blocks    map[common.Hash]*preCommits        // Votes collected per block
+	cast      map[preCommitSlot]*types.PreCommit // Votes cast per delegator and height
+	finalized uint64                             // Number of the last finalized block, older votes are dropped
+	certified common.Hash                        // Hash of the last finalized block, newer votes have to extend it
+	newest    uint64                             // Number of the newest voted block
+	lock      sync.Mutex
+}
+
+// NewFinalityTally creates a tally verifying the votes with the given engine.
+// Delegators voting for different blocks of the same height are reported to
+// the evidence pool, if any.
+func NewFinalityTally(engine *DElection, evidence *EvidencePool) *FinalityTally {
+	return &FinalityTally{
+		engine:   engine,
+		evidence: evidence,
+		blocks:   make(map[common.Hash]*preCommits),
+		cast:     make(map[preCommitSlot]*types.PreCommit),
+	}
+}
+
+// SetCertified records the given block as the last one certified final, e.g. the
+// finalized block of the local chain on startup. Blocks not above the last
+// certified one are ignored.
+func (t *FinalityTally) SetCertified(header *types.Header) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if number := header.Number.Uint64(); number > t.finalized {
+		t.finalized, t.certified = number, header.Hash()
+		t.prune(t.finalized)
+	}
+}
+
+// Extends returns whether the block of the given header extends the last block
+// certified final, which any block has to for a delegator to vote for it.
+func (t *FinalityTally) Extends(chain consensus.ChainReader, header *types.Header) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.extends(chain, header)
+}
+
+// extends returns whether the block of the given header is a descendant of the
+// last certified block, walking back its ancestors.
+func (t *FinalityTally) extends(chain consensus.ChainReader, header *types.Header) bool {
+	if t.certified == (common.Hash{}) {
+		return true
+	}
+	for header != nil && header.Number.Uint64() > t.finalized {
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header != nil && header.Hash() == t.certified
+}
+
+// Add verifies and records a pre-commit vote. Whether the vote was not known yet
+// is returned, along with the finality certificate of the voted block once its
+// votes reach the threshold. Votes for blocks not above the last finalized one
+// are ignored, unless they conflict with an earlier vote of their delegator, and
+// votes for blocks not extending it are rejected.
+func (t *FinalityTally) Add(chain consensus.ChainReader, vote *types.PreCommit) (*types.FinalityCertificate, bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if vote.Number+pendingFinality <= t.newest {
+		return nil, false, nil
+	}
+	slot := preCommitSlot{delegator: vote.Delegator, number: vote.Number}
+	if known, ok := t.cast[slot]; ok {
+		if known.Hash == vote.Hash {
+			return nil, false, nil
+		}
+		// The delegator voted for another block at the same height, report it
+		if _, err := t.engine.VerifyPreCommit(chain, vote); err != nil {
+			return nil, false, err
+		}
+		if t.evidence != nil {
+			t.evidence.ObservePreCommits(known, vote)
+		}
+		return nil, false, errConflictingPreCommit
+	}
+	if vote.Number <= t.finalized {
+		return nil, false, nil
+	}
+	schedule, err := t.engine.VerifyPreCommit(chain, vote)
+	if err != nil {
+		return nil, false, err
+	}
+	if !t.extends(chain, chain.GetHeader(vote.Hash, vote.Number)) {
+		return nil, false, errUncertifiedPreCommit
+	}
+	t.cast[slot] = vote
+	block, ok := t.blocks[vote.Hash]
+	if !ok {
+		block = &preCommits{number: vote.Number, votes: make(map[string]*types.PreCommit)}
+		t.blocks[vote.Hash] = block
+	}
+	block.votes[vote.Delegator] = vote
+
+	if len(block.votes) < finalityThreshold(len(schedule.Delegators)) {
+		if vote.Number > t.newest {
+			t.newest = vote.Number
+			t.prune(0)
+		}
+		return nil, true, nil
+	}
+	cert := &types.FinalityCertificate{Number: vote.Number, Hash: vote.Hash}
+	for _, vote := range block.votes {
+		cert.Votes = append(cert.Votes, vote)
+	}
+	sort.Slice(cert.Votes, func(i, j int) bool { return cert.Votes[i].Delegator < cert.Votes[j].Delegator })
+
+	// Votes of the finalized block and anything below aren't needed any more
+	if vote.Number > t.newest {
+		t.newest = vote.Number
+	}
+	t.finalized, t.certified = vote.Number, vote.Hash
+	t.prune(t.finalized)
+
+	return cert, true, nil
+}
+
+// prune drops the votes of the blocks up to the given number and of those too
+// far below the newest voted block. The votes cast by the delegators are kept
+// until they fall too far below, to catch conflicting votes for final blocks.
+func (t *FinalityTally) prune(number uint64) {
+	for hash, block := range t.blocks {
+		if block.number <= number || block.number+pendingFinality <= t.newest {
+			delete(t.blocks, hash)
+		}
+	}
+	for slot := range t.cast {
+		if slot.number+pendingFinality <= t.newest {
+			delete(t.cast, slot)
+		}
+	}
+}
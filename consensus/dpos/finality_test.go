@@ -0,0 +1,184 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+)
+
+// Tests that pre-commit votes of the delegators of an epoch are tallied until a
+// qualified majority certifies the finality of a block, and that forged, foreign
+// or duplicate votes are not counted.
+func TestFinalityTally(t *testing.T) {
+	// Create four delegators recorded in the genesis checkpoint and an outsider
+	engines := make([]*DElection, 5)
+	delegators := make([]string, 0, 4)
+	for i := range engines {
+		key, _ := crypto.GenerateKey()
+		id := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+		engines[i] = New(&config.DPoSConfig{Epoch: 10}, nil)
+		engines[i].Authorize(id, func(hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key)
+		})
+		if i < 4 {
+			delegators = append(delegators, id)
+		}
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Extra: testerCheckpointExtra(delegators...)}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}}
+
+	blocks := []*types.Header{genesis}
+	for i := 1; i <= 3; i++ {
+		header := &types.Header{ParentHash: blocks[i-1].Hash(), Number: big.NewInt(int64(i)), Time: big.NewInt(int64(i)), Round: uint64(i)}
+		chain.headers[header.Hash()] = header
+		blocks = append(blocks, header)
+	}
+	vote := func(engine *DElection, header *types.Header) *types.PreCommit {
+		vote, err := engine.SignPreCommit(header)
+		if err != nil {
+			t.Fatalf("failed to sign pre-commit: %v", err)
+		}
+		return vote
+	}
+	tally := NewFinalityTally(engines[0], nil)
+
+	// Votes of the delegators are fresh once, those of outsiders and forgeries are rejected
+	for i := 0; i < 2; i++ {
+		if cert, fresh, err := tally.Add(chain, vote(engines[i], blocks[2])); err != nil || !fresh || cert != nil {
+			t.Fatalf("vote %d: have %v, %v, %v, want no certificate, fresh", i, cert, fresh, err)
+		}
+	}
+	if _, fresh, err := tally.Add(chain, vote(engines[0], blocks[2])); err != nil || fresh {
+		t.Errorf("duplicate vote: have %v, %v, want known", fresh, err)
+	}
+	if _, _, err := tally.Add(chain, vote(engines[4], blocks[2])); err != errUnscheduledVoter {
+		t.Errorf("outsider vote: have %v, want %v", err, errUnscheduledVoter)
+	}
+	forged := vote(engines[3], blocks[2])
+	forged.Delegator = delegators[2]
+	if _, _, err := tally.Add(chain, forged); err != errInvalidPreCommit {
+		t.Errorf("forged vote: have %v, want %v", err, errInvalidPreCommit)
+	}
+	// The third delegator completes the qualified majority of the epoch
+	cert, fresh, err := tally.Add(chain, vote(engines[2], blocks[2]))
+	if err != nil || !fresh || cert == nil {
+		t.Fatalf("final vote: have %v, %v, %v, want certificate", cert, fresh, err)
+	}
+	if cert.Number != 2 || cert.Hash != blocks[2].Hash() || len(cert.Votes) != 3 {
+		t.Fatalf("certificate mismatch: have #%d [%x] with %d votes, want #2 [%x] with 3 votes", cert.Number, cert.Hash, len(cert.Votes), blocks[2].Hash())
+	}
+	if err := engines[4].VerifyFinality(chain, cert); err != nil {
+		t.Errorf("failed to verify certificate: %v", err)
+	}
+	// Votes of finalized heights are dropped
+	if _, fresh, err := tally.Add(chain, vote(engines[3], blocks[1])); err != nil || fresh {
+		t.Errorf("vote below finality: have %v, %v, want dropped", fresh, err)
+	}
+	// Votes for another block of a voted height conflict, even below finality
+	sibling := &types.Header{ParentHash: blocks[1].Hash(), Number: big.NewInt(2), Time: big.NewInt(3), Round: 3}
+	chain.headers[sibling.Hash()] = sibling
+	if _, fresh, err := tally.Add(chain, vote(engines[0], sibling)); err != errConflictingPreCommit || fresh {
+		t.Errorf("conflicting vote: have %v, %v, want %v", fresh, err, errConflictingPreCommit)
+	}
+	// Certificates short of votes or counting a delegator twice are invalid
+	short := &types.FinalityCertificate{Number: cert.Number, Hash: cert.Hash, Votes: cert.Votes[:2]}
+	if err := engines[0].VerifyFinality(chain, short); err != errInsufficientVotes {
+		t.Errorf("short certificate: have %v, want %v", err, errInsufficientVotes)
+	}
+	double := &types.FinalityCertificate{Number: cert.Number, Hash: cert.Hash, Votes: append(cert.Votes[:2:2], cert.Votes[0])}
+	if err := engines[0].VerifyFinality(chain, double); err != errInvalidPreCommit {
+		t.Errorf("double counted certificate: have %v, want %v", err, errInvalidPreCommit)
+	}
+	mixed := &types.FinalityCertificate{Number: cert.Number, Hash: cert.Hash, Votes: append(cert.Votes[:2:2], vote(engines[3], blocks[3]))}
+	if err := engines[0].VerifyFinality(chain, mixed); err != errInvalidPreCommit {
+		t.Errorf("certificate of mixed blocks: have %v, want %v", err, errInvalidPreCommit)
+	}
+}
+
+// Tests that once a block was certified final, no block of a conflicting fork
+// can be certified, even at a height nobody voted at yet, and that a tally set
+// up with the finalized block after a restart holds on to it as well.
+func TestFinalityConflictingForks(t *testing.T) {
+	engines := make([]*DElection, 4)
+	delegators := make([]string, 0, 4)
+	for i := range engines {
+		key, _ := crypto.GenerateKey()
+		id := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+		engines[i] = New(&config.DPoSConfig{Epoch: 10}, nil)
+		engines[i].Authorize(id, func(hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key)
+		})
+		delegators = append(delegators, id)
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Extra: testerCheckpointExtra(delegators...)}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}}
+
+	// Create two forks off the genesis, the first one final at its first block
+	fork := func(seed int64) []*types.Header {
+		blocks := []*types.Header{genesis}
+		for i := 1; i <= 3; i++ {
+			header := &types.Header{ParentHash: blocks[i-1].Hash(), Number: big.NewInt(int64(i)), Time: big.NewInt(seed + int64(i)), Round: uint64(i)}
+			chain.headers[header.Hash()] = header
+			blocks = append(blocks, header)
+		}
+		return blocks
+	}
+	forkA, forkB := fork(0), fork(100)
+
+	vote := func(engine *DElection, header *types.Header) *types.PreCommit {
+		vote, err := engine.SignPreCommit(header)
+		if err != nil {
+			t.Fatalf("failed to sign pre-commit: %v", err)
+		}
+		return vote
+	}
+	tally := NewFinalityTally(engines[0], nil)
+	for i := 0; i < 3; i++ {
+		tally.Add(chain, vote(engines[i], forkA[1]))
+	}
+	if !tally.Extends(chain, forkA[3]) || tally.Extends(chain, forkB[3]) {
+		t.Fatalf("certified fork mismatch: have A %v, B %v, want A only", tally.Extends(chain, forkA[3]), tally.Extends(chain, forkB[3]))
+	}
+	// A qualified majority voting for the conflicting fork must not certify it
+	for i := 0; i < 4; i++ {
+		if cert, _, err := tally.Add(chain, vote(engines[i], forkB[2])); err != errUncertifiedPreCommit || cert != nil {
+			t.Errorf("vote %d of conflicting fork: have %v, %v, want %v", i, cert, err, errUncertifiedPreCommit)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if cert, fresh, err := tally.Add(chain, vote(engines[i], forkA[3])); err != nil || !fresh || (cert != nil) != (i == 2) {
+			t.Errorf("vote %d of certified fork: have %v, %v, %v", i, cert, fresh, err)
+		}
+	}
+	// A restarted tally must keep rejecting the conflicting fork
+	restarted := NewFinalityTally(engines[0], nil)
+	restarted.SetCertified(forkA[1])
+	for i := 0; i < 4; i++ {
+		if cert, _, err := restarted.Add(chain, vote(engines[i], forkB[3])); err != errUncertifiedPreCommit || cert != nil {
+			t.Errorf("vote %d of conflicting fork after restart: have %v, %v, want %v", i, cert, err, errUncertifiedPreCommit)
+		}
+	}
+}
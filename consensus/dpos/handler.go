@@ -21,24 +21,36 @@ import (
 	"fmt"
 	"math/big"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/crypto/sha3"
 	"github.com/juchain/go-juchain/common/math"
+	"github.com/juchain/go-juchain/common/rlp"
 	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/core/store"
 	"github.com/juchain/go-juchain/core/state"
 	"github.com/juchain/go-juchain/core/types"
 	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/p2p/discover"
 	"github.com/juchain/go-juchain/rpc"
+	"github.com/juchain/go-juchain/vm/solc"
 
+	"github.com/hashicorp/golang-lru"
 	"gopkg.in/fatih/set.v0"
 	"bytes"
 )
 
+const (
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+
+	extraSeal = 65 // Fixed number of extra-data suffix bytes reserved for delegator seal
+)
+
 // DElection proof-of-work protocol constants.
 var (
-	ByzantiumBlockReward   *big.Int =  big.NewInt(3e+18) // Block reward in wei for successfully mining a block upward from Byzantium
 	maxUncles                       = 2                 // Maximum number of uncles allowed in a single block
 	allowedFutureBlockTime          = 15 * time.Second  // Max time from current time allowed for blocks, before they're considered future blocks
 )
@@ -57,27 +69,172 @@ var (
 	errInvalidDifficulty = errors.New("non-positive difficulty")
 	errInvalidMixDigest  = errors.New("invalid mix digest")
 	errInvalidPoW        = errors.New("invalid proof-of-work")
+
+	// errUnknownBlock is returned when the genesis block is attempted to be sealed.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	errMissingSignature = errors.New("extra-data 65 byte suffix signature missing")
+
+	// errInvalidSignature is returned if the seal of a block is no canonical
+	// secp256k1 signature, as one with its s value in the upper half of the curve.
+	errInvalidSignature = errors.New("invalid signature values")
+
+	// errInvalidSigner is returned if the recovered signer of a block does not
+	// match the president id declared in its header.
+	errInvalidSigner = errors.New("signer does not match president id")
+
+	// errInvalidRound is returned if the round of a block is not greater than
+	// the round of its parent.
+	errInvalidRound = errors.New("invalid round")
+
+	// errUnauthorizedDelegator is returned if a header is signed by a delegator
+	// which was not scheduled for the slot of the block.
+	errUnauthorizedDelegator = errors.New("unauthorized delegator")
+
+	// errInvalidCheckpointDelegators is returned if a checkpoint block records a
+	// delegator set which is malformed or differs from the one in its state.
+	errInvalidCheckpointDelegators = errors.New("invalid delegator list on checkpoint block")
+
+	// errUnauthorizedSealer is returned if the local node is asked to seal a block
+	// without being authorized as the president of the block.
+	errUnauthorizedSealer = errors.New("sealer is not authorized for this block")
+
+	// errInvalidEvidence is returned if two headers are no proof of a delegator
+	// sealing two different blocks for the same slot.
+	errInvalidEvidence = errors.New("invalid double signing evidence")
 )
 
+// SignerFn is a signer callback function to request a hash to be signed by the
+// node key of the local delegator.
+type SignerFn func(hash []byte) ([]byte, error)
+
+// sigHash returns the hash which is used as input for the delegator signing. It
+// is the hash of the entire header apart from the 65 byte signature contained at
+// the end of the extra data.
+//
+// Note, the method requires the extra data to be at least 65 bytes, otherwise it
+// panics. This is done to avoid accidentally using both forms (signature present
+// or not), which could be abused to produce different hashes for the same header.
+func sigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal], // Yes, this will panic if extra is too short
+		header.MixDigest,
+		header.Nonce,
+		header.Round,
+		header.Round2,
+		header.PresidentId,
+		header.DAppID,
+		header.DAppMainHash,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// ecrecover extracts the president id (the short node id of the delegator) from
+// a signed header.
+func ecrecover(header *types.Header, sigcache *lru.ARCCache) (string, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if presidentId, known := sigcache.Get(hash); known {
+		return presidentId.(string), nil
+	}
+	// Retrieve the signature from the header extra-data
+	if len(header.Extra) < extraSeal {
+		return "", errMissingSignature
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+
+	// Reject malleable signatures, flipping the s value of an honest seal would
+	// otherwise yield another valid header for the very same block
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64])
+	if !crypto.ValidateSignatureValues(signature[64], r, s, true) {
+		return "", errInvalidSignature
+	}
+	// Recover the public key and the node id of the delegator
+	pubkey, err := crypto.SigToPub(sigHash(header).Bytes(), signature)
+	if err != nil {
+		return "", err
+	}
+	presidentId := discover.PubkeyID(pubkey).TerminalString()
+
+	sigcache.Add(hash, presidentId)
+	return presidentId, nil
+}
+
 type DElection struct {
 	config    *config.DPoSConfig   // Consensus engine configuration parameters
 	db        store.Database       // Database to store and retrieve snapshot checkpoints
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
 	fakeDelay time.Duration // Time delay to sleep for before returning from verify
+
+	signatures *lru.ARCCache   // Signatures of recent blocks to speed up verification
+	schedules  *lru.ARCCache   // Delegator schedules of recent blocks to speed up verification
+	reader     DelegatorReader // Source of the delegators at the epoch checkpoints
+
+	presidentId string       // Short node id of the local delegator
+	signFn      SignerFn     // Signer function to authorize hashes with
+	lock        sync.RWMutex // Protects the signer and reader fields
 }
 
-// New creates a Clique proof-of-authority consensus engine with the initial
-// signers set to the ones provided by the user.
+// New creates a DPoS consensus engine. Blocks can only be sealed once the local
+// delegator has been authorized.
 func New(config *config.DPoSConfig, db store.Database) *DElection {
 	// Set any missing consensus parameters to their defaults
 	conf := *config
+	signatures, _ := lru.NewARC(inmemorySignatures)
+	schedules, _ := lru.NewARC(inmemorySchedules)
 
 	return &DElection{
 		config:     &conf,
 		db:         db,
+		signatures: signatures,
+		schedules:  schedules,
+		reader:     NewRegistryReader(maxDelegators),
 	}
 }
 
+// Authorize injects the node id and the signing function of the local delegator
+// into the consensus engine to package new blocks with.
+func (dpos *DElection) Authorize(presidentId string, signFn SignerFn) {
+	dpos.lock.Lock()
+	defer dpos.lock.Unlock()
+
+	dpos.presidentId = presidentId
+	dpos.signFn = signFn
+}
+
+// SetDelegatorReader injects the source the delegators are read from when a
+// checkpoint block is finalized, replacing the native delegator registry. Without
+// a reader checkpoints record an empty delegator set and only the signatures of
+// the next epoch are verified.
+func (dpos *DElection) SetDelegatorReader(reader DelegatorReader) {
+	dpos.lock.Lock()
+	defer dpos.lock.Unlock()
+
+	dpos.reader = reader
+}
+
+// Signer returns the president id recovered from the seal of the given header.
+func (dpos *DElection) Signer(header *types.Header) (string, error) {
+	return ecrecover(header, dpos.signatures)
+}
+
 // Author implements consensus.Engine, returning the header's coinbase as the
 // proof-of-work verified author of the block.
 func (dpos *DElection) Author(header *types.Header) (common.Address, error) {
@@ -87,6 +244,10 @@ func (dpos *DElection) Author(header *types.Header) (common.Address, error) {
 // VerifyHeader checks whether a header conforms to the consensus rules of the
 // stock Ethereum dpos engine.
 func (dpos *DElection) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	// If we're running a full engine faking, accept any input as valid
+	if dpos.config.PoSMode == config.ModeFullFake {
+		return nil
+	}
 	// Short circuit if the header is known, or it's parent not
 	number := header.Number.Uint64()
 	if chain.GetHeader(header.Hash(), number) != nil {
@@ -97,7 +258,7 @@ func (dpos *DElection) VerifyHeader(chain consensus.ChainReader, header *types.H
 		return consensus.ErrUnknownAncestor
 	}
 	// Sanity checks passed, do a proper verification
-	return dpos.verifyHeader(chain, header, parent, false, seal)
+	return dpos.verifyHeader(chain, header, parent, nil, false, seal)
 }
 
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
@@ -178,12 +339,16 @@ func (dpos *DElection) verifyHeaderWorker(chain consensus.ChainReader, headers [
 	if chain.GetHeader(headers[index].Hash(), headers[index].Number.Uint64()) != nil {
 		return nil // known block
 	}
-	return dpos.verifyHeader(chain, headers[index], parent, false, seals[index])
+	return dpos.verifyHeader(chain, headers[index], parent, headers[:index], false, seals[index])
 }
 
 // VerifyUncles verifies that the given block's uncles conform to the consensus
 // rules of the stock Ethereum dpos engine.
 func (dpos *DElection) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	// If we're running a full engine faking, accept any input as valid
+	if dpos.config.PoSMode == config.ModeFullFake {
+		return nil
+	}
 	// Verify that there are at most 2 uncles included in this block
 	if len(block.Uncles()) > maxUncles {
 		return errTooManyUncles
@@ -222,7 +387,7 @@ func (dpos *DElection) VerifyUncles(chain consensus.ChainReader, block *types.Bl
 		if ancestors[uncle.ParentHash] == nil || uncle.ParentHash == block.ParentHash() {
 			return errDanglingUncle
 		}
-		if err := dpos.verifyHeader(chain, uncle, ancestors[uncle.ParentHash], true, true); err != nil {
+		if err := dpos.verifyHeader(chain, uncle, ancestors[uncle.ParentHash], nil, true, true); err != nil {
 			return err
 		}
 	}
@@ -232,15 +397,24 @@ func (dpos *DElection) VerifyUncles(chain consensus.ChainReader, block *types.Bl
 // verifyHeader checks whether a header conforms to the consensus rules of the
 // stock Ethereum dpos engine.
 // See YP section 4.3.4. "Block Header Validity"
-func (dpos *DElection) verifyHeader(chain consensus.ChainReader, header, parent *types.Header, uncle bool, seal bool) error {
+func (dpos *DElection) verifyHeader(chain consensus.ChainReader, header, parent *types.Header, parents []*types.Header, uncle bool, seal bool) error {
 	// Ensure that the header's extra-data section is of a reasonable size
-	if uint64(len(header.Extra)) > config.MaximumExtraDataSize {
-		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), config.MaximumExtraDataSize)
+	if len(header.Extra) < extraSeal {
+		return errMissingSignature
+	}
+	// Checkpoint blocks carry the delegators of the next epoch after the vanity
+	if dpos.isCheckpoint(header.Number.Uint64()) {
+		if _, err := checkpointDelegators(header); err != nil {
+			return err
+		}
+	} else if uint64(len(header.Extra)-extraSeal) > config.MaximumExtraDataSize {
+		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra)-extraSeal, config.MaximumExtraDataSize)
 	}
 	if !bytes.Equal(header.DAppID.Bytes(),types.EmptyDAppIdHash.Bytes()) {
-		//todo dapp block verification
+		// DApp blocks must refer to the main chain block anchoring them, the anchor
+		// itself is verified against the main chain on import
 		if bytes.Equal(header.DAppMainHash.Bytes(), types.EmptyHash.Bytes()) {
-
+			return consensus.ErrMissingAnchor
 		}
 	}
 	// Verify the header's timestamp
@@ -287,7 +461,7 @@ func (dpos *DElection) verifyHeader(chain consensus.ChainReader, header, parent
 	}
 	// Verify the engine specific seal securing the block
 	if seal {
-		if err := dpos.VerifySeal(chain, header); err != nil {
+		if err := dpos.verifySeal(chain, header, parent, parents); err != nil {
 			return err
 		}
 	}
@@ -371,44 +545,146 @@ func (dpos *DElection) calcDifficultyByzantium(time uint64, parent *types.Header
 	return x
 }
 
-// VerifySeal implements consensus.Engine, checking whether the given block satisfies
-// the DPoS difficulty requirements.
+// VerifySeal implements consensus.Engine, checking whether the given block is
+// signed by the delegator declared in its header and whether that delegator was
+// scheduled for the slot of the block.
 func (dpos *DElection) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return dpos.verifySeal(chain, header, nil, nil)
+}
+
+// verifySeal checks whether the signature contained in the header satisfies the
+// consensus protocol requirements. The method accepts an optional parent and a
+// list of parents that aren't yet part of the local blockchain to resolve the
+// delegator schedule from.
+func (dpos *DElection) verifySeal(chain consensus.ChainReader, header, parent *types.Header, parents []*types.Header) error {
+	if dpos.config.PoSMode == config.ModeFullFake {
+		return nil
+	}
 	// Ensure that we have a valid difficulty for the block
 	if header.Difficulty.Sign() <= 0 {
 		return errInvalidDifficulty
 	}
+	// The genesis block is not sealed by anyone
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	if parent == nil {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if header.Round <= parent.Round {
+		return errInvalidRound
+	}
+	// Blocks of a round are sealed at the begin of its slot, off-grid timestamps
+	// would let delegators shift the slots of their successors
+	if err := dpos.verifySlotTime(header, parent); err != nil {
+		return err
+	}
+	// Resolve the authorization key and check against the declared president
+	signer, err := ecrecover(header, dpos.signatures)
+	if err != nil {
+		return err
+	}
+	if signer != header.PresidentId {
+		return errInvalidSigner
+	}
+	// Ensure the signer owns the slot of the block within the epoch schedule
+	schedule, err := dpos.schedule(chain, number, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	// An epoch without any delegator authorizes nobody to seal a block, unless no
+	// delegator was ever recorded and the chain is still bootstrapped
+	if !schedule.Authorizes(header.Round, signer) {
+		return errUnauthorizedDelegator
+	}
 	return nil
 }
 
 // Prepare implements consensus.Engine, initializing the difficulty field of a
-// header to conform to the dpos protocol. The changes are done inline.
+// header to conform to the dpos protocol and reserving the space of the seal.
+// The changes are done inline.
 func (dpos *DElection) Prepare(chain consensus.ChainReader, header *types.Header) error {
 	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
 	header.Difficulty = dpos.CalcDifficulty(chain, header.Time.Uint64(), parent)
+
+	// Checkpoint blocks reserve a fixed vanity, the delegators follow on finalization
+	extra := common.CopyBytes(header.Extra)
+	if dpos.isCheckpoint(header.Number.Uint64()) {
+		if len(extra) < extraVanity {
+			extra = append(extra, bytes.Repeat([]byte{0x00}, extraVanity-len(extra))...)
+		}
+		extra = extra[:extraVanity]
+	}
+	header.Extra = append(extra, make([]byte, extraSeal)...)
 	return nil
 }
 
-// Finalize implements consensus.Engine, accumulating the block and uncle rewards,
-// setting the final state and assembling the block.
+// Finalize implements consensus.Engine, distributing the block reward, slashing
+// delegators which missed their slots, recording the delegators of the next
+// epoch on checkpoint blocks, setting the final state and assembling the block.
 func (dpos *DElection) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
-	// Accumulate any block and uncle rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, uncles)
+	if dpos.config.PoSMode != config.ModeFullFake {
+		// Distribute the block reward, fake engines mint nothing
+		accumulateRewards(dpos.config, state, header)
+
+		// Track the liveness of the delegators, slashing the ones which missed too many of their slots
+		if err := dpos.slashMissedSlots(chain, header, state); err != nil {
+			return nil, err
+		}
+		dpos.recordProducedSlot(header, state)
+
+		// Checkpoint blocks jail the delegators missing too many slots of the
+		// ending epoch and record the delegators of the next epoch from their state
+		if dpos.isCheckpoint(header.Number.Uint64()) {
+			if err := dpos.jailUnhealthyDelegators(chain, header, state); err != nil {
+				return nil, err
+			}
+			if err := dpos.recordDelegators(chain, header, state); err != nil {
+				return nil, err
+			}
+		}
+	}
 	header.Root = state.IntermediateRoot(true)
 	//log.Info("Generated block with root: " + header.Root.String())
 	// Header seems complete, assemble into a block and return
 	return types.NewBlock(header, txs, uncles, receipts), nil
 }
 
-// Seal generates a new block for the given input block with the local miner's
-// seal place on top.
+// Seal generates a new block for the given input block with the local
+// delegator's seal place on top.
 func (dpos *DElection) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
 	header := block.Header()
-	//TODO
 	header.Nonce, header.MixDigest = types.BlockNonce{}, common.Hash{}
+
+	// Sealing the genesis block is not supported
+	if header.Number.Uint64() == 0 {
+		return nil, errUnknownBlock
+	}
+	if len(header.Extra) < extraSeal {
+		return nil, errMissingSignature
+	}
+	// Don't hold the signer fields for the entire sealing procedure
+	dpos.lock.RLock()
+	presidentId, signFn := dpos.presidentId, dpos.signFn
+	dpos.lock.RUnlock()
+
+	if signFn == nil || presidentId != header.PresidentId {
+		return nil, errUnauthorizedSealer
+	}
+	// Sign all the things!
+	sighash, err := signFn(sigHash(header).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+
 	return block.WithSeal(header), nil
 }
 
@@ -422,43 +698,45 @@ func (dpos *DElection) APIs(chain consensus.ChainReader) []rpc.API {
 	}}
 }
 
-// PublicDebugAPI is the collection of Ethereum APIs exposed over the public
-// debugging endpoint.
-type DPoSAPI struct {
-	chain  consensus.ChainReader
-	dpos *DElection
-}
-
-func (dpos *DPoSAPI) Test() string {
-	return "test";
+// accumulateRewards credits the emission of the given block. The treasury takes
+// its share first, the producing delegator keeps its commission of the rest and
+// the remainder is bonded for the voters of the delegator. The commission is paid
+// to the account owning the delegator in the registry, never to the coinbase the
+// producer picked. Delegators without any votes receive everything but the
+// treasury share, unregistered producers have no account to pay and receive
+// nothing at all.
+func accumulateRewards(config *config.DPoSConfig, state *state.StateDB, header *types.Header) {
+	emission := config.BlockReward(header.Number.Uint64())
+	reward := &vm.DelegatorReward{
+		Delegator:  header.PresidentId,
+		Emission:   emission,
+		Treasury:   new(big.Int),
+		Commission: new(big.Int),
+		Voters:     new(big.Int),
+	}
+	rest := new(big.Int).Set(emission)
+	if config.Treasury != nil {
+		reward.Treasury = permille(emission, config.TreasuryShare)
+		rest.Sub(rest, reward.Treasury)
+		state.AddBalance(*config.Treasury, reward.Treasury)
+	}
+	if candidate := vm.GetDelegatorCandidate(state, header.PresidentId); candidate != nil {
+		if candidate.Votes.Sign() > 0 {
+			reward.Commission = permille(rest, config.Commission)
+			reward.Voters = rest.Sub(rest, reward.Commission)
+		} else {
+			reward.Commission = rest
+		}
+		state.AddBalance(candidate.Owner, reward.Commission)
+	}
+	vm.DistributeReward(state, reward)
 }
 
-
-// Some weird constants to avoid constant memory allocs for them.
-var (
-	big8  = big.NewInt(8)
-	big32 = big.NewInt(32)
-)
-
-// AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *config.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
-	// Select the correct block reward based on chain progression
-	blockReward := ByzantiumBlockReward
-
-	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	for _, uncle := range uncles {
-		r.Add(uncle.Number, big8)
-		r.Sub(r, header.Number)
-		r.Mul(r, blockReward)
-		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
-
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
-	}
-	state.AddBalance(header.Coinbase, reward)
+// permille returns the given per mille of an amount, capped at the amount.
+func permille(amount *big.Int, rate uint64) *big.Int {
+	if rate >= 1000 {
+		return new(big.Int).Set(amount)
+	}
+	share := new(big.Int).Mul(amount, new(big.Int).SetUint64(rate))
+	return share.Div(share, big.NewInt(1000))
 }
@@ -0,0 +1,259 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// testerChainReader implements consensus.ChainReader to access a fixed set of
+// headers for seal verification.
+type testerChainReader struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (r *testerChainReader) Config() *config.ChainConfig                   { return config.TestChainConfig }
+func (r *testerChainReader) CurrentHeader() *types.Header                  { panic("not supported") }
+func (r *testerChainReader) GetHeaderByNumber(number uint64) *types.Header { panic("not supported") }
+func (r *testerChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.headers[hash]
+}
+func (r *testerChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r.headers[hash]
+}
+func (r *testerChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	panic("not supported")
+}
+
+// testerDelegator derives the node key of a delegator from a seed and returns
+// it together with its short node id.
+func testerDelegator(seed byte) (*ecdsa.PrivateKey, string) {
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte{seed}))
+	return key, discover.PubkeyID(&key.PublicKey).TerminalString()
+}
+
+// testerRegistration packs the registration of the delegator of the given node
+// key as a candidate owned by the given account.
+func testerRegistration(registry abi.ABI, owner common.Address, key *ecdsa.PrivateKey) []byte {
+	signature, _ := crypto.Sign(vm.DelegatorRegistrationHash(owner).Bytes(), key)
+	input, _ := registry.Pack("register", discover.PubkeyID(&key.PublicKey).TerminalString(), signature)
+	return input
+}
+
+// testerCheckpointExtra assembles the extra-data of a checkpoint block which
+// records the given delegators.
+func testerCheckpointExtra(delegators ...string) []byte {
+	blob, _ := rlp.EncodeToBytes(delegators)
+
+	extra := make([]byte, extraVanity, extraVanity+len(blob)+extraSeal)
+	extra = append(extra, blob...)
+	return append(extra, make([]byte, extraSeal)...)
+}
+
+// Tests that a block sealed by the delegator owning its slot passes the seal
+// verification and that forged or unscheduled seals are rejected.
+func TestSealVerification(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	presidentId := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	parent := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Time:       big.NewInt(0),
+		Extra:      testerCheckpointExtra(presidentId, "0000000000000000"),
+	}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(presidentId, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	schedule, err := engine.Schedule(chain, parent)
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule: %v", err)
+	}
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		Number:      big.NewInt(1),
+		PresidentId: presidentId,
+	}
+	for round := uint64(1); round <= 2; round++ {
+		header.Round, header.Time, header.Extra = round, new(big.Int).SetUint64(engine.SlotTime(parent, round)), nil
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		block, err := engine.Seal(chain, types.NewBlockWithHeader(header), nil)
+		if err != nil {
+			t.Fatalf("failed to seal block: %v", err)
+		}
+		// Only the slot owner of the round may package the block
+		err = engine.VerifySeal(chain, block.Header())
+		if schedule.Producer(round) == presidentId && err != nil {
+			t.Fatalf("round %d: failed to verify sealed block: %v", round, err)
+		}
+		if schedule.Producer(round) != presidentId && err != errUnauthorizedDelegator {
+			t.Errorf("round %d: unscheduled delegator: have %v, want %v", round, err, errUnauthorizedDelegator)
+		}
+		// Tampering with the president id must invalidate the seal
+		forged := block.Header()
+		forged.PresidentId = "0000000000000000"
+		if err := engine.VerifySeal(chain, forged); err != errInvalidSigner {
+			t.Errorf("round %d: forged president: have %v, want %v", round, err, errInvalidSigner)
+		}
+	}
+	// Sealing on behalf of a different president must be refused
+	header.PresidentId = "0000000000000000"
+	if _, err := engine.Seal(chain, types.NewBlockWithHeader(header), nil); err != errUnauthorizedSealer {
+		t.Errorf("foreign seal: have %v, want %v", err, errUnauthorizedSealer)
+	}
+}
+
+// Tests that DApp blocks are sealed by the producer of their anchor only, and
+// that their seal is verified against the anchor.
+func TestDAppSealVerification(t *testing.T) {
+	key, presidentId := testerDelegator(1)
+	_, otherId := testerDelegator(2)
+
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(presidentId, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	anchor := &types.Header{Number: big.NewInt(5), Round: 7, PresidentId: presidentId}
+	header := &types.Header{
+		Number:       big.NewInt(1),
+		Difficulty:   big.NewInt(1),
+		Extra:        make([]byte, extraSeal),
+		Round:        anchor.Round,
+		PresidentId:  anchor.PresidentId,
+		DAppID:       common.Address{0xda},
+		DAppMainHash: anchor.Hash(),
+	}
+	block, err := engine.sealDApp(types.NewBlockWithHeader(header))
+	if err != nil {
+		t.Fatalf("failed to seal DApp block: %v", err)
+	}
+	if err := engine.VerifyDAppSeal(anchor, block.Header()); err != nil {
+		t.Errorf("failed to verify DApp seal: %v", err)
+	}
+	// Blocks of anchors produced by other delegators may not be sealed nor claimed
+	foreign := types.CopyHeader(header)
+	foreign.PresidentId = otherId
+	if _, err := engine.sealDApp(types.NewBlockWithHeader(foreign)); err != errUnauthorizedSealer {
+		t.Errorf("foreign seal: have %v, want %v", err, errUnauthorizedSealer)
+	}
+	other := &types.Header{Number: big.NewInt(5), Round: 7, PresidentId: otherId}
+	if err := engine.VerifyDAppSeal(other, block.Header()); err != errUnauthorizedDelegator {
+		t.Errorf("foreign anchor: have %v, want %v", err, errUnauthorizedDelegator)
+	}
+	unsealed := types.NewBlockWithHeader(header)
+	if err := engine.VerifyDAppSeal(anchor, unsealed.Header()); err == nil {
+		t.Errorf("unsealed DApp block accepted")
+	}
+}
+
+// Tests that the emission of a block is split between the treasury, the
+// commission of the producing delegator and its voters pro rata to their stake.
+func TestAccumulateRewards(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	treasury := common.HexToAddress("0x7ea5")
+	conf := &config.DPoSConfig{Reward: big.NewInt(1000), Commission: 100, Treasury: &treasury, TreasuryShare: 200}
+
+	// Register a delegator backed by two voters with a stake of 1:3
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, config.TestChainConfig, vm.Config{})
+
+	key, id := testerDelegator(1)
+	voters := []common.Address{common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2})}
+
+	registry, _ := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
+	register := testerRegistration(registry, voters[0], key)
+	vote, _ := registry.Pack("vote", id)
+
+	if _, _, err := evm.Call(vm.AccountRef(voters[0]), vm.DelegatorRegistryAddress, register, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to register delegator: %v", err)
+	}
+	for i, voter := range voters {
+		if _, _, err := evm.Call(vm.AccountRef(voter), vm.DelegatorRegistryAddress, vote, 1000000, big.NewInt(int64(1000+2000*i))); err != nil {
+			t.Fatalf("voter %d: failed to vote: %v", i, err)
+		}
+	}
+	coinbase := common.HexToAddress("0xc0ffee")
+	accumulateRewards(conf, statedb, &types.Header{Number: big.NewInt(1), Coinbase: coinbase, PresidentId: id})
+
+	if balance := statedb.GetBalance(treasury); balance.Int64() != 200 {
+		t.Errorf("treasury balance mismatch: have %v, want %v", balance, 200)
+	}
+	// The commission goes to the owner of the delegator, not the chosen coinbase
+	if balance := statedb.GetBalance(voters[0]); balance.Int64() != 80 {
+		t.Errorf("owner balance mismatch: have %v, want %v", balance, 80)
+	}
+	if balance := statedb.GetBalance(coinbase); balance.Sign() != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", balance, 0)
+	}
+	for i, want := range []int64{1180, 3540} {
+		if stake := vm.GetDelegatorStake(statedb, voters[i]); stake.Bonded.Int64() != want {
+			t.Errorf("voter %d: bonded stake mismatch: have %v, want %v", i, stake.Bonded, want)
+		}
+	}
+	reward := vm.GetBlockReward(statedb)
+	if reward.Delegator != id || reward.Emission.Int64() != 1000 || reward.Treasury.Int64() != 200 || reward.Commission.Int64() != 80 || reward.Voters.Int64() != 720 {
+		t.Errorf("recorded reward mismatch: have %+v", reward)
+	}
+	// Delegators without any votes keep everything but the treasury share
+	unbackedKey, unbacked := testerDelegator(2)
+	owner := common.BytesToAddress([]byte{3})
+	if _, _, err := evm.Call(vm.AccountRef(owner), vm.DelegatorRegistryAddress, testerRegistration(registry, owner, unbackedKey), 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to register unbacked delegator: %v", err)
+	}
+	accumulateRewards(conf, statedb, &types.Header{Number: big.NewInt(2), Coinbase: coinbase, PresidentId: unbacked})
+	if balance := statedb.GetBalance(owner); balance.Int64() != 800 {
+		t.Errorf("unbacked owner balance mismatch: have %v, want %v", balance, 800)
+	}
+	if reward := vm.GetBlockReward(statedb); reward.Delegator != unbacked || reward.Commission.Int64() != 800 || reward.Voters.Sign() != 0 {
+		t.Errorf("unbacked recorded reward mismatch: have %+v", reward)
+	}
+	// Unregistered producers have no account to pay, only the treasury is paid
+	accumulateRewards(conf, statedb, &types.Header{Number: big.NewInt(3), Coinbase: coinbase, PresidentId: "0000000000000000"})
+	if balance := statedb.GetBalance(treasury); balance.Int64() != 600 {
+		t.Errorf("treasury balance mismatch: have %v, want %v", balance, 600)
+	}
+	if balance := statedb.GetBalance(coinbase); balance.Sign() != 0 {
+		t.Errorf("unregistered coinbase balance mismatch: have %v, want %v", balance, 0)
+	}
+	if reward := vm.GetBlockReward(statedb); reward.Commission.Sign() != 0 || reward.Voters.Sign() != 0 {
+		t.Errorf("unregistered recorded reward mismatch: have %+v", reward)
+	}
+}
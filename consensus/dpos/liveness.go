@@ -0,0 +1,64 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// recordProducedSlot accounts the given header as a slot produced by its
+// delegator in the liveness record.
+func (dpos *DElection) recordProducedSlot(header *types.Header, state *state.StateDB) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return
+	}
+	vm.RecordSlots(state, header.PresidentId, (number-1)/dpos.epochLength(), 1, 0)
+}
+
+// jailUnhealthyDelegators jails the delegators of the epoch closed by the given
+// checkpoint header which missed more than the allowed ratio of their slots. As
+// the checkpoint records the delegators of the next epoch right after, jailed
+// delegators are left out of its schedule. Their owners may release them once
+// the jail epochs passed.
+func (dpos *DElection) jailUnhealthyDelegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) error {
+	number := header.Number.Uint64()
+	schedule, err := dpos.schedule(chain, number, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	var (
+		ratio   = dpos.config.MaxMissRatio()
+		release = number/dpos.epochLength() + dpos.config.JailEpochs()
+	)
+	for _, delegator := range schedule.Delegators {
+		liveness := vm.GetDelegatorLiveness(state, delegator, schedule.Epoch)
+		if liveness == nil {
+			continue
+		}
+		slots := liveness.EpochProduced + liveness.EpochMissed
+		if slots > 0 && liveness.EpochMissed*1000 > slots*ratio {
+			vm.JailDelegator(state, delegator, release)
+			log.Info("Jailed delegator for missed slots", "delegator", delegator, "epoch", schedule.Epoch, "produced", liveness.EpochProduced, "missed", liveness.EpochMissed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// Tests that produced and missed slots are recorded per delegator, and that the
+// delegators missing too many slots of an epoch are jailed at its checkpoint and
+// left out of the schedule of the next epoch.
+func TestLivenessJailing(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	keyA, idA := testerDelegator(1)
+	keyB, idB := testerDelegator(2)
+
+	// Register two delegators with some bonded votes
+	chainConfig := *config.TestChainConfig
+	chainConfig.DPoS = &config.DPoSConfig{Epoch: 3, MissRatio: 500, Jail: 2}
+
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, &chainConfig, vm.Config{})
+
+	registry, _ := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
+	keys := map[string]*ecdsa.PrivateKey{idA: keyA, idB: keyB}
+	for i, id := range []string{idA, idB} {
+		voter := vm.AccountRef(common.BytesToAddress([]byte{byte(i + 1)}))
+
+		register := testerRegistration(registry, voter.Address(), keys[id])
+		vote, _ := registry.Pack("vote", id)
+		if _, _, err := evm.Call(voter, vm.DelegatorRegistryAddress, register, 1000000, new(big.Int)); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+		if _, _, err := evm.Call(voter, vm.DelegatorRegistryAddress, vote, 1000000, big.NewInt(1000)); err != nil {
+			t.Fatalf("failed to vote for %s: %v", id, err)
+		}
+	}
+	// Create an epoch of three blocks scheduling both delegators
+	headers := []*types.Header{{Number: big.NewInt(0), Extra: testerCheckpointExtra(idA, idB)}}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{headers[0].Hash(): headers[0]}}
+	for i := 1; i <= 3; i++ {
+		header := &types.Header{ParentHash: headers[i-1].Hash(), Number: big.NewInt(int64(i)), Round: uint64(i), Extra: make([]byte, extraVanity+extraSeal)}
+		headers = append(headers, header)
+		chain.headers[header.Hash()] = header
+	}
+	engine := New(chainConfig.DPoS, nil)
+
+	// One delegator produces its block, the other misses one and produces one
+	for _, header := range headers[1:] {
+		header.PresidentId = idB
+		engine.recordProducedSlot(header, statedb)
+	}
+	vm.RecordSlots(statedb, idA, 0, 1, 2)
+
+	want := &vm.DelegatorLiveness{Epoch: 0, EpochProduced: 3, Produced: 3}
+	if liveness := vm.GetDelegatorLiveness(statedb, idB, 0); !reflect.DeepEqual(liveness, want) {
+		t.Errorf("healthy liveness mismatch: have %+v, want %+v", liveness, want)
+	}
+	checkpoint := headers[3]
+	if err := engine.jailUnhealthyDelegators(chain, checkpoint, statedb); err != nil {
+		t.Fatalf("failed to jail unhealthy delegators: %v", err)
+	}
+	if candidate := vm.GetDelegatorCandidate(statedb, idA); !candidate.Jailed {
+		t.Errorf("unhealthy delegator not jailed")
+	}
+	if candidate := vm.GetDelegatorCandidate(statedb, idB); candidate.Jailed {
+		t.Errorf("healthy delegator jailed")
+	}
+	if err := engine.recordDelegators(chain, checkpoint, statedb); err != nil {
+		t.Fatalf("failed to record delegators: %v", err)
+	}
+	delegators, err := checkpointDelegators(checkpoint)
+	if err != nil {
+		t.Fatalf("failed to extract delegators: %v", err)
+	}
+	if want := []string{idB}; !reflect.DeepEqual(delegators, want) {
+		t.Errorf("scheduled delegators mismatch: have %v, want %v", delegators, want)
+	}
+	// The jailed delegator may be released by its owner after the jail epochs
+	unjail, _ := registry.Pack("unjail", idA)
+	owner := vm.AccountRef(common.BytesToAddress([]byte{1}))
+
+	evm.BlockNumber = big.NewInt(6)
+	if _, _, err := evm.Call(owner, vm.DelegatorRegistryAddress, unjail, 1000000, new(big.Int)); err == nil {
+		t.Errorf("delegator released before the jail epochs passed")
+	}
+	evm.BlockNumber = big.NewInt(9)
+	if _, _, err := evm.Call(owner, vm.DelegatorRegistryAddress, unjail, 1000000, new(big.Int)); err != nil {
+		t.Errorf("failed to release delegator: %v", err)
+	}
+}
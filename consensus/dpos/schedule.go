@@ -0,0 +1,268 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+const (
+	inmemorySchedules = 128 // Number of recent delegator schedules to keep in memory
+
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for vanity in checkpoint blocks
+
+	maxDelegators = 31 // Default number of top voted candidates scheduled as delegators
+)
+
+// DelegatorReader retrieves the registered delegators from the state of a
+// checkpoint block. Implementations must be deterministic, every node reading
+// the same state has to end up with the same delegators.
+type DelegatorReader interface {
+	Delegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) ([]string, error)
+}
+
+// registryReader reads the delegators from the native delegator registry in the
+// state of the checkpoint block.
+type registryReader struct {
+	max int // Maximum number of candidates to schedule
+}
+
+// NewRegistryReader creates a delegator reader scheduling the given number of
+// top voted candidates of the native delegator registry.
+func NewRegistryReader(max int) DelegatorReader {
+	return &registryReader{max: max}
+}
+
+func (r *registryReader) Delegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) ([]string, error) {
+	candidates := vm.TopDelegators(state, r.max)
+
+	delegators := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		delegators[i] = candidate.Id
+	}
+	return delegators, nil
+}
+
+// Schedule is the packaging order of the delegators within one epoch. It is
+// derived from the delegator set recorded in the checkpoint block closing the
+// previous epoch, shuffled with the seal-free hash of that checkpoint as seed
+// (see scheduleSeed). Any node
+// holding the checkpoint header is able to recompute who was allowed to
+// package a block.
+//
+// A chain whose genesis records no delegators, as the default genesis, is
+// bootstrapped: until a checkpoint records the first delegators, the blocks are
+// packaged by the election node, and any node's seal is accepted.
+type Schedule struct {
+	Epoch      uint64      `json:"epoch"`               // Epoch the schedule is valid for
+	Checkpoint common.Hash `json:"checkpoint"`          // Hash of the block the delegators were recorded in
+	Delegators []string    `json:"delegators"`          // Shuffled short node ids of the delegators
+	Bootstrap  bool        `json:"bootstrap,omitempty"` // Whether no delegators were ever recorded on the chain
+}
+
+// newSchedule sorts the given delegators and shuffles them with the given seed
+// derived from the checkpoint block.
+func newSchedule(epoch uint64, checkpoint common.Hash, seed common.Hash, delegators []string) *Schedule {
+	shuffled := make([]string, len(delegators))
+	copy(shuffled, delegators)
+	sort.Strings(shuffled)
+
+	for i := len(shuffled) - 1; i > 0; i-- {
+		seed = crypto.Keccak256Hash(seed.Bytes())
+		j := new(big.Int).Mod(seed.Big(), big.NewInt(int64(i+1))).Int64()
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	return &Schedule{Epoch: epoch, Checkpoint: checkpoint, Delegators: shuffled}
+}
+
+// scheduleSeed returns the seed the delegators recorded in a checkpoint header
+// are shuffled with. It leaves out the seal and the vanity of the checkpoint, so
+// its producer cannot grind the schedule of the next epoch by re-signing the
+// block or tweaking its vanity. Headers without room for a seal, as a genesis
+// block recording no delegators, are seeded with their plain hash.
+func scheduleSeed(header *types.Header) common.Hash {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return header.Hash()
+	}
+	unsealed := types.CopyHeader(header)
+	unsealed.Extra = append(make([]byte, extraVanity), header.Extra[extraVanity:]...)
+	return sigHash(unsealed)
+}
+
+// Producer returns the delegator entitled to package the block of the given
+// round, or an empty string if no delegator is scheduled at all.
+func (s *Schedule) Producer(round uint64) string {
+	if len(s.Delegators) == 0 {
+		return ""
+	}
+	return s.Delegators[round%uint64(len(s.Delegators))]
+}
+
+// Authorizes returns whether the given delegator may seal the block of the given
+// round: the scheduled producer, or any node while the chain is bootstrapped.
+func (s *Schedule) Authorizes(round uint64, presidentId string) bool {
+	if s.Bootstrap {
+		return presidentId != ""
+	}
+	producer := s.Producer(round)
+	return producer != "" && producer == presidentId
+}
+
+// Includes returns whether the given delegator is part of the schedule.
+func (s *Schedule) Includes(presidentId string) bool {
+	for _, delegator := range s.Delegators {
+		if delegator == presidentId {
+			return true
+		}
+	}
+	return false
+}
+
+// epochLength returns the number of blocks of an epoch.
+func (dpos *DElection) epochLength() uint64 {
+	return dpos.config.EpochLength()
+}
+
+// isCheckpoint returns whether the block of the given number records the
+// delegators of the next epoch.
+func (dpos *DElection) isCheckpoint(number uint64) bool {
+	return number > 0 && number%dpos.epochLength() == 0
+}
+
+// checkpointDelegators extracts the delegator set recorded in the extra-data of
+// a checkpoint header.
+func checkpointDelegators(header *types.Header) ([]string, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errInvalidCheckpointDelegators
+	}
+	var delegators []string
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &delegators); err != nil {
+		return nil, errInvalidCheckpointDelegators
+	}
+	return delegators, nil
+}
+
+// recordDelegators reads the delegators from the state of a checkpoint block
+// and records them in its extra-data. If the header already carries a delegator
+// set, as in the case of an imported block, it is checked against the state.
+func (dpos *DElection) recordDelegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) error {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errInvalidCheckpointDelegators
+	}
+	dpos.lock.RLock()
+	reader := dpos.reader
+	dpos.lock.RUnlock()
+
+	delegators := []string{}
+	if reader != nil {
+		var err error
+		if delegators, err = reader.Delegators(chain, header, state); err != nil {
+			return err
+		}
+	}
+	blob, err := rlp.EncodeToBytes(delegators)
+	if err != nil {
+		return err
+	}
+	recorded := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(recorded) == 0 {
+		extra := make([]byte, 0, extraVanity+len(blob)+extraSeal)
+		extra = append(extra, header.Extra[:extraVanity]...)
+		extra = append(extra, blob...)
+		header.Extra = append(extra, header.Extra[len(header.Extra)-extraSeal:]...)
+		return nil
+	}
+	if !bytes.Equal(recorded, blob) {
+		return errInvalidCheckpointDelegators
+	}
+	return nil
+}
+
+// Schedule retrieves the delegator schedule which is valid for the block on
+// top of the given parent header.
+func (dpos *DElection) Schedule(chain consensus.ChainReader, parent *types.Header) (*Schedule, error) {
+	return dpos.schedule(chain, parent.Number.Uint64()+1, parent.Hash(), nil)
+}
+
+// schedule retrieves the delegator schedule of the given block number, walking
+// back from its parent to the checkpoint of the epoch. The parents are headers
+// not yet in the database, as in the case of a batch verification.
+func (dpos *DElection) schedule(chain consensus.ChainReader, number uint64, parentHash common.Hash, parents []*types.Header) (*Schedule, error) {
+	length := dpos.epochLength()
+	epoch := (number - 1) / length
+
+	var (
+		hash  = parentHash
+		num   = number - 1
+		found *Schedule
+	)
+	for {
+		// If an in-memory schedule is known at this point of the branch, use it
+		if s, ok := dpos.schedules.Get(hash); ok && s.(*Schedule).Epoch == epoch {
+			found = s.(*Schedule)
+			break
+		}
+		var header *types.Header
+		if len(parents) > 0 && parents[len(parents)-1].Hash() == hash {
+			header, parents = parents[len(parents)-1], parents[:len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, num)
+		}
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		// The checkpoint closing the previous epoch is reached, collect the delegators
+		if num == epoch*length {
+			delegators, err := checkpointDelegators(header)
+			if err != nil {
+				if num > 0 {
+					return nil, err
+				}
+				// The genesis block may not record any delegators
+				delegators = nil
+			}
+			found = newSchedule(epoch, hash, scheduleSeed(header), delegators)
+			if len(delegators) == 0 {
+				// The chain stays bootstrapped until the first delegators are recorded
+				if num == 0 {
+					found.Bootstrap = true
+				} else {
+					previous, err := dpos.schedule(chain, num, header.ParentHash, parents)
+					if err != nil {
+						return nil, err
+					}
+					found.Bootstrap = previous.Bootstrap
+				}
+			}
+			dpos.schedules.Add(hash, found)
+			break
+		}
+		hash, num = header.ParentHash, num-1
+	}
+	dpos.schedules.Add(parentHash, found)
+	return found, nil
+}
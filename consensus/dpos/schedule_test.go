@@ -0,0 +1,210 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+)
+
+// testerCheckpointDelegators returns a delegator set depending on the number of
+// the checkpoint.
+func testerCheckpointDelegators(number uint64) []string {
+	delegators := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		delegators = append(delegators, fmt.Sprintf("%016x", number*10+uint64(i)))
+	}
+	return delegators
+}
+
+// testerReader reads the delegators of a checkpoint by its number.
+type testerReader struct{}
+
+func (testerReader) Delegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) ([]string, error) {
+	return testerCheckpointDelegators(header.Number.Uint64()), nil
+}
+
+// newTesterHeaderChain creates a linked list of headers of the given length,
+// recording the delegators in every checkpoint of the given epoch length.
+func newTesterHeaderChain(n int, epoch uint64) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := 0; i < n; i++ {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(int64(i)), Round: uint64(i)}
+		if uint64(i)%epoch == 0 {
+			headers[i].Extra = testerCheckpointExtra(testerCheckpointDelegators(uint64(i))...)
+		}
+		if i > 0 {
+			headers[i].ParentHash = headers[i-1].Hash()
+		}
+	}
+	return headers
+}
+
+// Tests that the delegators of an epoch are taken from the checkpoint block that
+// closes the previous epoch and are shuffled deterministically by its seal-free hash.
+func TestEpochSchedule(t *testing.T) {
+	headers := newTesterHeaderChain(10, 3)
+	chain := &testerChainReader{headers: make(map[common.Hash]*types.Header)}
+	for _, header := range headers {
+		chain.headers[header.Hash()] = header
+	}
+	engine := New(&config.DPoSConfig{Epoch: 3}, nil)
+
+	for number := 1; number < len(headers); number++ {
+		schedule, err := engine.Schedule(chain, headers[number-1])
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve schedule: %v", number, err)
+		}
+		checkpoint := headers[(number-1)/3*3]
+		if schedule.Checkpoint != checkpoint.Hash() {
+			t.Errorf("block %d: checkpoint mismatch: have %x, want %x", number, schedule.Checkpoint, checkpoint.Hash())
+		}
+		want := testerCheckpointDelegators(checkpoint.Number.Uint64())
+		have := append([]string{}, schedule.Delegators...)
+		sort.Strings(have)
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("block %d: delegators mismatch: have %v, want %v", number, have, want)
+		}
+		// A fresh engine must come to the very same packaging order
+		fresh, err := New(&config.DPoSConfig{Epoch: 3}, nil).Schedule(chain, headers[number-1])
+		if err != nil {
+			t.Fatalf("block %d: failed to recompute schedule: %v", number, err)
+		}
+		if !reflect.DeepEqual(schedule, fresh) {
+			t.Errorf("block %d: schedule not reproducible: have %v, want %v", number, fresh.Delegators, schedule.Delegators)
+		}
+	}
+}
+
+// Tests that re-sealing a checkpoint or changing its vanity does not change the
+// schedule seed, while the recorded delegators do.
+func TestScheduleSeed(t *testing.T) {
+	checkpoint := &types.Header{Number: big.NewInt(3), Time: big.NewInt(3), Extra: testerCheckpointExtra(testerCheckpointDelegators(3)...)}
+	seed := scheduleSeed(checkpoint)
+
+	resealed := types.CopyHeader(checkpoint)
+	resealed.Extra[len(resealed.Extra)-1] = 0x01
+	if have := scheduleSeed(resealed); have != seed {
+		t.Errorf("re-sealed checkpoint: seed mismatch: have %x, want %x", have, seed)
+	}
+	vanity := types.CopyHeader(checkpoint)
+	vanity.Extra[0] = 0x01
+	if have := scheduleSeed(vanity); have != seed {
+		t.Errorf("changed vanity: seed mismatch: have %x, want %x", have, seed)
+	}
+	other := types.CopyHeader(checkpoint)
+	other.Extra = testerCheckpointExtra(testerCheckpointDelegators(6)...)
+	if have := scheduleSeed(other); have == seed {
+		t.Errorf("changed delegators: seed unchanged %x", have)
+	}
+}
+
+// Tests that the schedule can be resolved through headers which are not yet
+// stored in the database, as during a batch import.
+func TestEpochScheduleUnknownParents(t *testing.T) {
+	headers := newTesterHeaderChain(8, 3)
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{headers[0].Hash(): headers[0]}}
+
+	engine := New(&config.DPoSConfig{Epoch: 3}, nil)
+	if _, err := engine.Schedule(chain, headers[6]); err != consensus.ErrUnknownAncestor {
+		t.Fatalf("missing parents: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+	schedule, err := engine.schedule(chain, 8, headers[7].Hash(), headers[1:8])
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule: %v", err)
+	}
+	if schedule.Checkpoint != headers[6].Hash() {
+		t.Errorf("checkpoint mismatch: have %x, want %x", schedule.Checkpoint, headers[6].Hash())
+	}
+}
+
+// Tests that checkpoint blocks record the delegators read from their state when
+// packaged, and that imported checkpoints must match their state.
+func TestCheckpointDelegators(t *testing.T) {
+	engine := New(&config.DPoSConfig{Epoch: 3}, nil)
+	engine.SetDelegatorReader(testerReader{})
+
+	parent := &types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(1), Time: big.NewInt(0)}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(3), Time: big.NewInt(5), Extra: []byte("vanity")}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare checkpoint: %v", err)
+	}
+	if len(header.Extra) != extraVanity+extraSeal {
+		t.Fatalf("prepared extra-data length mismatch: have %d, want %d", len(header.Extra), extraVanity+extraSeal)
+	}
+	if err := engine.recordDelegators(chain, header, nil); err != nil {
+		t.Fatalf("failed to record delegators: %v", err)
+	}
+	delegators, err := checkpointDelegators(header)
+	if err != nil {
+		t.Fatalf("failed to extract delegators: %v", err)
+	}
+	if want := testerCheckpointDelegators(3); !reflect.DeepEqual(delegators, want) {
+		t.Errorf("recorded delegators mismatch: have %v, want %v", delegators, want)
+	}
+	// Importing the very same checkpoint must pass, a forged one must fail
+	if err := engine.recordDelegators(chain, header, nil); err != nil {
+		t.Errorf("failed to verify recorded delegators: %v", err)
+	}
+	header.Extra = testerCheckpointExtra("0000000000000000")
+	if err := engine.recordDelegators(chain, header, nil); err != errInvalidCheckpointDelegators {
+		t.Errorf("forged delegators: have %v, want %v", err, errInvalidCheckpointDelegators)
+	}
+}
+
+// Tests that a chain whose genesis records no delegators is bootstrapped, any
+// node sealing its blocks, until the first delegators are recorded, and that an
+// epoch without delegators afterwards authorizes nobody.
+func TestBootstrapSchedule(t *testing.T) {
+	headers := newTesterHeaderChain(11, 3)
+	headers[0].Extra = make([]byte, extraVanity)
+	headers[3].Extra = testerCheckpointExtra()
+	headers[9].Extra = testerCheckpointExtra()
+	for i := 1; i < len(headers); i++ {
+		headers[i].ParentHash = headers[i-1].Hash()
+	}
+	chain := &testerChainReader{headers: make(map[common.Hash]*types.Header)}
+	for _, header := range headers {
+		chain.headers[header.Hash()] = header
+	}
+	engine := New(&config.DPoSConfig{Epoch: 3}, nil)
+
+	for number := 1; number < len(headers); number++ {
+		schedule, err := engine.Schedule(chain, headers[number-1])
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve schedule: %v", number, err)
+		}
+		bootstrap := number <= 6
+		if schedule.Bootstrap != bootstrap {
+			t.Errorf("block %d: bootstrap mismatch: have %v, want %v", number, schedule.Bootstrap, bootstrap)
+		}
+		if authorized := schedule.Authorizes(uint64(number), "0123456789abcdef"); authorized != bootstrap {
+			t.Errorf("block %d: unscheduled node authorization mismatch: have %v, want %v", number, authorized, bootstrap)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// VerifyDoubleSign implements consensus.Slasher, checking whether both headers
+// are different blocks sealed by the same delegator for the same round. The id
+// of the offending delegator is returned. Headers are compared by their signing
+// hash, two seals of the very same block are no evidence of double signing.
+func (dpos *DElection) VerifyDoubleSign(first, second *types.Header) (string, error) {
+	if len(first.Extra) < extraSeal || len(second.Extra) < extraSeal {
+		return "", errMissingSignature
+	}
+	if first.Round != second.Round || first.DAppID != second.DAppID || sigHash(first) == sigHash(second) {
+		return "", errInvalidEvidence
+	}
+	signer, err := ecrecover(first, dpos.signatures)
+	if err != nil {
+		return "", err
+	}
+	other, err := ecrecover(second, dpos.signatures)
+	if err != nil {
+		return "", err
+	}
+	if signer != other || signer != first.PresidentId || signer != second.PresidentId {
+		return "", errInvalidEvidence
+	}
+	return signer, nil
+}
+
+// VerifyDoublePreCommit implements consensus.Slasher, checking whether both votes
+// were signed by the same delegator for different blocks of the same height.
+// The id of the offending delegator is returned.
+func (dpos *DElection) VerifyDoublePreCommit(first, second *types.PreCommit) (string, error) {
+	if first.Number == 0 || first.Number != second.Number || first.Hash == second.Hash || first.Delegator != second.Delegator {
+		return "", errInvalidEvidence
+	}
+	if err := verifyPreCommitSigner(first); err != nil {
+		return "", err
+	}
+	if err := verifyPreCommitSigner(second); err != nil {
+		return "", err
+	}
+	return first.Delegator, nil
+}
+
+// slashMissedSlots accounts the rounds skipped between the parent and the given
+// header as missed slots of their scheduled delegators, both in their liveness
+// record and in their slashing count. Delegators reaching the limit of missed
+// slots within an epoch are slashed, and their slashing count restarts.
+func (dpos *DElection) slashMissedSlots(chain consensus.ChainReader, header *types.Header, state *state.StateDB) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if header.Round <= parent.Round+1 {
+		return nil
+	}
+	schedule, err := dpos.schedule(chain, number, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if len(schedule.Delegators) == 0 {
+		return nil
+	}
+	// Beyond a full rotation every delegator is slashed anyway, don't loop forever
+	limit := dpos.config.MissedSlotsLimit()
+	missed := header.Round - parent.Round - 1
+	if rotation := uint64(len(schedule.Delegators)) * limit; missed > rotation {
+		missed = rotation
+	}
+	for round := header.Round - missed; round < header.Round; round++ {
+		delegator := schedule.Producer(round)
+		vm.RecordSlots(state, delegator, schedule.Epoch, 0, 1)
+
+		count := vm.MissedSlots(state, delegator, schedule.Epoch) + 1
+		if count >= limit {
+			penalty := vm.SlashDelegator(state, delegator, dpos.config.MissedSlotPenalty(), false)
+			log.Info("Slashed delegator for missed slots", "delegator", delegator, "epoch", schedule.Epoch, "penalty", penalty)
+			count = 0
+		}
+		vm.SetMissedSlots(state, delegator, schedule.Epoch, count)
+	}
+	return nil
+}
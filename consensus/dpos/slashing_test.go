@@ -0,0 +1,182 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// Tests that two different blocks sealed by the same delegator for the same
+// round are accepted as double signing evidence, and nothing else is.
+func TestVerifyDoubleSign(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	presidentId := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(presidentId, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	seal := func(number int64, round uint64, president string) *types.Header {
+		header := &types.Header{
+			Number:      big.NewInt(number),
+			Time:        big.NewInt(number),
+			Round:       round,
+			PresidentId: president,
+			Extra:       make([]byte, extraSeal),
+		}
+		sighash, _ := crypto.Sign(sigHash(header).Bytes(), key)
+		copy(header.Extra, sighash)
+		return header
+	}
+	first, second := seal(1, 3, presidentId), seal(2, 3, presidentId)
+	if signer, err := engine.VerifyDoubleSign(first, second); err != nil || signer != presidentId {
+		t.Errorf("double signing: have %s, %v, want %s", signer, err, presidentId)
+	}
+	if _, err := engine.VerifyDoubleSign(first, first); err != errInvalidEvidence {
+		t.Errorf("identical blocks: have %v, want %v", err, errInvalidEvidence)
+	}
+	// Re-sealing the same block, as with a malleated signature, is no evidence
+	resealed := types.CopyHeader(first)
+	resealed.Extra = append(resealed.Extra[:0:0], first.Extra...)
+	resealed.Extra[len(resealed.Extra)-1] ^= 0x01
+	if _, err := engine.VerifyDoubleSign(first, resealed); err != errInvalidEvidence {
+		t.Errorf("re-sealed block: have %v, want %v", err, errInvalidEvidence)
+	}
+	malleated := types.CopyHeader(first)
+	malleated.Extra = append(malleated.Extra[:0:0], first.Extra...)
+	sig := malleated.Extra[len(malleated.Extra)-extraSeal:]
+	copy(sig[32:64], common.LeftPadBytes(new(big.Int).Sub(crypto.S256().Params().N, new(big.Int).SetBytes(sig[32:64])).Bytes(), 32))
+	sig[64] ^= 0x01
+	if _, err := engine.Signer(malleated); err != errInvalidSignature {
+		t.Errorf("malleated seal: have %v, want %v", err, errInvalidSignature)
+	}
+	if _, err := engine.VerifyDoubleSign(first, seal(2, 4, presidentId)); err != errInvalidEvidence {
+		t.Errorf("different rounds: have %v, want %v", err, errInvalidEvidence)
+	}
+	if _, err := engine.VerifyDoubleSign(first, seal(2, 3, "0000000000000000")); err != errInvalidEvidence {
+		t.Errorf("foreign president: have %v, want %v", err, errInvalidEvidence)
+	}
+}
+
+// Tests that pre-commit votes of the same delegator for different blocks of the
+// same height are accepted as double voting evidence, and nothing else is.
+func TestVerifyDoublePreCommit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	delegator := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(delegator, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	vote := func(number uint64, round uint64) *types.PreCommit {
+		vote, err := engine.SignPreCommit(&types.Header{Number: new(big.Int).SetUint64(number), Round: round})
+		if err != nil {
+			t.Fatalf("failed to sign pre-commit: %v", err)
+		}
+		return vote
+	}
+	first, second := vote(2, 2), vote(2, 3)
+	if signer, err := engine.VerifyDoublePreCommit(first, second); err != nil || signer != delegator {
+		t.Errorf("double voting: have %s, %v, want %s", signer, err, delegator)
+	}
+	if _, err := engine.VerifyDoublePreCommit(first, first); err != errInvalidEvidence {
+		t.Errorf("identical votes: have %v, want %v", err, errInvalidEvidence)
+	}
+	if _, err := engine.VerifyDoublePreCommit(first, vote(3, 3)); err != errInvalidEvidence {
+		t.Errorf("different heights: have %v, want %v", err, errInvalidEvidence)
+	}
+	forged := *second
+	forged.Hash = common.Hash{0x01}
+	if _, err := engine.VerifyDoublePreCommit(first, &forged); err != errInvalidPreCommit {
+		t.Errorf("forged vote: have %v, want %v", err, errInvalidPreCommit)
+	}
+}
+
+// Tests that the delegators scheduled for skipped rounds are accounted missed
+// slots and slashed once they reach the limit within an epoch.
+func TestMissedSlotSlashing(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	keyA, idA := testerDelegator(1)
+	keyB, idB := testerDelegator(2)
+
+	parent := &types.Header{
+		Number: big.NewInt(0),
+		Round:  0,
+		Extra:  testerCheckpointExtra(idA, idB),
+	}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+	engine := New(&config.DPoSConfig{MissedSlots: 2, MissedSlotSlash: 100}, nil)
+
+	// Register both delegators with some bonded votes
+	evm := vm.NewEVM(vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, config.TestChainConfig, vm.Config{})
+
+	registry, _ := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
+	keys := map[string]*ecdsa.PrivateKey{idA: keyA, idB: keyB}
+	for i, id := range []string{idA, idB} {
+		voter := vm.AccountRef(common.BytesToAddress([]byte{byte(i + 1)}))
+
+		register := testerRegistration(registry, voter.Address(), keys[id])
+		vote, _ := registry.Pack("vote", id)
+		if _, _, err := evm.Call(voter, vm.DelegatorRegistryAddress, register, 1000000, new(big.Int)); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+		if _, _, err := evm.Call(voter, vm.DelegatorRegistryAddress, vote, 1000000, big.NewInt(1000)); err != nil {
+			t.Fatalf("failed to vote for %s: %v", id, err)
+		}
+	}
+	schedule, err := engine.Schedule(chain, parent)
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule: %v", err)
+	}
+	// Skipping three rounds misses two slots of one delegator and one of the other
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), Round: 4}
+	if err := engine.slashMissedSlots(chain, header, statedb); err != nil {
+		t.Fatalf("failed to account missed slots: %v", err)
+	}
+	twice, once := schedule.Producer(1), schedule.Producer(2)
+	if candidate := vm.GetDelegatorCandidate(statedb, twice); candidate.Votes.Int64() != 900 {
+		t.Errorf("delegator missing twice: have %v votes, want %v", candidate.Votes, 900)
+	}
+	if missed := vm.MissedSlots(statedb, twice, schedule.Epoch); missed != 0 {
+		t.Errorf("delegator missing twice: have %d missed slots, want %d", missed, 0)
+	}
+	if candidate := vm.GetDelegatorCandidate(statedb, once); candidate.Votes.Int64() != 1000 {
+		t.Errorf("delegator missing once: have %v votes, want %v", candidate.Votes, 1000)
+	}
+	if missed := vm.MissedSlots(statedb, once, schedule.Epoch); missed != 1 {
+		t.Errorf("delegator missing once: have %d missed slots, want %d", missed, 1)
+	}
+}
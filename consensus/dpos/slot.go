@@ -0,0 +1,62 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+
+	"github.com/juchain/go-juchain/core/types"
+)
+
+// errInvalidSlotTime is returned if the timestamp of a block is not the begin of
+// the slot of its round, as derived from the timestamp and round of its parent.
+var errInvalidSlotTime = errors.New("timestamp off the slot grid")
+
+// SlotTime returns the time the slot of the given round begins on top of the
+// parent. Slots follow each other every period starting at the parent, so the
+// time of a block is fully determined by its parent and its round.
+func (dpos *DElection) SlotTime(parent *types.Header, round uint64) uint64 {
+	return parent.Time.Uint64() + (round-parent.Round)*dpos.config.BlockPeriod()
+}
+
+// CurrentSlot returns the round of the latest slot on top of the parent which
+// began at the given time, along with its begin. Before the first slot began,
+// the first slot is returned.
+func (dpos *DElection) CurrentSlot(parent *types.Header, now uint64) (uint64, uint64) {
+	round := parent.Round + 1
+	if start := parent.Time.Uint64(); now > start {
+		if elapsed := (now - start) / dpos.config.BlockPeriod(); elapsed > 1 {
+			round = parent.Round + elapsed
+		}
+	}
+	return round, dpos.SlotTime(parent, round)
+}
+
+// InGrace returns whether a delegator may still seal the slot beginning at the
+// given start at the given time.
+func (dpos *DElection) InGrace(start, now uint64) bool {
+	return now >= start && now-start <= dpos.config.GraceWindow()
+}
+
+// verifySlotTime checks whether the timestamp of the header is the begin of the
+// slot of its round.
+func (dpos *DElection) verifySlotTime(header, parent *types.Header) error {
+	if !header.Time.IsUint64() || header.Time.Uint64() != dpos.SlotTime(parent, header.Round) {
+		return errInvalidSlotTime
+	}
+	return nil
+}
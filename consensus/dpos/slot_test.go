@@ -0,0 +1,110 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/discover"
+)
+
+// Tests that the slots on top of a block follow its timestamp every period, and
+// that the delegators may seal their slot within the grace window only.
+func TestSlots(t *testing.T) {
+	engine := New(&config.DPoSConfig{Period: 10, Grace: 3}, nil)
+	parent := &types.Header{Number: big.NewInt(7), Time: big.NewInt(1000), Round: 20}
+
+	tests := []struct {
+		now   uint64
+		round uint64
+		start uint64
+		grace bool
+	}{
+		{now: 990, round: 21, start: 1010},  // clock behind the parent, first slot ahead
+		{now: 1005, round: 21, start: 1010}, // first slot ahead
+		{now: 1010, round: 21, start: 1010}, // first slot began
+		{now: 1013, round: 21, start: 1010}, // end of the grace window
+		{now: 1014, round: 21, start: 1010}, // grace window passed
+		{now: 1019, round: 21, start: 1010}, // right before the second slot
+		{now: 1020, round: 22, start: 1020}, // second slot began
+		{now: 1047, round: 24, start: 1040}, // fourth slot passed its grace window
+	}
+	for i, tt := range tests {
+		round, start := engine.CurrentSlot(parent, tt.now)
+		if round != tt.round || start != tt.start {
+			t.Errorf("test %d: slot mismatch: have round %d at %d, want round %d at %d", i, round, start, tt.round, tt.start)
+		}
+		if grace := engine.InGrace(start, tt.now); grace != (tt.now >= tt.start && tt.now <= tt.start+3) {
+			t.Errorf("test %d: grace mismatch: have %v", i, grace)
+		}
+	}
+	// The grace window defaults to half a period and always ends before the next slot
+	if grace := (&config.DPoSConfig{Period: 10}).GraceWindow(); grace != 5 {
+		t.Errorf("default grace window: have %d, want %d", grace, 5)
+	}
+	if grace := (&config.DPoSConfig{Period: 10, Grace: 15}).GraceWindow(); grace != 9 {
+		t.Errorf("oversized grace window: have %d, want %d", grace, 9)
+	}
+}
+
+// Tests that sealed blocks are only valid when stamped with the begin of the
+// slot of their round.
+func TestSlotTimeVerification(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	presidentId := discover.PubkeyID(&key.PublicKey).TerminalString()
+
+	parent := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Time:       big.NewInt(100),
+		Extra:      testerCheckpointExtra(presidentId),
+	}
+	chain := &testerChainReader{headers: map[common.Hash]*types.Header{parent.Hash(): parent}}
+
+	engine := New(&config.DPoSConfig{Period: 5}, nil)
+	engine.Authorize(presidentId, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	for _, time := range []int64{100, 104, 105, 106, 110} {
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			Number:      big.NewInt(1),
+			Time:        big.NewInt(time),
+			Round:       1,
+			PresidentId: presidentId,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		block, err := engine.Seal(chain, types.NewBlockWithHeader(header), nil)
+		if err != nil {
+			t.Fatalf("failed to seal block: %v", err)
+		}
+		err = engine.VerifySeal(chain, block.Header())
+		if time == 105 && err != nil {
+			t.Errorf("time %d: failed to verify block on the slot grid: %v", time, err)
+		}
+		if time != 105 && err != errInvalidSlotTime {
+			t.Errorf("time %d: off-grid block: have %v, want %v", time, err, errInvalidSlotTime)
+		}
+	}
+}
@@ -59,6 +59,12 @@ type Backend interface {
 	ChainDb() store.Database
 }
 
+// evidenceBackend is implemented by backends detecting double signing delegators,
+// whose evidence is included in the packaged blocks.
+type evidenceBackend interface {
+	EvidencePool() *EvidencePool
+}
+
 // Work is the workers current environment and holds
 // all of the current state information
 type Work struct {
@@ -98,9 +104,10 @@ type Packager struct {
 	chainSideSub event.Subscription
 	wg           sync.WaitGroup
 
-	eth     Backend
-	chain   *core.BlockChain
-	proc    core.Validator
+	eth      Backend
+	chain    *core.BlockChain
+	proc     core.Validator
+	evidence *EvidencePool // double signing evidence to slash with, nil if none detected
 
 	coinbase common.Address
 	extra    []byte
@@ -134,6 +141,9 @@ func NewPackager(config *config.ChainConfig, engine consensus.Engine, coinbase c
 		coinbase:       coinbase,
 		unconfirmed:    newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
 	}
+	if backend, ok := eth.(evidenceBackend); ok {
+		worker.evidence = backend.EvidencePool()
+	}
 	// Subscribe TxPreEvent for tx pool
 	worker.txSub = eth.TxPool().SubscribeTxPreEvent(worker.txCh)
 	// Subscribe events for blockchain
@@ -245,7 +255,7 @@ func (self *Packager) makeCurrent(parent *types.Block, header *types.Header) (*W
 	}
 	work := &Work{
 		config:    self.config,
-		signer:    types.NewChainSigner(self.config.ChainId),
+		signer:    types.MakeSigner(self.config, header.Number),
 		state:     state,
 		ancestors: set.New(),
 		family:    set.New(),
@@ -269,6 +279,17 @@ func (self *Packager) makeCurrent(parent *types.Block, header *types.Header) (*W
 }
 
 func (self *Packager) GenerateNewBlock(round uint64, presidentId string) *types.Block {
+	return self.generateNewBlock(round, presidentId, nil)
+}
+
+// SealNewBlock packages a block on top of the current head for engines sealing
+// whenever they are allowed to, rather than in the slots of the delegators. The
+// sealing is given up once stop is closed, returning nil.
+func (self *Packager) SealNewBlock(stop <-chan struct{}) *types.Block {
+	return self.generateNewBlock(0, "", stop)
+}
+
+func (self *Packager) generateNewBlock(round uint64, presidentId string, stop <-chan struct{}) *types.Block {
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	self.uncleMu.Lock()
@@ -277,7 +298,10 @@ func (self *Packager) GenerateNewBlock(round uint64, presidentId string) *types.
 	tstart := time.Now()
 	parent := self.chain.CurrentBlock()
 	tstamp := tstart.Unix()
-	if parent.Time().Cmp(new(big.Int).SetInt64(tstamp)) >= 0 {
+	if election, ok := self.engine.(*DElection); ok {
+		// The block is stamped with the begin of its slot, whenever it is sealed
+		tstamp = int64(election.SlotTime(parent.Header(), round))
+	} else if parent.Time().Cmp(new(big.Int).SetInt64(tstamp)) >= 0 {
 		tstamp = parent.Time().Int64() + 1
 	}
 	//log.Info("Parent block("+parent.Number().String()+") with State root " + parent.Root().String())
@@ -312,6 +336,7 @@ func (self *Packager) GenerateNewBlock(round uint64, presidentId string) *types.
 	}
 	txs := types.NewTransactionsByPriceAndNonce(work.signer, pending)
 	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	self.commitEvidence(work)
 
 	// compute uncles for the new block.
 	var (
@@ -341,6 +366,14 @@ func (self *Packager) GenerateNewBlock(round uint64, presidentId string) *types.
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return nil;
 	}
+	if work.Block, err = self.engine.Seal(self.chain, work.Block, stop); err != nil {
+		log.Error("Failed to seal block", "err", err)
+		return nil;
+	}
+	if work.Block == nil {
+		log.Debug("Sealing aborted", "number", header.Number)
+		return nil;
+	}
 
 	log.Debug("Committed new block", "number", work.Block.Number(), "txs", work.tcount, "uncles", len(uncles), "elapsed", common.PrettyDuration(time.Since(tstart)))
 	self.unconfirmed.Shift(work.Block.NumberU64() - 1)
@@ -385,6 +418,55 @@ func (self *Packager) GenerateNewBlock(round uint64, presidentId string) *types.
 }
 
 
+// commitEvidence slashes the delegators caught double signing, including a
+// registry call of the coinbase account for every proof not yet used. Without
+// an unlocked coinbase account the evidence is left to other delegators, and
+// before the system contracts fork there is no registry to slash in.
+func (self *Packager) commitEvidence(work *Work) {
+	if self.evidence == nil || self.eth == nil || !self.config.IsSystemContracts(work.header.Number) {
+		return
+	}
+	pending := self.evidence.Pending(work.state)
+	if len(pending) == 0 {
+		return
+	}
+	signer := account.Account{Address: self.coinbase}
+	wallet, err := self.eth.AccountManager().Find(signer)
+	if err != nil {
+		log.Debug("Coinbase unavailable to slash double signing", "coinbase", self.coinbase, "err", err)
+		return
+	}
+	for _, evidence := range pending {
+		data, err := evidence.pack()
+		if err != nil {
+			log.Error("Failed to pack double signing evidence", "hash", evidence.Hash, "err", err)
+			continue
+		}
+		gas, err := core.IntrinsicGas(data, false)
+		if err != nil {
+			continue
+		}
+		gas += config.DelegatorSlashGas
+		if work.header.GasLimit < work.header.GasUsed+gas {
+			return
+		}
+		tx := types.NewTransaction(work.state.GetNonce(self.coinbase), vm.DelegatorRegistryAddress, new(big.Int), gas, new(big.Int), data)
+		if tx, err = wallet.SignTx(signer, tx, self.config.ChainId); err != nil {
+			log.Debug("Coinbase unable to slash double signing", "coinbase", self.coinbase, "err", err)
+			return
+		}
+		work.state.Prepare(tx.Hash(), common.Hash{}, work.tcount)
+
+		gp := new(core.GasPool).AddGas(work.header.GasLimit - work.header.GasUsed)
+		if err, _ := work.commitTransaction(tx, self.chain, self.coinbase, gp); err != nil {
+			log.Debug("Failed to include double signing evidence", "hash", evidence.Hash, "err", err)
+			continue
+		}
+		work.tcount++
+		log.Info("Slashing double signing delegator", "delegator", evidence.Delegator, "round", evidence.Round, "evidence", evidence.Hash)
+	}
+}
+
 func (self *Packager) commitUncle(work *Work, uncle *types.Header) error {
 	hash := uncle.Hash()
 	if work.uncles.Has(hash) {
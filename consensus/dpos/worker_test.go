@@ -2,24 +2,100 @@ package dpos
 
 import (
 	"testing"
-	"github.com/juchain/go-juchain/config"
-	"math/big"
-	"github.com/juchain/go-juchain/common"
-	"github.com/juchain/go-juchain/p2p/protocol"
 
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
 )
 
 func TestGenerateNewBlock(t *testing.T) {
 
-	engine := New(&config.DPoSConfig{0,0}, nil);
-	eth := &protocol.JuchainService{
+	key, _ := crypto.GenerateKey()
+	engine := New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, nil)
+	engine.Authorize("testnode1", func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	db, _ := store.NewMemDatabase()
+	(&core.Genesis{Config: config.TestChainConfig}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
 	}
-	packager := NewPackager(&config.ChainConfig{big.NewInt(1337), big.NewInt(0) , nil, nil, new(config.DPoSConfig)}, engine, common.Address{},eth, eth.EventMux());
+	defer chain.Stop()
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, config.TestChainConfig, chain)
+	defer pool.Stop()
 
-	packager.Start();
+	packager := NewPackager1(config.TestChainConfig, engine, common.Address{}, chain, pool, new(event.TypeMux))
 
-	packager.GenerateNewBlock();
-	packager.GenerateNewBlock();
+	packager.Start()
 
-	packager.Stop();
+	for i := uint64(1); i <= 2; i++ {
+		block := packager.GenerateNewBlock(i, "testnode1")
+		if block == nil {
+			t.Fatalf("block %d: failed to generate", i)
+		}
+		if block.NumberU64() != i {
+			t.Errorf("block %d: number mismatch: have %d", i, block.NumberU64())
+		}
+	}
+	packager.Stop()
+}
+
+// Tests that a chain started from the default genesis, which records no
+// delegators, is bootstrapped by the election node, and that its blocks are
+// accepted by any other node.
+func TestGenerateOnDefaultGenesis(t *testing.T) {
+	key, presidentId := testerDelegator(1)
+	engine := New(&config.DPoSConfig{}, nil)
+	engine.Authorize(presidentId, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	db, _ := store.NewMemDatabase()
+	genesis := core.DefaultGenesisBlock().MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, config.MainnetChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, config.MainnetChainConfig, chain)
+	defer pool.Stop()
+
+	packager := NewPackager1(config.MainnetChainConfig, engine, common.Address{}, chain, pool, new(event.TypeMux))
+	packager.Start()
+	defer packager.Stop()
+
+	var blocks types.Blocks
+	for i := uint64(1); i <= 3; i++ {
+		block := packager.GenerateNewBlock(genesis.Round()+i, presidentId)
+		if block == nil {
+			t.Fatalf("block %d: failed to generate", i)
+		}
+		blocks = append(blocks, block)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 3 {
+		t.Fatalf("head mismatch: have %d, want %d", head, 3)
+	}
+	// A fresh node must verify and import the bootstrapped blocks
+	fresh, _ := store.NewMemDatabase()
+	core.DefaultGenesisBlock().MustCommit(fresh)
+	other, err := core.NewBlockChain(fresh, nil, config.MainnetChainConfig, New(&config.DPoSConfig{}, nil), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer other.Stop()
+	if n, err := other.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to import: %v", n, err)
+	}
+	if head := other.CurrentBlock().Hash(); head != blocks[len(blocks)-1].Hash() {
+		t.Errorf("imported head mismatch: have %x, want %x", head, blocks[len(blocks)-1].Hash())
+	}
 }
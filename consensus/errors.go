@@ -34,4 +34,8 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal it's parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrMissingAnchor is returned if a DApp block doesn't refer to the main chain
+	// block anchoring it.
+	ErrMissingAnchor = errors.New("missing anchor block")
 )
@@ -585,6 +585,21 @@ func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// Authorized returns whether the local signing key is amongst the signers voted
+// in at the given header, allowed to sign the blocks following it.
+func (c *Clique) Authorized(chain consensus.ChainReader, header *types.Header) bool {
+	c.lock.RLock()
+	signer := c.signer
+	c.lock.RUnlock()
+
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return false
+	}
+	_, authorized := snap.Signers[signer]
+	return authorized
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
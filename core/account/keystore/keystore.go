@@ -281,7 +281,7 @@ func (ks *KeyStore) SignTx(a account.Account, tx *types.Transaction, chainID *bi
 	if chainID == nil {
 		return nil, errors.New("chainID must not be empty")
 	}
-	return types.SignTx(tx, types.NewChainSigner(chainID), unlockedKey.PrivateKey)
+	return types.SignTx(tx, types.NewDAppSigner(chainID), unlockedKey.PrivateKey)
 }
 
 // SignHashWithPassphrase signs hash if the private key matching the given address
@@ -308,7 +308,7 @@ func (ks *KeyStore) SignTxWithPassphrase(a account.Account, passphrase string, t
 	if chainID == nil {
 		return nil, errors.New("chainID must not be empty")
 	}
-	return types.SignTx(tx, types.NewChainSigner(chainID), key.PrivateKey)
+	return types.SignTx(tx, types.NewDAppSigner(chainID), key.PrivateKey)
 }
 
 // Unlock unlocks the given account indefinitely.
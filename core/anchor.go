@@ -0,0 +1,343 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/trie"
+	"github.com/juchain/go-juchain/core/types"
+)
+
+// AnchorProof proves the inclusion of a DApp transaction in the main chain: the
+// transaction anchoring it is included in the transaction trie of a main chain
+// header.
+type AnchorProof struct {
+	Transaction *types.Transaction `json:"transaction"`   // DApp transaction proven
+	DAppBlock   common.Hash        `json:"dappBlockHash"` // DApp block including the transaction
+	Anchor      *types.Transaction `json:"anchor"`        // Main chain transaction anchoring it
+	Index       hexutil.Uint       `json:"index"`         // Index of the anchor in its main chain block
+	Header      *types.Header      `json:"header"`        // Main chain header including the anchor
+	Proof       []hexutil.Bytes    `json:"proof"`         // Trie nodes from the transaction root to the anchor
+}
+
+// anchoredBy returns whether the main chain transaction anchors the DApp
+// transaction: both belong to the same DApp, the payload of the anchor hashes to
+// the DApp transaction and the DApp transaction refers back to the anchor.
+func anchoredBy(tx, anchor *types.Transaction) bool {
+	if tx.DAppID() == nil || anchor.DAppID() == nil || *tx.DAppID() != *anchor.DAppID() {
+		return false
+	}
+	if tx.RefHashId() == nil || *tx.RefHashId() != anchor.UnsignedHash() {
+		return false
+	}
+	hash := tx.AnchorHash()
+	return bytes.Equal(anchor.Data(), hash[:])
+}
+
+// findAnchor returns the index of the transaction anchoring the DApp transaction
+// in the list, or -1 if it is not anchored by any of them.
+func findAnchor(txs types.Transactions, tx *types.Transaction) int {
+	for i, anchor := range txs {
+		if anchoredBy(tx, anchor) {
+			return i
+		}
+	}
+	return -1
+}
+
+// anchorBlock retrieves the canonical main chain block a DApp block refers to.
+func anchorBlock(main *BlockChain, header *types.Header) (*types.Block, error) {
+	anchor := main.GetHeaderByHash(header.DAppMainHash)
+	if anchor == nil {
+		return nil, ErrUnknownAnchor
+	}
+	number := anchor.Number.Uint64()
+	if GetCanonicalHash(main.db, number) != header.DAppMainHash {
+		return nil, ErrNonCanonicalAnchor
+	}
+	block := main.GetBlock(header.DAppMainHash, number)
+	if block == nil {
+		return nil, ErrUnknownAnchor
+	}
+	return block, nil
+}
+
+// VerifyAnchor checks whether a DApp block refers to a canonical block of the
+// main chain which anchors every transaction of the DApp block. Blocks of the
+// main chain are always valid.
+func VerifyAnchor(main *BlockChain, block *types.Block) error {
+	dappId := block.DAppID()
+	if dappId == *types.EmptyDAppIdHash {
+		return nil
+	}
+	anchor, err := anchorBlock(main, block.Header())
+	if err != nil {
+		return err
+	}
+	for _, tx := range block.Transactions() {
+		if tx.DAppID() == nil || *tx.DAppID() != dappId || findAnchor(anchor.Transactions(), tx) < 0 {
+			return ErrUnanchoredTransaction
+		}
+	}
+	return nil
+}
+
+// ProveAnchor creates the inclusion proof of a DApp transaction included in the
+// given DApp block back to the header of the main chain block anchoring it.
+func ProveAnchor(main *BlockChain, block *types.Block, tx *types.Transaction) (*AnchorProof, error) {
+	anchor, err := anchorBlock(main, block.Header())
+	if err != nil {
+		return nil, err
+	}
+	txs := anchor.Transactions()
+	index := findAnchor(txs, tx)
+	if index < 0 {
+		return nil, ErrUnanchoredTransaction
+	}
+	// Rebuild the transaction trie of the anchor block and prove the anchor
+	nodes, err := proveDerivable(txs, index, anchor.TxHash())
+	if err != nil {
+		return nil, err
+	}
+	return &AnchorProof{
+		Transaction: tx,
+		DAppBlock:   block.Hash(),
+		Anchor:      txs[index],
+		Index:       hexutil.Uint(index),
+		Header:      anchor.Header(),
+		Proof:       nodes,
+	}, nil
+}
+
+// proveDerivable rebuilds the trie of a list hashed into a block header, checking
+// it against the root of the header, and returns the trie nodes proving the item
+// at the given index.
+func proveDerivable(list types.DerivableList, index int, root common.Hash) ([]hexutil.Bytes, error) {
+	tr := new(trie.Trie)
+	for i := 0; i < list.Len(); i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, list.GetRlp(i))
+	}
+	if hash := tr.Hash(); hash != root {
+		return nil, fmt.Errorf("root hash mismatch: have %x, want %x", hash, root)
+	}
+	key, _ := rlp.EncodeToBytes(uint(index))
+	proofDb, _ := store.NewMemDatabase()
+	if err := tr.Prove(key, 0, proofDb); err != nil {
+		return nil, err
+	}
+	var nodes []hexutil.Bytes
+	for _, hash := range proofDb.Keys() {
+		node, _ := proofDb.Get(hash)
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// verifyDerivable returns the RLP encoding of the item at the given index of a
+// list hashed into the root, proven by the given trie nodes.
+func verifyDerivable(root common.Hash, index uint, nodes []hexutil.Bytes) ([]byte, error) {
+	proofDb, _ := store.NewMemDatabase()
+	for _, node := range nodes {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	key, _ := rlp.EncodeToBytes(index)
+	value, err, _ := trie.VerifyProof(root, key, proofDb)
+	return value, err
+}
+
+// VerifyAnchorProof checks whether the proof includes the anchor in the
+// transaction trie of the main chain header, and the anchor anchors the DApp
+// transaction.
+func VerifyAnchorProof(proof *AnchorProof) error {
+	value, err := verifyDerivable(proof.Header.TxHash, uint(proof.Index), proof.Proof)
+	if err != nil {
+		return err
+	}
+	enc, err := rlp.EncodeToBytes(proof.Anchor)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, enc) {
+		return fmt.Errorf("anchor not included in transaction root %x", proof.Header.TxHash)
+	}
+	if !anchoredBy(proof.Transaction, proof.Anchor) {
+		return ErrUnanchoredTransaction
+	}
+	return nil
+}
+
+// CalcDAppGasLimit computes the gas limit of the next block of a DApp chain,
+// fixed by the configuration of the DApp if it sets one.
+func CalcDAppGasLimit(chainConfig *config.ChainConfig, parent *types.Block) uint64 {
+	if dapp := chainConfig.DApp; dapp != nil && dapp.GasLimit > 0 {
+		return dapp.GasLimit
+	}
+	return CalcGasLimit(parent)
+}
+
+// CalcDAppTime computes the timestamp of the next block of a DApp chain, the
+// time of its anchor block but at least one block period after its parent.
+func CalcDAppTime(chainConfig *config.ChainConfig, parent *types.Block, anchor *types.Header) *big.Int {
+	next := new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(dappBlockPeriod(chainConfig)))
+	if anchor.Time.Cmp(next) < 0 {
+		return next
+	}
+	return new(big.Int).Set(anchor.Time)
+}
+
+// dappBlockPeriod returns the minimum number of seconds between the blocks of a
+// DApp chain.
+func dappBlockPeriod(chainConfig *config.ChainConfig) uint64 {
+	if dapp := chainConfig.DApp; dapp != nil && dapp.Period > 0 {
+		return dapp.Period
+	}
+	return 1
+}
+
+// verifyDAppHeader checks whether the header of a DApp block follows its parent
+// and anchor the way the DApp packager derives it, and the configuration of the
+// DApp chain. DApp blocks all weigh the same, the chain choosing between them by
+// their number only.
+func verifyDAppHeader(chainConfig *config.ChainConfig, header *types.Header, parent *types.Block, anchor *types.Header) error {
+	if header.Number == nil || header.Number.Cmp(new(big.Int).Add(parent.Number(), common.Big1)) != 0 {
+		return ErrInvalidNumber
+	}
+	if header.Difficulty == nil || header.Difficulty.Cmp(common.Big1) != 0 {
+		return fmt.Errorf("invalid difficulty: have %v, want 1", header.Difficulty)
+	}
+	if dapp := chainConfig.DApp; dapp != nil && dapp.GasLimit > 0 && header.GasLimit != dapp.GasLimit {
+		return fmt.Errorf("invalid gas limit: have %d, want %d", header.GasLimit, dapp.GasLimit)
+	}
+	if header.Time.Cmp(new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(dappBlockPeriod(chainConfig)))) < 0 {
+		return fmt.Errorf("block within the period of its parent: have %v, parent %v", header.Time, parent.Time())
+	}
+	if want := CalcDAppTime(chainConfig, parent, anchor); header.Time.Cmp(want) != 0 {
+		return fmt.Errorf("invalid timestamp: have %v, want %v", header.Time, want)
+	}
+	return nil
+}
+
+// InsertDAppChain imports DApp blocks packaged by other replicas of the DApp
+// chain. DApp blocks are sealed by the block producer of their anchor, instead
+// of the full consensus rules they are validated by their anchor, their seal
+// and by executing them the same way they were packaged. It returns the index
+// of the failing block as well as the error.
+func (bc *BlockChain) InsertDAppChain(chain types.Blocks) (int, error) {
+	n, events, logs, err := bc.insertDAppChain(chain)
+	bc.PostChainEvents(events, logs)
+	return n, err
+}
+
+// insertDAppChain executes the DApp block imports and aggregates their events.
+func (bc *BlockChain) insertDAppChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+	if bc.AnchorChain() == nil {
+		return 0, nil, nil, ErrNoAnchorChain
+	}
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	var (
+		events    []interface{}
+		logs      []*types.Log
+		lastCanon *types.Block
+		dappId    = bc.Genesis().DAppID()
+	)
+	for i, block := range chain {
+		if block.DAppID() != dappId {
+			return i, events, logs, ErrForeignDAppBlock
+		}
+		err := bc.Validator().ValidateBody(block)
+		if err == ErrKnownBlock {
+			continue
+		}
+		if err != nil {
+			return i, events, logs, err
+		}
+		parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		anchor := bc.AnchorChain().GetHeaderByHash(block.Header().DAppMainHash)
+		if anchor == nil {
+			return i, events, logs, ErrUnknownAnchor
+		}
+		if err := verifyDAppHeader(bc.chainConfig, block.Header(), parent, anchor); err != nil {
+			return i, events, logs, err
+		}
+		if sealer, ok := bc.AnchorChain().Engine().(consensus.DAppSealer); ok {
+			if err := sealer.VerifyDAppSeal(anchor, block.Header()); err != nil {
+				return i, events, logs, err
+			}
+		}
+		statedb, err := state.New(parent.Root(), bc.stateCache)
+		if err != nil {
+			return i, events, logs, err
+		}
+		// Mint the deposits and execute the transactions the way the DApp packager did
+		var (
+			header   = block.Header()
+			gp       = new(GasPool).AddGas(header.GasLimit)
+			usedGas  = new(uint64)
+			receipts types.Receipts
+		)
+		if _, err := ApplyDAppDeposits(bc.AnchorChain(), header, statedb); err != nil {
+			return i, events, logs, err
+		}
+		for j, tx := range block.Transactions() {
+			statedb.Prepare(tx.Hash(), block.Hash(), j)
+			receipt, _, err := ApplyTransaction(bc.chainConfig, bc, &header.Coinbase, gp, statedb, header, tx, usedGas, bc.vmConfig)
+			if err != nil {
+				return i, events, logs, err
+			}
+			receipts = append(receipts, receipt)
+		}
+		if err := bc.Validator().ValidateState(block, parent, statedb, receipts, *usedGas); err != nil {
+			return i, events, logs, err
+		}
+		status, err := bc.WriteBlockWithState(block, receipts, statedb)
+		if err != nil {
+			return i, events, logs, err
+		}
+		switch status {
+		case CanonStatTy:
+			events = append(events, ChainEvent{Block: block, Hash: block.Hash(), Logs: statedb.Logs()})
+			logs = append(logs, statedb.Logs()...)
+			lastCanon = block
+
+		case SideStatTy:
+			events = append(events, ChainSideEvent{Block: block})
+		}
+		log.Debug("Imported DApp block", "dapp", dappId, "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()))
+	}
+	if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
+		events = append(events, ChainHeadEvent{Block: lastCanon})
+	}
+	return 0, events, logs, nil
+}
@@ -0,0 +1,286 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// Tests that DApp blocks are only valid if every transaction is anchored by the
+// canonical main chain block they refer to, and that anchors can be proven back
+// to the main chain header.
+func TestDAppAnchoring(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dappId  = common.Address{0xda}
+		signer  = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine  = consensus.CreateFakeEngine()
+		db, _   = store.NewMemDatabase()
+		gspec   = &Genesis{Config: config.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(config.Ether)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	var anchored, unanchored *types.Transaction
+	blocks, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, 1, func(i int, gen *BlockGen) {
+		nonce := gen.TxNonce(addr)
+		anchored, _ = types.SignTx(types.NewDAppTransaction(&dappId, nonce, 100000, big.NewInt(1), []byte{0x01}), signer, key)
+		unanchored, _ = types.SignTx(types.NewDAppTransaction(&dappId, nonce+1, 100000, big.NewInt(1), []byte{0x02}), signer, key)
+		gen.AddTx(anchored)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert main chain: %v", err)
+	}
+	dappBlock := func(anchor common.Hash, txs ...*types.Transaction) *types.Block {
+		header := &types.Header{Number: big.NewInt(1), DAppID: dappId, DAppMainHash: anchor}
+		return types.NewBlock(header, txs, nil, nil)
+	}
+	tests := []struct {
+		block *types.Block
+		err   error
+	}{
+		{dappBlock(blocks[0].Hash(), anchored.DAppTx()), nil},
+		{dappBlock(common.Hash{0x01}, anchored.DAppTx()), ErrUnknownAnchor},
+		{dappBlock(blocks[0].Hash(), anchored.DAppTx(), unanchored.DAppTx()), ErrUnanchoredTransaction},
+		{dappBlock(blocks[0].Hash(), anchored), ErrUnanchoredTransaction},
+	}
+	for i, tt := range tests {
+		if err := VerifyAnchor(chain, tt.block); err != tt.err {
+			t.Errorf("test %d: anchor verification mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The anchor is proven back to the main chain header
+	proof, err := ProveAnchor(chain, tests[0].block, anchored.DAppTx())
+	if err != nil {
+		t.Fatalf("failed to prove anchor: %v", err)
+	}
+	if proof.Header.Hash() != blocks[0].Hash() || proof.Anchor.Hash() != anchored.Hash() {
+		t.Errorf("proof mismatch: have anchor %x in %x", proof.Anchor.Hash(), proof.Header.Hash())
+	}
+	if err := VerifyAnchorProof(proof); err != nil {
+		t.Errorf("failed to verify proof: %v", err)
+	}
+	forged := *proof
+	forged.Transaction = unanchored.DAppTx()
+	if err := VerifyAnchorProof(&forged); err != ErrUnanchoredTransaction {
+		t.Errorf("forged transaction: have %v, want %v", err, ErrUnanchoredTransaction)
+	}
+	forged = *proof
+	forged.Proof = forged.Proof[:0]
+	if err := VerifyAnchorProof(&forged); err == nil {
+		t.Errorf("missing proof nodes accepted")
+	}
+	// Anchors reorganised out of the main chain are no longer valid
+	fork, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, 2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if err := VerifyAnchor(chain, tests[0].block); err != ErrNonCanonicalAnchor {
+		t.Errorf("reorganised anchor: have %v, want %v", err, ErrNonCanonicalAnchor)
+	}
+}
+
+// Tests that DApp blocks packaged by another replica of the DApp chain are
+// imported by executing them, and that blocks which are not part of the chain
+// are rejected.
+func TestDAppChainImport(t *testing.T) {
+	var (
+		bank, _ = crypto.GenerateKey()
+		key, _  = crypto.GenerateKey()
+		dappId  = common.Address{0xda}
+		signer  = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine  = consensus.CreateFakeEngine()
+		db, _   = store.NewMemDatabase()
+		gspec   = &Genesis{Config: config.TestChainConfig, Alloc: GenesisAlloc{crypto.PubkeyToAddress(bank.PublicKey): {Balance: big.NewInt(config.Ether)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	// Free transactions are executable on the empty state of a new DApp chain
+	var tx *types.Transaction
+	blocks, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, 1, func(i int, gen *BlockGen) {
+		tx, _ = types.SignTx(types.NewDAppTransaction(&dappId, 0, 100000, new(big.Int), []byte{0x01}), signer, key)
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert main chain: %v", err)
+	}
+	newDAppChain := func() *BlockChain {
+		dappDb, _ := store.NewMemDatabase()
+		if _, err := gspec.DAppCommit(dappDb, &dappId); err != nil {
+			t.Fatalf("failed to commit DApp genesis: %v", err)
+		}
+		dappChain, _ := NewBlockChain(dappDb, nil, config.TestChainConfig, engine, vm.Config{})
+		dappChain.SetAnchorChain(chain)
+		return dappChain
+	}
+	// Package the DApp block on one replica
+	source := newDAppChain()
+	defer source.Stop()
+
+	parent := source.CurrentBlock()
+	statedb, _ := source.StateAt(parent.Root())
+	header := &types.Header{
+		ParentHash:   parent.Hash(),
+		Number:       big.NewInt(1),
+		GasLimit:     CalcGasLimit(parent),
+		Time:         blocks[0].Time(),
+		Difficulty:   big.NewInt(1),
+		DAppID:       dappId,
+		DAppMainHash: blocks[0].Hash(),
+	}
+	receipt, _, err := ApplyTransaction(config.TestChainConfig, source, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, tx.DAppTx(), &header.GasUsed, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to execute DApp transaction: %v", err)
+	}
+	header.Root = statedb.IntermediateRoot(true)
+	block := types.NewBlock(header, types.Transactions{tx.DAppTx()}, nil, []*types.Receipt{receipt})
+
+	// Import it on another one
+	dappChain := newDAppChain()
+	defer dappChain.Stop()
+
+	if _, err := dappChain.InsertDAppChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to import DApp block: %v", err)
+	}
+	if head := dappChain.CurrentBlock(); head.Hash() != block.Hash() {
+		t.Errorf("head mismatch: have %x, want %x", head.Hash(), block.Hash())
+	}
+	// Blocks of other chains or with a mismatching state are rejected
+	if _, err := chain.InsertDAppChain(types.Blocks{block}); err != ErrNoAnchorChain {
+		t.Errorf("main chain import: have %v, want %v", err, ErrNoAnchorChain)
+	}
+	foreign := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), DAppID: common.Address{0x01}, DAppMainHash: blocks[0].Hash()})
+	if _, err := dappChain.InsertDAppChain(types.Blocks{foreign}); err != ErrForeignDAppBlock {
+		t.Errorf("foreign block import: have %v, want %v", err, ErrForeignDAppBlock)
+	}
+	forged := types.CopyHeader(header)
+	forged.Root = common.Hash{0x01}
+	fresh := newDAppChain()
+	defer fresh.Stop()
+
+	if _, err := fresh.InsertDAppChain(types.Blocks{block.WithSeal(forged)}); err == nil {
+		t.Errorf("block with mismatching state imported")
+	}
+}
+
+// Tests that DApp chains run with their own configuration: a fixed gas limit, a
+// block period, a minimum gas price and an account paying the fees.
+func TestDAppChainConfig(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		payer   = common.Address{0xfe}
+		dappId  = common.Address{0xda}
+		signer  = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine  = consensus.CreateFakeEngine()
+		db, _   = store.NewMemDatabase()
+		main    = *config.TestChainConfig
+		gasUsed uint64
+	)
+	main.DApps = map[common.Address]*config.DAppConfig{
+		dappId: {GasLimit: 500000, MinGasPrice: big.NewInt(2), Period: 10, FeePayer: &payer, Sponsored: []common.Address{sender}},
+	}
+	dappConfig := main.DAppChainConfig(dappId)
+	if dappConfig.DApp != main.DApps[dappId] || *dappConfig.DAppId != dappId || dappConfig.DApps != nil {
+		t.Fatalf("DApp chain configuration mismatch: have %v", dappConfig)
+	}
+	gspec := &Genesis{Config: &main}
+	genesis := gspec.DAppMustCommit(db, &dappId)
+	if stored, _ := GetChainConfig(db, genesis.Hash()); stored == nil || stored.DApp == nil || stored.DApp.GasLimit != 500000 {
+		t.Fatalf("stored DApp chain configuration mismatch: have %v", stored)
+	}
+	chain, _ := NewBlockChain(db, nil, dappConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	// Blocks follow the gas limit and period of the DApp
+	anchor := &types.Header{Time: new(big.Int).Add(genesis.Time(), common.Big1)}
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   CalcDAppGasLimit(dappConfig, genesis),
+		Time:       CalcDAppTime(dappConfig, genesis, anchor),
+		Difficulty: big.NewInt(1),
+		DAppID:     dappId,
+	}
+	if header.GasLimit != 500000 || header.Time.Uint64() != genesis.Time().Uint64()+10 {
+		t.Errorf("DApp header mismatch: have gas limit %d at %v", header.GasLimit, header.Time)
+	}
+	if err := verifyDAppHeader(dappConfig, header, genesis, anchor); err != nil {
+		t.Errorf("valid DApp header rejected: %v", err)
+	}
+	early := types.CopyHeader(header)
+	early.Time = anchor.Time
+	if err := verifyDAppHeader(dappConfig, early, genesis, anchor); err == nil {
+		t.Errorf("DApp header within the block period accepted")
+	}
+	// Number, difficulty and time are derived from the parent and the anchor
+	late := types.CopyHeader(header)
+	late.Time = new(big.Int).Add(header.Time, common.Big1)
+	if err := verifyDAppHeader(dappConfig, late, genesis, anchor); err == nil {
+		t.Errorf("DApp header off its anchor time accepted")
+	}
+	skipped := types.CopyHeader(header)
+	skipped.Number = big.NewInt(2)
+	if err := verifyDAppHeader(dappConfig, skipped, genesis, anchor); err != ErrInvalidNumber {
+		t.Errorf("DApp header skipping a number: have %v, want %v", err, ErrInvalidNumber)
+	}
+	heavy := types.CopyHeader(header)
+	heavy.Difficulty = big.NewInt(2)
+	if err := verifyDAppHeader(dappConfig, heavy, genesis, anchor); err == nil {
+		t.Errorf("DApp header of increased difficulty accepted")
+	}
+	// Fees of sponsored senders are paid by the fee payer, transactions below the
+	// minimum price fail
+	statedb, _ := chain.State()
+	statedb.AddBalance(payer, big.NewInt(config.Ether))
+
+	tx, _ := types.SignTx(types.NewDAppTransaction(&dappId, 0, 100000, big.NewInt(2), nil), signer, key)
+	if _, _, err := ApplyTransaction(dappConfig, chain, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, tx.DAppTx(), &gasUsed, vm.Config{}); err != nil {
+		t.Fatalf("failed to execute DApp transaction: %v", err)
+	}
+	if have, want := statedb.GetBalance(payer), new(big.Int).Sub(big.NewInt(config.Ether), new(big.Int).SetUint64(2*gasUsed)); have.Cmp(want) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", have, want)
+	}
+	if balance := statedb.GetBalance(sender); balance.Sign() != 0 {
+		t.Errorf("sender charged: have %v", balance)
+	}
+	cheap, _ := types.SignTx(types.NewDAppTransaction(&dappId, 1, 100000, big.NewInt(1), nil), signer, key)
+	if _, _, err := ApplyTransaction(dappConfig, chain, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, cheap.DAppTx(), &gasUsed, vm.Config{}); err != ErrDAppUnderpriced {
+		t.Errorf("underpriced DApp transaction: have %v, want %v", err, ErrDAppUnderpriced)
+	}
+	// Senders not sponsored pay their own fees
+	other, _ := crypto.GenerateKey()
+	unsponsored, _ := types.SignTx(types.NewDAppTransaction(&dappId, 0, 100000, big.NewInt(2), nil), signer, other)
+	if _, _, err := ApplyTransaction(dappConfig, chain, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, unsponsored.DAppTx(), &gasUsed, vm.Config{}); err == nil {
+		t.Errorf("unsponsored DApp transaction paid by the fee payer")
+	}
+}
@@ -70,6 +70,12 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	// DApp blocks must be anchored to the main chain
+	if main := v.bc.AnchorChain(); main != nil {
+		if err := VerifyAnchor(main, block); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
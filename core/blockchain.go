@@ -109,6 +109,7 @@ type BlockChain struct {
 	checkpoint       int          // checkpoint counts towards the new checkpoint
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
+	currentFinalized atomic.Value // Header of the last block proven final, the chain is never reorganised below it
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
@@ -122,10 +123,11 @@ type BlockChain struct {
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
-	engine    consensus.Engine
-	processor Processor // block processor interface
-	validator Validator // block and state validator interface
-	vmConfig  vm.Config
+	engine      consensus.Engine
+	processor   Processor   // block processor interface
+	validator   Validator   // block and state validator interface
+	anchorChain *BlockChain // main chain anchoring the blocks of a DApp chain
+	vmConfig    vm.Config
 
 	badBlocks *lru.Cache // Bad block cache
 }
@@ -231,6 +233,16 @@ func (bc *BlockChain) loadLastState() error {
 		}
 	}
 
+	// Restore the last finalized block, falling back to the genesis if it was rewound
+	bc.currentFinalized.Store(bc.genesisBlock.Header())
+	if hash := GetFinalizedHash(bc.db); hash != (common.Hash{}) {
+		header := bc.GetHeaderByHash(hash)
+		if header != nil && header.Number.Uint64() <= currentBlock.NumberU64() && GetCanonicalHash(bc.db, header.Number.Uint64()) == hash {
+			bc.currentFinalized.Store(header)
+		} else {
+			log.Warn("Finalized block rewound, resetting finality", "hash", hash)
+		}
+	}
 	// Issue a status log for the user
 	currentFastBlock := bc.CurrentFastBlock()
 
@@ -241,6 +253,9 @@ func (bc *BlockChain) loadLastState() error {
 	log.Info("Loaded most recent local header", "bchain", bc.Genesis().DAppID().String(), "number", currentHeader.Number, "hash", currentHeader.Hash(), "difficulty", headerTd)
 	log.Info("Loaded most recent local full block", "bchain", bc.Genesis().DAppID().String(), "number", currentBlock.Number(), "hash", currentBlock.Hash(), "difficulty", blockTd)
 	log.Info("Loaded most recent local fast block", "bchain", bc.Genesis().DAppID().String(), "number", currentFastBlock.Number(), "hash", currentFastBlock.Hash(), "difficulty", fastTd)
+	if finalized := bc.CurrentFinalizedHeader(); finalized.Number.Sign() > 0 {
+		log.Info("Loaded most recent finalized block", "bchain", bc.Genesis().DAppID().String(), "number", finalized.Number, "hash", finalized.Hash())
+	}
 
 	return nil
 }
@@ -337,6 +352,73 @@ func (bc *BlockChain) CurrentFastBlock() *types.Block {
 	return bc.currentFastBlock.Load().(*types.Block)
 }
 
+// CurrentFinalizedHeader retrieves the header of the last block proven final,
+// the genesis header if no block was finalized yet.
+func (bc *BlockChain) CurrentFinalizedHeader() *types.Header {
+	return bc.currentFinalized.Load().(*types.Header)
+}
+
+// GetFinalityCertificate retrieves the certificate proving the finality of the
+// block corresponding to the hash, nil if the block was not finalized.
+func (bc *BlockChain) GetFinalityCertificate(hash common.Hash) *types.FinalityCertificate {
+	return GetFinalityCertificate(bc.db, hash)
+}
+
+// SetFinalized verifies the finality certificate with the consensus engine and
+// marks its block as the last final one, persisting the certificate. The chain is
+// never reorganised below a finalized block afterwards. Certificates of blocks not
+// above the current finalized one are ignored.
+func (bc *BlockChain) SetFinalized(cert *types.FinalityCertificate) error {
+	finalizer, ok := bc.engine.(consensus.Finalizer)
+	if !ok {
+		return ErrNoFinalizer
+	}
+	if err := finalizer.VerifyFinality(bc, cert); err != nil {
+		return err
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if cert.Number <= bc.CurrentFinalizedHeader().Number.Uint64() {
+		return nil
+	}
+	if GetCanonicalHash(bc.db, cert.Number) != cert.Hash {
+		return ErrNonCanonicalFinality
+	}
+	header := bc.GetHeader(cert.Hash, cert.Number)
+	if header == nil {
+		return ErrNonCanonicalFinality
+	}
+	if err := WriteFinalityCertificate(bc.db, cert); err != nil {
+		return err
+	}
+	if err := WriteFinalizedHash(bc.db, cert.Hash); err != nil {
+		return err
+	}
+	bc.currentFinalized.Store(header)
+
+	log.Info("Finalized block", "number", cert.Number, "hash", cert.Hash, "votes", len(cert.Votes))
+	return nil
+}
+
+// extendsFinalized checks whether the block of the given header descends from the
+// last finalized block, thus becoming canonical would not revert it.
+func (bc *BlockChain) extendsFinalized(header *types.Header) bool {
+	finalized := bc.CurrentFinalizedHeader()
+	number := finalized.Number.Uint64()
+	if number == 0 {
+		return true
+	}
+	for header != nil && header.Number.Uint64() > number {
+		// The canonical chain contains the finalized block, stop once it's reached
+		if GetCanonicalHash(bc.db, header.Number.Uint64()) == header.Hash() {
+			return true
+		}
+		header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header != nil && header.Hash() == finalized.Hash()
+}
+
 // SetProcessor sets the processor required for making state modifications.
 func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.procmu.Lock()
@@ -351,6 +433,21 @@ func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.validator = validator
 }
 
+// SetAnchorChain sets the main chain the blocks of this DApp chain are anchored
+// to. Incoming DApp blocks are only valid if the main chain anchors them.
+func (bc *BlockChain) SetAnchorChain(main *BlockChain) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.anchorChain = main
+}
+
+// AnchorChain returns the main chain anchoring this DApp chain, if any.
+func (bc *BlockChain) AnchorChain() *BlockChain {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.anchorChain
+}
+
 // Validator returns the current validator.
 func (bc *BlockChain) Validator() Validator {
 	bc.procmu.RLock()
@@ -403,6 +500,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	bc.hc.SetGenesis(bc.genesisBlock.Header())
 	bc.hc.SetCurrentHeader(bc.genesisBlock.Header())
 	bc.currentFastBlock.Store(bc.genesisBlock)
+	bc.currentFinalized.Store(bc.genesisBlock.Header())
 
 	return nil
 }
@@ -956,6 +1054,11 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		// Split same-difficulty blocks by number, then at random
 		reorg = block.NumberU64() < currentBlock.NumberU64() || (block.NumberU64() == currentBlock.NumberU64() && mrand.Float64() < 0.5)
 	}
+	if reorg && block.ParentHash() != currentBlock.Hash() && !bc.extendsFinalized(block.Header()) {
+		// Never revert a finalized block, keep the competing branch as a side chain
+		log.Warn("Refused reorg below finalized block", "number", block.Number(), "hash", block.Hash(), "finalized", bc.CurrentFinalizedHeader().Number)
+		reorg = false
+	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
@@ -1078,6 +1078,81 @@ func TestBlockchainHeaderchainReorgConsistency(t *testing.T) {
 	}
 }
 
+// testerFinalizer is a consensus engine accepting every finality certificate.
+type testerFinalizer struct {
+	consensus.Engine
+}
+
+func (testerFinalizer) VerifyFinality(chain consensus.ChainReader, cert *types.FinalityCertificate) error {
+	return nil
+}
+
+// Tests that the chain is never reorganised below the finalized block, while
+// forks on top of it are still accepted, and that finality survives a restart.
+func TestFinalizedReorg(t *testing.T) {
+	engine := testerFinalizer{consensus.CreateFakeEngine()}
+	db, _ := store.NewMemDatabase()
+	genesis := new(Genesis).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks := makeBlockChain(genesis, 4, engine, db, canonicalSeed)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	if header := chain.CurrentFinalizedHeader(); header.Hash() != genesis.Hash() {
+		t.Fatalf("initial finalized block mismatch: have #%d, want genesis", header.Number)
+	}
+	// Finalize the third block, finality certificates of side blocks are refused
+	early := makeBlockChain(blocks[0], 6, engine, db, forkSeed)
+	if _, err := chain.InsertChain(early[:1]); err != nil {
+		t.Fatalf("failed to insert side block: %v", err)
+	}
+	if err := chain.SetFinalized(&types.FinalityCertificate{Number: 2, Hash: early[0].Hash()}); err != ErrNonCanonicalFinality {
+		t.Errorf("side block finality: have %v, want %v", err, ErrNonCanonicalFinality)
+	}
+	cert := &types.FinalityCertificate{Number: 3, Hash: blocks[2].Hash()}
+	if err := chain.SetFinalized(cert); err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	if header := chain.CurrentFinalizedHeader(); header.Hash() != blocks[2].Hash() {
+		t.Errorf("finalized block mismatch: have #%d [%x…], want #3 [%x…]", header.Number, header.Hash().Bytes()[:4], blocks[2].Hash().Bytes()[:4])
+	}
+	if chain.GetFinalityCertificate(blocks[2].Hash()) == nil {
+		t.Errorf("finality certificate not persisted")
+	}
+	// A heavier fork reverting the finalized block must stay a side chain
+	if _, err := chain.InsertChain(early[1:]); err != nil {
+		t.Fatalf("failed to insert early fork: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[3].Hash() {
+		t.Errorf("reorged below finalized block: have head #%d [%x…], want #4 [%x…]", head.Number(), head.Hash().Bytes()[:4], blocks[3].Hash().Bytes()[:4])
+	}
+	// A heavier fork on top of the finalized block must be accepted
+	late := makeBlockChain(blocks[2], 3, engine, db, forkSeed)
+	if _, err := chain.InsertChain(late); err != nil {
+		t.Fatalf("failed to insert late fork: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != late[2].Hash() {
+		t.Errorf("late fork not canonical: have head #%d [%x…], want #6 [%x…]", head.Number(), head.Hash().Bytes()[:4], late[2].Hash().Bytes()[:4])
+	}
+	// Finality must be restored after a restart
+	chain.Stop()
+	restarted, err := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to restart tester chain: %v", err)
+	}
+	defer restarted.Stop()
+
+	if header := restarted.CurrentFinalizedHeader(); header.Hash() != blocks[2].Hash() {
+		t.Errorf("restored finalized block mismatch: have #%d [%x…], want #3 [%x…]", header.Number, header.Hash().Bytes()[:4], blocks[2].Hash().Bytes()[:4])
+	}
+}
+
 // Tests that importing small side forks doesn't leave junk in the trie database
 // cache (which would eventually cause memory issues).
 func TestTrieForkGC(t *testing.T) {
@@ -0,0 +1,213 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// dappDepositDepth is the number of blocks the main chain block a DApp block
+// mints the deposits of lies below the anchor of the DApp block, deep enough for
+// the main chain to have finalized it.
+const dappDepositDepth = 12
+
+// ReleaseProof proves a DApp transaction to have burned value in the DApp bridge
+// by a DApp block anchored in a final block of the main chain. It is the input of
+// the release method of the bridge.
+type ReleaseProof struct {
+	Anchor       *AnchorProof               // Inclusion of the burn in its anchor block
+	Header       *types.Header              // DApp block executing the burn, sealed for the anchor block
+	Index        uint                       // Index of the burn in its DApp block
+	TxProof      []hexutil.Bytes            // Trie nodes from the transaction root of the DApp block to the burn
+	ReceiptProof []hexutil.Bytes            // Trie nodes from the receipt root of the DApp block to the burn receipt
+	Finality     *types.FinalityCertificate // Finality of the anchor block or one of its descendants
+}
+
+// ProveRelease creates the proof of a DApp transaction burning value, included in
+// the given DApp block with the given receipts, without the finality certificate
+// of its anchor block.
+func ProveRelease(main *BlockChain, block *types.Block, receipts types.Receipts, tx *types.Transaction) (*ReleaseProof, error) {
+	anchor, err := ProveAnchor(main, block, tx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	index := -1
+	for i := range txs {
+		if txs[i].Hash() == tx.Hash() {
+			index = i
+			break
+		}
+	}
+	if index < 0 || index >= len(receipts) {
+		return nil, ErrUnprovenBurn
+	}
+	txProof, err := proveDerivable(txs, index, block.TxHash())
+	if err != nil {
+		return nil, err
+	}
+	receiptProof, err := proveDerivable(receipts, index, block.ReceiptHash())
+	if err != nil {
+		return nil, err
+	}
+	return &ReleaseProof{
+		Anchor:       anchor,
+		Header:       block.Header(),
+		Index:        uint(index),
+		TxProof:      txProof,
+		ReceiptProof: receiptProof,
+	}, nil
+}
+
+// VerifyReleaseFn returns a ReleaseFunc which verifies release proofs against the
+// canonical chain the referenced header extends. The burn has to be included in
+// a DApp block sealed for its anchor block, next to the receipt of its execution.
+func VerifyReleaseFn(ref *types.Header, chain ChainContext) func([]byte) (*types.Transaction, *types.Receipt, error) {
+	return func(input []byte) (*types.Transaction, *types.Receipt, error) {
+		proof := new(ReleaseProof)
+		if err := rlp.DecodeBytes(input, proof); err != nil {
+			return nil, nil, err
+		}
+		if proof.Anchor == nil || proof.Anchor.Header == nil || proof.Anchor.Transaction == nil || proof.Finality == nil {
+			return nil, nil, ErrUnanchoredTransaction
+		}
+		reader, ok := chain.(consensus.ChainReader)
+		if !ok {
+			return nil, nil, ErrNoFinalizer
+		}
+		finalizer, ok := chain.Engine().(consensus.Finalizer)
+		if !ok {
+			return nil, nil, ErrNoFinalizer
+		}
+		sealer, ok := chain.Engine().(consensus.DAppSealer)
+		if !ok {
+			return nil, nil, ErrNoDAppSealer
+		}
+		// Both the anchor and the finalized block have to be ancestors of the block
+		// executing the release, the anchor not above the finalized block
+		var (
+			getHash = GetHashFn(ref, chain)
+			anchor  = proof.Anchor.Header
+			cert    = proof.Finality
+		)
+		if number := anchor.Number.Uint64(); number >= ref.Number.Uint64() || getHash(number) != anchor.Hash() {
+			return nil, nil, ErrNonCanonicalAnchor
+		}
+		if cert.Number >= ref.Number.Uint64() || getHash(cert.Number) != cert.Hash {
+			return nil, nil, ErrNonCanonicalFinality
+		}
+		if cert.Number < anchor.Number.Uint64() {
+			return nil, nil, ErrUnfinalizedAnchor
+		}
+		if err := finalizer.VerifyFinality(reader, cert); err != nil {
+			return nil, nil, err
+		}
+		if err := VerifyAnchorProof(proof.Anchor); err != nil {
+			return nil, nil, err
+		}
+		receipt, err := verifyBurn(sealer, proof)
+		if err != nil {
+			return nil, nil, err
+		}
+		return proof.Anchor.Transaction, receipt, nil
+	}
+}
+
+// verifyBurn checks whether the DApp block of a release proof was sealed for the
+// anchor block of the burn and includes the burn, returning the receipt of its
+// execution proven by the receipt root of the DApp block.
+func verifyBurn(sealer consensus.DAppSealer, proof *ReleaseProof) (*types.Receipt, error) {
+	var (
+		header = proof.Header
+		anchor = proof.Anchor.Header
+		tx     = proof.Anchor.Transaction
+	)
+	if header == nil || header.DAppID != *tx.DAppID() || header.DAppMainHash != anchor.Hash() || header.Hash() != proof.Anchor.DAppBlock {
+		return nil, ErrUnprovenBurn
+	}
+	if err := sealer.VerifyDAppSeal(anchor, header); err != nil {
+		return nil, err
+	}
+	value, err := verifyDerivable(header.TxHash, proof.Index, proof.TxProof)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(value, enc) {
+		return nil, ErrUnprovenBurn
+	}
+	if value, err = verifyDerivable(header.ReceiptHash, proof.Index, proof.ReceiptProof); err != nil {
+		return nil, err
+	}
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(value, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// DAppDepositSource returns the header of the main chain block whose state the
+// deposits of a DApp block anchored to the given header are minted from: its
+// ancestor dappDepositDepth blocks below, or the genesis block for anchors not
+// that deep yet. It returns nil if an ancestor is unknown.
+func DAppDepositSource(main *BlockChain, anchor *types.Header) *types.Header {
+	header := anchor
+	for i := 0; i < dappDepositDepth && header != nil && header.Number.Sign() > 0; i++ {
+		header = main.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header
+}
+
+// ApplyDAppDeposits mints on a DApp chain the deposits locked for the DApp in the
+// state of the main chain block the header takes them from, which were not minted
+// by its ancestors yet. The mix digest of the header holds the hash of that block,
+// which has to be the deposit source of its anchor, so every replica mints the same
+// deposits regardless of the finality it observed. Headers without one mint
+// nothing. It returns the number of deposits minted.
+func ApplyDAppDeposits(main *BlockChain, header *types.Header, statedb *state.StateDB) (int, error) {
+	if header.MixDigest == (common.Hash{}) {
+		return 0, nil
+	}
+	anchor := main.GetHeaderByHash(header.DAppMainHash)
+	if anchor == nil {
+		return 0, ErrUnknownAnchor
+	}
+	source := DAppDepositSource(main, anchor)
+	if source == nil || source.Hash() != header.MixDigest {
+		return 0, ErrUnfinalizedDeposits
+	}
+	mainState, err := main.StateAt(source.Root)
+	if err != nil {
+		return 0, err
+	}
+	deposits := vm.BridgeDeposits(mainState, header.DAppID, vm.MintedDeposits(statedb, header.DAppID))
+	for _, deposit := range deposits {
+		vm.MintDeposit(statedb, deposit, header.Number.Uint64())
+	}
+	return len(deposits), nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// Tests that value locked in the DApp bridge of the main chain is minted once by
+// the DApp blocks anchored after it, whether packaged or imported.
+func TestDAppDeposits(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.Address{0x0e}
+		dappId    = common.Address{0xda}
+		signer    = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine    = consensus.CreateFakeEngine()
+		db, _     = store.NewMemDatabase()
+		gspec     = &Genesis{Config: config.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(config.Ether)}}}
+		genesis   = gspec.MustCommit(db)
+	)
+	chain, _ := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	bridge, _ := abi.JSON(strings.NewReader(vm.DAppBridgeABI))
+	input, _ := bridge.Pack("lock", dappId, recipient)
+	blocks, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, dappDepositDepth+2, func(i int, gen *BlockGen) {
+		if i < 2 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), vm.DAppBridgeAddress, big.NewInt(1000), 200000, new(big.Int), input), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert main chain: %v", err)
+	}
+	mainState, _ := chain.State()
+	if locked := vm.LockedValue(mainState, dappId); locked.Cmp(big.NewInt(2000)) != 0 {
+		t.Fatalf("locked value mismatch: have %v, want 2000", locked)
+	}
+	dappConfig := config.TestChainConfig.DAppChainConfig(dappId)
+	newDAppChain := func() *BlockChain {
+		dappDb, _ := store.NewMemDatabase()
+		if _, err := gspec.DAppCommit(dappDb, &dappId); err != nil {
+			t.Fatalf("failed to commit DApp genesis: %v", err)
+		}
+		dappChain, _ := NewBlockChain(dappDb, nil, dappConfig, engine, vm.Config{})
+		dappChain.SetAnchorChain(chain)
+		return dappChain
+	}
+	// Package a DApp block anchored right above the deposit depth, then one anchored
+	// a block deeper burying the first deposit: each mints the deposits of its source
+	// not minted by its parent
+	source := newDAppChain()
+	defer source.Stop()
+
+	var (
+		anchors    = types.Blocks{blocks[dappDepositDepth-1], blocks[dappDepositDepth]}
+		dappBlocks types.Blocks
+	)
+	for i, want := range []int{0, 1} {
+		parent := source.CurrentBlock()
+		statedb, _ := source.StateAt(parent.Root())
+		header := &types.Header{
+			ParentHash:   parent.Hash(),
+			Number:       new(big.Int).Add(parent.Number(), common.Big1),
+			GasLimit:     CalcDAppGasLimit(dappConfig, parent),
+			Time:         CalcDAppTime(dappConfig, parent, anchors[i].Header()),
+			Difficulty:   big.NewInt(1),
+			DAppID:       dappId,
+			DAppMainHash: anchors[i].Hash(),
+			MixDigest:    DAppDepositSource(chain, anchors[i].Header()).Hash(),
+		}
+		minted, err := ApplyDAppDeposits(chain, header, statedb)
+		if err != nil {
+			t.Fatalf("block %d: failed to mint deposits: %v", i, err)
+		}
+		if minted != want {
+			t.Errorf("block %d: minted deposits mismatch: have %d, want %d", i, minted, want)
+		}
+		header.Root = statedb.IntermediateRoot(true)
+		block := types.NewBlock(header, nil, nil, nil)
+		if _, err := source.WriteBlockWithState(block, nil, statedb); err != nil {
+			t.Fatalf("block %d: failed to write DApp block: %v", i, err)
+		}
+		dappBlocks = append(dappBlocks, block)
+	}
+	// Import the blocks on another replica, minting the same deposits
+	dappChain := newDAppChain()
+	defer dappChain.Stop()
+
+	if _, err := dappChain.InsertDAppChain(dappBlocks); err != nil {
+		t.Fatalf("failed to import DApp blocks: %v", err)
+	}
+	dappState, _ := dappChain.State()
+	if balance := dappState.GetBalance(recipient); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("minted balance mismatch: have %v, want 1000", balance)
+	}
+	if minted := vm.MintedDeposits(dappState, dappId); minted != 1 {
+		t.Errorf("minted deposits mismatch: have %d, want 1", minted)
+	}
+	// Deposits of blocks not buried below the anchor are never minted, even if the
+	// local node observed them final
+	chain.currentFinalized.Store(blocks[1].Header())
+	unfinal := &types.Header{Number: big.NewInt(3), DAppID: dappId, DAppMainHash: blocks[1].Hash(), MixDigest: blocks[1].Hash()}
+	if _, err := ApplyDAppDeposits(chain, unfinal, dappState); err != ErrUnfinalizedDeposits {
+		t.Errorf("unfinalized deposits: have %v, want %v", err, ErrUnfinalizedDeposits)
+	}
+	// Releases are only verified by chains able to prove finality
+	proof, _ := rlp.EncodeToBytes(&ReleaseProof{Anchor: &AnchorProof{Transaction: blocks[0].Transactions()[0], Anchor: blocks[0].Transactions()[0], Header: blocks[0].Header()}, Header: blocks[0].Header(), Finality: new(types.FinalityCertificate)})
+	if _, _, err := VerifyReleaseFn(chain.CurrentHeader(), chain)(proof); err != ErrNoFinalizer {
+		t.Errorf("release without finality: have %v, want %v", err, ErrNoFinalizer)
+	}
+}
+
+// finalEngine is a consensus engine accepting every finality certificate and DApp
+// block seal.
+type finalEngine struct {
+	consensus.Engine
+}
+
+func (e *finalEngine) VerifyFinality(chain consensus.ChainReader, cert *types.FinalityCertificate) error {
+	return nil
+}
+
+func (e *finalEngine) VerifyDAppSeal(anchor, header *types.Header) error {
+	return nil
+}
+
+// Tests that releases prove the successful execution of the burn by the DApp block
+// sealed for its anchor.
+func TestDAppRelease(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.Address{0x0e}
+		dappId    = common.Address{0xda}
+		signer    = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine    = &finalEngine{consensus.CreateFakeEngine()}
+		db, _     = store.NewMemDatabase()
+		gspec     = &Genesis{Config: config.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(config.Ether)}}}
+		genesis   = gspec.MustCommit(db)
+	)
+	chain, _ := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	// Anchor the burn in the first main block, the second one executes the release
+	bridge, _ := abi.JSON(strings.NewReader(vm.DAppBridgeABI))
+	input, _ := bridge.Pack("burn", recipient)
+	var burn *types.Transaction
+	blocks, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, 2, func(i int, gen *BlockGen) {
+		if i == 0 {
+			burn, _ = types.SignTx(types.NewDAppCall(&dappId, gen.TxNonce(addr), vm.DAppBridgeAddress, big.NewInt(300), 100000, new(big.Int), input), signer, key)
+			gen.AddTx(burn)
+		}
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert main chain: %v", err)
+	}
+	// Execute the burn in a DApp block anchored to the first main block
+	dappConfig := config.TestChainConfig.DAppChainConfig(dappId)
+	dappBlock := func(chainConfig *config.ChainConfig) (*types.Block, types.Receipts) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.SetNonce(addr, burn.DAppTx().Nonce())
+		statedb.AddBalance(addr, big.NewInt(1000))
+		statedb.Prepare(burn.DAppTx().Hash(), common.Hash{}, 0)
+		header := &types.Header{
+			Number:       big.NewInt(1),
+			GasLimit:     CalcDAppGasLimit(dappConfig, blocks[0]),
+			Time:         blocks[0].Time(),
+			Difficulty:   big.NewInt(1),
+			DAppID:       dappId,
+			DAppMainHash: blocks[0].Hash(),
+		}
+		receipt, _, err := ApplyTransaction(chainConfig, chain, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, burn.DAppTx(), &header.GasUsed, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to execute burn: %v", err)
+		}
+		header.Root = statedb.IntermediateRoot(true)
+		receipts := types.Receipts{receipt}
+		return types.NewBlock(header, types.Transactions{burn.DAppTx()}, nil, receipts), receipts
+	}
+	cert := &types.FinalityCertificate{Number: 1, Hash: blocks[0].Hash()}
+	verify := VerifyReleaseFn(blocks[1].Header(), chain)
+
+	block, receipts := dappBlock(dappConfig)
+	proof, err := ProveRelease(chain, block, receipts, burn.DAppTx())
+	if err != nil {
+		t.Fatalf("failed to prove release: %v", err)
+	}
+	proof.Finality = cert
+	enc, _ := rlp.EncodeToBytes(proof)
+	tx, receipt, err := verify(enc)
+	if err != nil {
+		t.Fatalf("failed to verify release: %v", err)
+	}
+	if tx.Hash() != burn.DAppTx().Hash() || receipt.Status != types.ReceiptStatusSuccessful || len(receipt.Logs) != 1 {
+		t.Errorf("released burn mismatch: have %x, status %d, %d logs", tx.Hash(), receipt.Status, len(receipt.Logs))
+	}
+	// Receipts not committed to by the sealed DApp block are rejected
+	forged := *proof
+	forged.Header = types.CopyHeader(proof.Header)
+	forged.Header.ReceiptHash = common.Hash{0x01}
+	enc, _ = rlp.EncodeToBytes(&forged)
+	if _, _, err := verify(enc); err != ErrUnprovenBurn {
+		t.Errorf("forged receipt root: have %v, want %v", err, ErrUnprovenBurn)
+	}
+	// Failed burns are proven with their failed receipt, burning outside of a DApp
+	// chain reverts
+	block, receipts = dappBlock(config.TestChainConfig)
+	if proof, err = ProveRelease(chain, block, receipts, burn.DAppTx()); err != nil {
+		t.Fatalf("failed to prove failed release: %v", err)
+	}
+	proof.Finality = cert
+	enc, _ = rlp.EncodeToBytes(proof)
+	if _, receipt, err = verify(enc); err != nil {
+		t.Fatalf("failed to verify failed release: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("failed burn status mismatch: have %d, want %d", receipt.Status, types.ReceiptStatusFailed)
+	}
+}
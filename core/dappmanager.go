@@ -0,0 +1,125 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// dappManagerCallGas is the gas allowance of read only calls into the DApp
+// manager contract.
+const dappManagerCallGas = 5000000
+
+// dappIdsSlot is the storage slot of the length of the dappIds array in the DApp
+// manager contract.
+var dappIdsSlot = common.BigToHash(big.NewInt(1))
+
+// dappManagerABI is the parsed interface of the DApp manager contract.
+var dappManagerABI, _ = abi.JSON(strings.NewReader(DAPPContractABI))
+
+// DAppInfo is the registration of a DApp in the DApp manager contract. The field
+// names follow the outputs of the contract.
+type DAppInfo struct {
+	DappAddress      common.Address `json:"dappAddress"`
+	DappName         string         `json:"dappName"`
+	OrgName          string         `json:"orgName"`
+	OrgDescription   string         `json:"orgDescription"`
+	NationalityCode  uint8          `json:"nationalityCode"`
+	LedgerReplicated uint8          `json:"ledgerReplicated"` // Number of storage nodes replicating the DApp chain
+	Icon             string         `json:"icon"`
+	State            uint8          `json:"state"`
+	LastActive       *big.Int       `json:"lastActive"`
+	Initialized      bool           `json:"initialized"`
+}
+
+// PackDAppManager packs the input of a DApp manager contract method.
+func PackDAppManager(method string, args ...interface{}) ([]byte, error) {
+	return dappManagerABI.Pack(method, args...)
+}
+
+// callDAppManager executes a constant method of the DApp manager contract on
+// top of the given state and unpacks its outputs into result. The state is not
+// modified.
+func callDAppManager(chainConfig *config.ChainConfig, header *types.Header, statedb *state.StateDB, result interface{}, method string, args ...interface{}) error {
+	input, err := dappManagerABI.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+	context := vm.Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).Set(header.Time),
+		Difficulty:  new(big.Int),
+		GasLimit:    header.GasLimit,
+		GasPrice:    new(big.Int),
+	}
+	evm := vm.NewEVM(context, statedb.Copy(), chainConfig, vm.Config{})
+	output, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), DAPPContractAddress, input, dappManagerCallGas)
+	if err != nil {
+		return err
+	}
+	return dappManagerABI.Unpack(result, method, output)
+}
+
+// ReadDAppInfo retrieves the registration of a DApp from the DApp manager
+// contract in the given state.
+func ReadDAppInfo(chainConfig *config.ChainConfig, header *types.Header, statedb *state.StateDB, dappId common.Address) (*DAppInfo, error) {
+	info := new(DAppInfo)
+	if err := callDAppManager(chainConfig, header, statedb, info, "dappInfoMap", dappId); err != nil {
+		return nil, err
+	}
+	if !info.Initialized {
+		return nil, ErrUnregisteredDApp
+	}
+	return info, nil
+}
+
+// ReadDAppCount retrieves the number of DApps registered in the DApp manager
+// contract in the given state.
+func ReadDAppCount(statedb *state.StateDB) uint64 {
+	return statedb.GetState(DAPPContractAddress, dappIdsSlot).Big().Uint64()
+}
+
+// ReadDAppIds retrieves at most limit DApps registered in the DApp manager
+// contract in the given state, starting at the offset in registration order.
+func ReadDAppIds(chainConfig *config.ChainConfig, header *types.Header, statedb *state.StateDB, offset, limit uint64) ([]common.Address, error) {
+	count := ReadDAppCount(statedb)
+	if offset >= count {
+		return nil, nil
+	}
+	if limit > count-offset {
+		limit = count - offset
+	}
+	dappIds := make([]common.Address, limit)
+	for i := range dappIds {
+		index := new(big.Int).SetUint64(offset + uint64(i))
+		if err := callDAppManager(chainConfig, header, statedb, &dappIds[i], "dappIds", index); err != nil {
+			return nil, err
+		}
+	}
+	return dappIds, nil
+}
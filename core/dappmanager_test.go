@@ -0,0 +1,83 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// Tests that the registration of a DApp, including the number of storage nodes
+// replicating its chain, is read back from the DApp manager contract and listed
+// among the registered DApps.
+func TestReadDAppInfo(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		dappId  = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.NewDAppSigner(config.TestChainConfig.ChainId)
+		engine  = consensus.CreateFakeEngine()
+		db, _   = store.NewMemDatabase()
+		gspec   = &Genesis{Config: config.TestChainConfig, Alloc: GenesisAlloc{dappId: {Balance: big.NewInt(config.Ether)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	defer chain.Stop()
+
+	statedb, _ := chain.State()
+	if _, err := ReadDAppInfo(config.TestChainConfig, chain.CurrentHeader(), statedb, dappId); err != ErrUnregisteredDApp {
+		t.Fatalf("unregistered DApp: have %v, want %v", err, ErrUnregisteredDApp)
+	}
+	input, err := dappManagerABI.Pack("registerDAppInfo", "dapp", "org", "description", uint8(86), uint8(3), "icon")
+	if err != nil {
+		t.Fatalf("failed to pack registration: %v", err)
+	}
+	blocks, _ := GenerateChain(config.TestChainConfig, genesis, engine, db, 1, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(dappId), DAPPContractAddress, new(big.Int), 1000000, new(big.Int), input), signer, key)
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert registration: %v", err)
+	}
+	statedb, _ = chain.State()
+	info, err := ReadDAppInfo(config.TestChainConfig, chain.CurrentHeader(), statedb, dappId)
+	if err != nil {
+		t.Fatalf("failed to read DApp info: %v", err)
+	}
+	if info.DappAddress != dappId || info.DappName != "dapp" || info.LedgerReplicated != 3 {
+		t.Errorf("DApp info mismatch: have %x %q with %d replicas", info.DappAddress, info.DappName, info.LedgerReplicated)
+	}
+	if _, err := ReadDAppInfo(config.TestChainConfig, chain.CurrentHeader(), statedb, common.Address{0xda}); err != ErrUnregisteredDApp {
+		t.Errorf("other DApp: have %v, want %v", err, ErrUnregisteredDApp)
+	}
+	if count := ReadDAppCount(statedb); count != 1 {
+		t.Errorf("registered DApp count mismatch: have %d, want %d", count, 1)
+	}
+	if dappIds, err := ReadDAppIds(config.TestChainConfig, chain.CurrentHeader(), statedb, 0, 10); err != nil || len(dappIds) != 1 || dappIds[0] != dappId {
+		t.Errorf("registered DApps mismatch: have %x, %v", dappIds, err)
+	}
+	if dappIds, _ := ReadDAppIds(config.TestChainConfig, chain.CurrentHeader(), statedb, 1, 10); len(dappIds) != 0 {
+		t.Errorf("page past the registered DApps: have %x", dappIds)
+	}
+}
@@ -47,6 +47,8 @@ var (
 	headHeaderKey = []byte("LastHeader")
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
+	finalizedKey  = []byte("LastFinalized")
+	preCommitKey  = []byte("LastPreCommit")
 	trieSyncKey   = []byte("TrieSync")
 
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`).
@@ -58,6 +60,7 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	finalityPrefix      = []byte("f") // finalityPrefix + hash -> finality certificate
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the store
@@ -149,6 +152,25 @@ func GetHeadFastBlockHash(db DatabaseReader) common.Hash {
 	return common.BytesToHash(data)
 }
 
+// GetFinalizedHash retrieves the hash of the last block proven final.
+func GetFinalizedHash(db DatabaseReader) common.Hash {
+	data, _ := db.Get(finalizedKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// GetLastPreCommit retrieves the number of the last block the local delegator
+// voted for to become final.
+func GetLastPreCommit(db DatabaseReader) uint64 {
+	data, _ := db.Get(preCommitKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
 // GetTrieSyncProgress retrieves the number of tries nodes fast synced to allow
 // reportinc correct numbers across restarts.
 func GetTrieSyncProgress(db DatabaseReader) uint64 {
@@ -264,6 +286,21 @@ func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.
 	return receipts
 }
 
+// GetFinalityCertificate retrieves the certificate proving the finality of the
+// block corresponding to the hash, nil if none found.
+func GetFinalityCertificate(db DatabaseReader, hash common.Hash) *types.FinalityCertificate {
+	data, _ := db.Get(append(finalityPrefix, hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	cert := new(types.FinalityCertificate)
+	if err := rlp.DecodeBytes(data, cert); err != nil {
+		log.Error("Invalid finality certificate RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return cert
+}
+
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func GetTxLookupEntry(db DatabaseReader, hash common.Hash) (common.Hash, uint64, uint64) {
@@ -387,6 +424,20 @@ func WriteHeadFastBlockHash(db store.Putter, hash common.Hash) error {
 	return nil
 }
 
+// WriteFinalizedHash stores the hash of the last block proven final.
+func WriteFinalizedHash(db store.Putter, hash common.Hash) error {
+	if err := db.Put(finalizedKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store last finalized block's hash", "err", err)
+	}
+	return nil
+}
+
+// WriteLastPreCommit stores the number of the last block the local delegator
+// voted for to become final, so it never votes twice at a height across restarts.
+func WriteLastPreCommit(db store.Putter, number uint64) error {
+	return db.Put(preCommitKey, new(big.Int).SetUint64(number).Bytes())
+}
+
 // WriteTrieSyncProgress stores the fast sync trie process counter to support
 // retrieving it across restarts.
 func WriteTrieSyncProgress(db store.Putter, count uint64) error {
@@ -481,6 +532,19 @@ func WriteBlockReceipts(db store.Putter, hash common.Hash, number uint64, receip
 	return nil
 }
 
+// WriteFinalityCertificate stores the certificate proving the finality of a
+// block into the database.
+func WriteFinalityCertificate(db store.Putter, cert *types.FinalityCertificate) error {
+	data, err := rlp.EncodeToBytes(cert)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(finalityPrefix, cert.Hash.Bytes()...), data); err != nil {
+		log.Crit("Failed to store finality certificate", "err", err)
+	}
+	return nil
+}
+
 // WriteTxLookupEntries stores a positional metadata for every transaction from
 // a block, enabling hash based transaction and receipt lookups.
 func WriteTxLookupEntries(db store.Putter, block *types.Block) error {
@@ -48,4 +48,61 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal it's parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrNoSlasher is returned if double signing evidence is submitted on a chain
+	// whose consensus engine is unable to verify it.
+	ErrNoSlasher = errors.New("consensus engine does not support slashing")
+
+	// ErrNoFinalizer is returned if a finality certificate is submitted on a chain
+	// whose consensus engine is unable to verify it.
+	ErrNoFinalizer = errors.New("consensus engine does not support finality")
+
+	// ErrNonCanonicalFinality is returned if a finality certificate is submitted
+	// for a block which is not part of the canonical chain.
+	ErrNonCanonicalFinality = errors.New("finalized block not canonical")
+
+	// ErrUnknownAnchor is returned if a DApp block refers to a main chain block
+	// which is not known.
+	ErrUnknownAnchor = errors.New("unknown anchor block")
+
+	// ErrNonCanonicalAnchor is returned if a DApp block refers to a main chain
+	// block which is not part of the canonical main chain.
+	ErrNonCanonicalAnchor = errors.New("anchor block not canonical")
+
+	// ErrUnanchoredTransaction is returned if a DApp block includes a transaction
+	// which is not referenced by any transaction of its anchor block.
+	ErrUnanchoredTransaction = errors.New("transaction not anchored")
+
+	// ErrUnfinalizedAnchor is returned if the release of a DApp bridge burn is
+	// proven by a main chain block which is not final.
+	ErrUnfinalizedAnchor = errors.New("anchor block not final")
+
+	// ErrUnprovenBurn is returned if the release of a DApp bridge burn does not
+	// prove the successful execution of the burn by a DApp block committed to
+	// through its anchor.
+	ErrUnprovenBurn = errors.New("burn not proven by its DApp block")
+
+	// ErrNoDAppSealer is returned if the release of a DApp bridge burn is verified
+	// on a chain whose consensus engine does not seal the DApp blocks.
+	ErrNoDAppSealer = errors.New("consensus engine does not seal DApp blocks")
+
+	// ErrUnfinalizedDeposits is returned if a DApp block mints the deposits locked
+	// in a main chain block other than the one at the deposit depth below its anchor.
+	ErrUnfinalizedDeposits = errors.New("deposit block not confirmed")
+
+	// ErrNoAnchorChain is returned if DApp blocks are imported into a chain which
+	// is not anchored to a main chain.
+	ErrNoAnchorChain = errors.New("chain not anchored")
+
+	// ErrForeignDAppBlock is returned if a DApp block is imported into the chain
+	// of another DApp.
+	ErrForeignDAppBlock = errors.New("block of another DApp chain")
+
+	// ErrUnregisteredDApp is returned if a DApp is not registered in the DApp
+	// manager contract.
+	ErrUnregisteredDApp = errors.New("DApp not registered")
+
+	// ErrDAppUnderpriced is returned if a DApp transaction is priced below the
+	// minimum gas price of its DApp chain.
+	ErrDAppUnderpriced = errors.New("gas price below DApp minimum")
 )
@@ -45,16 +45,23 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		beneficiary = *author
 	}
 	return vm.Context{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Origin:      msg.From(),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).Set(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		GasLimit:    header.GasLimit,
-		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		CanTransfer:           CanTransfer,
+		Transfer:              Transfer,
+		GetHash:               GetHashFn(header, chain),
+		VerifyDoubleSign:      VerifyDoubleSignFn(chain),
+		VerifyDoublePreCommit: VerifyDoublePreCommitFn(chain),
+		VerifyRelease:         VerifyReleaseFn(header, chain),
+		Origin:                msg.From(),
+		Coinbase:              beneficiary,
+		BlockNumber:           new(big.Int).Set(header.Number),
+		Time:                  new(big.Int).Set(header.Time),
+		Difficulty:            new(big.Int).Set(header.Difficulty),
+		GasLimit:              header.GasLimit,
+		GasPrice:              new(big.Int).Set(msg.GasPrice()),
+		DAppID:                header.DAppID,
+		DAppMainHash:          header.DAppMainHash,
+		Round:                 header.Round,
+		PresidentId:           header.PresidentId,
 	}
 }
 
@@ -88,6 +95,28 @@ func GetHashFn(ref *types.Header, chain ChainContext) func(n uint64) common.Hash
 	}
 }
 
+// VerifyDoubleSignFn returns a DoubleSignFunc which verifies double signing
+// evidence with the consensus engine of the chain.
+func VerifyDoubleSignFn(chain ChainContext) func(first, second *types.Header) (string, error) {
+	return func(first, second *types.Header) (string, error) {
+		if slasher, ok := chain.Engine().(consensus.Slasher); ok {
+			return slasher.VerifyDoubleSign(first, second)
+		}
+		return "", ErrNoSlasher
+	}
+}
+
+// VerifyDoublePreCommitFn returns a DoublePreCommitFunc which verifies
+// conflicting pre-commit votes with the consensus engine of the chain.
+func VerifyDoublePreCommitFn(chain ChainContext) func(first, second *types.PreCommit) (string, error) {
+	return func(first, second *types.PreCommit) (string, error) {
+		if slasher, ok := chain.Engine().(consensus.Slasher); ok {
+			return slasher.VerifyDoublePreCommit(first, second)
+		}
+		return "", ErrNoSlasher
+	}
+}
+
 // CanTransfer checks wether there are enough funds in the address' account to make a transfer.
 // This does not take the necessary gas in to account to make the transfer valid.
 func CanTransfer(db vm.StateDB, addr common.Address, amount *big.Int) bool {
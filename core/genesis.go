@@ -164,6 +164,11 @@ func SetupGenesisBlock(db store.Database, genesis *Genesis) (*config.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return config.MainnetChainConfig, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.Config.CheckForkOrder(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -231,6 +236,11 @@ func SetupDAppGenesisBlock(dappAddress *common.Address, db store.Database, genes
 	if genesis != nil && genesis.Config == nil {
 		return config.MainnetChainConfig, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.Config.CheckForkOrder(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -242,19 +252,19 @@ func SetupDAppGenesisBlock(dappAddress *common.Address, db store.Database, genes
 			log.Info("Writing custom genesis block")
 		}
 		block, err := genesis.DAppCommit(db, dappAddress)
-		return genesis.Config, block.Hash(), err
+		return genesis.Config.DAppChainConfig(*dappAddress), block.Hash(), err
 	}
 
 	// Check whether the genesis block is already written.
 	if genesis != nil {
 		hash := genesis.ToDAppBlock(nil, dappAddress).Hash()
 		if hash != stored {
-			return genesis.Config, hash, &GenesisMismatchError{stored, hash}
+			return genesis.Config.DAppChainConfig(*dappAddress), hash, &GenesisMismatchError{stored, hash}
 		}
 	}
 
 	// Get the existing chain configuration.
-	newcfg := genesis.configOrDefault(stored)
+	newcfg := genesis.configOrDefault(stored).DAppChainConfig(*dappAddress)
 	storedcfg, err := GetChainConfig(db, stored)
 	if err != nil {
 		if err == ErrChainConfigNotFound {
@@ -273,9 +283,10 @@ func SetupDAppGenesisBlock(dappAddress *common.Address, db store.Database, genes
 
 	// Check config compatibility and write the config. Compatibility errors
 	// are returned to the caller unless we're already at block zero.
+	// Changed DApp rules rewind the DApp chain to its genesis.
 	height := GetBlockNumber(db, GetHeadHeaderHash(db))
 	compatErr := storedcfg.CheckCompatible(newcfg, height)
-	if compatErr != nil && height != 0 && compatErr.RewindTo != 0 {
+	if compatErr != nil && height != 0 {
 		return newcfg, stored, compatErr
 	}
 	return newcfg, stored, WriteChainConfig(db, stored, newcfg)
@@ -294,12 +305,9 @@ func (g *Genesis) ToBlock(db store.Database) *types.Block {
 	if db == nil {
 		db, _ = store.NewMemDatabase()
 	}
-	var dappAddr *common.Address;
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dappAddr := g.Alloc.dappManagerCreator()
 	for addr, account := range g.Alloc {
-		if dappAddr == nil {
-			dappAddr = &addr; // set the first address as the creator of dapp contract.
-		}
 		// if the balance of selected dapp account is zero, will meet gas underpriced exception.
 		statedb.AddBalance(addr, account.Balance)
 		statedb.SetCode(addr, account.Code)
@@ -352,20 +360,13 @@ func (g *Genesis) ToBlock(db store.Database) *types.Block {
 		return nil
 	}
 
-	// install dpos ballot contract into genesis block of main chain.
-	gpool1 := new(GasPool).AddGas(head.GasLimit);
-	data1 := common.Hex2Bytes(DPOSBallotBinCode);
-	dapptx1 := types.NewContractCreation(uint64(1), amount, gasLimit, gasPrice, data1);
-	//this special transaction does not need to sign.
-	//dapptx, err := types.SignTx(dapptx, types.MakeSigner(config.AllDPoSProtocolChanges, head.Number),nil)
-	receipt1, _, err1 := ApplyGenesisTransaction(dappAddr, config.MainnetChainConfig, nil, &head.Coinbase, gpool1, statedb, head, dapptx1, &head.GasUsed,
-		vm.Config{DisableGasMetering:true, Debug: false, Tracer: tracer})
-	if err1 != nil {
-		log.Error("Failed to initialize DPOS ballot contract(ApplyTx)! ", "cause", err1.Error())
-		return nil
+	// the genesis transactions are not metered, raise the gas limit to cover them.
+	// the genesis used to deploy the DPoS ballot contract as well and to take the
+	// gas used as its gas limit, databases initialized before the native delegator
+	// registry hold a different genesis block and have to be initialized anew.
+	if head.GasUsed > head.GasLimit {
+		head.GasLimit = head.GasUsed
 	}
-
-	head.GasLimit = head.GasUsed
 	root := statedb.IntermediateRoot(false)
 	head.Root = root;
 	// commit state into db.
@@ -376,9 +377,7 @@ func (g *Genesis) ToBlock(db store.Database) *types.Block {
 	log.Info("Created Genesis Block.");
 	b.ToString();
 	log.Info("Installed DApp decentralized manager. Address: " + receipt.ContractAddress.String())
-	log.Info("Installed DPoS ballot manager. Address: " + receipt1.ContractAddress.String())
 	DAPPContractAddress = receipt.ContractAddress;
-	DPOSBallotContractAddress = receipt1.ContractAddress;
 
 	return b;
 }
@@ -493,7 +492,7 @@ func (g *Genesis) DAppCommit(db store.Database, dappAddress *common.Address) (*t
 	if config0 == nil {
 		config0 = config.MainnetChainConfig
 	}
-	return block, WriteChainConfig(db, block.Hash(), config0)
+	return block, WriteChainConfig(db, block.Hash(), config0.DAppChainConfig(*dappAddress))
 }
 
 func (g *Genesis) DAppMustCommit(db store.Database, dappAddress *common.Address) *types.Block {
@@ -534,12 +533,17 @@ func DefaultTestnetGenesisBlock() *Genesis {
 	}
 }
 
-// DeveloperGenesisBlock returns the 'geth --dev' genesis block. Note, this must
-// be seeded with the
+// DeveloperGenesisBlock returns the genesis block of the --dev mode, a clique
+// chain sealed by the faucet alone, every period or on every transaction without
+// one. The faucet is the richest pre-funded account, deploying the DApp manager.
+// The DPoS ballot genesis contract no longer exists, the native delegator
+// registry taking its place is installed next to the precompiles instead.
 func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	// Override the default period to the user requested one
 	config := *config.AllCliqueProtocolChanges
-	config.Clique.Period = period
+	clique := *config.Clique
+	clique.Period = period
+	config.Clique = &clique
 
 	// Assemble and return the genesis with the precompiles and faucet pre-funded
 	return &Genesis{
@@ -548,19 +552,40 @@ func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 		GasLimit:   6283185,
 		Difficulty: big.NewInt(1),
 		Alloc: map[common.Address]GenesisAccount{
-			common.BytesToAddress([]byte{1}): {Balance: big.NewInt(1)}, // ECRecover
-			common.BytesToAddress([]byte{2}): {Balance: big.NewInt(1)}, // SHA256
-			common.BytesToAddress([]byte{3}): {Balance: big.NewInt(1)}, // RIPEMD
-			common.BytesToAddress([]byte{4}): {Balance: big.NewInt(1)}, // Identity
-			common.BytesToAddress([]byte{5}): {Balance: big.NewInt(1)}, // ModExp
-			common.BytesToAddress([]byte{6}): {Balance: big.NewInt(1)}, // ECAdd
-			common.BytesToAddress([]byte{7}): {Balance: big.NewInt(1)}, // ECScalarMul
-			common.BytesToAddress([]byte{8}): {Balance: big.NewInt(1)}, // ECPairing
-			faucet: {Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))},
+			common.BytesToAddress([]byte{1}): {Balance: big.NewInt(1)},          // ECRecover
+			common.BytesToAddress([]byte{2}): {Balance: big.NewInt(1)},          // SHA256
+			common.BytesToAddress([]byte{3}): {Balance: big.NewInt(1)},          // RIPEMD
+			common.BytesToAddress([]byte{4}): {Balance: big.NewInt(1)},          // Identity
+			common.BytesToAddress([]byte{5}): {Balance: big.NewInt(1)},          // ModExp
+			common.BytesToAddress([]byte{6}): {Balance: big.NewInt(1)},          // ECAdd
+			common.BytesToAddress([]byte{7}): {Balance: big.NewInt(1)},          // ECScalarMul
+			common.BytesToAddress([]byte{8}): {Balance: big.NewInt(1)},          // ECPairing
+			vm.DelegatorRegistryAddress:      {Balance: new(big.Int), Nonce: 1}, // Delegator registry
+			faucet:                           {Balance: new(big.Int).Lsh(big.NewInt(1), 255)},
 		},
 	}
 }
 
+// dappManagerCreator picks the pre-allocated account deploying the DApp manager:
+// the richest one, the lowest address among equally rich ones, so the address
+// of the DApp manager doesn't depend on the iteration order of the allocation.
+func (ga GenesisAlloc) dappManagerCreator() *common.Address {
+	var (
+		creator *common.Address
+		balance *big.Int
+	)
+	for addr, account := range ga {
+		addr, funds := addr, account.Balance
+		if funds == nil {
+			funds = new(big.Int)
+		}
+		if creator == nil || funds.Cmp(balance) > 0 || (funds.Cmp(balance) == 0 && bytes.Compare(addr[:], creator[:]) < 0) {
+			creator, balance = &addr, funds
+		}
+	}
+	return creator
+}
+
 func decodePrealloc(data string) GenesisAlloc {
 	var p []struct{ Addr, Balance *big.Int }
 	if err := rlp.NewStream(strings.NewReader(data), 0).Decode(&p); err != nil {
@@ -23,15 +23,20 @@ import (
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/core/state"
 	"github.com/juchain/go-juchain/core/store"
 	"github.com/juchain/go-juchain/config"
 	"github.com/juchain/go-juchain/vm/solc"
 	"github.com/juchain/go-juchain/consensus"
 )
 
+// The genesis hashes changed with the native delegator registry, which is no
+// longer deployed by the genesis block, and the genesis gas limit, which is no
+// longer lowered to the gas used by the genesis transactions.
 var (
-	MainnetGenesisHash = common.HexToHash("0xaac5a02784773dad8b576cd7434cca5a6865566a5ca0ae0dc69a4d64a7597995") // Mainnet genesis hash to enforce below configs on
-	TestnetGenesisHash = common.HexToHash("0x10236961b6d4c17462d88af07224782bc626066f750dd7ed09581fa67a4277fc") // Testnet genesis hash to enforce below configs on
+	MainnetGenesisHash = common.HexToHash("0x19c9824c962b53b9da8decac5d5c1d262498cf047acc61764894e3fc967c365d") // Mainnet genesis hash to enforce below configs on
+	TestnetGenesisHash = common.HexToHash("0x65a48e262dfc11415819e16bd7020c69a56eea1dd238f97f17ce10ce9cf6c142") // Testnet genesis hash to enforce below configs on
 )
 
 func TestDefaultGenesisBlock(t *testing.T) {
@@ -49,7 +54,7 @@ func TestDefaultGenesisBlock(t *testing.T) {
 
 func TestSetupGenesis(t *testing.T) {
 	var (
-		customghash = common.HexToHash("0x3f26eafc08da45cf4f1da01c60d50e153af2a036a562fcf872a8a58de0658f36")
+		customghash = common.HexToHash("0x6ce4b3799054fd4745ec3382cfce9118500aa9379778bdbbfa7f2b9ecd63f255")
 		customg     = Genesis{
 			Config: &config.ChainConfig{},
 			Alloc: GenesisAlloc{
@@ -167,3 +172,43 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the developer genesis runs a clique chain sealed by the faucet, with
+// the precompiles funded, the delegator registry installed and the DApp manager
+// deployed by the faucet whatever the order of the allocation.
+func TestDeveloperGenesisBlock(t *testing.T) {
+	faucet := common.Address{0xfa}
+	db, _ := store.NewMemDatabase()
+	genesis := DeveloperGenesisBlock(5, faucet)
+	block := genesis.MustCommit(db)
+
+	if genesis.Config.Clique == nil || genesis.Config.Clique.Period != 5 || genesis.Config.DPoS != nil {
+		t.Fatalf("developer chain not run by clique: %v", genesis.Config)
+	}
+	if config.AllCliqueProtocolChanges.Clique.Period != 0 {
+		t.Errorf("shared clique period modified: %d", config.AllCliqueProtocolChanges.Clique.Period)
+	}
+	if signer := common.BytesToAddress(block.Extra()[32:52]); signer != faucet {
+		t.Errorf("signer mismatch: have %x, want %x", signer, faucet)
+	}
+	statedb, _ := state.New(block.Root(), state.NewDatabase(db))
+	if balance := statedb.GetBalance(faucet); balance.Cmp(new(big.Int).Lsh(big.NewInt(1), 254)) < 0 {
+		t.Errorf("faucet not funded: %v", balance)
+	}
+	if manager := crypto.CreateAddress(faucet, 0); DAPPContractAddress != manager || len(statedb.GetCode(manager)) == 0 {
+		t.Errorf("DApp manager not deployed by the faucet: have %x, want %x", DAPPContractAddress, manager)
+	}
+	for i := byte(1); i <= 8; i++ {
+		if balance := statedb.GetBalance(common.BytesToAddress([]byte{i})); balance.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("precompile %d balance mismatch: have %v, want 1", i, balance)
+		}
+	}
+	if nonce := statedb.GetNonce(vm.DelegatorRegistryAddress); nonce != 1 {
+		t.Errorf("delegator registry not installed: nonce %d", nonce)
+	}
+	for i := 0; i < 8; i++ {
+		if hash := DeveloperGenesisBlock(5, faucet).ToBlock(nil).Hash(); hash != block.Hash() {
+			t.Fatalf("genesis hash mismatch: have %x, want %x", hash, block.Hash())
+		}
+	}
+}
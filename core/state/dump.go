@@ -17,8 +17,10 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/big"
 
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/common/rlp"
@@ -71,6 +73,29 @@ func (self *StateDB) RawDump() Dump {
 	return dump
 }
 
+// ForEachContract iterates over the accounts of the state trie holding code,
+// without loading their code or storage, until the callback returns false.
+func (self *StateDB) ForEachContract(cb func(addr common.Address, codeHash common.Hash, balance *big.Int) bool) error {
+	it := trie.NewIterator(self.trie.NodeIterator(nil))
+	for it.Next() {
+		var data Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return err
+		}
+		if bytes.Equal(data.CodeHash, emptyCodeHash) {
+			continue
+		}
+		addr := self.trie.GetKey(it.Key)
+		if addr == nil {
+			return fmt.Errorf("missing preimage of account %x", it.Key)
+		}
+		if !cb(common.BytesToAddress(addr), common.BytesToHash(data.CodeHash), data.Balance) {
+			break
+		}
+	}
+	return it.Err
+}
+
 func (self *StateDB) Dump() []byte {
 	json, err := json.MarshalIndent(self.RawDump(), "", "    ")
 	if err != nil {
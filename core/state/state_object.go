@@ -166,6 +166,23 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		return value
 	}
 	// Load from DB in case it is missing.
+	value = self.loadState(db, key)
+	self.cachedStorage[key] = value
+	return value
+}
+
+// GetCommittedState returns a value in account storage as it was before the
+// current transaction, whose modifications are flushed to the trie once it ends.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		return self.GetState(db, key)
+	}
+	return self.loadState(db, key)
+}
+
+// loadState reads a value from the storage trie of the account.
+func (self *stateObject) loadState(db Database, key common.Hash) common.Hash {
+	var value common.Hash
 	enc, err := self.getTrie(db).TryGet(key[:])
 	if err != nil {
 		self.setError(err)
@@ -178,7 +195,6 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	self.cachedStorage[key] = value
 	return value
 }
 
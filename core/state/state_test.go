@@ -86,6 +86,32 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestForEachContract(c *checker.C) {
+	// generate an account and a contract
+	obj1 := s.state.GetOrNewStateObject(toAddr([]byte{0x01}))
+	obj1.AddBalance(big.NewInt(22))
+	obj2 := s.state.GetOrNewStateObject(toAddr([]byte{0x01, 0x02}))
+	obj2.SetCode(crypto.Keccak256Hash([]byte{3, 3, 3}), []byte{3, 3, 3})
+	obj2.AddBalance(big.NewInt(33))
+	s.state.Commit(false)
+
+	// check that only the contract is iterated
+	var contracts []common.Address
+	err := s.state.ForEachContract(func(addr common.Address, codeHash common.Hash, balance *big.Int) bool {
+		if codeHash != crypto.Keccak256Hash([]byte{3, 3, 3}) || balance.Int64() != 33 {
+			c.Errorf("contract %x mismatch: code hash %x, balance %v", addr, codeHash, balance)
+		}
+		contracts = append(contracts, addr)
+		return true
+	})
+	if err != nil {
+		c.Fatalf("failed to iterate contracts: %v", err)
+	}
+	if len(contracts) != 1 || contracts[0] != toAddr([]byte{0x01, 0x02}) {
+		c.Errorf("contracts mismatch: have %x", contracts)
+	}
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db, _ = store.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
@@ -149,9 +149,15 @@ func (st *StateTransition) useGas(amount uint64) error {
 	return nil
 }
 
+// payer returns the account paying for the gas of the message, the fee payer of
+// the DApp chain if it sponsors the sender, otherwise the sender.
+func (st *StateTransition) payer() common.Address {
+	return st.evm.ChainConfig().DApp.PaidBy(st.msg.From())
+}
+
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if st.state.GetBalance(st.msg.From()).Cmp(mgval) < 0 {
+	if st.state.GetBalance(st.payer()).Cmp(mgval) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -160,7 +166,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	st.state.SubBalance(st.msg.From(), mgval)
+	st.state.SubBalance(st.payer(), mgval)
 	return nil
 }
 
@@ -174,6 +180,10 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure the gas price reaches the minimum of the DApp chain, if any.
+	if dapp := st.evm.ChainConfig().DApp; dapp != nil && dapp.MinGasPrice != nil && st.gasPrice.Cmp(dapp.MinGasPrice) < 0 {
+		return ErrDAppUnderpriced
+	}
 	return st.buyGas()
 }
 
@@ -236,7 +246,7 @@ func (st *StateTransition) refundGas() {
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	st.state.AddBalance(st.payer(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -335,11 +335,15 @@ func (l *txList) Remove(tx *types.Transaction) (bool, types.Transactions) {
 	return true, nil
 }
 
-func (l *txList) RemoveByNouce(nouce uint64) (types.Transaction) {
-	// Remove the transaction from the set
-	tx := l.txs.Get(nouce)
-	//l.Remove(tx)
-	return *tx
+// Get retrieves the transaction of the given nonce, nil if unknown.
+func (l *txList) Get(nonce uint64) *types.Transaction {
+	return l.txs.Get(nonce)
+}
+
+// RemoveByNonce removes the transaction of the given nonce from the list without
+// invalidating the ones following it, returning whether it was found.
+func (l *txList) RemoveByNonce(nonce uint64) bool {
+	return l.txs.Remove(nonce)
 }
 
 // Ready retrieves a sequentially increasing list of transactions starting at the
@@ -370,6 +374,101 @@ func (l *txList) Flatten() types.Transactions {
 	return l.txs.Flatten()
 }
 
+// dappTxList is the list of the transactions of a DApp chain waiting for their
+// main chain anchors, indexed by the main chain reference they are anchored by
+// and by the nonce of their sender on the DApp chain.
+type dappTxList struct {
+	refs  map[common.Hash]*types.Transaction        // Transactions by main chain reference
+	from  map[common.Hash]common.Address            // Senders of the transactions by reference
+	nonce map[common.Address]map[uint64]common.Hash // References by sender and DApp nonce
+}
+
+// newDAppTxList creates a new empty list of pending DApp transactions.
+func newDAppTxList() *dappTxList {
+	return &dappTxList{
+		refs:  make(map[common.Hash]*types.Transaction),
+		from:  make(map[common.Hash]common.Address),
+		nonce: make(map[common.Address]map[uint64]common.Hash),
+	}
+}
+
+// Get retrieves the transaction anchored by the given reference, nil if unknown.
+func (l *dappTxList) Get(ref common.Hash) *types.Transaction {
+	return l.refs[ref]
+}
+
+// Overlaps returns the transaction of the sender with the same DApp nonce as the
+// one specified, nil if there is none.
+func (l *dappTxList) Overlaps(from common.Address, tx *types.Transaction) *types.Transaction {
+	if ref, ok := l.nonce[from][tx.Nonce()]; ok {
+		return l.refs[ref]
+	}
+	return nil
+}
+
+// Add tries to insert a new transaction of the sender into the list, returning
+// whether the transaction was accepted, and if yes, any previous transaction of
+// the same nonce it replaced. A replacement has to bump the gas price by the
+// given percentage.
+func (l *dappTxList) Add(from common.Address, tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
+	old := l.Overlaps(from, tx)
+	if old != nil {
+		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
+			return false, nil
+		}
+		l.Remove(*old.RefHashId())
+	}
+	ref := *tx.RefHashId()
+	l.refs[ref] = tx
+	l.from[ref] = from
+	if l.nonce[from] == nil {
+		l.nonce[from] = make(map[uint64]common.Hash)
+	}
+	l.nonce[from][tx.Nonce()] = ref
+	return true, old
+}
+
+// Remove deletes the transaction anchored by the given reference, returning
+// whether it was found.
+func (l *dappTxList) Remove(ref common.Hash) bool {
+	tx, ok := l.refs[ref]
+	if !ok {
+		return false
+	}
+	from := l.from[ref]
+	delete(l.refs, ref)
+	delete(l.from, ref)
+	if nonces := l.nonce[from]; nonces[tx.Nonce()] == ref {
+		delete(nonces, tx.Nonce())
+		if len(nonces) == 0 {
+			delete(l.nonce, from)
+		}
+	}
+	return true
+}
+
+// Count returns the number of transactions of the sender in the list.
+func (l *dappTxList) Count(from common.Address) int {
+	return len(l.nonce[from])
+}
+
+// Nonce returns the DApp nonce following the highest one of the sender in the
+// list, or the given nonce if it is higher.
+func (l *dappTxList) Nonce(from common.Address, nonce uint64) uint64 {
+	for n := range l.nonce[from] {
+		if n >= nonce {
+			nonce = n + 1
+		}
+	}
+	return nonce
+}
+
+// Len returns the number of transactions in the list.
+func (l *dappTxList) Len() int {
+	return len(l.refs)
+}
+
 // priceHeap is a heap.Interface implementation over transactions for retrieving
 // price-sorted transactions to discard when the pool fills up.
 type priceHeap []*types.Transaction
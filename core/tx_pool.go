@@ -34,7 +34,6 @@ import (
 	"github.com/juchain/go-juchain/config"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 	"strconv"
-	"bytes"
 )
 
 const (
@@ -78,6 +77,26 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrNotDAppTransaction is returned if a transaction gossiped as a DApp
+	// transaction does not belong to a DApp or does not refer to its anchor.
+	ErrNotDAppTransaction = errors.New("not a DApp transaction")
+
+	// ErrUnknownDApp is returned if a DApp transaction belongs to a DApp chain
+	// which is not replicated by the local node.
+	ErrUnknownDApp = errors.New("DApp not replicated")
+
+	// ErrDAppDiskQuota is returned if a DApp transaction belongs to a DApp chain
+	// whose database exceeds its disk quota on the local node.
+	ErrDAppDiskQuota = errors.New("DApp disk quota exceeded")
+
+	// ErrDAppReference is returned if a DApp transaction is not anchored by the
+	// main chain transaction it travels with, or is signed by another sender.
+	ErrDAppReference = errors.New("DApp transaction mismatches its main chain reference")
+
+	// ErrDAppPoolFull is returned if a DApp transaction exceeds the pending DApp
+	// transaction slots of its sender or of its DApp chain.
+	ErrDAppPoolFull = errors.New("DApp transaction pool is full")
 )
 
 var (
@@ -87,8 +106,8 @@ var (
 
 var (
 	// Metrics for the pending pool
-	dappPendingDiscardCounter   = metrics.NewRegisteredCounter("txpool/pending/discard", nil)
-	dappPendingReplaceCounter   = metrics.NewRegisteredCounter("txpool/pending/replace", nil)
+	dappPendingDiscardCounter = metrics.NewRegisteredCounter("txpool/dapp/discard", nil)
+	dappPendingReplaceCounter = metrics.NewRegisteredCounter("txpool/dapp/replace", nil)
 
 	pendingDiscardCounter   = metrics.NewRegisteredCounter("txpool/pending/discard", nil)
 	pendingReplaceCounter   = metrics.NewRegisteredCounter("txpool/pending/replace", nil)
@@ -139,6 +158,7 @@ type TxPoolConfig struct {
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
+	DAppSlots    uint64 // Maximum number of DApp transaction slots waiting for their anchors per DApp
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 }
@@ -156,6 +176,7 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalSlots:  4096,
 	AccountQueue: 64,
 	GlobalQueue:  1024,
+	DAppSlots:    1024,
 
 	Lifetime: 3 * time.Hour,
 }
@@ -176,6 +197,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.DAppSlots < 1 {
+		log.Warn("Sanitizing invalid txpool DApp slots", "provided", conf.DAppSlots, "updated", DefaultTxPoolConfig.DAppSlots)
+		conf.DAppSlots = DefaultTxPoolConfig.DAppSlots
+	}
 	return conf
 }
 
@@ -190,7 +215,6 @@ type TxPool struct {
 	config       TxPoolConfig
 	chainconfig  *config.ChainConfig
 	chain        blockChain
-	dappchains   map[common.Address]*BlockChain
 
 	gasPrice     *big.Int
 	txFeed       event.Feed
@@ -207,7 +231,8 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
-	dappPending map[common.Address]*txList         // All DApp processable transactions
+	dappPending map[common.Address]*dappTxList            // DApp transactions waiting for their anchor, by DApp
+	dappState   func(common.Address) *state.StateDB // Current state of the replicated DApp chains
 	pending     map[common.Address]*txList         // All currently processable transactions
 	queue       map[common.Address]*txList         // Queued but non-processable transactions
 	beats       map[common.Address]time.Time       // Last heartbeat from each known account
@@ -219,7 +244,7 @@ type TxPool struct {
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
 // transactions from the network.
-func NewTxPool(config TxPoolConfig, chainconfig *config.ChainConfig, chain blockChain, dappChains map[common.Address]*BlockChain) *TxPool {
+func NewTxPool(config TxPoolConfig, chainconfig *config.ChainConfig, chain blockChain) *TxPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
 	config = (&config).sanitize()
 
@@ -228,9 +253,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *config.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		dappchains:  dappChains,
-		signer:      types.NewChainSigner(chainconfig.ChainId),
-		dappPending: make(map[common.Address]*txList),
+		signer:      types.LatestSigner(chainconfig),
+		dappPending: make(map[common.Address]*dappTxList),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
@@ -419,40 +443,6 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	pool.addTxsLocked(reinject, false)
 
-	// generate dapp blocks after generating main block.
-	newBlock := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
-	txSize := newBlock.Transactions().Len();
-	if txSize > 0 {
-		for dappId, list := range pool.dappPending {
-			log.Debug(fmt.Sprintf("packaging dapp %v", dappId.String()))
-			dapptxs := []*types.Transaction{};
-			for i := 0; i < txSize; i++ {
-				if bytes.Equal(newBlock.Transactions()[i].DAppID().Bytes(), dappId.Bytes()) {
-					tx := list.RemoveByNouce(newBlock.Transactions()[i].Nonce())
-					log.Debug(fmt.Sprintf("read dapp's transaction %v", tx.Nonce()))
-					dapptxs = append(dapptxs, &tx);
-				}
-			}
-			if len(dapptxs) > 0 {
-				parentHeader := pool.dappchains[dappId].CurrentBlock().Header()
-				dappHeader := types.CopyHeader(parentHeader)
-				dappHeader.DAppID = dappId;
-				dappHeader.DAppMainHash = newBlock.Hash()
-				dappHeader.ParentHash = parentHeader.Hash()
-				dappHeader.Number = dappHeader.Number.Add(dappHeader.Number, big.NewInt(1))
-				dappHeader.Nonce = types.BlockNonce{} //TODO
-
-				// package a dapp block.
-				newblock := types.NewBlock(dappHeader, dapptxs, nil, nil)
-				if _, err := pool.dappchains[dappId].InsertChain(types.Blocks{newblock}); err != nil {
-					log.Warn("Downloaded item processing failed", "number", newblock.Number(), "hash", newblock.Hash(), "err", err)
-				} else {
-					log.Info(fmt.Sprintf("generated dapps %v block: ", dappId.String()))
-					newblock.ToString()
-				}
-			}
-		}
-	}
 	// validate the pool of pending transactions, this will remove
 	// any transactions that have been included in the block or
 	// have been invalidated because of another transaction (e.g.
@@ -575,6 +565,231 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// DAppTransactions retrieves the pending transactions of a DApp chain anchored by
+// the given main chain transactions, in the order of their anchors. A DApp
+// transaction is anchored by the main chain transaction of its sender it refers
+// to, whose payload hashes to the DApp transaction.
+func (pool *TxPool) DAppTransactions(dappId common.Address, anchors types.Transactions) types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pending := pool.dappPending[dappId]
+	if pending == nil {
+		return nil
+	}
+	var txs types.Transactions
+	for _, anchor := range anchors {
+		if anchor.DAppID() == nil || *anchor.DAppID() != dappId {
+			continue
+		}
+		tx := pending.Get(anchor.UnsignedHash())
+		if tx == nil || !anchoredBy(tx, anchor) {
+			continue
+		}
+		from, err := types.Sender(pool.signer, anchor)
+		if err != nil {
+			continue
+		}
+		if sender, err := types.Sender(pool.signer, tx); err == nil && sender == from {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+// RemoveDAppTransactions drops transactions from the pending transactions of a
+// DApp chain once they were packaged into it.
+func (pool *TxPool) RemoveDAppTransactions(dappId common.Address, txs types.Transactions) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pending := pool.dappPending[dappId]
+	if pending == nil {
+		return
+	}
+	for _, tx := range txs {
+		if tx.RefHashId() != nil {
+			pending.Remove(*tx.RefHashId())
+		}
+	}
+	if pending.Len() == 0 {
+		delete(pool.dappPending, dappId)
+	}
+}
+
+// DropDAppTransactions drops all pending transactions of a DApp chain, once the
+// chain is no longer packaged by this node.
+func (pool *TxPool) DropDAppTransactions(dappId common.Address) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.dappPending, dappId)
+}
+
+// SetDAppState sets the function retrieving the current state of the DApp chains
+// replicated by the local node, nil if a DApp is not replicated. It is used to
+// reject DApp transactions replaying nonces already used on their DApp chain.
+func (pool *TxPool) SetDAppState(fn func(dappId common.Address) *state.StateDB) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.dappState = fn
+}
+
+// currentDAppState retrieves the current state of a replicated DApp chain. The
+// pool lock must not be held, the DApp chains are guarded by their own.
+func (pool *TxPool) currentDAppState(dappId common.Address) *state.StateDB {
+	pool.mu.RLock()
+	fn := pool.dappState
+	pool.mu.RUnlock()
+
+	if fn == nil {
+		return nil
+	}
+	return fn(dappId)
+}
+
+// DAppNonce returns the next nonce of an account on the chain of a DApp: DApp
+// transactions use their own nonce space, one per DApp chain.
+func (pool *TxPool) DAppNonce(dappId common.Address, addr common.Address) uint64 {
+	var nonce uint64
+	if statedb := pool.currentDAppState(dappId); statedb != nil {
+		nonce = statedb.GetNonce(addr)
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if pending := pool.dappPending[dappId]; pending != nil {
+		nonce = pending.Nonce(addr, nonce)
+	}
+	return nonce
+}
+
+// AddDAppTransactions enqueues DApp transactions gossiped by the peer group of
+// their DApp into its pending transactions, where they wait for the main chain
+// transactions anchoring them. DApp transactions have to be signed for their
+// DApp and may not reuse a nonce of their DApp chain. The returned errors are nil
+// for the transactions which were not known yet.
+func (pool *TxPool) AddDAppTransactions(txs []*types.Transaction) []error {
+	// Resolve the DApp chain states first, they are locked independently
+	states := make(map[common.Address]*state.StateDB)
+	for _, tx := range txs {
+		if dappId := tx.DAppID(); dappId != nil {
+			if _, ok := states[*dappId]; !ok {
+				states[*dappId] = pool.currentDAppState(*dappId)
+			}
+		}
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		dappId := tx.DAppID()
+		if dappId == nil || *dappId == *types.EmptyDAppIdHash || tx.RefHashId() == nil || *tx.RefHashId() == *types.EmptyHash {
+			errs[i] = ErrNotDAppTransaction
+			continue
+		}
+		if !config.DAppAddresses.Has(dappId) {
+			errs[i] = ErrUnknownDApp
+			continue
+		}
+		if !config.DAppAddresses.HasDiskSpace(dappId) {
+			errs[i] = ErrDAppDiskQuota
+			continue
+		}
+		if err := pool.validateDAppTx(tx); err != nil {
+			errs[i] = err
+			continue
+		}
+		from, err := types.Sender(pool.signer, tx)
+		if err != nil {
+			errs[i] = ErrInvalidSender
+			continue
+		}
+		if statedb := states[*dappId]; statedb != nil && statedb.GetNonce(from) > tx.Nonce() {
+			errs[i] = ErrNonceTooLow
+			continue
+		}
+		if pending := pool.dappPending[*dappId]; pending != nil && pending.Get(*tx.RefHashId()) != nil {
+			errs[i] = fmt.Errorf("known transaction: %x", tx.Hash())
+			continue
+		}
+		errs[i] = pool.addDAppTx(*dappId, from, tx)
+	}
+	return errs
+}
+
+// checkDAppTx checks whether a DApp transaction of the sender fits into the
+// pending transactions of its DApp chain: it is either known already, replaces a
+// pending one of the same DApp nonce with the required price bump, or there is
+// a free slot for both its sender and its DApp.
+func (pool *TxPool) checkDAppTx(dappId common.Address, from common.Address, tx *types.Transaction) error {
+	pending := pool.dappPending[dappId]
+	if pending == nil || pending.Get(*tx.RefHashId()) != nil {
+		return nil
+	}
+	if old := pending.Overlaps(from, tx); old != nil {
+		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump))), big.NewInt(100))
+		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
+			return ErrReplaceUnderpriced
+		}
+		return nil
+	}
+	if uint64(pending.Count(from)) >= pool.config.AccountSlots || uint64(pending.Len()) >= pool.config.DAppSlots {
+		return ErrDAppPoolFull
+	}
+	return nil
+}
+
+// addDAppTx inserts a DApp transaction of the sender into the pending
+// transactions of its DApp chain unless it is known already, replacing any
+// pending one of the same DApp nonce. The pool lock must be held.
+func (pool *TxPool) addDAppTx(dappId common.Address, from common.Address, tx *types.Transaction) error {
+	if err := pool.checkDAppTx(dappId, from, tx); err != nil {
+		dappPendingDiscardCounter.Inc(1)
+		return err
+	}
+	pending := pool.dappPending[dappId]
+	if pending != nil && pending.Get(*tx.RefHashId()) != nil {
+		return nil
+	}
+	if pending == nil {
+		pending = newDAppTxList()
+		pool.dappPending[dappId] = pending
+	}
+	if _, old := pending.Add(from, tx, pool.config.PriceBump); old != nil {
+		log.Trace("Replaced pending DApp transaction", "dapp", dappId, "old", old.Hash(), "new", tx.Hash())
+		dappPendingReplaceCounter.Inc(1)
+	}
+	return nil
+}
+
+// dappConfig retrieves the configuration of the chain of a DApp transaction,
+// empty if the DApp doesn't configure its own rules.
+func (pool *TxPool) dappConfig(tx *types.Transaction) *config.DAppConfig {
+	if tx != nil && tx.DAppID() != nil {
+		if dapp := pool.chainconfig.DApps[*tx.DAppID()]; dapp != nil {
+			return dapp
+		}
+	}
+	return new(config.DAppConfig)
+}
+
+// validateDAppTx checks whether a DApp transaction adheres to the rules of its
+// DApp chain: the DApp gas limit and the DApp minimum gas price. The main chain
+// transaction anchoring it is still priced against the local node.
+func (pool *TxPool) validateDAppTx(tx *types.Transaction) error {
+	dapp := pool.dappConfig(tx)
+	if dapp.GasLimit > 0 && tx.Gas() > dapp.GasLimit {
+		return ErrGasLimit
+	}
+	if dapp.MinGasPrice != nil && tx.GasPrice().Cmp(dapp.MinGasPrice) < 0 {
+		return ErrDAppUnderpriced
+	}
+	return nil
+}
+
 // local retrieves all currently known local transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -608,6 +823,20 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		if !config.DAppAddresses.HasDiskSpace(tx.DAppTx().DAppID()) {
 			return fmt.Errorf("DAppId: %x! does not have enough disk space.", tx.DAppID())
 		}
+		if err := pool.validateDAppTx(tx.DAppTx()); err != nil {
+			return err
+		}
+		// The DApp transaction is signed by the same sender and anchored by tx
+		dappFrom, err := types.Sender(pool.signer, tx.DAppTx())
+		if err != nil {
+			return ErrInvalidSender
+		}
+		if from, err := types.Sender(pool.signer, tx); err == nil && (from != dappFrom || !anchoredBy(tx.DAppTx(), tx)) {
+			return ErrDAppReference
+		}
+		if err := pool.checkDAppTx(*tx.DAppTx().DAppID(), dappFrom, tx.DAppTx()); err != nil {
+			return err
+		}
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
@@ -801,37 +1030,15 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 
-	// If it is a local DApp transaction.
-	if tx.DAppTx() != nil {
-		dappId := *tx.DAppTx().DAppID()
-		if pool.dappPending[dappId] == nil {
-			pool.dappPending[dappId] = newTxList(true)
-			pool.dappPending[dappId].Add(tx.DAppTx(), pool.config.PriceBump)
-			// send it to all assigned dapp nodes.
-			// config.DAppAddresses.GetAssignedNodes(tx.DAppID())
-		} else if !pool.dappPending[dappId].Overlaps(tx.DAppTx()) {
-			pool.dappPending[dappId].Add(tx.DAppTx(), pool.config.PriceBump)
-			// send it to all assigned dapp nodes.
-			// config.DAppAddresses.GetAssignedNodes(tx.DAppID())
-		}
-		//go pool.txFeed.Send(DAppTxPreEvent{tx})
-	}
-	// If it is a remote DApp transaction.
-	if tx.DAppID() != types.EmptyDAppIdHash && tx.RefHashId() != types.EmptyHash {
-		dappId := *tx.DAppID()
-		if pool.dappPending[dappId] == nil {
-			pool.dappPending[dappId] = newTxList(true)
-			pool.dappPending[dappId].Add(tx, pool.config.PriceBump)
-			// send it to all assigned dapp nodes.
-			// config.DAppAddresses.GetAssignedNodes(tx)
-		} else if !pool.dappPending[dappId].Overlaps(tx) {
-			pool.dappPending[dappId].Add(tx, pool.config.PriceBump)
-			// send it to all assigned dapp nodes.
-			// config.DAppAddresses.GetAssignedNodes(tx)
+	// If it is a local DApp transaction, the DApp part is gossiped to the peer
+	// group of the DApp along with the main transaction.
+	if dappTx := tx.DAppTx(); dappTx != nil {
+		if from, err := types.Sender(pool.signer, dappTx); err == nil {
+			if err := pool.addDAppTx(*dappTx.DAppID(), from, dappTx); err != nil {
+				log.Debug("Discarding DApp transaction", "hash", dappTx.Hash(), "err", err)
+			}
 		}
-		//go pool.txFeed.Send(DAppTxPreEvent{tx})
 	}
-
 	go pool.txFeed.Send(TxPreEvent{tx})
 }
 
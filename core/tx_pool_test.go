@@ -33,8 +33,6 @@ import (
 	"github.com/juchain/go-juchain/core/store"
 	"github.com/juchain/go-juchain/common/event"
 	"github.com/juchain/go-juchain/config"
-	"github.com/juchain/go-juchain/vm/solc"
-	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/common/log"
 )
 
@@ -49,6 +47,9 @@ var dappTxData2 = []byte{1,2,3,4,5,6,7,8,10,4,5,6,7,8,10,1,2,3,4,5,6,7,8,10,4,5,
 func init() {
 	testTxPoolConfig = DefaultTxPoolConfig
 	testTxPoolConfig.Journal = ""
+
+	// The transactions of the test DApps are accepted as if their chains were attached
+	config.DAppAddresses = &config.DAppAddress{Addresse: []common.Address{dappAId, dappBId}}
 }
 
 type testBlockChain struct {
@@ -89,29 +90,18 @@ func dappTransaction(dapp *common.Address, nonce uint64, gaslimit uint64, key *e
 }
 
 func pricedDappTransaction(dapp *common.Address, nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
-	tx, _ := types.SignTx(types.NewDAppTransaction(dapp, nonce, gaslimit, gasprice, dappTxData), types.DefaultSigner{}, key)
+	tx, _ := types.SignTx(types.NewDAppTransaction(dapp, nonce, gaslimit, gasprice, dappTxData), types.NewDAppSigner(config.TestChainConfig.ChainId), key)
 	return tx
 }
 
 
 func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
-	engine    := consensus.CreateFakeEngine()
 	diskdb, _ := store.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	diskdb1, _ := store.NewMemDatabase()
-	blockchain1, _ := NewBlockChain(diskdb1, nil, config.TestChainConfig, engine, vm.Config{})
-	diskdb2, _ := store.NewMemDatabase()
-	blockchain2, _ := NewBlockChain(diskdb2, nil, config.TestChainConfig, engine, vm.Config{})
-
-	dappChains := map[common.Address]*BlockChain{
-		dappAId: blockchain1,
-		dappBId: blockchain2,
-	}
-
 	key, _ := crypto.GenerateKey()
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, dappChains)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 
 	return pool, key
 }
@@ -168,7 +158,7 @@ func validateEvents(events chan TxPreEvent, count int) error {
 }
 
 func deriveSender(tx *types.Transaction) (common.Address, error) {
-	return types.Sender(types.DefaultSigner{}, tx)
+	return types.Sender(types.NewDAppSigner(config.TestChainConfig.ChainId), tx)
 }
 
 type testChain struct {
@@ -216,12 +206,14 @@ func TestStateChangeDuringTransactionPoolReset(t *testing.T) {
 	blockchain := &testChain{&testBlockChain{statedb, 1000000000, new(event.Feed)}, address, &trigger}
 
 	dappId := common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	config.DAppAddresses.Add(dappId)
+
 	tx0 := transaction(0, 100000, key)
 	tx1 := transaction(1, 100000, key)
 	tx2 := dappTransaction(&dappId, 2, 100000, key)
 	tx3 := dappTransaction(&dappId,3, 100000, key)
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	nonce := pool.State().GetNonce(address)
@@ -371,7 +363,7 @@ func TestDAppTransactionQueue(t *testing.T) {
 		t.Error("expected valid txs to be 1 is", len(pool.pending))
 	}
 	if pool.dappPending[dappAId].Len() != 1 {
-		t.Error("expected len(dappPending) == 1, got", pool.dappPending[from].Len())
+		t.Error("expected len(dappPending) == 1, got", pool.dappPending[dappAId].Len())
 	}
 	nonce := pool.State().GetNonce(from)
 	if nonce != 1 {
@@ -420,6 +412,191 @@ func TestDAppTransactionQueue(t *testing.T) {
 	}
 }
 
+// Tests that pending DApp transactions are only handed out for the main chain
+// transactions anchoring them, and that removing packaged ones keeps the rest.
+func TestDAppTransactionsAnchoring(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	txs := types.Transactions{dappTransaction(&dappAId, 0, 100, key), dappTransaction(&dappAId, 1, 100, key), dappTransaction(&dappAId, 2, 100, key)}
+	from, _ := deriveSender(txs[0])
+	pool.currentState.AddBalance(from, big.NewInt(1000))
+	pool.lockedReset(nil, nil)
+	for _, tx := range txs {
+		pool.enqueueTx(tx.Hash(), tx)
+	}
+	pool.promoteExecutables([]common.Address{from})
+
+	// Only anchors of the DApp and the sender hashing to the transaction select it
+	other, _ := crypto.GenerateKey()
+	anchors := types.Transactions{txs[2], dappTransaction(&dappBId, 1, 100, key), dappTransaction(&dappAId, 1, 100, other), dappTransaction(&dappAId, 1, 200, key), txs[0]}
+	anchored := pool.DAppTransactions(dappAId, anchors)
+	if len(anchored) != 2 || anchored[0].Nonce() != 2 || anchored[1].Nonce() != 0 {
+		t.Fatalf("anchored transactions mismatch: have %d, want nonces 2 and 0", len(anchored))
+	}
+	if anchored[0].Hash() != txs[2].DAppTx().Hash() {
+		t.Errorf("anchored transaction mismatch: have %x, want %x", anchored[0].Hash(), txs[2].DAppTx().Hash())
+	}
+	if none := pool.DAppTransactions(dappBId, anchors); len(none) != 0 {
+		t.Errorf("foreign DApp transactions: have %d, want none", len(none))
+	}
+	// Packaged transactions are dropped without invalidating the others
+	pool.RemoveDAppTransactions(dappAId, anchored)
+	if left := pool.DAppTransactions(dappAId, txs); len(left) != 1 || left[0].Nonce() != 1 {
+		t.Errorf("remaining transactions mismatch: have %d, want nonce 1", len(left))
+	}
+	pool.RemoveDAppTransactions(dappAId, types.Transactions{txs[1].DAppTx()})
+	if _, ok := pool.dappPending[dappAId]; ok {
+		t.Errorf("empty DApp transaction list retained")
+	}
+}
+
+// Tests that DApp transactions follow the gas limit and minimum gas price of
+// their DApp chain, while the main chain transaction anchoring them is still
+// priced against the local node.
+func TestDAppTransactionRules(t *testing.T) {
+	t.Parallel()
+
+	chainConfig := *config.TestChainConfig
+	chainConfig.DApps = map[common.Address]*config.DAppConfig{
+		dappAId: {GasLimit: 200000, MinGasPrice: big.NewInt(5)},
+		dappBId: {MinGasPrice: big.NewInt(1)},
+	}
+	diskdb, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	pool := NewTxPool(testTxPoolConfig, &chainConfig, &testBlockChain{statedb, 1000000, new(event.Feed)})
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(config.Ether))
+	pool.SetGasPrice(big.NewInt(2))
+
+	tests := []struct {
+		tx  *types.Transaction
+		err error
+	}{
+		{pricedDappTransaction(&dappAId, 0, 100000, big.NewInt(5), key), nil},
+		{pricedDappTransaction(&dappAId, 1, 100000, big.NewInt(3), key), ErrDAppUnderpriced},
+		{pricedDappTransaction(&dappAId, 1, 300000, big.NewInt(5), key), ErrGasLimit},
+		{pricedDappTransaction(&dappBId, 0, 100000, big.NewInt(1), key), ErrUnderpriced},
+	}
+	for i, tt := range tests {
+		if err := pool.AddRemote(tt.tx); err != tt.err {
+			t.Errorf("test %d: main transaction error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The rules apply to the DApp transactions gossiped by the peer group as well
+	errs := pool.AddDAppTransactions([]*types.Transaction{tests[1].tx.DAppTx(), tests[2].tx.DAppTx()})
+	if errs[0] != ErrDAppUnderpriced || errs[1] != ErrGasLimit {
+		t.Errorf("DApp transaction errors mismatch: have %v", errs)
+	}
+}
+
+// Tests that DApp transactions are only accepted if signed for their DApp and
+// chain, and that they may not replay a nonce already used on their DApp chain.
+func TestDAppTransactionSignatures(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(config.Ether))
+	pool.lockedReset(nil, nil)
+
+	// The DApp chain already executed the first transaction of the sender
+	diskdb, _ := store.NewMemDatabase()
+	dappState, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	dappState.SetNonce(from, 1)
+	pool.SetDAppState(func(dappId common.Address) *state.StateDB {
+		if dappId == dappAId {
+			return dappState
+		}
+		return nil
+	})
+	signer := types.NewDAppSigner(config.TestChainConfig.ChainId)
+	unsigned := types.NewDAppTransaction(&dappAId, 0, 100000, big.NewInt(1), nil).WithDAppNonce(1)
+	unprotected, _ := types.SignTx(unsigned, types.DefaultSigner{}, key)
+	replayed, _ := types.SignTx(types.NewDAppTransaction(&dappAId, 0, 100000, big.NewInt(1), nil), signer, key)
+	valid, _ := types.SignTx(unsigned, signer, key)
+
+	if err := pool.AddRemote(unprotected); err != ErrInvalidSender {
+		t.Errorf("unprotected main transaction: have %v, want %v", err, ErrInvalidSender)
+	}
+	errs := pool.AddDAppTransactions([]*types.Transaction{unsigned.DAppTx(), unprotected.DAppTx(), replayed.DAppTx(), valid.DAppTx(), valid.DAppTx()})
+	if errs[0] != ErrInvalidSender || errs[1] != ErrInvalidSender || errs[2] != ErrNonceTooLow || errs[3] != nil || errs[4] == nil {
+		t.Errorf("DApp transaction errors mismatch: have %v", errs)
+	}
+	// DApp nonces are counted on the DApp chain, independent of the main chain
+	if nonce := pool.DAppNonce(dappAId, from); nonce != 2 {
+		t.Errorf("DApp nonce mismatch: have %d, want 2", nonce)
+	}
+	if nonce := pool.DAppNonce(dappBId, from); nonce != 0 {
+		t.Errorf("unreplicated DApp nonce mismatch: have %d, want 0", nonce)
+	}
+	if err := pool.AddRemote(valid); err != nil {
+		t.Fatalf("failed to add main transaction: %v", err)
+	}
+	if anchored := pool.DAppTransactions(dappAId, types.Transactions{valid}); len(anchored) != 1 || anchored[0].Nonce() != 1 {
+		t.Errorf("anchored transactions mismatch: have %d, want nonce 1", len(anchored))
+	}
+}
+
+// Tests that the pending DApp transactions are bounded per sender and per DApp,
+// and that a DApp transaction only replaces a pending one of the same DApp nonce
+// if it bumps the gas price.
+func TestDAppTransactionSlots(t *testing.T) {
+	t.Parallel()
+
+	poolConfig := testTxPoolConfig
+	poolConfig.AccountSlots = 2
+	poolConfig.DAppSlots = 3
+
+	diskdb, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	pool := NewTxPool(poolConfig, config.TestChainConfig, &testBlockChain{statedb, 1000000, new(event.Feed)})
+	defer pool.Stop()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(config.Ether))
+
+	// The references don't cover the sender, use distinct gas limits per sender
+	dappTx := func(nonce uint64, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		gas := uint64(100000)
+		if key == key2 {
+			gas++
+		}
+		return pricedDappTransaction(&dappAId, nonce, gas, big.NewInt(price), key).DAppTx()
+	}
+	// The slots of a sender fill up before the ones of the DApp
+	errs := pool.AddDAppTransactions([]*types.Transaction{dappTx(0, 100, key1), dappTx(1, 100, key1), dappTx(2, 100, key1), dappTx(0, 100, key2), dappTx(1, 100, key2)})
+	if errs[0] != nil || errs[1] != nil || errs[2] != ErrDAppPoolFull || errs[3] != nil || errs[4] != ErrDAppPoolFull {
+		t.Errorf("DApp transaction errors mismatch: have %v", errs)
+	}
+	// Main chain transactions carrying a DApp transaction are rejected as well
+	if err := pool.AddRemote(pricedDappTransaction(&dappAId, 1, 100001, big.NewInt(100), key2)); err != ErrDAppPoolFull {
+		t.Errorf("main transaction error mismatch: have %v, want %v", err, ErrDAppPoolFull)
+	}
+	// A full pool still accepts replacements of the same nonce with a price bump
+	old, cheap, bumped := dappTx(1, 100, key1), dappTx(1, 109, key1), dappTx(1, 110, key1)
+	errs = pool.AddDAppTransactions([]*types.Transaction{cheap, bumped})
+	if errs[0] != ErrReplaceUnderpriced || errs[1] != nil {
+		t.Errorf("DApp transaction replacement errors mismatch: have %v", errs)
+	}
+	pending := pool.dappPending[dappAId]
+	if pending.Len() != 3 {
+		t.Errorf("pending DApp transactions mismatch: have %d, want 3", pending.Len())
+	}
+	if pending.Get(*old.RefHashId()) != nil || pending.Get(*bumped.RefHashId()) == nil {
+		t.Errorf("replaced DApp transaction retained")
+	}
+	if nonce := pool.DAppNonce(dappAId, crypto.PubkeyToAddress(key1.PublicKey)); nonce != 2 {
+		t.Errorf("DApp nonce mismatch: have %d, want 2", nonce)
+	}
+}
 
 func TestTransactionNegativeValue(t *testing.T) {
 	t.Parallel()
@@ -483,7 +660,7 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	resetState()
 
 	dappId := addr
-	signer := types.NewChainSigner(common.Big1)
+	signer := types.NewDAppSigner(common.Big1)
 	tx1, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), signer, key)
 	tx2, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(2), nil), signer, key)
 	tx3, _ := types.SignTx(types.NewDAppTransaction(&dappId, 0, 1000000, big.NewInt(1), dappTxData), signer, key)
@@ -674,7 +851,7 @@ func TestTransactionPostponing(t *testing.T) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create two test accounts to produce different gap profiles with
@@ -896,7 +1073,7 @@ func testTransactionQueueGlobalLimiting(t *testing.T, nolocals bool) {
 	config0.NoLocals = nolocals
 	config0.GlobalQueue = config0.AccountQueue*3 - 1 // reduce the queue limits to shorten test time (-1 to make it non divisible)
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them (last one will be the local)
@@ -985,7 +1162,7 @@ func testTransactionQueueTimeLimiting(t *testing.T, nolocals bool) {
 	config0.Lifetime = time.Second
 	config0.NoLocals = nolocals
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create two test accounts to ensure remotes expire but locals do not
@@ -1139,7 +1316,7 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 	config0 := testTxPoolConfig
 	config0.GlobalSlots = config0.AccountSlots * 10
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1188,7 +1365,7 @@ func TestTransactionCapClearsFromAll(t *testing.T) {
 	config0.AccountQueue = 2
 	config0.GlobalSlots = 8
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1221,7 +1398,7 @@ func TestTransactionPendingMinimumAllowance(t *testing.T) {
 	config0 := testTxPoolConfig
 	config0.GlobalSlots = 0
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1267,7 +1444,7 @@ func TestTransactionPoolRepricing(t *testing.T) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1389,7 +1566,7 @@ func TestTransactionPoolRepricingKeepsLocals(t *testing.T) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
@@ -1456,7 +1633,7 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	config0.GlobalSlots = 2
 	config0.GlobalQueue = 2
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1563,7 +1740,7 @@ func TestTransactionPoolStableUnderpricing(t *testing.T) {
 	config0.GlobalSlots = 128
 	config0.GlobalQueue = 0
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1626,7 +1803,7 @@ func TestTransactionReplacement(t *testing.T) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Keep track of transaction events to ensure all executables get announced
@@ -1726,7 +1903,7 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	config0.Journal = journal
 	config0.Rejournal = time.Second
 
-	pool := NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(config0, config.TestChainConfig, blockchain)
 
 	// Create two test accounts to ensure remotes expire but locals do not
 	local, _ := crypto.GenerateKey()
@@ -1763,7 +1940,7 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
 	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool = NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool = NewTxPool(config0, config.TestChainConfig, blockchain)
 
 	pending, queued = pool.Stats()
 	if queued != 0 {
@@ -1789,7 +1966,7 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 
 	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
 	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
-	pool = NewTxPool(config0, config.TestChainConfig, blockchain, nil)
+	pool = NewTxPool(config0, config.TestChainConfig, blockchain)
 
 	pending, queued = pool.Stats()
 	if pending != 0 {
@@ -1820,7 +1997,7 @@ func TestTransactionStatusCheck(t *testing.T) {
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
 
-	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain, nil)
+	pool := NewTxPool(testTxPoolConfig, config.TestChainConfig, blockchain)
 	defer pool.Stop()
 
 	// Create the test accounts to check various transaction statuses with
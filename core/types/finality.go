@@ -0,0 +1,47 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+)
+
+// preCommitDomain separates the signing hash of pre-commit votes from any other
+// hash a delegator signs with its node key.
+const preCommitDomain = "pre-commit"
+
+// PreCommit is the signed vote of a delegator for a block to become final.
+type PreCommit struct {
+	Number    uint64        `json:"number"`    // Number of the voted block
+	Hash      common.Hash   `json:"hash"`      // Hash of the voted block
+	Delegator string        `json:"delegator"` // Short node id of the voting delegator
+	Signature hexutil.Bytes `json:"signature"` // Signature of the delegator over the signing hash
+}
+
+// SigHash returns the hash the delegator signs to vote for the block.
+func (v *PreCommit) SigHash() common.Hash {
+	return rlpHash([]interface{}{preCommitDomain, v.Number, v.Hash})
+}
+
+// FinalityCertificate proves that a block was voted final by a qualified
+// majority of the delegators of its epoch.
+type FinalityCertificate struct {
+	Number uint64       `json:"number"` // Number of the finalized block
+	Hash   common.Hash  `json:"hash"`   // Hash of the finalized block
+	Votes  []*PreCommit `json:"votes"`  // Pre-commit votes of the delegators for the block
+}
@@ -149,23 +149,53 @@ func newTransaction(dappId *common.Address, nonce uint64, to *common.Address, am
 	if dappId != nil && !bytes.Equal(dappId.Bytes(), EmptyDAppIdHash.Bytes()) {
 		// create the dapp transaction.
 		d.DAppId = dappId; // the main tx carries a dappid if it is.
-		dappTx := newDAppTransaction(dappId, EmptyHash, nonce, gasLimit, gasPrice, data);
-		tx := &Transaction{data: d};
-		// set the dapp hash without main tx.hash into tx.payload for verification. tx.hash can't be supported in here.
-		// the reversed verification of tx.data.Payload must replacing the RefHashId field as empty value.
-		tx.data.Payload = dappTx.Hash().Bytes();
-		//tx.data.Recipient = dappId; // dapp account receives this transaction.
-		txhash := tx.Hash();
-		dappTx.data.RefHashId = &txhash; //bing tx.hash into dapp.tx.RefHashId
-		tx.dappTx = dappTx;
-		return tx;
+		dappTx := newDAppTransaction(dappId, EmptyHash, nonce, nil, nil, gasLimit, gasPrice, data);
+		return anchorDAppTransaction(d, dappTx);
 	} else {
 		return &Transaction{data: d}
 	}
 }
 
+// NewDAppCall creates a DApp transaction calling an account of the DApp chain
+// with the given value. The value is spent on the DApp chain, the main chain
+// transaction anchoring it carries none.
+func NewDAppCall(dappId *common.Address, nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+	if dappId == nil || *dappId == *EmptyDAppIdHash {
+		return NewTransaction(nonce, to, amount, gasLimit, gasPrice, data)
+	}
+	d := newTransaction(EmptyDAppIdHash, nonce, EmptyDAppIdHash, nil, gasLimit, gasPrice, nil).data
+	d.DAppId = dappId
+	return anchorDAppTransaction(d, newDAppTransaction(dappId, EmptyHash, nonce, &to, amount, gasLimit, gasPrice, data))
+}
+
+// WithDAppNonce returns a copy of an unsigned main chain transaction whose DApp
+// transaction uses the given nonce. DApp transactions are ordered by the nonce of
+// their sender on the DApp chain, independent of the main chain nonce.
+func (tx *Transaction) WithDAppNonce(nonce uint64) *Transaction {
+	if tx.dappTx == nil {
+		return tx
+	}
+	dappTx := &Transaction{data: tx.dappTx.data}
+	dappTx.data.AccountNonce = nonce
+	return anchorDAppTransaction(tx.data, dappTx)
+}
 
-func newDAppTransaction(dappId *common.Address, refHashId *common.Hash, nonce uint64, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+// anchorDAppTransaction binds a DApp transaction to the main chain transaction
+// anchoring it: the main transaction carries the anchor hash of the DApp
+// transaction as payload, and the DApp transaction refers back to the unsigned
+// hash of the main one by its RefHashId.
+func anchorDAppTransaction(d txdata, dappTx *Transaction) *Transaction {
+	tx := &Transaction{data: d}
+	anchorHash := dappTx.AnchorHash()
+	tx.data.Payload = anchorHash.Bytes()
+
+	refHashId := tx.UnsignedHash()
+	dappTx.data.RefHashId = &refHashId
+	tx.dappTx = dappTx
+	return tx
+}
+
+func newDAppTransaction(dappId *common.Address, refHashId *common.Hash, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
 	if len(data) > 0 {
 		data = common.CopyBytes(data)
 	}
@@ -174,6 +204,7 @@ func newDAppTransaction(dappId *common.Address, refHashId *common.Hash, nonce ui
 		DAppId:       dappId,
 		RefHashId:    refHashId,
 		AccountNonce: nonce,
+		Recipient:    to,
 		Payload:      data,
 		Amount:       new(big.Int),
 		GasLimit:     gasLimit,
@@ -182,6 +213,9 @@ func newDAppTransaction(dappId *common.Address, refHashId *common.Hash, nonce ui
 		R:            new(big.Int),
 		S:            new(big.Int),
 	}
+	if amount != nil {
+		d.Amount.Set(amount)
+	}
 	if gasPrice != nil {
 		d.Price.Set(gasPrice)
 	}
@@ -263,6 +297,12 @@ func (tx *Transaction) Nonce() uint64        { return tx.data.AccountNonce }
 func (tx *Transaction) CheckNonce() bool     { return true }
 func (tx *Transaction) DAppTx() *Transaction { return tx.dappTx }
 
+// isDApp returns whether the transaction belongs to a DApp, either as the DApp
+// transaction itself or as the main chain transaction anchoring it.
+func (tx *Transaction) isDApp() bool {
+	return tx.data.DAppId != nil && *tx.data.DAppId != *EmptyDAppIdHash
+}
+
 
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
@@ -285,6 +325,24 @@ func (tx *Transaction) Hash() common.Hash {
 	return v
 }
 
+// UnsignedHash hashes the RLP encoding of tx without its signature. A DApp
+// transaction refers to its main transaction by this hash in RefHashId.
+func (tx *Transaction) UnsignedHash() common.Hash {
+	cpy := tx.data
+	cpy.V, cpy.R, cpy.S = new(big.Int), new(big.Int), new(big.Int)
+	return rlpHash(&Transaction{data: cpy})
+}
+
+// AnchorHash hashes the RLP encoding of a DApp transaction without its signature
+// and its reference to the main transaction. The main transaction anchoring it
+// carries this hash as payload.
+func (tx *Transaction) AnchorHash() common.Hash {
+	cpy := tx.data
+	cpy.RefHashId = EmptyHash
+	cpy.V, cpy.R, cpy.S = new(big.Int), new(big.Int), new(big.Int)
+	return rlpHash(&Transaction{data: cpy})
+}
+
 // Size returns the true RLP encoded storage size of the transaction, either by
 // encoding and returning it, or returning a previsouly cached value.
 func (tx *Transaction) Size() common.StorageSize {
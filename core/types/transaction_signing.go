@@ -29,6 +29,10 @@ import (
 
 var (
 	ErrInvalidChainId = errors.New("invalid chain id for signer")
+
+	// ErrUnprotectedDAppTx is returned if a transaction of a DApp is not signed
+	// for a chain id.
+	ErrUnprotectedDAppTx = errors.New("DApp transaction not protected by a chain id")
 )
 
 // sigCache is used to cache the derived sender and contains
@@ -40,6 +44,18 @@ type sigCache struct {
 
 // MakeSigner returns a Signer based on the given chain config and block number.
 func MakeSigner(config *config.ChainConfig, blockNumber *big.Int) Signer {
+	if config.IsDAppSign(blockNumber) {
+		return NewDAppSigner(config.ChainId)
+	}
+	return NewChainSigner(config.ChainId)
+}
+
+// LatestSigner returns the Signer of the most recent rules scheduled by the given
+// chain config, for signing and accepting transactions ahead of their block.
+func LatestSigner(config *config.ChainConfig) Signer {
+	if config.DAppSignBlock != nil {
+		return NewDAppSigner(config.ChainId)
+	}
 	return NewChainSigner(config.ChainId)
 }
 
@@ -51,7 +67,9 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 		return nil, err
 	}
 	signedTx, err := tx.WithSignature(s, sig)
-
+	if err != nil {
+		return nil, err
+	}
 	// sign dapp tx with the same private key as well.
 	if tx.dappTx != nil {
 		h := s.Hash(tx.dappTx)
@@ -59,9 +77,11 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 		if err != nil {
 			return nil, err
 		}
-		signedTx.dappTx, err = tx.dappTx.WithSignature(s, sig)
+		if signedTx.dappTx, err = tx.dappTx.WithSignature(s, sig); err != nil {
+			return nil, err
+		}
 	}
-	return signedTx, err;
+	return signedTx, nil
 }
 
 // Sender returns the address derived from the signature (V, R, S) using secp256k1
@@ -177,6 +197,59 @@ func (s ChainSigner) Hash(tx *Transaction) common.Hash {
 	})
 }
 
+// DAppSigner implements the signing rules from the DApp signing fork on.
+// Transactions of a DApp also sign their DApp id and the reference to their main
+// chain transaction, so they can't be replayed on another DApp chain or anchored
+// by another main transaction, and have to be protected by a chain id.
+type DAppSigner struct{ ChainSigner }
+
+func NewDAppSigner(chainId *big.Int) DAppSigner {
+	return DAppSigner{NewChainSigner(chainId)}
+}
+
+func (s DAppSigner) Equal(s2 Signer) bool {
+	dapp, ok := s2.(DAppSigner)
+	return ok && dapp.chainId.Cmp(s.chainId) == 0
+}
+
+func (s DAppSigner) Sender(tx *Transaction) (common.Address, error) {
+	if !tx.isDApp() {
+		return s.ChainSigner.Sender(tx)
+	}
+	if !tx.Protected() {
+		return common.Address{}, ErrUnprotectedDAppTx
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V := new(big.Int).Sub(tx.data.V, s.chainIdMul)
+	V.Sub(V, big8)
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+// Hash returns the hash to be signed by the sender, covering the DApp id and main
+// chain reference of DApp transactions.
+func (s DAppSigner) Hash(tx *Transaction) common.Hash {
+	if !tx.isDApp() {
+		return s.ChainSigner.Hash(tx)
+	}
+	refHashId := EmptyHash
+	if tx.data.RefHashId != nil {
+		refHashId = tx.data.RefHashId
+	}
+	return rlpHash([]interface{}{
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		*tx.data.DAppId,
+		*refHashId,
+		s.chainId, uint(0), uint(0),
+	})
+}
+
 // Default Transaction implements TransactionInterface using the default rules.
 type DefaultSigner struct{ signer }
 
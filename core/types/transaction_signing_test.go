@@ -17,12 +17,14 @@
 package types
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/common/crypto"
 	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
 )
 
 func TestEIP155Signing(t *testing.T) {
@@ -217,3 +219,79 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+// Tests that DApp transactions sign their DApp id and main chain reference, and
+// have to be protected by a chain id.
+func TestDAppSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	dappId, otherId := common.Address{0xda}, common.Address{0xdb}
+
+	signer := NewDAppSigner(big.NewInt(18))
+	tx, err := SignTx(NewDAppTransaction(&dappId, 3, 100, new(big.Int), dappTxData).WithDAppNonce(7), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dappTx := tx.DAppTx()
+	if tx.Nonce() != 3 || dappTx.Nonce() != 7 {
+		t.Fatalf("nonce mismatch: have %d and %d, want 3 and 7", tx.Nonce(), dappTx.Nonce())
+	}
+	if hash := dappTx.AnchorHash(); !bytes.Equal(tx.Data(), hash[:]) || *dappTx.RefHashId() != tx.UnsignedHash() {
+		t.Fatalf("DApp transaction not anchored by its main transaction")
+	}
+	if from, err := Sender(signer, dappTx); err != nil || from != addr {
+		t.Fatalf("DApp sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// Moving the transaction to another DApp or main transaction changes its sender
+	moved := &Transaction{data: dappTx.data}
+	moved.data.DAppId = &otherId
+	if from, _ := Sender(signer, moved); from == addr {
+		t.Errorf("DApp transaction replayed on another DApp")
+	}
+	moved = &Transaction{data: dappTx.data}
+	moved.data.RefHashId = &common.Hash{0x01}
+	if from, _ := Sender(signer, moved); from == addr {
+		t.Errorf("DApp transaction anchored by another main transaction")
+	}
+	// Transactions of a DApp have to be signed for a chain
+	unprotected, err := SignTx(NewDAppTransaction(&dappId, 3, 100, new(big.Int), dappTxData), DefaultSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(signer, unprotected.DAppTx()); err != ErrUnprotectedDAppTx {
+		t.Errorf("unprotected DApp transaction: have %v, want %v", err, ErrUnprotectedDAppTx)
+	}
+}
+
+// Tests that DApp transactions are signed by the DApp signing rules from the fork
+// block on only.
+func TestDAppSigningFork(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	dappId := common.Address{0xda}
+	chainConfig := &config.ChainConfig{ChainId: big.NewInt(18), DAppSignBlock: big.NewInt(10)}
+
+	before, after := MakeSigner(chainConfig, big.NewInt(9)), MakeSigner(chainConfig, big.NewInt(10))
+	if _, ok := before.(ChainSigner); !ok {
+		t.Fatalf("signer before the fork: have %T, want ChainSigner", before)
+	}
+	if _, ok := after.(DAppSigner); !ok {
+		t.Fatalf("signer from the fork on: have %T, want DAppSigner", after)
+	}
+	if latest := LatestSigner(chainConfig); !latest.Equal(after) {
+		t.Errorf("latest signer mismatch: have %T, want %T", latest, after)
+	}
+	// Main chain transactions are signed alike on both sides of the fork
+	plain, _ := SignTx(NewTransaction(0, addr, new(big.Int), 21000, new(big.Int), nil), before, key)
+	if from, err := Sender(after, plain); err != nil || from != addr {
+		t.Errorf("main chain sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// The anchoring stub of a DApp transaction signs its DApp from the fork on
+	stub, _ := SignTx(NewDAppTransaction(&dappId, 0, 100, new(big.Int), dappTxData), before, key)
+	if from, err := Sender(before, stub); err != nil || from != addr {
+		t.Errorf("stub sender before the fork mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if from, _ := Sender(after, stub); from == addr {
+		t.Errorf("stub signed before the fork accepted after it")
+	}
+}
@@ -128,6 +128,25 @@ func TestDAppCodec(t *testing.T) {
 
 }
 
+// Tests that a signed main transaction and its DApp transaction still reference
+// each other through their unsigned hashes.
+func TestDAppAnchorHashes(t *testing.T) {
+	key, _ := defaultTestKey()
+	tx, err := SignTx(dappTX, NewChainSigner(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash() == tx.UnsignedHash() {
+		t.Errorf("signature not covered by the transaction hash")
+	}
+	if have, want := *tx.DAppTx().RefHashId(), tx.UnsignedHash(); have != want {
+		t.Errorf("reference mismatch: have %x, want %x", have, want)
+	}
+	if have, want := common.BytesToHash(tx.Data()), tx.DAppTx().AnchorHash(); have != want {
+		t.Errorf("anchor hash mismatch: have %x, want %x", have, want)
+	}
+}
+
 func TestRecipientEmpty(t *testing.T) {
 	txb, err := rlp.EncodeToBytes(rightvrsTx)
 	if err != nil {
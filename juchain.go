@@ -147,6 +147,8 @@ type FilterQuery struct {
 	// {{A}}, {B}}        matches topic A in first position, B in second position
 	// {{A, B}}, {C, D}}  matches topic (A OR B) in first position, (C OR D) in second position
 	Topics [][]common.Hash
+
+	DAppId *common.Address // restricts matches to the chain of a DApp, nil means the main chain
 }
 
 // LogFilterer provides access to contract log events using a one-off query or continuous
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -47,6 +48,9 @@ import (
 
 const (
 	defaultGasPrice = 50 * config.Shannon
+
+	// maxDAppListing is the maximum number of DApps returned per listing request.
+	maxDAppListing = 100
 )
 
 // PublicJuchainAPI provides an API to access Juchain related information.
@@ -296,18 +300,81 @@ func (s *PrivateAccountAPI) NewAccount(password string) (common.Address, error)
 	return common.Address{}, err
 }
 
-// NewDAppAccount will create a new account and returns the address for the new Dapp user.
-func (s *PrivateAccountAPI) NewDAppAccount(dappName string, orgName string, orgDescription,
-	nationalityCode int, password string) (common.Address, error) {
-	acc, err := fetchKeystore(s.am).NewAccount(password)
-	if err == nil {
-		// we need to write the dapp info into block as a transaction in order to keep the date consistence.
-		// initializa DApp P2P nodes selection
+// DAppRegistration is the account of a new DApp along with the transaction
+// registering it in the DApp manager contract.
+type DAppRegistration struct {
+	Address     common.Address `json:"address"`     // Account of the DApp, used as its id
+	Transaction common.Hash    `json:"transaction"` // Transaction registering the DApp
+}
 
+// NewDAppAccount will create a new account for a DApp and register the DApp in
+// the DApp manager contract. The account holds no funds yet, so the registration
+// is submitted free of charge.
+func (s *PrivateAccountAPI) NewDAppAccount(ctx context.Context, dappName string, orgName string, orgDescription string,
+	nationalityCode uint8, ledgerReplicated uint8, icon string, password string) (*DAppRegistration, error) {
+	input, err := core.PackDAppManager("registerDAppInfo", dappName, orgName, orgDescription, nationalityCode, ledgerReplicated, icon)
+	if err != nil {
+		return nil, err
+	}
+	// Registrations of fresh accounts only differ by their sender, so dry run it
+	// before creating the account.
+	gas, err := s.estimateDAppRegistration(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := fetchKeystore(s.am).NewAccount(password)
+	if err != nil {
+		return nil, err
+	}
+	args := SendTxArgs{
+		From:     acc.Address,
+		To:       &core.DAPPContractAddress,
+		Gas:      (*hexutil.Uint64)(&gas),
+		GasPrice: new(hexutil.Big),
+		Data:     (*hexutil.Bytes)(&input),
+	}
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return nil, err
+	}
+	// The account manager learns about the new account asynchronously, so sign
+	// with the keystore holding it.
+	signed, err := fetchKeystore(s.am).SignTxWithPassphrase(acc, password, args.toTransaction(), s.b.ChainConfig().ChainId)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := submitTransaction(ctx, s.b, signed)
+	if err != nil {
+		return nil, err
+	}
+	return &DAppRegistration{Address: acc.Address, Transaction: hash}, nil
+}
 
-		return acc.Address, nil
+// estimateDAppRegistration executes the registration of a DApp on the latest
+// state, returning the gas it uses or an error if the contract rejects it.
+func (s *PrivateAccountAPI) estimateDAppRegistration(ctx context.Context, input []byte) (uint64, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return 0, err
 	}
-	return common.Address{}, err
+	if state == nil {
+		return 0, errors.New("latest state not available")
+	}
+	msg := types.NewMessage(common.Address{}, &core.DAPPContractAddress, 0, new(big.Int), header.GasLimit, new(big.Int), input, false)
+	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vm.Config{})
+	if err != nil {
+		return 0, err
+	}
+	_, gas, failed, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(header.GasLimit))
+	if err := vmError(); err != nil {
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	if failed {
+		return 0, errors.New("DApp registration rejected by the DApp manager")
+	}
+	return gas, nil
 }
 
 // fetchKeystore retrives the encrypted keystore from the account manager.
@@ -362,28 +429,141 @@ func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 //	return 0, nil;
 //}
 
-func (s *PrivateAccountAPI) UpdateDAppStorageNode(dappId common.Address, password string, nodes []string) (error) {
-	return nil;
+// UpdateDAppStorageNode assigns the storage nodes replicating the chain of a
+// DApp. The assignment is authorised by the key of the DApp account, and can't
+// exceed the number of replicas the DApp registered in the DApp manager contract.
+func (s *PrivateAccountAPI) UpdateDAppStorageNode(ctx context.Context, dappId common.Address, password string, nodes []string) (error) {
+	if err := s.verifyDAppOwner(dappId, password); err != nil {
+		return err
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return err
+	}
+	info, err := core.ReadDAppInfo(s.b.ChainConfig(), header, state, dappId)
+	if err != nil {
+		return err
+	}
+	if len(nodes) > int(info.LedgerReplicated) {
+		return fmt.Errorf("too many storage nodes: have %d, registered %d", len(nodes), info.LedgerReplicated)
+	}
+	return s.b.AssignDAppNodes(dappId, nodes)
 }
 
+// ListDAppStorageNodeInfo returns the storage nodes assigned to replicate the
+// chain of a DApp.
 func (s *PrivateAccountAPI) ListDAppStorageNodeInfo(dappId common.Address, password string) ([]string, error) {
-	return config.DAppAddresses.ToString(), nil;
+	if err := s.verifyDAppOwner(dappId, password); err != nil {
+		return nil, err
+	}
+	return config.DAppAddresses.GetAssignedNodes(&dappId), nil;
+}
+
+// verifyDAppOwner checks whether the password decrypts the key of the DApp
+// account, without unlocking it.
+func (s *PrivateAccountAPI) verifyDAppOwner(dappId common.Address, password string) error {
+	_, err := fetchKeystore(s.am).SignHashWithPassphrase(account.Account{Address: dappId}, password, dappId.Hash().Bytes())
+	return err
 }
 
 func (s *PrivateAccountAPI) HashDApp(dappId *common.Address) (bool) {
 	return config.DAppAddresses.Has(dappId);
 }
 
-func (s *PrivateAccountAPI) ListDAppContracts(dappId common.Address) (error) {
-	return nil;
+// DAppContract is a contract deployed on the chain of a DApp.
+type DAppContract struct {
+	Address  common.Address `json:"address"`
+	CodeHash common.Hash    `json:"codeHash"`
+	Balance  *hexutil.Big   `json:"balance"`
 }
 
-func (s *PrivateAccountAPI) ListAllDAppIds(offset uint8) ([]common.Address, error) {
-	return make([]common.Address, 0), nil;
+// ListDAppContracts returns the contracts deployed on the chain of a DApp
+// attached to the node, ordered by address.
+func (s *PrivateAccountAPI) ListDAppContracts(dappId common.Address) ([]*DAppContract, error) {
+	chain := s.b.DAppChain(dappId)
+	if chain == nil {
+		return nil, fmt.Errorf("DApp chain %x not attached", dappId)
+	}
+	state, err := chain.State()
+	if err != nil {
+		return nil, err
+	}
+	contracts := make([]*DAppContract, 0)
+	err = state.ForEachContract(func(addr common.Address, codeHash common.Hash, balance *big.Int) bool {
+		contracts = append(contracts, &DAppContract{
+			Address:  addr,
+			CodeHash: codeHash,
+			Balance:  (*hexutil.Big)(balance),
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return bytes.Compare(contracts[i].Address[:], contracts[j].Address[:]) < 0
+	})
+	return contracts, nil
 }
 
-func (s *PrivateAccountAPI) TotalDApps() (int, error) {
-	return len(config.DAppAddresses.Addresse), nil;
+// ListAllDAppIds returns a page of the DApps registered in the DApp manager
+// contract, in registration order. At most maxDAppListing DApps are returned.
+func (s *PrivateAccountAPI) ListAllDAppIds(ctx context.Context, offset uint64, limit *uint64) ([]common.Address, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	count := uint64(maxDAppListing)
+	if limit != nil && *limit < count {
+		count = *limit
+	}
+	dappIds, err := core.ReadDAppIds(s.b.ChainConfig(), header, state, offset, count)
+	if dappIds == nil && err == nil {
+		dappIds = make([]common.Address, 0)
+	}
+	return dappIds, err
+}
+
+// ListAllDApps returns the registrations of a page of the DApps registered in
+// the DApp manager contract, in registration order.
+func (s *PrivateAccountAPI) ListAllDApps(ctx context.Context, offset uint64, limit *uint64) ([]*core.DAppInfo, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	count := uint64(maxDAppListing)
+	if limit != nil && *limit < count {
+		count = *limit
+	}
+	dappIds, err := core.ReadDAppIds(s.b.ChainConfig(), header, state, offset, count)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*core.DAppInfo, len(dappIds))
+	for i, dappId := range dappIds {
+		if infos[i], err = core.ReadDAppInfo(s.b.ChainConfig(), header, state, dappId); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+// GetDAppInfo returns the registration of a DApp in the DApp manager contract.
+func (s *PrivateAccountAPI) GetDAppInfo(ctx context.Context, dappId common.Address) (*core.DAppInfo, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return core.ReadDAppInfo(s.b.ChainConfig(), header, state, dappId)
+}
+
+// TotalDApps returns the number of DApps registered in the DApp manager contract.
+func (s *PrivateAccountAPI) TotalDApps(ctx context.Context) (uint64, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return 0, err
+	}
+	return core.ReadDAppCount(state), nil
 }
 
 
@@ -529,6 +709,8 @@ func (s *PrivateAccountAPI) SignAndSendTransaction(ctx context.Context, args Sen
 
 // PublicBlockChainAPI provides an API to access the Juchain blockchain.
 // It offers only methods that operate on public data that is freely available to anyone.
+// Every method accepts an optional DApp ID as its last argument, resolving the
+// request against the chain of that DApp instead of the main chain.
 type PublicBlockChainAPI struct {
 	b Backend
 }
@@ -538,17 +720,42 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// dapp returns the API resolving against the chain of the given DApp, or the API
+// itself if no DApp is given, along with the function releasing the DApp chain.
+func (s *PublicBlockChainAPI) dapp(dappId *common.Address) (*PublicBlockChainAPI, func(), error) {
+	if dappId == nil {
+		return s, func() {}, nil
+	}
+	b, release, err := s.b.DAppBackend(*dappId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PublicBlockChainAPI{b}, release, nil
+}
+
 // rpc.BlockNumber returns the block number of the chain head.
-func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
-	header, _ := s.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
-	return header.Number
+func (s *PublicBlockChainAPI) BlockNumber(dappId *common.Address) (*big.Int, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	header, _ := api.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
+	return header.Number, nil
 }
 
 // GetBalance returns the amount of wei for the given address in the state of the
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
-func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber, dappId *common.Address) (*big.Int, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -558,10 +765,16 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
-func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
-	block, err := s.b.BlockByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool, dappId *common.Address) (map[string]interface{}, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	block, err := api.b.BlockByNumber(ctx, blockNr)
 	if block != nil {
-		response, err := s.rpcOutputBlock(block, true, fullTx)
+		response, err := api.rpcOutputBlock(block, true, fullTx)
 		if err == nil && blockNr == rpc.PendingBlockNumber {
 			// Pending blocks need to nil out a few fields
 			for _, field := range []string{"hash", "nonce", "miner"} {
@@ -575,18 +788,30 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.
 
 // GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full
 // detail, otherwise only the transaction hash is returned.
-func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
-	block, err := s.b.GetBlock(ctx, blockHash)
+func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool, dappId *common.Address) (map[string]interface{}, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	block, err := api.b.GetBlock(ctx, blockHash)
 	if block != nil {
-		return s.rpcOutputBlock(block, true, fullTx)
+		return api.rpcOutputBlock(block, true, fullTx)
 	}
 	return nil, err
 }
 
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
-func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
-	block, err := s.b.BlockByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint, dappId *common.Address) (map[string]interface{}, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	block, err := api.b.BlockByNumber(ctx, blockNr)
 	if block != nil {
 		uncles := block.Uncles()
 		if index >= hexutil.Uint(len(uncles)) {
@@ -594,15 +819,21 @@ func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context,
 			return nil, nil
 		}
 		block = types.NewBlockWithHeader(uncles[index])
-		return s.rpcOutputBlock(block, false, false)
+		return api.rpcOutputBlock(block, false, false)
 	}
 	return nil, err
 }
 
 // GetUncleByBlockHashAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
-func (s *PublicBlockChainAPI) GetUncleByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (map[string]interface{}, error) {
-	block, err := s.b.GetBlock(ctx, blockHash)
+func (s *PublicBlockChainAPI) GetUncleByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint, dappId *common.Address) (map[string]interface{}, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	block, err := api.b.GetBlock(ctx, blockHash)
 	if block != nil {
 		uncles := block.Uncles()
 		if index >= hexutil.Uint(len(uncles)) {
@@ -610,14 +841,20 @@ func (s *PublicBlockChainAPI) GetUncleByBlockHashAndIndex(ctx context.Context, b
 			return nil, nil
 		}
 		block = types.NewBlockWithHeader(uncles[index])
-		return s.rpcOutputBlock(block, false, false)
+		return api.rpcOutputBlock(block, false, false)
 	}
 	return nil, err
 }
 
 // GetUncleCountByBlockNumber returns number of uncles in the block for the given block number
-func (s *PublicBlockChainAPI) GetUncleCountByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) *hexutil.Uint {
-	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
+func (s *PublicBlockChainAPI) GetUncleCountByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber, dappId *common.Address) *hexutil.Uint {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.BlockByNumber(ctx, blockNr); block != nil {
 		n := hexutil.Uint(len(block.Uncles()))
 		return &n
 	}
@@ -625,8 +862,14 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockNumber(ctx context.Context, bl
 }
 
 // GetUncleCountByBlockHash returns number of uncles in the block for the given block hash
-func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash common.Hash) *hexutil.Uint {
-	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash common.Hash, dappId *common.Address) *hexutil.Uint {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.GetBlock(ctx, blockHash); block != nil {
 		n := hexutil.Uint(len(block.Uncles()))
 		return &n
 	}
@@ -634,8 +877,14 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 }
 
 // GetCode returns the code stored at the given address in the state for the given block number.
-func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber, dappId *common.Address) (hexutil.Bytes, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -646,8 +895,14 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 // GetStorageAt returns the storage from the state at the given address, key and
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
-func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber, dappId *common.Address) (hexutil.Bytes, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -730,8 +985,14 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, dappId *common.Address) (hexutil.Bytes, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, _, _, err := api.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
@@ -926,7 +1187,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.DefaultSigner{}
 	if tx.Protected() {
-		signer = types.NewChainSigner(tx.ChainId())
+		signer = types.NewDAppSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -986,7 +1247,9 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 	return nil
 }
 
-// PublicTransactionPoolAPI exposes methods for the RPC interface
+// PublicTransactionPoolAPI exposes methods for the RPC interface.
+// The methods reading transactions accept an optional DApp ID as their last
+// argument, resolving the request against the chain of that DApp.
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
@@ -997,9 +1260,28 @@ func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransa
 	return &PublicTransactionPoolAPI{b, nonceLock}
 }
 
+// dapp returns the API resolving against the chain of the given DApp, or the API
+// itself if no DApp is given, along with the function releasing the DApp chain.
+func (s *PublicTransactionPoolAPI) dapp(dappId *common.Address) (*PublicTransactionPoolAPI, func(), error) {
+	if dappId == nil {
+		return s, func() {}, nil
+	}
+	b, release, err := s.b.DAppBackend(*dappId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PublicTransactionPoolAPI{b, s.nonceLock}, release, nil
+}
+
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
-func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber) *hexutil.Uint {
-	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
+func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber, dappId *common.Address) *hexutil.Uint {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.BlockByNumber(ctx, blockNr); block != nil {
 		n := hexutil.Uint(len(block.Transactions()))
 		return &n
 	}
@@ -1007,8 +1289,14 @@ func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByNumber(ctx context.
 }
 
 // GetBlockTransactionCountByHash returns the number of transactions in the block with the given hash.
-func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Context, blockHash common.Hash) *hexutil.Uint {
-	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Context, blockHash common.Hash, dappId *common.Address) *hexutil.Uint {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.GetBlock(ctx, blockHash); block != nil {
 		n := hexutil.Uint(len(block.Transactions()))
 		return &n
 	}
@@ -1016,40 +1304,70 @@ func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Co
 }
 
 // GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
-func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) *RPCTransaction {
-	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
+func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint, dappId *common.Address) *RPCTransaction {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.BlockByNumber(ctx, blockNr); block != nil {
 		return newRPCTransactionFromBlockIndex(block, uint64(index))
 	}
 	return nil
 }
 
 // GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
-func (s *PublicTransactionPoolAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) *RPCTransaction {
-	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+func (s *PublicTransactionPoolAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint, dappId *common.Address) *RPCTransaction {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.GetBlock(ctx, blockHash); block != nil {
 		return newRPCTransactionFromBlockIndex(block, uint64(index))
 	}
 	return nil
 }
 
 // GetRawTransactionByBlockNumberAndIndex returns the bytes of the transaction for the given block number and index.
-func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) hexutil.Bytes {
-	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
+func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint, dappId *common.Address) hexutil.Bytes {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.BlockByNumber(ctx, blockNr); block != nil {
 		return newRPCRawTransactionFromBlockIndex(block, uint64(index))
 	}
 	return nil
 }
 
 // GetRawTransactionByBlockHashAndIndex returns the bytes of the transaction for the given block hash and index.
-func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) hexutil.Bytes {
-	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
+func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint, dappId *common.Address) hexutil.Bytes {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	if block, _ := api.b.GetBlock(ctx, blockHash); block != nil {
 		return newRPCRawTransactionFromBlockIndex(block, uint64(index))
 	}
 	return nil
 }
 
 // GetTransactionCount returns the number of transactions the given address has sent for the given block number
-func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber, dappId *common.Address) (*hexutil.Uint64, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1058,13 +1376,19 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 }
 
 // GetTransactionByHash returns the transaction for the given hash
-func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
+func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash, dappId *common.Address) *RPCTransaction {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
 	// Try to return an already finalized transaction
-	if tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash); tx != nil {
+	if tx, blockHash, blockNumber, index := core.GetTransaction(api.b.ChainDb(), hash); tx != nil {
 		return newRPCTransaction(tx, blockHash, blockNumber, index)
 	}
 	// No finalized transaction, try to retrieve it from the pool
-	if tx := s.b.GetPoolTransaction(hash); tx != nil {
+	if tx := api.b.GetPoolTransaction(hash); tx != nil {
 		return newRPCPendingTransaction(tx)
 	}
 	// Transaction unknown, return as such
@@ -1072,12 +1396,18 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 }
 
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
-func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash, dappId *common.Address) (hexutil.Bytes, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	var tx *types.Transaction
 
 	// Retrieve a finalized transaction, or a pooled otherwise
-	if tx, _, _, _ = core.GetTransaction(s.b.ChainDb(), hash); tx == nil {
-		if tx = s.b.GetPoolTransaction(hash); tx == nil {
+	if tx, _, _, _ = core.GetTransaction(api.b.ChainDb(), hash); tx == nil {
+		if tx = api.b.GetPoolTransaction(hash); tx == nil {
 			// Transaction not found anywhere, abort
 			return nil, nil
 		}
@@ -1087,12 +1417,18 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
-	tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash)
+func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash, dappId *common.Address) (map[string]interface{}, error) {
+	api, release, err := s.dapp(dappId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tx, blockHash, blockNumber, index := core.GetTransaction(api.b.ChainDb(), hash)
 	if tx == nil {
 		return nil, nil
 	}
-	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	receipts, err := api.b.GetReceipts(ctx, blockHash)
 	if err != nil {
 		return nil, err
 	}
@@ -1103,7 +1439,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 
 	var signer types.Signer = types.DefaultSigner{}
 	if tx.Protected() {
-		signer = types.NewChainSigner(tx.ChainId())
+		signer = types.MakeSigner(api.b.ChainConfig(), new(big.Int).SetUint64(blockNumber))
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1163,6 +1499,9 @@ type SendTxArgs struct {
 	GasPrice *hexutil.Big    `json:"gasPrice"`
 	Value    *hexutil.Big    `json:"value"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
+	// Nonce of the sender on the DApp chain, DApp transactions don't share the
+	// nonces of the main chain.
+	DAppNonce *hexutil.Uint64 `json:"dappNonce"`
 	// We accept "data" and "input" for backwards-compatibility reasons. "input" is the
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
@@ -1192,6 +1531,18 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		}
 		args.Nonce = (*hexutil.Uint64)(&nonce)
 	}
+	if args.DAppID != nil && *args.DAppID != *types.EmptyDAppIdHash && args.DAppNonce == nil {
+		dapp, release, err := b.DAppBackend(*args.DAppID)
+		if err != nil {
+			return fmt.Errorf("missing DApp nonce: %v", err)
+		}
+		nonce, err := dapp.GetPoolNonce(ctx, args.From)
+		release()
+		if err != nil {
+			return err
+		}
+		args.DAppNonce = (*hexutil.Uint64)(&nonce)
+	}
 	if args.Data != nil && args.Input != nil && !bytes.Equal(*args.Data, *args.Input) {
 		return errors.New(`Both "data" and "input" are set and not equal. Please use "input" to pass transaction call data.`)
 	}
@@ -1218,10 +1569,16 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 		input = *args.Input
 	}
 
+	var tx *types.Transaction
 	if args.To == nil {
-		return types.NewDAppContractCreation(args.DAppID, uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+		tx = types.NewDAppContractCreation(args.DAppID, uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	} else {
+		tx = types.NewDAppCall(args.DAppID, uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	}
+	if args.DAppNonce != nil {
+		tx = tx.WithDAppNonce(uint64(*args.DAppNonce))
 	}
-	return types.NewDAppTransaction(args.DAppID, uint64(*args.Nonce), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	return tx
 }
 
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
@@ -1360,7 +1717,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.DefaultSigner{}
 		if tx.Protected() {
-			signer = types.NewChainSigner(tx.ChainId())
+			signer = types.NewDAppSigner(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(account.Account{Address: from}); err == nil {
@@ -1388,7 +1745,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.DefaultSigner{}
 		if p.Protected() {
-			signer = types.NewChainSigner(p.ChainId())
+			signer = types.NewDAppSigner(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
@@ -68,4 +68,9 @@ type Backend interface {
 
 	ChainConfig() *config.ChainConfig
 	CurrentBlock() *types.Block
+
+	// DApp API
+	DAppChain(dappId common.Address) *core.BlockChain
+	AssignDAppNodes(dappId common.Address, nodes []string) error
+	DAppBackend(dappId common.Address) (Backend, func(), error) // Backend of a DApp chain, to be released after use
 }
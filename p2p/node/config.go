@@ -18,11 +18,13 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/juchain/go-juchain/core/account"
@@ -39,6 +41,7 @@ import (
 
 const (
 	datadirDappKeys        = "dappkeys"           // Path within the datadir to the dapp keys
+	datadirDappNodes       = "dapp-nodes.json"    // Path within the datadir to the storage nodes of the dapps
 	datadirPrivateKey      = "nodekey"            // Path within the datadir to the node's private key
 	datadirDefaultKeyStore = "keystore"           // Path within the datadir to the keystore
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
@@ -332,9 +335,74 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// DAppStorageNodes returns the storage nodes persisted as replicating the chains
+// of DApps.
+func (c *Config) DAppStorageNodes() map[common.Address][]string {
+	if c.DataDir == "" {
+		return nil
+	}
+	path := c.resolvePath(datadirDappNodes)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	var nodes map[common.Address][]string
+	if err := common.LoadJSON(path, &nodes); err != nil {
+		log.Error(fmt.Sprintf("Can't load DApp storage node file %s: %v", path, err))
+		return nil
+	}
+	return nodes
+}
+
+// SaveDAppStorageNodes persists the storage nodes assigned to replicate the
+// chains of DApps, next to the DApps assigned to the node. Nothing is persisted
+// without a data directory.
+func (c *Config) SaveDAppStorageNodes(nodes map[common.Address][]string) error {
+	if c.DataDir == "" {
+		return nil
+	}
+	path := c.resolvePath(datadirDappNodes)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) DAppAddresses() []common.Address {
-	return c.parseDAppAddresses(c.resolvePath(datadirDappKeys))
+	addresses, _ := c.parseDAppAddresses(c.resolvePath(datadirDappKeys))
+	return addresses
+}
+
+// DAppQuotas returns the disk quotas persisted for the DApps assigned to the node.
+func (c *Config) DAppQuotas() map[common.Address]uint64 {
+	_, quotas := c.parseDAppAddresses(c.resolvePath(datadirDappKeys))
+	return quotas
+}
+
+// SaveDAppAddresses persists the DApps assigned to the node along with their disk
+// quotas, to be reattached on the next start. Nothing is persisted without a data
+// directory.
+func (c *Config) SaveDAppAddresses(addresses []common.Address, quotas map[common.Address]uint64) error {
+	if c.DataDir == "" {
+		return nil
+	}
+	path := c.resolvePath(datadirDappKeys)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	var content []byte
+	for _, addr := range addresses {
+		if quota, ok := quotas[addr]; ok {
+			content = append(content, fmt.Sprintf("%s %d\n", addr.Hex(), quota)...)
+		} else {
+			content = append(content, addr.Hex()+"\n"...)
+		}
+	}
+	return ioutil.WriteFile(path, content, 0600)
 }
 
 // StaticNodes returns a list of node enode URLs configured as static nodes.
@@ -379,29 +447,42 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	return nodes
 }
 
-func (c *Config) parseDAppAddresses(path string) []common.Address {
+// parseDAppAddresses parses the DApps assigned to the node, one per line and
+// optionally followed by the disk quota of the DApp in bytes.
+func (c *Config) parseDAppAddresses(path string) ([]common.Address, map[common.Address]uint64) {
 	if c.DataDir == "" {
-		return nil
+		return nil, nil
 	}
 	if _, err := os.Stat(path); err != nil {
-		return nil
+		return nil, nil
 	}
 	f, err := os.Open(path);
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 	defer f.Close()
 
 	addresses := make([]common.Address, 0)
+	quotas := make(map[common.Address]uint64)
 	rd := bufio.NewReader(f)
 	for {
 		line, err := rd.ReadString('\n') //以'\n'为结束符读入一行
+		if fields := strings.Fields(line); len(fields) > 0 {
+			addr := common.HexToAddress(fields[0])
+			addresses = append(addresses, addr)
+			if len(fields) > 1 {
+				if quota, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+					quotas[addr] = quota
+				} else {
+					log.Error(fmt.Sprintf("Invalid disk quota of DApp %s: %v", fields[0], err))
+				}
+			}
+		}
 		if err != nil || io.EOF == err {
 			break
 		}
-		addresses = append(addresses, common.HexToAddress(line))
 	}
-	return addresses;
+	return addresses, quotas;
 }
 
 
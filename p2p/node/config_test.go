@@ -21,9 +21,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
+	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/common/crypto"
 	"github.com/juchain/go-juchain/p2p"
 )
@@ -148,3 +150,46 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the DApps assigned to a node are persisted and loaded again along
+// with their disk quotas and storage nodes.
+func TestDAppAddressPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Name: "unit-test", DataDir: dir}
+	if addresses := config.DAppAddresses(); len(addresses) != 0 {
+		t.Fatalf("fresh node has DApps assigned: %v", addresses)
+	}
+	addresses := []common.Address{{0x01}, {0x02}}
+	quotas := map[common.Address]uint64{{0x02}: 1024}
+	if err := config.SaveDAppAddresses(addresses, quotas); err != nil {
+		t.Fatalf("failed to persist DApps: %v", err)
+	}
+	if have := config.DAppAddresses(); !reflect.DeepEqual(have, addresses) {
+		t.Errorf("persisted DApps mismatch: have %v, want %v", have, addresses)
+	}
+	if have := config.DAppQuotas(); !reflect.DeepEqual(have, quotas) {
+		t.Errorf("persisted quotas mismatch: have %v, want %v", have, quotas)
+	}
+	if err := config.SaveDAppAddresses(nil, nil); err != nil {
+		t.Fatalf("failed to persist DApps: %v", err)
+	}
+	if have := config.DAppAddresses(); len(have) != 0 {
+		t.Errorf("removed DApps loaded: %v", have)
+	}
+	// Storage nodes are kept next to the DApps
+	if nodes := config.DAppStorageNodes(); len(nodes) != 0 {
+		t.Fatalf("fresh node has storage nodes assigned: %v", nodes)
+	}
+	nodes := map[common.Address][]string{{0x01}: {"node1", "node2"}}
+	if err := config.SaveDAppStorageNodes(nodes); err != nil {
+		t.Fatalf("failed to persist storage nodes: %v", err)
+	}
+	if have := config.DAppStorageNodes(); !reflect.DeepEqual(have, nodes) {
+		t.Errorf("persisted storage nodes mismatch: have %v, want %v", have, nodes)
+	}
+}
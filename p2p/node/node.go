@@ -544,6 +544,17 @@ func (n *Node) DAppAddresses() []common.Address {
 	return n.config.DAppAddresses()
 }
 
+// DAppQuotas returns the disk quotas persisted for the DApps assigned to the node.
+func (n *Node) DAppQuotas() map[common.Address]uint64 {
+	return n.config.DAppQuotas()
+}
+
+// DAppStorageNodes returns the storage nodes persisted as replicating the chains
+// of DApps.
+func (n *Node) DAppStorageNodes() map[common.Address][]string {
+	return n.config.DAppStorageNodes()
+}
+
 // AccountManager retrieves the account manager used by the protocol stack.
 func (n *Node) AccountManager() *account.Manager {
 	return n.accman
@@ -33,6 +33,7 @@ import (
 	"github.com/juchain/go-juchain/rpc"
 	"github.com/juchain/go-juchain/core/trie"
 	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/vm/solc"
 )
 
 // PublicEthereumAPI provides an API to access JuchainService full node-related
@@ -61,6 +62,155 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return 0
 }
 
+// PublicDAppAPI provides an API to access the DApp chains anchored to the main
+// chain.
+type PublicDAppAPI struct {
+	e *JuchainService
+}
+
+// NewPublicDAppAPI creates a new API for the DApp chains of a full node.
+func NewPublicDAppAPI(e *JuchainService) *PublicDAppAPI {
+	return &PublicDAppAPI{e}
+}
+
+// GetAnchorProof returns the Merkle inclusion proof of a DApp transaction back
+// to the header of the main chain block anchoring it.
+func (api *PublicDAppAPI) GetAnchorProof(dappId common.Address, hash common.Hash) (*core.AnchorProof, error) {
+	chain, db, release := api.e.useDAppChain(dappId)
+	if chain == nil {
+		return nil, fmt.Errorf("unknown DApp %x", dappId)
+	}
+	defer release()
+
+	tx, blockHash, number, _ := core.GetTransaction(db, hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	block := chain.GetBlock(blockHash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	return core.ProveAnchor(api.e.blockchain, block, tx)
+}
+
+// GetDeposit returns a deposit locked in the DApp bridge of the main chain, and
+// whether it was minted on the DApp chain if the node follows it.
+func (api *PublicDAppAPI) GetDeposit(id common.Hash) (map[string]interface{}, error) {
+	statedb, err := api.e.blockchain.State()
+	if err != nil {
+		return nil, err
+	}
+	deposit := vm.GetBridgeDeposit(statedb, id)
+	if deposit == nil {
+		return nil, fmt.Errorf("deposit %x not found", id)
+	}
+	fields := map[string]interface{}{
+		"id":          deposit.Id,
+		"dappId":      deposit.DAppId,
+		"sender":      deposit.Sender,
+		"recipient":   deposit.Recipient,
+		"amount":      (*hexutil.Big)(deposit.Amount),
+		"blockNumber": hexutil.Uint64(deposit.Number),
+		"status":      "locked",
+	}
+	if chain, _, release := api.e.useDAppChain(deposit.DAppId); chain != nil {
+		defer release()
+
+		dappState, err := chain.State()
+		if err != nil {
+			return nil, err
+		}
+		if number := vm.DepositMinted(dappState, id); number != 0 {
+			fields["status"] = "minted"
+			fields["mintedIn"] = hexutil.Uint64(number)
+		}
+	}
+	return fields, nil
+}
+
+// GetWithdrawal returns the value burned in the DApp bridge by a DApp transaction
+// and how far it got: burned on the DApp chain, final once its anchor block is,
+// and released on the main chain.
+func (api *PublicDAppAPI) GetWithdrawal(dappId common.Address, hash common.Hash) (map[string]interface{}, error) {
+	chain, db, release := api.e.useDAppChain(dappId)
+	if chain == nil {
+		return nil, fmt.Errorf("unknown DApp %x", dappId)
+	}
+	defer release()
+
+	tx, blockHash, number, _ := core.GetTransaction(db, hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	recipient, ok := vm.BurnRecipient(tx)
+	if !ok {
+		return nil, fmt.Errorf("transaction %x burns no value", hash)
+	}
+	fields := map[string]interface{}{
+		"dappId":      dappId,
+		"hash":        hash,
+		"recipient":   recipient,
+		"amount":      (*hexutil.Big)(tx.Value()),
+		"blockHash":   blockHash,
+		"blockNumber": hexutil.Uint64(number),
+		"status":      "burned",
+	}
+	if receipt, _, _, _ := core.GetReceipt(db, hash); receipt != nil && receipt.Status == types.ReceiptStatusFailed {
+		fields["status"] = "failed"
+		return fields, nil
+	}
+	statedb, err := api.e.blockchain.State()
+	if err != nil {
+		return nil, err
+	}
+	if released := vm.ReleasedBurn(statedb, hash); released != 0 {
+		fields["status"] = "released"
+		fields["releasedIn"] = hexutil.Uint64(released)
+		return fields, nil
+	}
+	if header := chain.GetHeader(blockHash, number); header != nil {
+		if anchor := api.e.blockchain.GetHeaderByHash(header.DAppMainHash); anchor != nil {
+			finalized := api.e.blockchain.CurrentFinalizedHeader()
+			if anchor.Number.Cmp(finalized.Number) <= 0 && core.GetCanonicalHash(api.e.chainDb, anchor.Number.Uint64()) == anchor.Hash() {
+				fields["status"] = "finalized"
+			}
+		}
+	}
+	return fields, nil
+}
+
+// GetReleaseProof returns the proof releasing the value burned by a DApp
+// transaction on the main chain, the input of the release method of the DApp
+// bridge. The block anchoring the burn has to be final.
+func (api *PublicDAppAPI) GetReleaseProof(dappId common.Address, hash common.Hash) (hexutil.Bytes, error) {
+	chain, db, release := api.e.useDAppChain(dappId)
+	if chain == nil {
+		return nil, fmt.Errorf("unknown DApp %x", dappId)
+	}
+	defer release()
+
+	tx, blockHash, number, _ := core.GetTransaction(db, hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	block := chain.GetBlock(blockHash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	proof, err := core.ProveRelease(api.e.blockchain, block, core.GetBlockReceipts(db, blockHash, number), tx)
+	if err != nil {
+		return nil, err
+	}
+	finalized := api.e.blockchain.CurrentFinalizedHeader()
+	if proof.Anchor.Header.Number.Cmp(finalized.Number) > 0 {
+		return nil, core.ErrUnfinalizedAnchor
+	}
+	if proof.Finality = api.e.blockchain.GetFinalityCertificate(finalized.Hash()); proof.Finality == nil {
+		return nil, core.ErrUnfinalizedAnchor
+	}
+	return rlp.EncodeToBytes(proof)
+}
+
 // PrivateAdminAPI is the collection of JuchainService full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -154,6 +304,37 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AttachDApp attaches the chain of a DApp to the running node.
+func (api *PrivateAdminAPI) AttachDApp(dappId common.Address) (bool, error) {
+	if err := api.eth.AttachDApp(dappId); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DetachDApp detaches the chain of a DApp from the running node, removing its
+// database if pruning is requested.
+func (api *PrivateAdminAPI) DetachDApp(dappId common.Address, prune *bool) (bool, error) {
+	if err := api.eth.DetachDApp(dappId, prune != nil && *prune); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetDAppQuota limits the disk space in bytes the chain database of an attached
+// DApp may use, zero falling back to the default quota.
+func (api *PrivateAdminAPI) SetDAppQuota(dappId common.Address, quota hexutil.Uint64) (bool, error) {
+	if err := api.eth.SetDAppQuota(dappId, uint64(quota)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListDApps retrieves the DApp chains attached to the node along with their heads.
+func (api *PrivateAdminAPI) ListDApps() []*DAppChainInfo {
+	return api.eth.DApps()
+}
+
 // PublicDebugAPI is the collection of JuchainService full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -28,7 +28,9 @@ import (
 	"github.com/juchain/go-juchain/core/state"
 	"github.com/juchain/go-juchain/core/types"
 	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/p2p"
 	"github.com/juchain/go-juchain/p2p/protocol/downloader"
+	"github.com/juchain/go-juchain/p2p/protocol/filters"
 	"github.com/juchain/go-juchain/p2p/protocol/gasprice"
 	"github.com/juchain/go-juchain/core/store"
 	"github.com/juchain/go-juchain/common/event"
@@ -40,19 +42,77 @@ import (
 type EthApiBackend struct {
 	eth *JuchainService
 	gpo *gasprice.Oracle
+
+	dappChain   *core.BlockChain // Chain of the DApp the backend resolves against, nil for the main chain
+	dappChainDb store.Database   // Database of the DApp chain
+}
+
+// blockchain returns the chain the backend resolves against.
+func (b *EthApiBackend) blockchain() *core.BlockChain {
+	if b.dappChain != nil {
+		return b.dappChain
+	}
+	return b.eth.blockchain
+}
+
+// chainDb returns the database of the chain the backend resolves against.
+func (b *EthApiBackend) chainDb() store.Database {
+	if b.dappChain != nil {
+		return b.dappChainDb
+	}
+	return b.eth.chainDb
 }
 
 func (b *EthApiBackend) ChainConfig() *config.ChainConfig {
-	return b.eth.chainConfig
+	return b.blockchain().Config()
 }
 
 func (b *EthApiBackend) CurrentBlock() *types.Block {
-	return b.eth.blockchain.CurrentBlock()
+	return b.blockchain().CurrentBlock()
+}
+
+func (b *EthApiBackend) DAppChain(dappId common.Address) *core.BlockChain {
+	chain, _ := b.eth.DAppChain(dappId)
+	return chain
+}
+
+func (b *EthApiBackend) AssignDAppNodes(dappId common.Address, nodes []string) error {
+	return b.eth.AssignDAppNodes(dappId, nodes)
+}
+
+// DAppBackend returns a backend resolving the chain data against the chain of an
+// attached DApp. The DApp chain is not closed until the backend is released.
+func (b *EthApiBackend) DAppBackend(dappId common.Address) (p2p.Backend, func(), error) {
+	backend, release, err := b.dappBackend(dappId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return backend, release, nil
+}
+
+// DAppFilterBackend returns a backend filtering the logs of the chain of an
+// attached DApp. The DApp chain is not closed until the backend is released.
+func (b *EthApiBackend) DAppFilterBackend(dappId common.Address) (filters.Backend, func(), error) {
+	backend, release, err := b.dappBackend(dappId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return backend, release, nil
+}
+
+func (b *EthApiBackend) dappBackend(dappId common.Address) (*EthApiBackend, func(), error) {
+	dappChain, dappChainDb, release := b.eth.useDAppChain(dappId)
+	if dappChain == nil {
+		return nil, nil, errDAppNotAttached
+	}
+	return &EthApiBackend{eth: b.eth, gpo: b.gpo, dappChain: dappChain, dappChainDb: dappChainDb}, release, nil
 }
 
 func (b *EthApiBackend) SetHead(number uint64) {
-	b.eth.protocolManager.downloader.Cancel()
-	b.eth.blockchain.SetHead(number)
+	if b.dappChain == nil {
+		b.eth.protocolManager.downloader.Cancel()
+	}
+	b.blockchain().SetHead(number)
 }
 
 func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
@@ -64,9 +124,12 @@ func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.eth.blockchain.CurrentBlock().Header(), nil
+		return b.blockchain().CurrentBlock().Header(), nil
+	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.blockchain().CurrentFinalizedHeader(), nil
 	}
-	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
+	return b.blockchain().GetHeaderByNumber(uint64(blockNr)), nil
 }
 
 func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
@@ -78,9 +141,13 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.eth.blockchain.CurrentBlock(), nil
+		return b.blockchain().CurrentBlock(), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+	if blockNr == rpc.FinalizedBlockNumber {
+		header := b.blockchain().CurrentFinalizedHeader()
+		return b.blockchain().GetBlock(header.Hash(), header.Number.Uint64()), nil
+	}
+	return b.blockchain().GetBlockByNumber(uint64(blockNr)), nil
 }
 
 func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
@@ -95,20 +162,20 @@ func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.blockchain().StateAt(header.Root)
 	return stateDb, header, err
 }
 
 func (b *EthApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(blockHash), nil
+	return b.blockchain().GetBlockByHash(blockHash), nil
 }
 
 func (b *EthApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
-	return core.GetBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash)), nil
+	return core.GetBlockReceipts(b.chainDb(), blockHash, core.GetBlockNumber(b.chainDb(), blockHash)), nil
 }
 
 func (b *EthApiBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error) {
-	receipts := core.GetBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash))
+	receipts := core.GetBlockReceipts(b.chainDb(), blockHash, core.GetBlockNumber(b.chainDb(), blockHash))
 	if receipts == nil {
 		return nil, nil
 	}
@@ -120,35 +187,35 @@ func (b *EthApiBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][
 }
 
 func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
-	return b.eth.blockchain.GetTdByHash(blockHash)
+	return b.blockchain().GetTdByHash(blockHash)
 }
 
 func (b *EthApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
 	state.SetBalance(msg.From(), math.MaxBig256)
 	vmError := func() error { return nil }
 
-	context := core.NewEVMContext(msg, header, b.eth.BlockChain(), nil)
-	return vm.NewEVM(context, state, b.eth.chainConfig, vmCfg), vmError, nil
+	context := core.NewEVMContext(msg, header, b.blockchain(), nil)
+	return vm.NewEVM(context, state, b.ChainConfig(), vmCfg), vmError, nil
 }
 
 func (b *EthApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
-	return b.eth.BlockChain().SubscribeRemovedLogsEvent(ch)
+	return b.blockchain().SubscribeRemovedLogsEvent(ch)
 }
 
 func (b *EthApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
-	return b.eth.BlockChain().SubscribeChainEvent(ch)
+	return b.blockchain().SubscribeChainEvent(ch)
 }
 
 func (b *EthApiBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
-	return b.eth.BlockChain().SubscribeChainHeadEvent(ch)
+	return b.blockchain().SubscribeChainHeadEvent(ch)
 }
 
 func (b *EthApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
-	return b.eth.BlockChain().SubscribeChainSideEvent(ch)
+	return b.blockchain().SubscribeChainSideEvent(ch)
 }
 
 func (b *EthApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
-	return b.eth.BlockChain().SubscribeLogsEvent(ch)
+	return b.blockchain().SubscribeLogsEvent(ch)
 }
 
 func (b *EthApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
@@ -172,6 +239,9 @@ func (b *EthApiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 }
 
 func (b *EthApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	if b.dappChain != nil {
+		return b.eth.txPool.DAppNonce(b.dappChain.Genesis().DAppID(), addr), nil
+	}
 	return b.eth.txPool.State().GetNonce(addr), nil
 }
 
@@ -200,7 +270,7 @@ func (b *EthApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 }
 
 func (b *EthApiBackend) ChainDb() store.Database {
-	return b.eth.ChainDb()
+	return b.chainDb()
 }
 
 func (b *EthApiBackend) EventMux() *event.TypeMux {
@@ -212,6 +282,10 @@ func (b *EthApiBackend) AccountManager() *account.Manager {
 }
 
 func (b *EthApiBackend) BloomStatus() (uint64, uint64) {
+	// DApp chains are not indexed, their logs are filtered block by block
+	if b.dappChain != nil {
+		return config.BloomBitsBlocks, 0
+	}
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return config.BloomBitsBlocks, sections
 }
@@ -17,13 +17,27 @@
 package protocol
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus/dpos"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/account"
+	"github.com/juchain/go-juchain/core/account/keystore"
 	"github.com/juchain/go-juchain/core/state"
 	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/p2p"
+	"github.com/juchain/go-juchain/p2p/node"
+	"github.com/juchain/go-juchain/p2p/protocol/filters"
+	"github.com/juchain/go-juchain/vm/solc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -89,3 +103,268 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that DApp chains are attached to and detached from a running node, and
+// listed along with their heads and disk quotas.
+func TestDAppChainLifecycle(t *testing.T) {
+	defer func(addresses *config.DAppAddress) { config.DAppAddresses = addresses }(config.DAppAddresses)
+	config.DAppAddresses = &config.DAppAddress{Maximum: 2}
+
+	datadir, err := ioutil.TempDir("", "dapps")
+	if err != nil {
+		t.Fatalf("failed to create data directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	var (
+		db, _         = store.NewMemDatabase()
+		gspec         = &core.Genesis{Config: config.TestChainConfig}
+		_             = gspec.MustCommit(db)
+		engine        = dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+		conf          = DefaultConfig
+	)
+	defer blockchain.Stop()
+
+	conf.Genesis = gspec
+	eth := &JuchainService{
+		ctx:         &node.ServiceContext{Config: &node.Config{DataDir: datadir}},
+		config:      &conf,
+		chainConfig: config.TestChainConfig,
+		blockchain:  blockchain,
+		dappConfig:  config.DAppAddresses,
+		dappChainDb: make(map[common.Address]store.Database),
+		dappchains:  make(map[common.Address]*core.BlockChain),
+		eventMux:    new(event.TypeMux),
+		engine:      engine,
+		txPool:      core.NewTxPool(DefaultConfig.TxPool, config.TestChainConfig, blockchain),
+	}
+	defer eth.txPool.Stop()
+
+	eth.dappPackager = dpos.NewDAppPackager(eth.chainConfig, engine, blockchain, eth.dappchains, eth.txPool, eth.eventMux)
+	defer eth.dappPackager.Stop()
+
+	// DApps given on the command line are followed but never persisted
+	api := NewPrivateAdminAPI(eth)
+	static := common.Address{0xdc}
+	config.DAppAddresses.AddStatic(static)
+	if _, err := api.AttachDApp(static); err != nil {
+		t.Fatalf("failed to attach static DApp chain: %v", err)
+	}
+	dappId := common.Address{0xda}
+	if _, err := api.AttachDApp(dappId); err != nil {
+		t.Fatalf("failed to attach DApp chain: %v", err)
+	}
+	if _, err := api.AttachDApp(dappId); err != errDAppAttached {
+		t.Errorf("attached twice: have %v, want %v", err, errDAppAttached)
+	}
+	dapps := api.ListDApps()
+	if len(dapps) != 2 || dapps[0].DAppId != dappId || dapps[0].Number != 0 {
+		t.Fatalf("attached DApp chains mismatch: have %v", dapps)
+	}
+	if chain, _ := eth.DAppChain(dappId); chain == nil || chain.AnchorChain() != blockchain || chain.Genesis().Hash() != dapps[0].Hash {
+		t.Errorf("attached DApp chain not anchored to the main chain")
+	}
+	if addresses := config.DAppAddresses.List(); !reflect.DeepEqual(addresses, []common.Address{static, dappId}) {
+		t.Errorf("assigned DApps mismatch: have %v", addresses)
+	}
+	if addresses := eth.ctx.Config.DAppAddresses(); !reflect.DeepEqual(addresses, []common.Address{dappId}) {
+		t.Errorf("persisted DApps mismatch: have %v", addresses)
+	}
+	if _, err := api.AttachDApp(common.Address{0xdb}); err != errTooManyDApps {
+		t.Errorf("attached over the maximum: have %v, want %v", err, errTooManyDApps)
+	}
+	// Disk quotas only apply to attached DApp chains
+	if _, err := api.SetDAppQuota(common.Address{0xdb}, 1024); err != errDAppNotAttached {
+		t.Errorf("quota of unknown DApp: have %v, want %v", err, errDAppNotAttached)
+	}
+	if _, err := api.SetDAppQuota(dappId, 1024); err != nil {
+		t.Fatalf("failed to set DApp quota: %v", err)
+	}
+	if dapps := api.ListDApps(); dapps[0].DiskQuota != 1024 {
+		t.Errorf("DApp quota mismatch: have %d, want %d", dapps[0].DiskQuota, 1024)
+	}
+	// Storage nodes outlive a restart of the node
+	if err := eth.AssignDAppNodes(dappId, []string{"node1"}); err != nil {
+		t.Fatalf("failed to assign storage nodes: %v", err)
+	}
+	if nodes := eth.ctx.Config.DAppStorageNodes(); !reflect.DeepEqual(nodes, map[common.Address][]string{dappId: {"node1"}}) {
+		t.Errorf("persisted storage nodes mismatch: have %v", nodes)
+	}
+	// Detaching releases the chain and its assignment once no longer in use
+	prune := true
+	if _, err := api.DetachDApp(common.Address{0xdb}, &prune); err != errDAppNotAttached {
+		t.Errorf("detached unknown DApp: have %v, want %v", err, errDAppNotAttached)
+	}
+	if _, err := api.DetachDApp(static, &prune); err != errDAppStatic {
+		t.Errorf("detached static DApp: have %v, want %v", err, errDAppStatic)
+	}
+	_, _, release := eth.useDAppChain(dappId)
+	detached := make(chan error)
+	go func() {
+		_, err := api.DetachDApp(dappId, &prune)
+		detached <- err
+	}()
+	for chain, _ := eth.DAppChain(dappId); chain != nil; chain, _ = eth.DAppChain(dappId) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := api.AttachDApp(dappId); err != errDAppDetaching {
+		t.Errorf("reattached while detaching: have %v, want %v", err, errDAppDetaching)
+	}
+	select {
+	case err := <-detached:
+		t.Fatalf("detached DApp chain in use: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	if err := <-detached; err != nil {
+		t.Fatalf("failed to detach DApp chain: %v", err)
+	}
+	if dapps := api.ListDApps(); len(dapps) != 1 || dapps[0].DAppId != static {
+		t.Errorf("detached DApp chain listed: %v", dapps)
+	}
+	if addresses := config.DAppAddresses.List(); !reflect.DeepEqual(addresses, []common.Address{static}) {
+		t.Errorf("detached DApp still assigned: %v", addresses)
+	}
+	if addresses := eth.ctx.Config.DAppAddresses(); len(addresses) != 0 {
+		t.Errorf("detached DApp still persisted: %v", addresses)
+	}
+	if nodes := eth.ctx.Config.DAppStorageNodes(); len(nodes) != 0 {
+		t.Errorf("storage nodes of detached DApp still persisted: %v", nodes)
+	}
+	if _, err := os.Stat(eth.ctx.ResolvePath(dappChainDbName(dappId))); !os.IsNotExist(err) {
+		t.Errorf("detached DApp chain database not pruned: %v", err)
+	}
+}
+
+// Tests that the chain and log APIs resolve requests carrying a DApp ID against
+// the chain of that DApp, and reject DApps not attached to the node.
+func TestDAppChainRPC(t *testing.T) {
+	defer func(addresses *config.DAppAddress) { config.DAppAddresses = addresses }(config.DAppAddresses)
+	config.DAppAddresses = new(config.DAppAddress)
+
+	var (
+		db, _         = store.NewMemDatabase()
+		gspec         = &core.Genesis{Config: config.TestChainConfig}
+		genesis       = gspec.MustCommit(db)
+		engine        = dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+		conf          = DefaultConfig
+	)
+	defer blockchain.Stop()
+
+	conf.Genesis = gspec
+	eth := &JuchainService{
+		ctx:         &node.ServiceContext{Config: &node.Config{}},
+		config:      &conf,
+		chainConfig: config.TestChainConfig,
+		chainDb:     db,
+		blockchain:  blockchain,
+		dappConfig:  config.DAppAddresses,
+		dappChainDb: make(map[common.Address]store.Database),
+		dappchains:  make(map[common.Address]*core.BlockChain),
+		eventMux:    new(event.TypeMux),
+		engine:      engine,
+		txPool:      core.NewTxPool(DefaultConfig.TxPool, config.TestChainConfig, blockchain),
+	}
+	defer eth.txPool.Stop()
+
+	eth.dappPackager = dpos.NewDAppPackager(eth.chainConfig, engine, blockchain, eth.dappchains, eth.txPool, eth.eventMux)
+	defer eth.dappPackager.Stop()
+	eth.ApiBackend = &EthApiBackend{eth: eth}
+	defer eth.eventMux.Stop()
+
+	dappId := common.Address{0xda}
+	if err := eth.AttachDApp(dappId); err != nil {
+		t.Fatalf("failed to attach DApp chain: %v", err)
+	}
+	dappChain, _ := eth.DAppChain(dappId)
+
+	var (
+		ctx       = context.Background()
+		blockApi  = p2p.NewPublicBlockChainAPI(eth.ApiBackend)
+		filterApi = filters.NewPublicFilterAPI(eth.ApiBackend, false)
+		unknown   = common.Address{0xdb}
+	)
+	if block, err := blockApi.GetBlockByNumber(ctx, 0, false, nil); err != nil || block["hash"] != genesis.Hash() {
+		t.Errorf("main chain genesis mismatch: have %v, %v", block["hash"], err)
+	}
+	if block, err := blockApi.GetBlockByNumber(ctx, 0, false, &dappId); err != nil || block["hash"] != dappChain.Genesis().Hash() {
+		t.Errorf("DApp chain genesis mismatch: have %v, %v", block["hash"], err)
+	}
+	if _, err := blockApi.GetBlockByNumber(ctx, 0, false, &unknown); err != errDAppNotAttached {
+		t.Errorf("block of unknown DApp: have %v, want %v", err, errDAppNotAttached)
+	}
+	if number, err := blockApi.BlockNumber(&dappId); err != nil || number.Sign() != 0 {
+		t.Errorf("DApp chain head mismatch: have %v, %v", number, err)
+	}
+	if _, err := filterApi.GetLogs(ctx, filters.FilterCriteria{DAppId: &dappId}); err != nil {
+		t.Errorf("failed to filter DApp logs: %v", err)
+	}
+	if _, err := filterApi.GetLogs(ctx, filters.FilterCriteria{DAppId: &unknown}); err != errDAppNotAttached {
+		t.Errorf("logs of unknown DApp: have %v, want %v", err, errDAppNotAttached)
+	}
+	if _, err := filterApi.NewFilter(filters.FilterCriteria{}); err != nil {
+		t.Errorf("failed to install main chain log filter: %v", err)
+	}
+	if _, err := filterApi.NewFilter(filters.FilterCriteria{DAppId: &dappId}); err != nil {
+		t.Errorf("failed to install DApp log filter: %v", err)
+	}
+}
+
+// Tests that registering a DApp over the personal API dry runs the registration
+// before creating the account of the DApp, and submits it once it passes.
+func TestDAppRegistration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("failed to create keystore dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _         = store.NewMemDatabase()
+		gspec         = &core.Genesis{Config: config.TestChainConfig}
+		_             = gspec.MustCommit(db)
+		engine        = dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+		ks            = keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+		txConfig      = DefaultConfig.TxPool
+	)
+	defer blockchain.Stop()
+
+	txConfig.Journal = ""
+
+	eth := &JuchainService{
+		config:         &DefaultConfig,
+		chainConfig:    config.TestChainConfig,
+		chainDb:        db,
+		blockchain:     blockchain,
+		eventMux:       new(event.TypeMux),
+		engine:         engine,
+		accountManager: account.NewManager(ks),
+		txPool:         core.NewTxPool(txConfig, config.TestChainConfig, blockchain),
+	}
+	defer eth.txPool.Stop()
+	eth.ApiBackend = &EthApiBackend{eth: eth}
+
+	api := p2p.NewPrivateAccountAPI(eth.ApiBackend, new(p2p.AddrLocker))
+	ctx := context.Background()
+
+	// Registrations rejected by the DApp manager leave no account behind
+	if _, err := api.NewDAppAccount(ctx, "", "org", "description", 86, 3, "icon", "password"); err == nil {
+		t.Fatalf("DApp registration without a name accepted")
+	}
+	if accounts := ks.Accounts(); len(accounts) != 0 {
+		t.Fatalf("account created for a rejected registration: %v", accounts)
+	}
+	registration, err := api.NewDAppAccount(ctx, "dapp", "org", "description", 86, 3, "icon", "password")
+	if err != nil {
+		t.Fatalf("failed to register DApp: %v", err)
+	}
+	if !ks.HasAddress(registration.Address) {
+		t.Errorf("DApp account %x not created", registration.Address)
+	}
+	if tx := eth.txPool.Get(registration.Transaction); tx == nil || *tx.To() != core.DAPPContractAddress {
+		t.Errorf("DApp registration %x not submitted", registration.Transaction)
+	}
+}
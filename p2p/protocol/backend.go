@@ -28,6 +28,7 @@ import (
 	"github.com/juchain/go-juchain/common/hexutil"
 	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/consensus/dpos"
+	"github.com/juchain/go-juchain/consensus/solo"
 	"github.com/juchain/go-juchain/core"
 	"github.com/juchain/go-juchain/core/bloombits"
 	"github.com/juchain/go-juchain/core/types"
@@ -55,6 +56,7 @@ type LesServer interface {
 // Juchain implements the Juchain full node service.
 type JuchainService struct {
 	server      *p2p.Server
+	ctx         *node.ServiceContext
 	config      *Config
 	chainConfig *config.ChainConfig
 	dappConfig  *config.DAppAddress
@@ -67,8 +69,12 @@ type JuchainService struct {
 	txPool          *core.TxPool
 	blockchain      *core.BlockChain
 	dappchains      map[common.Address]*core.BlockChain
+	dappPackager    *dpos.DAppPackager // Packages the attached DApp chains, nil if not run by the dpos engine
+	dappUsers       map[common.Address]*sync.WaitGroup // In-flight users of the attached DApp chains
+	dappDetaching   map[common.Address]struct{}        // DApp chains waiting for their users to be closed
 
 	protocolManager *ProtocolManager
+	evidence        *dpos.EvidencePool // Double signing detection, nil if not run by the dpos engine
 
 	// DB interfaces
 	chainDb         store.Database // Block chain database
@@ -82,6 +88,7 @@ type JuchainService struct {
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
 	ApiBackend *EthApiBackend
+	filterAPI  *filters.PublicFilterAPI // Filters of the main and DApp chains, torn down per DApp on detach
 
 	gasPrice  *big.Int
 	etherbase common.Address
@@ -112,9 +119,13 @@ func New(node *node.Node, ctx *node.ServiceContext, config0 *Config) (*JuchainSe
 		return nil, genesisErr
 	}
 	log.Info("Initialized main chain configuration", "config0", chainConfig)
+	if config.DAppAddresses == nil {
+		config.DAppAddresses = new(config.DAppAddress)
+	}
 
 	eth := &JuchainService{
 		server:         node.Server0,
+		ctx:            ctx,
 		config:         config0,
 		chainDb:        chainDb,
 		chainConfig:    chainConfig,
@@ -132,24 +143,6 @@ func New(node *node.Node, ctx *node.ServiceContext, config0 *Config) (*JuchainSe
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, config.BloomBitsBlocks),
 	}
-	for i := range config.DAppAddresses.Addresse {
-		dappId := config.DAppAddresses.Addresse[i]
-		dbName := "dappchain" + dappId.String()
-		dappChainDb, err := CreateDB(ctx, config0, dbName)
-		if err != nil {
-			return nil, err
-		}
-		// bind genesis with DB.
-		_, _, genesisErr := core.SetupDAppGenesisBlock(&dappId, dappChainDb, config0.Genesis)
-		if _, ok := genesisErr.(*config.ConfigCompatError); genesisErr != nil && !ok {
-			//core.WriteChainConfig(chainDb, genesisHash, chainConfig)
-			return nil, genesisErr
-		}
-		log.Info("Initialized dapp chain configuration", "dbName", dbName)
-
-		eth.dappChainDb[dappId] = dappChainDb;
-	}
-
 	log.Info("Initializing Blockchain Protocols", "versions", ProtocolVersions, "network", config0.NetworkId)
 
 	if !config0.SkipBcVersionCheck {
@@ -159,20 +152,18 @@ func New(node *node.Node, ctx *node.ServiceContext, config0 *Config) (*JuchainSe
 		}
 		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
-	var (
-		vmConfig    = vm.Config{EnablePreimageRecording: config0.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config0.NoPruning, TrieNodeLimit: config0.TrieCache, TrieTimeLimit: config0.TrieTimeout}
-	)
-	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig)
+	eth.blockchain, err = core.NewBlockChain(chainDb, eth.cacheConfig(), eth.chainConfig, eth.engine, eth.vmConfig())
 	if err != nil {
 		return nil, err
 	}
-	for key, dappChainDB := range eth.dappChainDb {
-		eth.dappchains[key], err = core.NewBlockChain(dappChainDB, cacheConfig, eth.chainConfig, eth.engine, vmConfig)
+	for _, dappId := range eth.dappConfig.List() {
+		dappChainDb, dappChain, err := eth.openDAppChain(dappId)
 		if err != nil {
-			log.Error("Fail to instantiate DAppChainDB!", "dapp address", key)
+			log.Error("Fail to instantiate DAppChainDB!", "dapp address", dappId)
 			return nil, err
 		}
+		eth.dappChainDb[dappId], eth.dappchains[dappId] = dappChainDb, dappChain
+		eth.dappConfig.SetDiskUsage(dappId, eth.dappDiskUsage(dappId))
 	}
 	// Rewind the chain in case of an incompatible config0 upgrade.
 	if compat, ok := genesisErr.(*config.ConfigCompatError); ok {
@@ -185,13 +176,19 @@ func New(node *node.Node, ctx *node.ServiceContext, config0 *Config) (*JuchainSe
 	if config0.TxPool.Journal != "" {
 		config0.TxPool.Journal = ctx.ResolvePath(config0.TxPool.Journal)
 	}
-	eth.txPool = core.NewTxPool(config0.TxPool, eth.chainConfig, eth.blockchain, eth.dappchains)
-	eth.ApiBackend = &EthApiBackend{eth, nil}
+	eth.txPool = core.NewTxPool(config0.TxPool, eth.chainConfig, eth.blockchain)
+	eth.txPool.SetDAppState(eth.dappState)
+	if election, ok := eth.engine.(*dpos.DElection); ok {
+		eth.dappPackager = dpos.NewDAppPackager(eth.chainConfig, election, eth.blockchain, eth.dappchains, eth.txPool, eth.eventMux)
+		eth.evidence = dpos.NewEvidencePool(election, eth.blockchain, chainDb, eth.eventMux)
+	}
+	eth.ApiBackend = &EthApiBackend{eth: eth}
 	gpoconfig := config0.GPO
 	if gpoconfig.Default == nil {
 		gpoconfig.Default = config0.GasPrice
 	}
 	eth.ApiBackend.gpo = gasprice.NewOracle(eth.ApiBackend, gpoconfig)
+	eth.filterAPI = filters.NewPublicFilterAPI(eth.ApiBackend, false)
 	if eth.protocolManager, err = NewProtocolManager(eth, eth.chainConfig, ctx.Config, config0.SyncMode, config0.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
@@ -215,6 +212,16 @@ func makeExtraData(extra []byte) []byte {
 	return extra
 }
 
+// cacheConfig returns the caching configuration of the chains of the service.
+func (s *JuchainService) cacheConfig() *core.CacheConfig {
+	return &core.CacheConfig{Disabled: s.config.NoPruning, TrieNodeLimit: s.config.TrieCache, TrieTimeLimit: s.config.TrieTimeout}
+}
+
+// vmConfig returns the EVM configuration of the chains of the service.
+func (s *JuchainService) vmConfig() vm.Config {
+	return vm.Config{EnablePreimageRecording: s.config.EnablePreimageRecording}
+}
+
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (store.Database, error) {
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
@@ -230,6 +237,10 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (store.Data
 // CreateConsensusEngine creates the required type of consensus engine instance for an JuchainService service
 func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *config.ChainConfig, db store.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
+	if chainConfig.Clique != nil {
+		return clique.New(chainConfig.Clique, db)
+	}
+	// Otherwise run the delegated proof-of-stake
 	if (chainConfig.DPoS == nil) {
 		chainConfig.DPoS = &config.DPoSConfig{};
 	}
@@ -243,6 +254,13 @@ func (s *JuchainService) APIs() []rpc.API {
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
+	if s.evidence != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "dpos",
+			Version:   "1.0",
+			Service:   dpos.NewEvidenceAPI(s.evidence),
+		})
+	}
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -251,6 +269,11 @@ func (s *JuchainService) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicEthereumAPI(s),
 			Public:    true,
+		}, {
+			Namespace: "dapp",
+			Version:   "1.0",
+			Service:   NewPublicDAppAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "block",
 			Version:   "1.0",
@@ -259,7 +282,7 @@ func (s *JuchainService) APIs() []rpc.API {
 		}, {
 			Namespace: "block",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false),
+			Service:   s.filterAPI,
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -330,6 +353,10 @@ func (s *JuchainService) EthVersion() int                    { return int(s.prot
 func (s *JuchainService) NetVersion() uint64                 { return s.networkId }
 func (s *JuchainService) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// EvidencePool returns the double signing detection, nil if the chain is not run
+// by the dpos engine.
+func (s *JuchainService) EvidencePool() *dpos.EvidencePool { return s.evidence }
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *JuchainService) Protocols() []p2p.Protocol {
@@ -356,6 +383,9 @@ func (s *JuchainService) Start(srvr *p2p.Server) error {
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
 
+	// Keep track of the disk usage of the DApp chains against their quotas
+	go s.dappDiskUsageLoop()
+
 	return nil
 }
 
@@ -368,6 +398,20 @@ func (s *JuchainService) Stop() error {
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
+	if s.evidence != nil {
+		s.evidence.Stop()
+	}
+	if s.dappPackager != nil {
+		s.dappPackager.Stop()
+	}
+	s.lock.Lock()
+	for _, dappChain := range s.dappchains {
+		dappChain.Stop()
+	}
+	for _, dappChainDb := range s.dappChainDb {
+		dappChainDb.Close()
+	}
+	s.lock.Unlock()
 
 	s.txPool.Stop()
 	s.eventMux.Stop()
@@ -0,0 +1,158 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus/dpos"
+	"github.com/juchain/go-juchain/consensus/solo"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/account"
+)
+
+// CliqueProtocolManager seals the blocks of chains run by the clique
+// proof-of-authority engine with the etherbase account, in place of the voting
+// and packaging processes of the delegators.
+//
+// A sealing attempt on top of every new head waits for the period of the chain,
+// and for the wiggle of out-of-turn signers, before signing. Chains without a
+// period only seal blocks carrying transactions, as soon as they arrive.
+type CliqueProtocolManager struct {
+	eth        *JuchainService
+	blockchain *core.BlockChain
+	txPool     *core.TxPool
+	engine     *clique.Clique
+	period     uint64
+
+	packager *dpos.Packager
+	sealing  int32 // number of sealing attempts running
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCliqueProtocolManager creates the sealer of a chain run by the clique engine.
+func NewCliqueProtocolManager(eth *JuchainService, config *config.ChainConfig, engine *clique.Clique) *CliqueProtocolManager {
+	return &CliqueProtocolManager{
+		eth:        eth,
+		blockchain: eth.BlockChain(),
+		txPool:     eth.TxPool(),
+		engine:     engine,
+		period:     config.Clique.Period,
+		packager:   dpos.NewPackager(config, engine, eth.etherbase, eth, eth.EventMux()),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start authorizes the engine to sign with the etherbase account and starts
+// sealing. Without an etherbase in the keystore the node only follows the chain.
+func (pm *CliqueProtocolManager) Start() {
+	signer, err := pm.eth.Etherbase()
+	if err != nil {
+		log.Warn("Clique sealing disabled", "err", err)
+		return
+	}
+	wallet, err := pm.eth.AccountManager().Find(account.Account{Address: signer})
+	if err != nil {
+		log.Warn("Clique sealing disabled, etherbase not in the keystore", "etherbase", signer, "err", err)
+		return
+	}
+	pm.engine.Authorize(signer, wallet.SignHash)
+
+	log.Info("Starting Clique Consensus", "signer", signer, "period", pm.period)
+	pm.packager.Start()
+
+	pm.wg.Add(1)
+	go pm.schedule()
+}
+
+// Stop aborts any sealing attempt and waits for it to return.
+func (pm *CliqueProtocolManager) Stop() {
+	select {
+	case <-pm.quit:
+	default:
+		close(pm.quit)
+	}
+	pm.wg.Wait()
+	pm.packager.Stop()
+	log.Info("Clique Consensus stopped")
+}
+
+// schedule restarts sealing on top of every new head, aborting the attempt on
+// top of the previous one. Chains without a period also seal on transactions
+// arriving while no attempt is running.
+func (pm *CliqueProtocolManager) schedule() {
+	defer pm.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	headSub := pm.blockchain.SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	txCh := make(chan core.TxPreEvent, txChanSize)
+	txSub := pm.txPool.SubscribeTxPreEvent(txCh)
+	defer txSub.Unsubscribe()
+
+	var abort chan struct{}
+	seal := func() {
+		if abort != nil {
+			close(abort)
+		}
+		abort = make(chan struct{})
+
+		if !pm.engine.Authorized(pm.blockchain, pm.blockchain.CurrentHeader()) {
+			log.Debug("Not authorized to seal the next block")
+			return
+		}
+		if pending, _ := pm.txPool.Stats(); pm.period == 0 && pending == 0 {
+			return
+		}
+		atomic.AddInt32(&pm.sealing, 1)
+		pm.wg.Add(1)
+		go func(stop <-chan struct{}) {
+			defer pm.wg.Done()
+			defer atomic.AddInt32(&pm.sealing, -1)
+
+			if block := pm.packager.SealNewBlock(stop); block != nil {
+				log.Info("Sealed new block", "number", block.Number(), "hash", block.Hash(), "txs", len(block.Transactions()))
+			}
+		}(abort)
+	}
+	seal()
+	for {
+		select {
+		case <-headCh:
+			seal()
+		case <-txCh:
+			if pm.period == 0 && atomic.LoadInt32(&pm.sealing) == 0 {
+				seal()
+			}
+		case <-headSub.Err():
+			close(abort)
+			return
+		case <-txSub.Err():
+			close(abort)
+			return
+		case <-pm.quit:
+			close(abort)
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/consensus/solo"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/account"
+	"github.com/juchain/go-juchain/core/account/keystore"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p/node"
+	"github.com/juchain/go-juchain/p2p/protocol/downloader"
+	"github.com/juchain/go-juchain/vm/solc"
+)
+
+// Tests that chains with a clique section in their genesis are run by the clique
+// engine, whose signers seal the pending transactions instead of the delegators.
+func TestCliqueSealing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clique-sealing")
+	if err != nil {
+		t.Fatalf("failed to create keystore dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	signer, err := ks.ImportECDSA(testBankKey, "")
+	if err != nil {
+		t.Fatalf("failed to import signer: %v", err)
+	}
+	if err := ks.Unlock(signer, ""); err != nil {
+		t.Fatalf("failed to unlock signer: %v", err)
+	}
+	chainConfig := &config.ChainConfig{
+		ChainId: big.NewInt(1),
+		Clique:  &config.CliqueConfig{Period: 0, Epoch: 30000},
+	}
+	var (
+		evmux = new(event.TypeMux)
+		db, _ = store.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config:    chainConfig,
+			ExtraData: append(append(make([]byte, 32), testBank[:]...), make([]byte, 65)...),
+			GasLimit:  4712388,
+			Alloc:     core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000000000)}},
+		}
+		_ = gspec.MustCommit(db)
+	)
+	engine, ok := CreateConsensusEngine(nil, chainConfig, db).(*clique.Clique)
+	if !ok {
+		t.Fatalf("clique genesis not run by the clique engine")
+	}
+	blockchain, _ := core.NewBlockChain(db, nil, chainConfig, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	eth := &JuchainService{
+		config:         &DefaultConfig,
+		chainDb:        db,
+		chainConfig:    chainConfig,
+		blockchain:     blockchain,
+		dappchains:     make(map[common.Address]*core.BlockChain),
+		eventMux:       evmux,
+		accountManager: account.NewManager(ks),
+		engine:         engine,
+		etherbase:      testBank,
+		txPool:         core.NewTxPool(DefaultConfig.TxPool, chainConfig, blockchain),
+	}
+	defer eth.txPool.Stop()
+
+	pm, err := NewProtocolManager(eth, chainConfig, &node.Config{}, downloader.FullSync, DefaultConfig.NetworkId, evmux, eth.txPool, engine, blockchain, db)
+	if err != nil {
+		t.Fatalf("failed to create protocol manager: %v", err)
+	}
+	if pm.dposManager != nil || pm.cliqueManager == nil {
+		t.Fatalf("clique chain run by the delegators")
+	}
+	pm.Start(1000)
+	defer pm.Stop()
+
+	// Without a period, a block is only sealed once a transaction arrives
+	headCh := make(chan core.ChainHeadEvent, 1)
+	headSub := blockchain.SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	nonce := eth.txPool.State().GetNonce(testBank)
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil), types.NewChainSigner(chainConfig.ChainId), testBankKey)
+	if err := eth.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case ev := <-headCh:
+		if ev.Block.NumberU64() != 1 || len(ev.Block.Transactions()) != 1 || ev.Block.Transactions()[0].Hash() != tx.Hash() {
+			t.Fatalf("sealed block mismatch: number %d, txs %d", ev.Block.NumberU64(), len(ev.Block.Transactions()))
+		}
+		if author, err := engine.Author(ev.Block.Header()); err != nil || author != testBank {
+			t.Errorf("block signer mismatch: have %x (%v), want %x", author, err, testBank)
+		}
+		if err := engine.VerifyHeader(blockchain, ev.Block.Header(), true); err != nil {
+			t.Errorf("sealed block rejected: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no block sealed within 5 seconds")
+	}
+	// The clique namespace is served by the engine
+	if apis := engine.APIs(blockchain); len(apis) != 1 || apis[0].Namespace != "clique" {
+		t.Errorf("clique API mismatch: %v", apis)
+	}
+}
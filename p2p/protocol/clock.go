@@ -0,0 +1,43 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import "time"
+
+// Clock is the source of time of the voting and packaging processes of the
+// delegators. Every round, slot and timeout of the election is derived from it,
+// so replacing it lets tests script several nodes deterministically.
+type Clock interface {
+	Now() time.Time                            // Current wall clock time
+	AfterFunc(d time.Duration, f func()) Timer // Calls f once d elapsed
+}
+
+// Timer is a call scheduled on a clock.
+type Timer interface {
+	Stop() bool // Cancels the call, false if it was already called or cancelled
+}
+
+// systemClock is the clock of the operating system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
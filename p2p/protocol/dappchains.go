@@ -0,0 +1,340 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/p2p/protocol/filters"
+)
+
+// dappDiskUsageRefresh is the interval of measuring the disk usage of the DApp
+// chain databases against their quotas.
+const dappDiskUsageRefresh = time.Minute
+
+var (
+	errDAppAttached    = errors.New("DApp chain already attached")
+	errDAppNotAttached = errors.New("DApp chain not attached")
+	errDAppDetaching   = errors.New("DApp chain still being detached")
+	errDAppStatic      = errors.New("DApp chain assigned on the command line")
+	errTooManyDApps    = errors.New("maximum number of DApp chains attached")
+)
+
+// DAppChainInfo represents a short summary of a DApp chain attached to the node.
+type DAppChainInfo struct {
+	DAppId common.Address `json:"dappId"` // DApp the chain belongs to
+	Number uint64         `json:"number"` // Number of the head block
+	Hash   common.Hash    `json:"hash"`   // Hash of the head block
+	Anchor common.Hash    `json:"anchor"` // Main chain block anchoring the head block
+
+	DiskUsage uint64 `json:"diskUsage"` // Measured size of the chain database in bytes
+	DiskQuota uint64 `json:"diskQuota"` // Disk space the chain database may use, zero if unlimited
+}
+
+// dappChainDbName returns the name of the database of a DApp chain.
+func dappChainDbName(dappId common.Address) string {
+	return "dappchain" + dappId.String()
+}
+
+// openDAppChain opens the database of a DApp chain, bootstrapping it from the
+// genesis on first use, and loads the chain anchored to the main chain.
+func (s *JuchainService) openDAppChain(dappId common.Address) (store.Database, *core.BlockChain, error) {
+	dbName := dappChainDbName(dappId)
+	dappChainDb, err := CreateDB(s.ctx, s.config, dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+	// bind genesis with DB.
+	_, genesisHash, genesisErr := core.SetupDAppGenesisBlock(&dappId, dappChainDb, s.config.Genesis)
+	if _, ok := genesisErr.(*config.ConfigCompatError); genesisErr != nil && !ok {
+		dappChainDb.Close()
+		return nil, nil, genesisErr
+	}
+	log.Info("Initialized dapp chain configuration", "dbName", dbName)
+
+	// The DApp chain runs with its own configuration, set in the main chain one
+	dappConfig := s.chainConfig.DAppChainConfig(dappId)
+	dappChain, err := core.NewBlockChain(dappChainDb, s.cacheConfig(), dappConfig, s.engine, s.vmConfig())
+	if err != nil {
+		dappChainDb.Close()
+		return nil, nil, err
+	}
+	// Rewind the chain in case of an incompatible configuration upgrade.
+	if compat, ok := genesisErr.(*config.ConfigCompatError); ok {
+		log.Warn("Rewinding DApp chain to upgrade configuration", "dapp", dappId, "err", compat)
+		dappChain.SetHead(compat.RewindTo)
+		core.WriteChainConfig(dappChainDb, genesisHash, dappConfig)
+	}
+	dappChain.SetAnchorChain(s.blockchain)
+	return dappChainDb, dappChain, nil
+}
+
+// AttachDApp opens the chain of a DApp and starts following it at runtime. The
+// chain is reattached on the next start of the node.
+func (s *JuchainService) AttachDApp(dappId common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.dappchains[dappId]; ok {
+		return errDAppAttached
+	}
+	if _, ok := s.dappDetaching[dappId]; ok {
+		return errDAppDetaching
+	}
+	if s.dappConfig.Full() {
+		return errTooManyDApps
+	}
+	dappChainDb, dappChain, err := s.openDAppChain(dappId)
+	if err != nil {
+		return err
+	}
+	s.dappChainDb[dappId], s.dappchains[dappId] = dappChainDb, dappChain
+	if s.dappPackager != nil {
+		s.dappPackager.Attach(dappId, dappChain)
+	}
+	s.dappConfig.Add(dappId)
+	s.dappConfig.SetDiskUsage(dappId, s.dappDiskUsage(dappId))
+	if s.protocolManager != nil {
+		go s.protocolManager.SyncDApp(dappId)
+	}
+
+	log.Info("Attached DApp chain", "dapp", dappId, "number", dappChain.CurrentBlock().Number())
+	return s.saveDAppAddresses()
+}
+
+// DetachDApp stops following the chain of a DApp attached at runtime and drops
+// its pending transactions. The database of the chain is closed once the API
+// calls using it are done, and removed if pruning is requested.
+func (s *JuchainService) DetachDApp(dappId common.Address, prune bool) error {
+	s.lock.Lock()
+	dappChain, ok := s.dappchains[dappId]
+	if !ok {
+		s.lock.Unlock()
+		return errDAppNotAttached
+	}
+	if s.dappConfig.Static(dappId) {
+		s.lock.Unlock()
+		return errDAppStatic
+	}
+	if s.dappPackager != nil {
+		s.dappPackager.Detach(dappId)
+	}
+	s.txPool.DropDAppTransactions(dappId)
+	s.dappConfig.Remove(dappId)
+
+	dappChainDb, users := s.dappChainDb[dappId], s.dappUsers[dappId]
+	delete(s.dappchains, dappId)
+	delete(s.dappChainDb, dappId)
+	delete(s.dappUsers, dappId)
+	if s.dappDetaching == nil {
+		s.dappDetaching = make(map[common.Address]struct{})
+	}
+	s.dappDetaching[dappId] = struct{}{}
+	s.lock.Unlock()
+
+	// No new users get hold of the chain, wait for the in-flight ones
+	if s.filterAPI != nil {
+		filters.StopDAppEvents(s.filterAPI, dappId)
+	}
+	dappChain.Stop()
+	if users != nil {
+		users.Wait()
+	}
+	dappChainDb.Close()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.dappDetaching, dappId)
+	if prune && s.ctx != nil {
+		if path := s.ctx.ResolvePath(dappChainDbName(dappId)); path != "" {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+	log.Info("Detached DApp chain", "dapp", dappId, "pruned", prune)
+	return s.saveDAppAddresses()
+}
+
+// DAppChain retrieves the chain of an attached DApp and its database, nil if the
+// DApp is not attached.
+func (s *JuchainService) DAppChain(dappId common.Address) (*core.BlockChain, store.Database) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.dappchains[dappId], s.dappChainDb[dappId]
+}
+
+// useDAppChain retrieves the chain of an attached DApp and its database, along
+// with the function releasing them, nil if the DApp is not attached. Detaching
+// the DApp waits for the chain to be released before closing its database.
+func (s *JuchainService) useDAppChain(dappId common.Address) (*core.BlockChain, store.Database, func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dappChain, ok := s.dappchains[dappId]
+	if !ok {
+		return nil, nil, nil
+	}
+	if s.dappUsers == nil {
+		s.dappUsers = make(map[common.Address]*sync.WaitGroup)
+	}
+	users, ok := s.dappUsers[dappId]
+	if !ok {
+		users = new(sync.WaitGroup)
+		s.dappUsers[dappId] = users
+	}
+	users.Add(1)
+	return dappChain, s.dappChainDb[dappId], users.Done
+}
+
+// dappState retrieves the current state of an attached DApp chain, nil if the
+// DApp is not attached.
+func (s *JuchainService) dappState(dappId common.Address) *state.StateDB {
+	chain, _ := s.DAppChain(dappId)
+	if chain == nil {
+		return nil
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		return nil
+	}
+	return statedb
+}
+
+// DApps returns the summaries of the attached DApp chains, ordered by DApp.
+func (s *JuchainService) DApps() []*DAppChainInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	infos := make([]*DAppChainInfo, 0, len(s.dappchains))
+	for dappId, dappChain := range s.dappchains {
+		head := dappChain.CurrentBlock()
+		infos = append(infos, &DAppChainInfo{
+			DAppId: dappId,
+			Number: head.NumberU64(),
+			Hash:   head.Hash(),
+			Anchor: head.Header().DAppMainHash,
+
+			DiskUsage: s.dappConfig.DiskUsage(dappId),
+			DiskQuota: s.dappConfig.DiskQuota(dappId),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return bytes.Compare(infos[i].DAppId[:], infos[j].DAppId[:]) < 0
+	})
+	return infos
+}
+
+// saveDAppAddresses persists the DApps attached at runtime to be reattached on
+// restart, leaving out the ones assigned on the command line, along with the
+// storage nodes assigned to the DApps.
+func (s *JuchainService) saveDAppAddresses() error {
+	if s.ctx == nil {
+		return nil
+	}
+	if err := s.ctx.Config.SaveDAppAddresses(s.dappConfig.Persisted(), s.dappConfig.Quotas()); err != nil {
+		return err
+	}
+	return s.ctx.Config.SaveDAppStorageNodes(s.dappConfig.AssignedNodes())
+}
+
+// AssignDAppNodes sets the storage nodes replicating the chain of a DApp, kept
+// across restarts of the node.
+func (s *JuchainService) AssignDAppNodes(dappId common.Address, nodes []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.dappConfig.AssignNodes(dappId, nodes)
+
+	log.Info("Assigned DApp storage nodes", "dapp", dappId, "nodes", len(nodes))
+	return s.saveDAppAddresses()
+}
+
+// SetDAppQuota limits the disk space the chain database of an attached DApp may
+// use, zero falling back to the default quota. Transactions of the DApp are
+// rejected once its database exceeds the quota.
+func (s *JuchainService) SetDAppQuota(dappId common.Address, quota uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.dappchains[dappId]; !ok {
+		return errDAppNotAttached
+	}
+	s.dappConfig.SetQuota(dappId, quota)
+
+	log.Info("Updated DApp disk quota", "dapp", dappId, "quota", s.dappConfig.DiskQuota(dappId))
+	return s.saveDAppAddresses()
+}
+
+// dappDiskUsage measures the size of the database of a DApp chain, zero if the
+// database is not persisted.
+func (s *JuchainService) dappDiskUsage(dappId common.Address) uint64 {
+	if s.ctx == nil {
+		return 0
+	}
+	path := s.ctx.ResolvePath(dappChainDbName(dappId))
+	if path == "" {
+		return 0
+	}
+	var size uint64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size
+}
+
+// dappDiskUsageLoop periodically measures the disk usage of the attached DApp
+// chains, until the service is stopped.
+func (s *JuchainService) dappDiskUsageLoop() {
+	ticker := time.NewTicker(dappDiskUsageRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.lock.RLock()
+			for dappId := range s.dappchains {
+				usage := s.dappDiskUsage(dappId)
+				s.dappConfig.SetDiskUsage(dappId, usage)
+				if !s.dappConfig.HasDiskSpace(&dappId) {
+					log.Warn("DApp chain exceeds its disk quota", "dapp", dappId, "usage", usage, "quota", s.dappConfig.DiskQuota(dappId))
+				}
+			}
+			s.lock.RUnlock()
+
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import (
+	"math/rand"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+)
+
+// maxDAppBlockFetch is the amount of DApp blocks to be fetched per request.
+const maxDAppBlockFetch = 128
+
+// dappChainReader provides the DApp chains replicated by the local node.
+type dappChainReader interface {
+	// DAppChain retrieves the chain of a replicated DApp and its database, nil if
+	// the DApp is not replicated.
+	DAppChain(dappId common.Address) (*core.BlockChain, store.Database)
+}
+
+// dappChain retrieves the chain of a DApp replicated by the local node.
+func (pm *ProtocolManager) dappChain(dappId common.Address) *core.BlockChain {
+	if pm.dapps == nil {
+		return nil
+	}
+	chain, _ := pm.dapps.DAppChain(dappId)
+	return chain
+}
+
+// syncDApps requests the blocks of the DApp chains shared with a newly connected
+// peer which are missing locally.
+func (pm *ProtocolManager) syncDApps(p *peer) {
+	for _, dappId := range config.DAppAddresses.List() {
+		if p.Replicates(dappId) {
+			pm.syncDApp(p, dappId)
+		}
+	}
+}
+
+// SyncDApp synchronises the chain of a DApp with a random peer of its peer
+// group, fetching the blocks following the local head.
+func (pm *ProtocolManager) SyncDApp(dappId common.Address) {
+	peers := pm.peers.PeersWithDApp(dappId)
+	if len(peers) == 0 {
+		return
+	}
+	pm.syncDApp(peers[rand.Intn(len(peers))], dappId)
+}
+
+// syncDApp requests the blocks of a DApp chain following the local head from a
+// peer of its peer group.
+func (pm *ProtocolManager) syncDApp(p *peer, dappId common.Address) {
+	chain := pm.dappChain(dappId)
+	if chain == nil {
+		return
+	}
+	if err := p.RequestDAppBlocks(dappId, chain.CurrentBlock().NumberU64()+1, maxDAppBlockFetch); err != nil {
+		log.Debug("Failed to request DApp blocks", "dapp", dappId, "peer", p.id, "err", err)
+	}
+}
+
+// importDAppBlock imports a DApp block propagated by a peer of its peer group and
+// relays it inside the group. If the block does not link to the local chain, the
+// missing blocks are requested from the peer instead.
+func (pm *ProtocolManager) importDAppBlock(p *peer, block *types.Block) {
+	dappId := block.DAppID()
+	chain := pm.dappChain(dappId)
+	if chain == nil || chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return
+	}
+	if !chain.HasBlock(block.ParentHash(), block.NumberU64()-1) {
+		pm.syncDApp(p, dappId)
+		return
+	}
+	if _, err := chain.InsertDAppChain(types.Blocks{block}); err != nil {
+		log.Debug("Discarded propagated DApp block", "dapp", dappId, "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	pm.BroadcastBlock(block, true)
+}
+
+// deliverDAppBlocks imports a batch of DApp blocks requested from a peer, and
+// continues fetching from the same peer while it serves full batches.
+func (pm *ProtocolManager) deliverDAppBlocks(p *peer, dappId common.Address, blocks []*types.Block) {
+	chain := pm.dappChain(dappId)
+	if chain == nil || len(blocks) == 0 {
+		return
+	}
+	if n, err := chain.InsertDAppChain(blocks); err != nil {
+		log.Debug("Failed to import DApp blocks", "dapp", dappId, "peer", p.id, "number", blocks[n].Number(), "hash", blocks[n].Hash(), "err", err)
+		return
+	}
+	log.Debug("Synchronised DApp chain", "dapp", dappId, "peer", p.id, "number", chain.CurrentBlock().Number())
+	if len(blocks) == maxDAppBlockFetch {
+		pm.syncDApp(p, dappId)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package protocol
+
+import (
+	"github.com/juchain/go-juchain/common/log"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p"
+)
+
+// startFinality starts voting for every new head block to become final, as long
+// as the local node is a delegator of the epoch of the block.
+func (pm *DPoSProtocolManager) startFinality() {
+	if pm.finality == nil {
+		return
+	}
+	pm.headCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+	pm.headSub = pm.blockchain.SubscribeChainHeadEvent(pm.headCh)
+	go pm.finalityLoop()
+}
+
+// stopFinality stops voting for new head blocks.
+func (pm *DPoSProtocolManager) stopFinality() {
+	if pm.headSub != nil {
+		pm.headSub.Unsubscribe()
+	}
+}
+
+func (pm *DPoSProtocolManager) finalityLoop() {
+	for {
+		select {
+		case ev := <-pm.headCh:
+			pm.preCommit(ev.Block.Header())
+		case <-pm.headSub.Err():
+			return
+		}
+	}
+}
+
+// preCommit signs a pre-commit vote for the given head block and broadcasts it,
+// unless the local node is not scheduled in the epoch of the block, already voted
+// at its height or the block does not extend the last certified block.
+func (pm *DPoSProtocolManager) preCommit(header *types.Header) {
+	number := header.Number.Uint64()
+	if number == 0 || number <= pm.lastPreCommit {
+		return
+	}
+	parent := pm.blockchain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return
+	}
+	schedule, err := pm.engine.Schedule(pm.blockchain, parent)
+	if err != nil || !schedule.Includes(pm.nodeId) {
+		return
+	}
+	if !pm.finality.Extends(pm.blockchain, header) {
+		log.Debug("Skipped pre-commit vote of an uncertified fork", "number", number, "hash", header.Hash())
+		return
+	}
+	vote, err := pm.engine.SignPreCommit(header)
+	if err != nil {
+		log.Warn("Failed to sign pre-commit vote", "number", number, "hash", header.Hash(), "err", err)
+		return
+	}
+	// Record the vote before anyone sees it, a restart must not vote again
+	if err := core.WriteLastPreCommit(pm.eth.ChainDb(), number); err != nil {
+		log.Error("Failed to store pre-commit vote", "number", number, "err", err)
+		return
+	}
+	pm.lastPreCommit = number
+	pm.addPreCommit(vote, nil)
+}
+
+// handlePreCommit processes a pre-commit vote received from a remote peer. Votes
+// which can't be verified, e.g. as their block is not imported yet, are dropped
+// without penalizing the peer.
+func (pm *DPoSProtocolManager) handlePreCommit(msg *p2p.Msg, p *peer) error {
+	var vote types.PreCommit
+	if err := msg.Decode(&vote); err != nil {
+		return errResp(DPOSErrDecode, "%v: %v", msg, err)
+	}
+	if pm.finality == nil {
+		return nil
+	}
+	pm.addPreCommit(&vote, p)
+	return nil
+}
+
+// addPreCommit tallies a pre-commit vote, relays it to all peers but the one it
+// was received from if it wasn't known yet and finalizes its block once the vote
+// completes a qualified majority.
+func (pm *DPoSProtocolManager) addPreCommit(vote *types.PreCommit, from *peer) {
+	cert, fresh, err := pm.finality.Add(pm.blockchain, vote)
+	if err != nil {
+		log.Debug("Discarded pre-commit vote", "number", vote.Number, "hash", vote.Hash, "delegator", vote.Delegator, "err", err)
+		return
+	}
+	if !fresh {
+		return
+	}
+	for _, peer := range pm.ethManager.peers.Peers() {
+		if peer == from {
+			continue
+		}
+		if err := peer.SendPreCommit(vote); err != nil {
+			peer.Log().Debug("Failed to relay pre-commit vote", "err", err)
+		}
+	}
+	if cert != nil {
+		if err := pm.blockchain.SetFinalized(cert); err != nil {
+			log.Warn("Failed to finalize block", "number", cert.Number, "hash", cert.Hash, "err", err)
+		}
+	}
+}
@@ -19,14 +19,11 @@ package protocol
 
 import (
 	"time"
-	"strconv"
 	"sync"
 	"github.com/juchain/go-juchain/common"
-	"github.com/juchain/go-juchain/common/rlp"
-	"github.com/juchain/go-juchain/common/crypto/sha3"
+	"github.com/juchain/go-juchain/common/crypto"
 	"github.com/juchain/go-juchain/common/log"
 	"github.com/juchain/go-juchain/core"
-	"github.com/juchain/go-juchain/vm/solc/abi"
 	"github.com/juchain/go-juchain/p2p/protocol/downloader"
 	"github.com/juchain/go-juchain/p2p"
 	"github.com/juchain/go-juchain/p2p/discover"
@@ -34,54 +31,35 @@ import (
 	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/consensus/dpos"
 	"github.com/juchain/go-juchain/config"
-	"github.com/juchain/go-juchain/vm/solc"
 	"github.com/juchain/go-juchain/core/types"
-	"github.com/juchain/go-juchain/rpc"
-	"fmt"
-	"reflect"
-	"math/big"
-	"context"
-	"math"
-	"strings"
-	"errors"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/common/event"
 )
 
 // DPoS consensus handler of delegator packaging process.
 // only 31 delegators voted, then this process will be started.
 /**
    Sample code:
-   for round i
-   dlist_i = get N delegates sort by votes
-   dlist_i = mixorder(dlist_i)
+   for epoch i
+   dlist_i = get N delegates from the state of the checkpoint block
+   dlist_i = mixorder(dlist_i, hash of the checkpoint block)
    loop
-       slot = global_time_offset / block_interval
-       pos = slot % N
-       if dlist_i[pos] exists in this node
-           generateBlock(keypair of dlist_i[pos])
+       round = parent round + elapsed time / block_interval
+       pos = round % N
+       if dlist_i[pos] exists in this node and within grace window of the slot
+           generateBlock(keypair of dlist_i[pos], parent time + (round - parent round) * block_interval)
        else
            skip
+       sleep until the next slot
  */
 var (
-	currNodeId           string;           // current short node id.
-	currNodeIdHash       []byte;           // short node id hash.
 	TotalDelegatorNumber uint8  = 31;                               // we make 31 candidates as the best group for packaging.
-	SmallPeriodInterval  uint32 = 5;                                // small period for packaging node in every 5 seconds.
-	GigPeriodInterval    uint32 = uint32(TotalDelegatorNumber) * 5; // create a big period for all delegated nodes in every 155 seconds.
-
-	BigPeriodHistorySize  uint8 = 10; // keep 10 records for the confirmation of delayed block
-	GigPeriodHistory      = make([]GigPeriodTable, 0); // <GigPeriodTable>
-	GigPeriodInstance     *GigPeriodTable; // we use two versions of election info for switching delegated nodes smoothly.
-	NextGigPeriodInstance *GigPeriodTable;
-
-	VotingAccessor  DelegatorAccessor; // responsible for access voting data.
-	DelegatorsTable   []string;         // only for all delegated node ids. the table will receive from a voting contract.
-	DelegatorNodeInfo []*discover.Node; // all delegated peers. = make([]*discover.Node, 0, len(urls))
-
 )
 
-// Delegator table refers to the voting contract.
+// Delegator table refers to the native delegator registry. The delegators are read
+// from the state of every checkpoint block and recorded in its header.
 type DelegatorAccessor interface {
-	Refresh() (delegatorsTable []string, delegatorNodes []*discover.Node, e error)
+	dpos.DelegatorReader
 }
 
 // only for test purpose.
@@ -89,141 +67,8 @@ type DelegatorAccessorTestImpl struct {
 	currNodeId           string;           // current short node id.
 	currNodeIdHash       []byte;           // short node id hash.
 }
-func (d *DelegatorAccessorTestImpl) Refresh() (delegatorsTable []string, delegatorNodes []*discover.Node, e error) {
-	return []string{d.currNodeId}, []*discover.Node{}, nil
-}
-
-// access production contract.
-type DelegatorAccessorImpl struct {
-	blockchain    *core.BlockChain;
-	b             p2p.Backend;
-	dappabi       abi.ABI;
-}
-type DelegatedNodeInfoMapping struct {
-	ip     string
-	port   uint
-	ticket uint64
-}
-// https://solidity.readthedocs.io/en/develop/abi-spec.html#use-of-dynamic-types
-// The first four bytes of the call data for a function call specifies the function to be called.
-// It is the first (left, high-order in big-endian) four bytes of the Keccak (SHA-3) hash of the signature of the function.
-// The signature is defined as the canonical expression of the basic prototype, i.e.
-// the function name with the parenthesised list of parameter types. Parameter types are split by a single comma
-// no spaces are used. for example: bytes4(sha3("set(uint256[])"))
-// "0xb4701401": "birusu()",
-// "0x1ab88d26": "delegatorInfo(string)",
-// "0x61b29d69": "delegatorList()",
-// https://solidity.readthedocs.io/en/develop/abi-spec.html#examples
-// please also refer to abi_test.go
-// hw.Sum(data[:0])
-func (d *DelegatorAccessorImpl) Refresh() (delegatorsTable []string, delegatorNodes []*discover.Node, e error) {
-	// call delegatorList()
-	data, err0 := d.dappabi.Pack("delegatorList")
-	if err0 != nil {
-		log.Error("Error to encode delegatorList function call.")
-		return nil,nil, errors.New("Error to encode delegatorList function call.")
-	}
-	//var data = common.Hex2Bytes("0x61b29d69")
-	var result string;
-	output, err0 := d.doCall(data);
-	if err0 != nil {
-		log.Error("Error to call delegatorList function.")
-		return nil,nil, errors.New("Error to call delegatorList function.")
-	}
-	if len(output) == 0 {
-		// no result
-		return nil,nil, errors.New("Delegator list must not be empty! the state of this node is incorrect.")
-	}
-	err0 = d.dappabi.Unpack(&result, "result", output)
-	if err0 != nil {
-		log.Error("Error to parse the result of delegatorList function.")
-		return nil,nil, errors.New("Error to parse the result of delegatorList function.")
-	}
-	if len(result) == 0 {
-		log.Error("Delegator list must not be empty! the state of this node is incorrect.")
-		return nil,nil, errors.New("Delegator list must not be empty! the state of this node is incorrect.")
-	}
-
-	delegatorIds := strings.Split(result, ";")
-	ids := make([]string, len(delegatorIds))
-	peerinfo := make([]*discover.Node, len(delegatorIds))
-	for i,delegatorId := range delegatorIds {
-		// call delegatorInfo(string) 0x6162630000000000000000000000000000000000000000000000000000000000
-		data1, err0 := d.dappabi.Pack("delegatorInfo", delegatorId)
-		if err0 != nil {
-			log.Error("Error to parse delegatorInfo function.")
-			return nil,nil, errors.New("Error to parse delegatorInfo function.")
-		}
-		output1, err0 := d.doCall(data1)
-		if err0 != nil {
-			log.Error("Error to call delegatorInfo function.")
-			return nil,nil, errors.New("Error to call delegatorInfo function.")
-		}
-		var result DelegatedNodeInfoMapping
-		//string ip, uint port, uint256 ticket
-		err0 = d.dappabi.Unpack(&result, "result", output1)
-		if err0 != nil {
-			log.Error("Error to parse the result of delegatorInfo function.")
-			return nil,nil, errors.New("Error to parse the result of delegatorInfo function.")
-		}
-		ids[i] = delegatorId
-		peerinfo[i] = &discover.Node{}
-	}
-	return ids, peerinfo, nil;
-}
-
-func (d *DelegatorAccessorImpl) doCall(data []byte) ([]byte, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
-	ctx := context.Background()
-	state, header, err := d.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
-	if state == nil || err != nil {
-		return nil, err
-	}
-	// Set sender address or use a default if none specified
-	addr := common.Address{};
-	if !TestMode {
-		if wallets := d.b.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-	}
-	// Set default gas & gas price if none were set
-	defaultGasPrice := uint64(50 * config.Shannon)
-	gas, gasPrice := uint64(math.MaxUint64 / 2), new(big.Int).SetUint64(defaultGasPrice)
-
-	// Create new call message
-	msg := types.NewMessage(addr, &core.DPOSBallotContractAddress, 0, new(big.Int), gas, gasPrice, data, false)
-
-	// Setup context so it may be cancelled the call has completed.
-	var cancel context.CancelFunc
-	ctx, cancel = context.WithCancel(ctx)
-
-	// Make sure the context is cancelled when the call has completed
-	// this makes sure resources are cleaned up.
-	defer cancel()
-
-	// Get a new instance of the EVM.
-	evm, vmError, err := d.b.GetEVM(ctx, msg, state, header, vm.Config{})
-	if err != nil {
-		return nil, err
-	}
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
-
-	// Setup the gas pool (also for unmetered requests)
-	// and apply the message.
-	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, _, err := core.ApplyMessage(evm, msg, gp)
-	if err := vmError(); err != nil {
-		return nil, err
-	}
-	return res, err
+func (d *DelegatorAccessorTestImpl) Delegators(chain consensus.ChainReader, header *types.Header, state *state.StateDB) ([]string, error) {
+	return []string{d.currNodeId}, nil
 }
 
 type DPoSProtocolManager struct {
@@ -231,10 +76,21 @@ type DPoSProtocolManager struct {
 	eth           *JuchainService;
 	ethManager    *ProtocolManager;
 	blockchain    *core.BlockChain;
+	engine        *dpos.DElection; // nil if the chain is not run by the dpos engine.
 
 	lock          *sync.Mutex; // protects running
 	packager      *dpos.Packager;
-	t1            *time.Timer; // global synchronized timer.
+	quit          chan struct{};
+
+	nodeId        string; // current short node id, the id of the local delegator.
+	nodeIdHash    []byte; // short node id hash.
+	clock         Clock;  // source of the slots of the delegators.
+	timer         Timer;  // wakes up the packaging at the begin of the next slot.
+
+	finality      *dpos.FinalityTally; // pre-commit votes collected towards finality, nil without the dpos engine.
+	headCh        chan core.ChainHeadEvent;
+	headSub       event.Subscription;
+	lastPreCommit uint64; // number of the last block voted for, persisted to never vote twice at a height.
 }
 
 // NewProtocolManager returns a new obod sub protocol manager. The JuchainService sub protocol manages peers capable
@@ -250,369 +106,104 @@ func NewDPoSProtocolManager(eth *JuchainService, ethManager *ProtocolManager, co
 		blockchain:        blockchain,
 		lock:              &sync.Mutex{},
 		packager:          dpos.NewPackager(config, engine, DefaultConfig.Etherbase, eth, eth.EventMux()),
-	}
-	currNodeId = discover.PubkeyID(&config2.NodeKey().PublicKey).TerminalString();
-	currNodeIdHash = common.Hex2Bytes(currNodeId);
-	if TestMode {
-		VotingAccessor = &DelegatorAccessorTestImpl{currNodeId:currNodeId, currNodeIdHash:currNodeIdHash};
-		DelegatorsTable, DelegatorNodeInfo, _ = VotingAccessor.Refresh();
-	} else {
-		/**
-		var addr common.Address;
-		if wallets := eth.ApiBackend.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-		if addr == (common.Address{}) {
-			log.Error("We must have a default address to activate dpos delegator consensus")
-			return nil, errors.New("we must have a default address to activate dpos delegator consensus")
-		}*/
-		dappabi, err := abi.JSON(strings.NewReader(core.DPOSBallotABI))
-		if err != nil {
-			log.Error("Unable to load DPoS Ballot ABI object!")
-			return nil, errors.New("Unable to load DPoS Ballot ABI object!")
-		}
-		VotingAccessor = &DelegatorAccessorImpl{dappabi: dappabi, blockchain: eth.blockchain, b: eth.ApiBackend};
-		DelegatorsTable, DelegatorNodeInfo, err = VotingAccessor.Refresh();
+		quit:              make(chan struct{}),
+		clock:             systemClock{},
+	}
+	nodeKey := config2.NodeKey();
+	manager.nodeId = discover.PubkeyID(&nodeKey.PublicKey).TerminalString();
+	manager.nodeIdHash = common.Hex2Bytes(manager.nodeId);
+
+	// every packaged block is sealed by the node key of the delegator,
+	// and every checkpoint block records the delegators of the next epoch.
+	if election, ok := engine.(*dpos.DElection); ok {
+		election.Authorize(manager.nodeId, func(hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, nodeKey)
+		});
+		election.SetDelegatorReader(dpos.NewRegistryReader(int(TotalDelegatorNumber)));
+		manager.engine = election;
+		manager.finality = dpos.NewFinalityTally(election, eth.evidence);
+		manager.finality.SetCertified(manager.blockchain.CurrentFinalizedHeader());
+		manager.lastPreCommit = core.GetLastPreCommit(eth.ChainDb());
 	}
 	return manager, nil;
 }
 
 func (pm *DPoSProtocolManager) Start() {
-	if pm.isDelegatedNode() {
-		log.Info("I am a delegator.")
-		pm.packager.Start();
-		go pm.schedule();
-		if !TestMode {
-			time.AfterFunc(time.Second*time.Duration(SmallPeriodInterval), pm.syncDelegatedNodeSafely) //initial attempt.
-		}
-	}
-}
+	log.Info("Starting DPoS Delegation Consensus")
+	pm.packager.Start();
 
-func (pm *DPoSProtocolManager) schedule() {
-	t2 := time.NewTimer(time.Second * time.Duration(1))
-	for {
-		select {
-		case <-t2.C:
-			go pm.roundRobinSafely();
-			t2 = time.NewTimer(time.Second * time.Duration(1))
-		}
-	}
+	pm.lock.Lock()
+	pm.timer = pm.clock.AfterFunc(0, pm.schedule);
+	pm.lock.Unlock()
 }
 
-// this is a loop function for electing node.
-func (pm *DPoSProtocolManager) syncDelegatedNodeSafely() {
-	if !pm.isDelegatedNode() {
-		// only candidate node is able to participant to this process.
+// schedule packages the block of the local delegator if its slot began, and
+// wakes up again at the begin of the next slot. Every block is stamped with the
+// begin of its slot, so the slots of any new head stay on the grid laid out by
+// the genesis block, and waking up at the begin of every slot follows them.
+func (pm *DPoSProtocolManager) schedule() {
+	select {
+	case <-pm.quit:
 		return;
+	default:
 	}
+	wait := pm.roundRobinSafely()
+
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
-	log.Info("Preparing for next big period...");
-	// pull the newest delegators from voting contract.
-	a, b, err0 := VotingAccessor.Refresh()
-	if err0 != nil {
-		log.Error(err0.Error())
-		return;
-	}
-	DelegatorsTable = a
-	DelegatorNodeInfo = b
-	if uint8(len(GigPeriodHistory)) >= BigPeriodHistorySize {
-		GigPeriodHistory = GigPeriodHistory[1:] //remove the first old one.
-	}
-	if len(DelegatorsTable) == 0 || pm.ethManager.peers.Len() == 0 {
-		log.Info("Sorry, could not detect any delegator!");
-		return;
-	}
-	round := uint64(1)
-	activeTime := uint64(time.Now().Unix() + int64(GigPeriodInterval))
-	if NextGigPeriodInstance != nil {
-		if !TestMode {
-			gap := int64(NextGigPeriodInstance.activeTime) - time.Now().Unix()
-			if gap > 2 || gap < -2 {
-				log.Warn(fmt.Sprintf("Scheduling of the new electing round is improper! current gap: %v seconds", gap))
-				//restart the scheduler
-				NextElectionInfo = nil;
-				go pm.syncDelegatedNodeSafely();
-				return;
-			}
-		}
-		round = NextGigPeriodInstance.round + 1
-		activeTime = GigPeriodInstance.activeTime + uint64(GigPeriodInterval)
-		// keep the big period history for block validation.
-		GigPeriodHistory[len(GigPeriodHistory)-1] = *NextGigPeriodInstance;
-
-		GigPeriodInstance = &GigPeriodTable{
-			NextGigPeriodInstance.round,
-			NextGigPeriodInstance.state,
-			NextGigPeriodInstance.delegatedNodes,
-			NextGigPeriodInstance.delegatedNodesSign,
-			NextGigPeriodInstance.confirmedTickets,
-			NextGigPeriodInstance.confirmedBestNode,
-			NextGigPeriodInstance.activeTime,
-		};
-		log.Info(fmt.Sprintf("Switched the new big period round. %d ", GigPeriodInstance.round));
+	select {
+	case <-pm.quit:
+	default:
+		pm.timer = pm.clock.AfterFunc(wait, pm.schedule);
 	}
-
-	// make sure all delegators are synced at this round.
-	NextGigPeriodInstance = &GigPeriodTable{
-		round,
-		STATE_LOOKING,
-		DelegatorsTable,
-		SignCandidates(DelegatorsTable),
-		make(map[string]uint32),
-		make(map[string]*GigPeriodTable),
-		activeTime,
-	};
-	pm.trySyncAllDelegators()
 }
 
-func (pm *DPoSProtocolManager) trySyncAllDelegators() {
-	if TestMode {
-		return;
-	}
-	//send this round to all delegated peers.
-	//all delegated must giving the response in SYNC_BIGPERIOD_RESPONSE state.
-	for _, delegator := range NextGigPeriodInstance.delegatedNodes {
-		// make sure all delegator are alive.
-		if pm.ethManager.peers.Peer(delegator) == nil {
-			// try to add DelegatorNodeInfo[i] into peers table.
-			// but can't talk to it directly.
-			for i,e := range DelegatorsTable {
-				if e == delegator {
-					pm.eth.server.AddPeer(DelegatorNodeInfo[i]);
-					break;
-				}
-			}
-		} else {
-			err := pm.ethManager.peers.Peer(delegator).SendSyncBigPeriodRequest(
-				&SyncBigPeriodRequest{NextGigPeriodInstance.round,
-					NextGigPeriodInstance.activeTime,
-					NextGigPeriodInstance.delegatedNodes,
-					NextGigPeriodInstance.delegatedNodesSign,
-					currNodeIdHash});
-			if err != nil {
-				log.Debug("Error occurred while sending SyncBigPeriodRequest: " + err.Error())
-			}
-		}
-	}
+// untilSlot returns the time left until the given slot time.
+func (pm *DPoSProtocolManager) untilSlot(slot uint64) time.Duration {
+	return time.Unix(int64(slot), 0).Sub(pm.clock.Now())
 }
 
-// handleMsg is invoked whenever an inbound message is received from a remote
-// peer. The remote connection is torn down upon returning any error.
-func (pm *DPoSProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
-	pm.lock.Lock()
-	defer pm.lock.Unlock()
-	// Handle the message depending on its contents
-	switch {
-	case msg.Code == SYNC_BIGPERIOD_REQUEST:
-		var request SyncBigPeriodRequest;
-		if err := msg.Decode(&request); err != nil {
-			return errResp(DPOSErrDecode, "%v: %v", msg, err);
-		}
-		if SignCandidates(request.DelegatedTable) != request.DelegatedTableSign {
-			return errResp(DPOSErroDelegatorSign, "");
-		}
-		if DelegatorsTable == nil || len(DelegatorsTable) == 0 {
-			// i am not ready.
-			log.Info("I am not ready!!!")
-			return nil;
-		}
-		if request.Round == NextGigPeriodInstance.round {
-			if NextGigPeriodInstance.state == STATE_CONFIRMED {
-				log.Debug(fmt.Sprintf("I am in the agreed round %v", NextGigPeriodInstance.round));
-				// if i have already confirmed this round. send this round to peer.
-				if TestMode {
-					return nil;
-				}
-				return p.SendSyncBigPeriodResponse(&SyncBigPeriodResponse{
-					NextGigPeriodInstance.round,
-					NextGigPeriodInstance.activeTime,
-					NextGigPeriodInstance.delegatedNodes,
-					NextGigPeriodInstance.delegatedNodesSign,
-					STATE_CONFIRMED,
-					currNodeIdHash});
-			} else {
-				if !reflect.DeepEqual(DelegatorsTable, request.DelegatedTable) {
-					if len(DelegatorsTable) < len(request.DelegatedTable) {
-						// refresh table if mismatch.
-						DelegatorsTable, DelegatorNodeInfo, _ = VotingAccessor.Refresh()
-					}
-					if !reflect.DeepEqual(DelegatorsTable, request.DelegatedTable) {
-						log.Debug("Delegators are mismatched in two tables.");
-						if TestMode {
-							return nil;
-						}
-						// both delegators are not matched,  both lose the election power of this round.
-						return p.SendSyncBigPeriodResponse(&SyncBigPeriodResponse{
-							NextGigPeriodInstance.round,
-							NextGigPeriodInstance.activeTime,
-							NextGigPeriodInstance.delegatedNodes,
-							NextGigPeriodInstance.delegatedNodesSign,
-							STATE_MISMATCHED_DNUMBER,
-							currNodeIdHash});
-					}
-				}
-				NextGigPeriodInstance.state = STATE_CONFIRMED;
-				NextGigPeriodInstance.delegatedNodes = request.DelegatedTable;
-				NextGigPeriodInstance.delegatedNodesSign = request.DelegatedTableSign;
-				NextGigPeriodInstance.activeTime = request.ActiveTime;
-
-				pm.setNextRoundTimer();//sync the timer.
-				log.Debug(fmt.Sprintf("Agreed this table %v as %v round", NextGigPeriodInstance.delegatedNodes, NextGigPeriodInstance.round));
-				if TestMode {
-					return nil;
-				}
-				// broadcast it to all peers again.
-				for _, peer := range pm.ethManager.peers.peers {
-					err := peer.SendSyncBigPeriodResponse(&SyncBigPeriodResponse{
-						NextGigPeriodInstance.round,
-						NextGigPeriodInstance.activeTime,
-						NextGigPeriodInstance.delegatedNodes,
-						NextGigPeriodInstance.delegatedNodesSign,
-						STATE_CONFIRMED,
-						currNodeIdHash})
-					if (err != nil) {
-						log.Warn("Error occurred while sending VoteElectionRequest: " + err.Error())
-					}
-				}
-			}
-		} else if request.Round < NextGigPeriodInstance.round {
-			log.Debug(fmt.Sprintf("Mismatched request.round %v, CurrRound %v: ", request.Round, NextGigPeriodInstance.round))
-			if TestMode {
-				return nil;
-			}
-			return p.SendSyncBigPeriodResponse(&SyncBigPeriodResponse{
-				NextGigPeriodInstance.round,
-				NextGigPeriodInstance.activeTime,
-				NextGigPeriodInstance.delegatedNodes,
-				NextGigPeriodInstance.delegatedNodesSign,
-				STATE_MISMATCHED_ROUND,
-				currNodeIdHash});
-		} else if request.Round > NextGigPeriodInstance.round {
-			if (request.Round - NextElectionInfo.round) == 1 {
-				// the most reason could be the round timeframe switching later than this request.
-				// but we are continue switching as regular.
-			} else {
-				// attack happens.
-			}
-		}
-	case msg.Code == SYNC_BIGPERIOD_RESPONSE:
-		var response SyncBigPeriodResponse;
-		if err := msg.Decode(&response); err != nil {
-			return errResp(DPOSErrDecode, "%v: %v", msg, err);
-		}
-		if response.Round != NextGigPeriodInstance.round {
-			return nil;
-		}
-		if SignCandidates(response.DelegatedTable) != response.DelegatedTableSign {
-			return errResp(DPOSErroDelegatorSign, "");
-		}
-		nodeId := common.Bytes2Hex(response.NodeId)
-		log.Debug("Received SYNC Big Period response: " + nodeId);
-		NextGigPeriodInstance.confirmedTickets[nodeId] ++;
-		NextGigPeriodInstance.confirmedBestNode[nodeId] = &GigPeriodTable{
-			response.Round,
-			STATE_CONFIRMED,
-			response.DelegatedTable,
-			response.DelegatedTableSign,
-			nil,
-			nil,
-			response.ActiveTime,
-		};
-
-		maxTickets, bestNodeId := uint32(0), "";
-		for key, value := range NextGigPeriodInstance.confirmedTickets {
-			if maxTickets < value {
-				maxTickets = value;
-				bestNodeId = key;
-			}
-		}
-		if NextGigPeriodInstance.state == STATE_CONFIRMED {
-			// set the best node as the final state.
-			bestNode := NextGigPeriodInstance.confirmedBestNode[bestNodeId];
-			NextGigPeriodInstance.delegatedNodes = bestNode.delegatedNodes;
-			NextGigPeriodInstance.delegatedNodesSign = bestNode.delegatedNodesSign;
-			NextGigPeriodInstance.activeTime = bestNode.activeTime;
-			log.Debug(fmt.Sprintf("Updated the best table: %v", bestNode.delegatedNodes));
-			pm.setNextRoundTimer();
-		} else if NextGigPeriodInstance.state == STATE_LOOKING && uint32(NextGigPeriodInstance.confirmedTickets[bestNodeId]) > uint32(len(NextGigPeriodInstance.delegatedNodes)) {
-			NextGigPeriodInstance.state = STATE_CONFIRMED;
-			NextGigPeriodInstance.delegatedNodes = response.DelegatedTable;
-			NextGigPeriodInstance.delegatedNodesSign = response.DelegatedTableSign;
-			NextGigPeriodInstance.activeTime = response.ActiveTime;
-
-			pm.setNextRoundTimer();
-		} else if response.State == STATE_MISMATCHED_ROUND {
-			// force to create new round
-			NextGigPeriodInstance = &GigPeriodTable{
-				response.Round,
-				STATE_LOOKING,
-				response.DelegatedTable,
-				response.DelegatedTableSign,
-				make(map[string]uint32),
-				make(map[string]*GigPeriodTable),
-				response.ActiveTime,
-			};
-			pm.trySyncAllDelegators()
-		} else if response.State == STATE_MISMATCHED_DNUMBER {
-			// refresh table only, and this node loses the election power of this round.
-			DelegatorsTable, DelegatorNodeInfo, _ = VotingAccessor.Refresh()
-		}
+// delegators returns the delegators scheduled for the block on top of the current head.
+func (pm *DPoSProtocolManager) delegators() []string {
+	schedule := pm.currentSchedule()
+	if schedule == nil {
 		return nil;
-	default:
-		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
-	return nil
+	return schedule.Delegators;
 }
 
-func (pm *DPoSProtocolManager) setNextRoundTimer() {
-	leftTime := int64(NextGigPeriodInstance.activeTime) - time.Now().Unix()
-	if leftTime < 1 {
-		log.Warn("Discard this round due to the expiration of the active time.")
-		go pm.syncDelegatedNodeSafely()
-		return;
+// currentSchedule returns the delegator schedule of the epoch the next block belongs to.
+func (pm *DPoSProtocolManager) currentSchedule() *dpos.Schedule {
+	if pm.engine == nil {
+		return nil;
 	}
-	if pm.t1 != nil {
-		// potentially could be an issue if the timer is unable to be cancelled.
-		pm.t1.Stop()
-		pm.t1 = time.AfterFunc(time.Second*time.Duration(leftTime), pm.syncDelegatedNodeSafely)
-	} else {
-		pm.t1 = time.AfterFunc(time.Second*time.Duration(leftTime), pm.syncDelegatedNodeSafely)
+	schedule, err := pm.engine.Schedule(pm.blockchain, pm.blockchain.CurrentHeader())
+	if err != nil {
+		log.Warn("Failed to retrieve the delegator schedule", "err", err)
+		return nil;
 	}
-	log.Debug(fmt.Sprintf("scheduled for next round in %v seconds", leftTime))
+	return schedule;
 }
 
 // the node would not be a candidate if it is not qualified.
 func (pm *DPoSProtocolManager) isDelegatedNode() bool {
-	if DelegatorsTable == nil {
-		return false;
-	}
-	for i :=0; i < len(DelegatorsTable); i++ {
-		if DelegatorsTable[i] == currNodeId {
-			return true;
-		}
-	}
-	return false;
+	schedule := pm.currentSchedule()
+	return schedule != nil && schedule.Includes(pm.nodeId);
 }
 
-func (pm *DPoSProtocolManager) isDelegatedNode2(nodeId string) bool {
-	if DelegatorsTable == nil {
-		return false;
+func (pm *DPoSProtocolManager) Stop() {
+	pm.lock.Lock()
+	select {
+	case <-pm.quit:
+	default:
+		close(pm.quit)
 	}
-	for i :=0; i < len(DelegatorsTable); i++ {
-		if DelegatorsTable[i] == nodeId {
-			return true;
-		}
+	if pm.timer != nil {
+		pm.timer.Stop()
 	}
-	return false;
-}
+	pm.lock.Unlock()
 
-func (pm *DPoSProtocolManager) Stop() {
-	if pm.isDelegatedNode() {
-		pm.packager.Stop();
-	}
+	pm.packager.Stop();
 	// Quit the sync loop.
 	log.Info("DPoS Consensus stopped")
 }
@@ -622,95 +213,43 @@ func (pm *DPoSProtocolManager) newPeer(pv uint, p *p2p.Peer, rw p2p.MsgReadWrite
 }
 
 // --------------------Packaging Process-------------------//
-// start round robin for packaging blocks in small period.
-func (self *DPoSProtocolManager) roundRobinSafely() {
-	if !self.isDelegatedNode() || GigPeriodInstance == nil {
-		return;
-	}
-	log.Info(GigPeriodInstance.whosTurn())
-	// generate block by election node.
-	if GigPeriodInstance.isMyTurn() {
-		log.Debug("it's my turn now " + time.Now().String());
-		round := self.blockchain.CurrentFastBlock().Header().Round;
-		block := self.packager.GenerateNewBlock(round+1, currNodeId);
-		block.ToString();
-		//response := &PackageResponse{block.Round(), currNodeId, block.Hash(),DPOSMSG_SUCCESS};
-	}
-}
-// this GigPeriodTable only serves for delegators.
-type GigPeriodTable struct {
-	round              uint64;       // synchronization round
-	state              uint8;       // STATE_LOOKING
-	delegatedNodes     []string;    // all 31 nodes id
-	delegatedNodesSign common.Hash; // a security sign for all delegated nodes which can be verified from node array.
-	confirmedTickets   map[string]uint32;   // 31 node must be confirmed this ticket or must equal to delegatedNodes length.
-	confirmedBestNode  map[string]*GigPeriodTable;  // confirmed the next active time from all peers. <nodeid><GigPeriodTable>
-	activeTime         uint64;       // Unix timestamp for all nodes.
-}
-func (t *GigPeriodTable) wasHisTurn(round uint64, nodeId string, minedTime int64) bool {
-	for i :=0; i < len(t.delegatedNodes); i++ {
-		if t.delegatedNodes[i] == nodeId {
-			beatStartTime := int64(t.activeTime) + (int64(i) * int64(SmallPeriodInterval))
-			if beatStartTime <= minedTime && (beatStartTime+ int64(SmallPeriodInterval)) >= minedTime {
-				return true;
-			}
-		}
-	}
-	// check the history.
-	if len(GigPeriodHistory) > 0 {
-		for _, v := range GigPeriodHistory {
-			if int64(v.activeTime) <= minedTime && (int64(v.activeTime) + int64(SmallPeriodInterval)) >= minedTime {
-				for i :=0; i < len(v.delegatedNodes); i++ {
-					if v.delegatedNodes[i] == nodeId {
-						//todo check round as well.
-						return true;
-					}
-				}
-			}
-		}
-	}
-	return false;
-}
-func (t *GigPeriodTable) isMyTurn() bool {
-	for i :=0; i < len(t.delegatedNodes); i++ {
-		if t.delegatedNodes[i] == currNodeId {
-			beatStartTime := int64(t.activeTime) + (int64(i) * int64(SmallPeriodInterval))
-			currTime := time.Now().Unix()
-			// we only give 4s to avoid the mismatched timestamp issue of last packaging.
-			if beatStartTime <= currTime && (beatStartTime+ int64(SmallPeriodInterval)) > currTime {
-				return true;
-			}
-		}
-	}
-	return false;
-}
-func (t *GigPeriodTable) whosTurn() string {
-	currTime := time.Now().Unix()
-	for i :=0; i < len(t.delegatedNodes); i++ {
-		beatStartTime := int64(t.activeTime) + (int64(i) * int64(SmallPeriodInterval))
-		if beatStartTime <= currTime && (beatStartTime+ int64(SmallPeriodInterval)) >= currTime {
-			return "Who's turn: {position: " + strconv.Itoa(i) + ", delegator: " + t.delegatedNodes[i] + " }";
-		}
-	}
-	return "";
-}
-func (t *GigPeriodTable) isDelegatedNode(nodeId string) bool {
-	for i :=0; i < len(t.delegatedNodes); i++ {
-		if t.delegatedNodes[i] == nodeId {
-			return true;
-		}
-	}
-	return false;
+// start round robin for packaging blocks in slots.
+// the slots follow the parent block every period, derived from its timestamp
+// rather than the local clock, so a missing delegator only skips its own slot
+// and a skewed clock only shifts when a delegator seals, never what it seals.
+// the time until the next slot is returned.
+func (self *DPoSProtocolManager) roundRobinSafely() time.Duration {
+	// Only the state is read under the lock, the packaging may take a while
+	self.lock.Lock()
+	if self.engine == nil {
+		self.lock.Unlock()
+		return time.Second;
+	}
+	parent := self.blockchain.CurrentBlock().Header()
+	now := uint64(self.clock.Now().Unix())
+	round, start := self.engine.CurrentSlot(parent, now)
+	next := self.engine.SlotTime(parent, round+1)
+	inGrace := self.engine.InGrace(start, now)
+	schedule := self.currentSchedule()
+	self.lock.Unlock()
+
+	if now < start {
+		return self.untilSlot(start);
+	}
+	if schedule == nil || !schedule.Includes(self.nodeId) {
+		return self.untilSlot(next);
+	}
+	producer := schedule.Producer(round)
+	log.Info("Delegator scheduled for the slot", "round", round, "producer", producer)
+	// generate block by the delegator of this slot.
+	if producer == self.nodeId {
+		if !inGrace {
+			log.Warn("Missed grace window of own slot", "round", round, "slot", start, "now", now)
+			return self.untilSlot(next);
+		}
+		if block := self.packager.GenerateNewBlock(round, self.nodeId); block != nil {
+			log.Info("Packaged block in own slot", "round", round, "number", block.NumberU64(), "hash", block.Hash())
+		}
+	}
+	return self.untilSlot(next);
 }
-
-func RemoveCanditate(s []string, i int) []string {
-	s[len(s)-1], s[i] = s[i], s[len(s)-1]
-	return s[:len(s)-1]
-}
-
-func SignCandidates(candidates []string) common.Hash {
-	var signCandidates = []byte{}
-	hw := sha3.NewKeccak256()
-	rlp.Encode(hw, candidates)
-	return common.BytesToHash(hw.Sum(signCandidates))
-}
\ No newline at end of file
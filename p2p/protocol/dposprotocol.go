@@ -39,8 +39,7 @@ const (
 	VOTE_ElectionNode_Response  = 0xa2
 	VOTE_ElectionNode_Broadcast = 0xa3
 	VOTE_BESTNODE_CONFLICT      = 0xa4
-	SYNC_BIGPERIOD_REQUEST      = 0xb1
-	SYNC_BIGPERIOD_RESPONSE     = 0xb2
+	VOTE_PreCommit              = 0xa5 // signed vote of a delegator for a block to become final
 
 
 	DPOSMSG_SUCCESS = iota
@@ -55,20 +54,12 @@ const (
 	DPOSErroPACKAGE_EMPTY
 	DPOSErroVOTE_VERIFY_FAILURE
 	DPOSErroCandidateFull
-	DPOSErroDelegatorSign
 
 	// voting sync status
 	VOTESTATE_LOOKING  = 0xb0
 	VOTESTATE_SELECTED = 0xb1
 	VOTESTATE_STOP     = 0xb2
 	VOTESTATE_MISMATCHED_ROUND = 0xb2
-
-	// delegator sync status
-	STATE_LOOKING   = 0xc0
-	STATE_CONFIRMED = 0xc1
-	// sync response
-	STATE_MISMATCHED_ROUND = 0xc2
-	STATE_MISMATCHED_DNUMBER = 0xc3
 )
 
 type DPOSErrCode int
@@ -89,26 +80,6 @@ var DPOSerrorToString = map[int]string{
 	DPOSErroPACKAGE_NOTSYNC:        "Failed to package block due to blocks syncing is not completed yet",
 	DPOSErroPACKAGE_EMPTY:          "Packaging block is skipped due to there was no transaction found at the remote peer",
 	DPOSErroVOTE_VERIFY_FAILURE:    "VotePresidentRequest is invalid",
-	DPOSErroDelegatorSign:          "Delegators' signature is incorrect",
-}
-
-//
-type SyncBigPeriodRequest struct {
-	Round              uint64;
-	ActiveTime         uint64;
-	DelegatedTable     []string; // all 31 nodes id
-	DelegatedTableSign common.Hash;
-	NodeId             []byte
-}
-
-//
-type SyncBigPeriodResponse struct {
-	Round              uint64;
-	ActiveTime         uint64;
-	DelegatedTable     []string; // all 31 nodes id
-	DelegatedTableSign common.Hash;
-	State              uint8
-	NodeId             []byte
 }
 
 //
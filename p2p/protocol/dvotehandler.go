@@ -26,7 +26,6 @@ import (
 	"github.com/juchain/go-juchain/core"
 	"github.com/juchain/go-juchain/p2p/protocol/downloader"
 	"github.com/juchain/go-juchain/p2p"
-	"github.com/juchain/go-juchain/p2p/discover"
 	"github.com/juchain/go-juchain/p2p/node"
 	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/consensus/dpos"
@@ -47,13 +46,9 @@ import (
 // 2. solve the best node confliction if has.
 // 3. exchange the voted best node from all peers.
 var (
-	TestMode          bool   = false; // only for test case.
 	PackagingInterval uint32 = 2;     // vote for packaging node in every 5 seconds.
 	ElectingInterval  uint32 = 15;    // elect for new node in every 30 seconds.
 
-	ElectionInfo0    *ElectionInfo; // we use two versions of election info for switching election node smoothly.
-	NextElectionInfo *ElectionInfo;
-	LastElectedNodeId string;
 	//enableBNConflict  bool   = false;
 	BNConflictInterval uint32 = 4; // must be small than ElectingInterval / 2
 )
@@ -81,8 +76,14 @@ type DVoteProtocolManager struct {
 	packager      *dpos.Packager;
 	dposManager	  *DPoSProtocolManager;
 
-	t1            *time.Timer; // global synchronized timer.
+	clock         Clock;      // source of the rounds and timeouts of the election.
+	rand          *rand.Rand; // source of the election tickets, protected by lock.
+	t1            Timer;      // global synchronized timer.
+	quit          chan struct{}; // closed once stopped, silencing the pending timers.
 
+	electionInfo      *ElectionInfo; // we use two versions of election info for switching election node smoothly.
+	nextElectionInfo  *ElectionInfo;
+	lastElectedNodeId string;
 }
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The JuchainService sub protocol manages peers capable
@@ -96,6 +97,9 @@ func NewDVoteProtocolManager(eth *JuchainService, ethManager *ProtocolManager, c
 		blockchain:  blockchain,
 		lock:        &sync.Mutex{},
 		packager:    dpos.NewPackager(config, engine, DefaultConfig.Etherbase, eth, eth.EventMux()),
+		clock:       systemClock{},
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		quit:        make(chan struct{}),
 	}
 	//manager.dposManager
 	manager0, err0 := NewDPoSProtocolManager(eth, ethManager, config, config2, mode, networkId, blockchain, engine);
@@ -103,10 +107,6 @@ func NewDVoteProtocolManager(eth *JuchainService, ethManager *ProtocolManager, c
 		return nil, err0;
 	}
 	manager.dposManager = manager0;
-	currNodeId = discover.PubkeyID(&config2.NodeKey().PublicKey).TerminalString();
-	currNodeIdHash = common.Hex2Bytes(currNodeId);
-	ElectionInfo0 = nil;
-	NextElectionInfo = nil;
 	return manager, nil
 }
 
@@ -114,15 +114,27 @@ func (pm *DVoteProtocolManager) Start(maxPeers int) {
 	// get data from contract
 	log.Info("Starting DPoS Voting Consensus")
 	pm.packager.Start();
-	if !TestMode {
-		go pm.schedule();
-		go pm.scheduleElecting();
+	pm.dposManager.startFinality();
+	pm.clock.AfterFunc(0, pm.schedule);
+	pm.clock.AfterFunc(0, pm.scheduleElecting);
+}
+
+// stopped returns whether the manager was stopped, the timers still pending
+// once stopped do nothing.
+func (pm *DVoteProtocolManager) stopped() bool {
+	select {
+	case <-pm.quit:
+		return true;
+	default:
+		return false;
 	}
 }
 
 func (pm *DVoteProtocolManager) schedule() {
-	if DelegatorsTable != nil && len(DelegatorsTable) > 2 {
-		log.Info("Starting DPoS Delegation Consensus")
+	if pm.stopped() {
+		return;
+	}
+	if pm.isDelegationActivated() {
 		pm.dposManager.Start();
 		return;
 	}
@@ -131,104 +143,123 @@ func (pm *DVoteProtocolManager) schedule() {
 
 func (pm *DVoteProtocolManager) schedulePackaging() {
 	// generate block by election node.
-	if pm.isElectionNode() {
-		round := pm.blockchain.CurrentFastBlock().Header().Round;
-		block := pm.packager.GenerateNewBlock(round+1, currNodeId);
-		block.ToString();
+	// the election node packages in the slots of the dpos engine as well, and
+	// only in the ones it is scheduled for, the others would not be accepted.
+	// while no delegator was ever recorded, it packages in every slot.
+	if pm.isElectionNode() && pm.dposManager.engine != nil {
+		parent := pm.blockchain.CurrentBlock().Header();
+		now := uint64(pm.clock.Now().Unix());
+		schedule := pm.dposManager.currentSchedule();
+		if round, start := pm.dposManager.engine.CurrentSlot(parent, now); pm.dposManager.engine.InGrace(start, now) && schedule != nil && schedule.Authorizes(round, pm.dposManager.nodeId) {
+			if block := pm.packager.GenerateNewBlock(round, pm.dposManager.nodeId); block != nil {
+				block.ToString();
+			}
+		}
 	}
 	// confirm broadcasting result.
-	time.AfterFunc(time.Second * time.Duration(PackagingInterval), pm.schedule)
+	pm.clock.AfterFunc(time.Second * time.Duration(PackagingInterval), pm.schedule)
+}
+
+// isDelegationActivated returns whether enough delegators are recorded at the
+// last checkpoint to switch from the election node to the delegator schedule.
+func (pm *DVoteProtocolManager) isDelegationActivated() bool {
+	return len(pm.dposManager.delegators()) > 2;
 }
 
 func (pm *DVoteProtocolManager) isElectionNode() bool {
-	return ElectionInfo0 != nil && ElectionInfo0.electionNodeId == currNodeId;
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	return pm.electionInfo != nil && pm.electionInfo.electionNodeId == pm.dposManager.nodeId;
 }
 
 func (pm *DVoteProtocolManager) scheduleElecting() {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
-	DelegatorsTable, DelegatorNodeInfo, _ = VotingAccessor.Refresh();
-	if DelegatorsTable != nil && len(DelegatorsTable) > 2 {
+	if pm.stopped() || pm.isDelegationActivated() {
 		// dpos delegator consensus is activated!
 		return;
 	}
 	pm.t1 = nil;
 	round := uint64(1);
-	if NextElectionInfo != nil {
+	if pm.nextElectionInfo != nil {
 		bestNodeId, tickets, activeTime := pm.getBestNodeInfo2()
 		if tickets == 0 {
 			// 99% should not in here.
 			log.Warn("Selecting of the best node is conflicted!")
 			//restart the scheduler
-			NextElectionInfo = nil;
-			go pm.scheduleElecting();
+			pm.nextElectionInfo = nil;
+			pm.clock.AfterFunc(0, pm.scheduleElecting);
 			return;
 		}
 		bestNodeIdStr := common.Bytes2Hex(bestNodeId)
-		if !TestMode {
-			gap := int64(activeTime) - time.Now().Unix()
-			if gap > 2 || gap < -2 {
-				log.Warn(fmt.Sprintf("Scheduling of the new electing round is improper! current gap: %v seconds", gap))
-				//restart the scheduler
-				NextElectionInfo = nil;
-				go pm.scheduleElecting();
-				return;
-			}
+		gap := int64(activeTime) - pm.clock.Now().Unix()
+		if gap > 2 || gap < -2 {
+			log.Warn(fmt.Sprintf("Scheduling of the new electing round is improper! current gap: %v seconds", gap))
+			//restart the scheduler
+			pm.nextElectionInfo = nil;
+			pm.clock.AfterFunc(0, pm.scheduleElecting);
+			return;
 		}
-		round = NextElectionInfo.round + 1
-		LastElectedNodeId = bestNodeIdStr;
+		round = pm.nextElectionInfo.round + 1
+		pm.lastElectedNodeId = bestNodeIdStr;
 
 		log.Info(fmt.Sprintf("Confirmed the best election node: %v, activate the new round", bestNodeIdStr));
 
-		ElectionInfo0 = &ElectionInfo{
-			NextElectionInfo.round,
+		pm.electionInfo = &ElectionInfo{
+			pm.nextElectionInfo.round,
 			VOTESTATE_SELECTED,
 			tickets,
 			bestNodeIdStr,
 			bestNodeId,
 			activeTime,
-			NextElectionInfo.latestActiveENode,
-			NextElectionInfo.confirmedTickets,
-			NextElectionInfo.confirmedActiveTimes,
+			pm.nextElectionInfo.latestActiveENode,
+			pm.nextElectionInfo.confirmedTickets,
+			pm.nextElectionInfo.confirmedActiveTimes,
 		};
 	}
-	NextElectionInfo = &ElectionInfo{
+	pm.nextElectionInfo = &ElectionInfo{
 		round,
 		VOTESTATE_LOOKING,
-		uint32(rand.Intn(100)),
-		currNodeId,
-		currNodeIdHash,
-		uint64(time.Now().Unix() + int64(ElectingInterval)), //UTC time is an universe time. but we need an offset for different country, check here http://tutorials.jenkov.com/java-internationalization/time-zones.html
-		time.Now(),
+		uint32(pm.rand.Intn(100)),
+		pm.dposManager.nodeId,
+		pm.dposManager.nodeIdHash,
+		uint64(pm.clock.Now().Unix() + int64(ElectingInterval)), //UTC time is an universe time. but we need an offset for different country, check here http://tutorials.jenkov.com/java-internationalization/time-zones.html
+		pm.clock.Now(),
 		make(map[string]uint32),
 		make(map[string]uint64),
 	};
 	log.Info(fmt.Sprintf("Elect for next round %v...", round));
 	pm.electNodeSafely();
 
-	time.AfterFunc(time.Second * time.Duration(BNConflictInterval), pm.checkBestNodeConflict)
+	pm.clock.AfterFunc(time.Second * time.Duration(BNConflictInterval), pm.checkBestNodeConflict)
 }
 
-// this is a loop function for electing node.
+// this is a loop function for electing node, the lock must be held.
 func (pm *DVoteProtocolManager) electNodeSafely() {
-	switch NextElectionInfo.enodestate {
+	switch pm.nextElectionInfo.enodestate {
 	case VOTESTATE_STOP:
 		return;
 	case VOTESTATE_LOOKING:
 		{
-			if TestMode { return;}
 			// initialize the tickets with the number of all peers connected.
-			NextElectionInfo.latestActiveENode = time.Now();
-			if uint32(len(pm.ethManager.peers.peers)) == 0 {
+			pm.nextElectionInfo.latestActiveENode = pm.clock.Now();
+			peers := pm.ethManager.peers.Peers()
+			if len(peers) == 0 {
 				log.Debug("Looking for election node but no any peer found.");
 				// we choose rand number as the interval to reduce the conflict while electing.
-				time.AfterFunc(time.Second*time.Duration(rand.Intn(5)), pm.electNodeSafely);
+				pm.clock.AfterFunc(time.Second*time.Duration(pm.rand.Intn(5)), func() {
+					pm.lock.Lock()
+					defer pm.lock.Unlock()
+					if !pm.stopped() && pm.nextElectionInfo != nil {
+						pm.electNodeSafely();
+					}
+				});
 				return;
 			}
-			log.Debug("Start looking for election node with my tickets: " + strconv.Itoa(int(NextElectionInfo.electionTickets)) + " with round: " + strconv.Itoa(int(NextElectionInfo.round)));
-			for _, peer := range pm.ethManager.peers.peers {
-				err := peer.SendVoteElectionRequest(&VoteElectionRequest{NextElectionInfo.round,
-				NextElectionInfo.electionTickets, NextElectionInfo.activeTime, currNodeIdHash});
+			log.Debug("Start looking for election node with my tickets: " + strconv.Itoa(int(pm.nextElectionInfo.electionTickets)) + " with round: " + strconv.Itoa(int(pm.nextElectionInfo.round)));
+			for _, peer := range peers {
+				err := peer.SendVoteElectionRequest(&VoteElectionRequest{pm.nextElectionInfo.round,
+				pm.nextElectionInfo.electionTickets, pm.nextElectionInfo.activeTime, pm.dposManager.nodeIdHash});
 				if (err != nil) {
 					log.Warn("Error occurred while sending VoteElectionRequest: " + err.Error())
 				}
@@ -244,17 +275,29 @@ func (pm *DVoteProtocolManager) electNodeSafely() {
 }
 
 func (pm *DVoteProtocolManager) Stop() {
-	if DelegatorsTable != nil && len(DelegatorsTable) > 0 {
+	pm.lock.Lock()
+	if !pm.stopped() {
+		close(pm.quit)
+	}
+	if pm.t1 != nil {
+		pm.t1.Stop()
+	}
+	pm.lock.Unlock()
+
+	if pm.isDelegationActivated() {
 		pm.dposManager.Stop();
 	} else {
-		if ElectionInfo0 != nil {
-			ElectionInfo0.enodestate = VOTESTATE_STOP;
+		pm.lock.Lock()
+		if pm.electionInfo != nil {
+			pm.electionInfo.enodestate = VOTESTATE_STOP;
 		}
-		if NextElectionInfo != nil {
-			NextElectionInfo.enodestate = VOTESTATE_STOP;
+		if pm.nextElectionInfo != nil {
+			pm.nextElectionInfo.enodestate = VOTESTATE_STOP;
 		}
+		pm.lock.Unlock()
 		pm.packager.Stop();
 	}
+	pm.dposManager.stopFinality();
 	// Quit the sync loop.
 	log.Info("DPoS Voting Consensus stopped")
 }
@@ -262,10 +305,14 @@ func (pm *DVoteProtocolManager) Stop() {
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
 func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
+	// Pre-commit votes are independent of the election of the packaging node
+	if msg.Code == VOTE_PreCommit {
+		return pm.dposManager.handlePreCommit(msg, p)
+	}
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
-	if ElectionInfo0 != nil {
-		ElectionInfo0.latestActiveENode = time.Now();
+	if pm.electionInfo != nil {
+		pm.electionInfo.latestActiveENode = pm.clock.Now();
 	}
 	switch {
 	case msg.Code == VOTE_ElectionNode_Request:
@@ -273,24 +320,21 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 		if err := msg.Decode(&request); err != nil {
 			return errResp(DPOSErrDecode, "%v: %v", msg, err);
 		}
-		if NextElectionInfo == nil {//sometime happens
+		if pm.nextElectionInfo == nil {//sometime happens
 			return nil;
 		}
-		log.Debug(fmt.Sprintf("Received request round %v, nodeid %v, CurrRound %v", request.Round, common.Bytes2Hex(request.NodeId), NextElectionInfo.round));
-		if request.Round == NextElectionInfo.round {
-			if NextElectionInfo.enodestate == VOTESTATE_SELECTED {
-				log.Debug("I am in agreed state " + NextElectionInfo.electionNodeId);
-				if TestMode {
-					return nil;
-				}
+		log.Debug(fmt.Sprintf("Received request round %v, nodeid %v, CurrRound %v", request.Round, common.Bytes2Hex(request.NodeId), pm.nextElectionInfo.round));
+		if request.Round == pm.nextElectionInfo.round {
+			if pm.nextElectionInfo.enodestate == VOTESTATE_SELECTED {
+				log.Debug("I am in agreed state " + pm.nextElectionInfo.electionNodeId);
 				// update the best node.
 				nodeId := common.Bytes2Hex(request.NodeId[:8])
-				NextElectionInfo.confirmedTickets[nodeId] ++;
-				NextElectionInfo.confirmedActiveTimes[nodeId] = request.ActiveTime;
+				pm.nextElectionInfo.confirmedTickets[nodeId] ++;
+				pm.nextElectionInfo.confirmedActiveTimes[nodeId] = request.ActiveTime;
 				bestNodeId, tickets, activeTime := pm.getBestNodeInfo()
 				pm.setNextRoundTimer(activeTime);
 				return p.SendVoteElectionResponse(&VoteElectionResponse{
-					NextElectionInfo.round,
+					pm.nextElectionInfo.round,
 					tickets,
 					activeTime,
 					VOTESTATE_SELECTED,
@@ -298,32 +342,29 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 			} else {
 				// this comparision will decide who is the winner.
 				// remote win.
-				if request.Tickets > NextElectionInfo.electionTickets {
+				if request.Tickets > pm.nextElectionInfo.electionTickets {
 					//update the best counter.
 					nodeId := common.Bytes2Hex(request.NodeId[:8])
-					NextElectionInfo.confirmedTickets[nodeId] ++;
-					NextElectionInfo.confirmedActiveTimes[nodeId] = request.ActiveTime;
+					pm.nextElectionInfo.confirmedTickets[nodeId] ++;
+					pm.nextElectionInfo.confirmedActiveTimes[nodeId] = request.ActiveTime;
 
 					//this candidate have more broadcasting power.
-					log.Debug("Agreed the request node as the election node: " + NextElectionInfo.electionNodeId);
+					log.Debug("Agreed the request node as the election node: " + pm.nextElectionInfo.electionNodeId);
 				} else {
 					//update the best counter
-					NextElectionInfo.confirmedTickets[currNodeId] ++;
-					NextElectionInfo.confirmedActiveTimes[currNodeId] = NextElectionInfo.activeTime;
-					log.Debug("I win！ " + currNodeId);
+					pm.nextElectionInfo.confirmedTickets[pm.dposManager.nodeId] ++;
+					pm.nextElectionInfo.confirmedActiveTimes[pm.dposManager.nodeId] = pm.nextElectionInfo.activeTime;
+					log.Debug("I win！ " + pm.dposManager.nodeId);
 					// I win. the remote loses broadcasting power.
 				}
 				//update current state
-				NextElectionInfo.enodestate = VOTESTATE_SELECTED;
-				if TestMode {
-					return nil;
-				}
+				pm.nextElectionInfo.enodestate = VOTESTATE_SELECTED;
 				bestNodeId, tickets, activeTime := pm.getBestNodeInfo()
 				pm.setNextRoundTimer(activeTime);//sync the timer.
 				// broadcast it to all peers again.
-				for _, peer := range pm.ethManager.peers.peers {
+				for _, peer := range pm.ethManager.peers.Peers() {
 					err := peer.SendBroadcastVotedElection(&BroadcastVotedElection{
-						NextElectionInfo.round,
+						pm.nextElectionInfo.round,
 						tickets,
 						activeTime,
 						VOTESTATE_SELECTED,
@@ -334,20 +375,17 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 					}
 				}
 			}
-		} else if request.Round < NextElectionInfo.round {
-			log.Debug(fmt.Sprintf("Mismatched request.round %v, CurrRound %v ", request.Round, NextElectionInfo.round))
-			if TestMode {
-				return nil;
-			}
+		} else if request.Round < pm.nextElectionInfo.round {
+			log.Debug(fmt.Sprintf("Mismatched request.round %v, CurrRound %v ", request.Round, pm.nextElectionInfo.round))
 			bestNodeId, tickets, activeTime := pm.getBestNodeInfo()
 			return p.SendVoteElectionResponse(&VoteElectionResponse{
-				NextElectionInfo.round,
+				pm.nextElectionInfo.round,
 				tickets,
 				activeTime,
 				VOTESTATE_MISMATCHED_ROUND,
 				bestNodeId});
-		} else if request.Round > NextElectionInfo.round {
-			if (request.Round - NextElectionInfo.round) == 1 {
+		} else if request.Round > pm.nextElectionInfo.round {
+			if (request.Round - pm.nextElectionInfo.round) == 1 {
 				// the most reason could be the round timeframe switching later than this request.
 				// but we are continue switching as regular.
 			} else {
@@ -361,21 +399,20 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 		if err := msg.Decode(&response); err != nil {
 			return errResp(DPOSErrDecode, "%v: %v", msg, err);
 		}
-		if NextElectionInfo == nil {//sometime happens
+		if pm.nextElectionInfo == nil {//sometime happens
 			return nil;
 		}
 		log.Debug("Received a voted response: " + common.Bytes2Hex(response.ElectionNodeId));
-		if response.State == VOTESTATE_SELECTED && NextElectionInfo.round == response.Round {
+		if response.State == VOTESTATE_SELECTED && pm.nextElectionInfo.round == response.Round {
 			nodeId := common.Bytes2Hex(response.ElectionNodeId)
-			NextElectionInfo.confirmedTickets[nodeId] ++;
-			NextElectionInfo.confirmedActiveTimes[nodeId] = response.ActiveTime;
+			pm.nextElectionInfo.confirmedTickets[nodeId] ++;
+			pm.nextElectionInfo.confirmedActiveTimes[nodeId] = response.ActiveTime;
 
 			bestNodeId, tickets, activeTime := pm.getBestNodeInfo();
 			pm.setNextRoundTimer(activeTime);
-			if TestMode { return nil;}
-			for _, peer := range pm.ethManager.peers.peers {
+			for _, peer := range pm.ethManager.peers.Peers() {
 				err := peer.SendBroadcastVotedElection(&BroadcastVotedElection{
-					NextElectionInfo.round,
+					pm.nextElectionInfo.round,
 					tickets,
 					activeTime,
 					VOTESTATE_SELECTED,
@@ -386,17 +423,17 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 				}
 			}
 			return nil;
-		} else if response.State == VOTESTATE_MISMATCHED_ROUND && NextElectionInfo.enodestate == VOTESTATE_LOOKING {
-			log.Info(fmt.Sprintf("Mismatched round %v, switch to %v and then refresh again", NextElectionInfo.round, response.Round))
+		} else if response.State == VOTESTATE_MISMATCHED_ROUND && pm.nextElectionInfo.enodestate == VOTESTATE_LOOKING {
+			log.Info(fmt.Sprintf("Mismatched round %v, switch to %v and then refresh again", pm.nextElectionInfo.round, response.Round))
 			// update round and resend
-			NextElectionInfo = &ElectionInfo{
+			pm.nextElectionInfo = &ElectionInfo{
 				response.Round,
 				VOTESTATE_LOOKING,
 				0,
-				currNodeId,
-				currNodeIdHash,
+				pm.dposManager.nodeId,
+				pm.dposManager.nodeIdHash,
 				response.ActiveTime,
-				time.Now(),
+				pm.clock.Now(),
 				make(map[string]uint32),
 				make(map[string]uint64),
 			};
@@ -408,15 +445,14 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 		if err := msg.Decode(&response); err != nil {
 			return errResp(DPOSErrDecode, "%v: %v", msg, err);
 		}
-		if NextElectionInfo == nil || response.Round != NextElectionInfo.round {
+		if pm.nextElectionInfo == nil || response.Round != pm.nextElectionInfo.round {
 			return nil;
 		}
 		// simply response the best node.
 		bestNodeId, tickets, activeTime := pm.getBestNodeInfo()
 		pm.setNextRoundTimer(activeTime);
-		if TestMode { return nil;}
 		return p.SendVoteElectionResponse(&VoteElectionResponse{
-			NextElectionInfo.round,
+			pm.nextElectionInfo.round,
 			tickets,
 			activeTime,
 			VOTESTATE_SELECTED,
@@ -427,62 +463,62 @@ func (pm *DVoteProtocolManager) handleMsg(msg *p2p.Msg, p *peer) error {
 		if err := msg.Decode(&response); err != nil {
 			return errResp(DPOSErrDecode, "%v: %v", msg, err);
 		}
-		if NextElectionInfo == nil || response.Round != NextElectionInfo.round {
+		if pm.nextElectionInfo == nil || response.Round != pm.nextElectionInfo.round {
 			return nil;
 		}
 		nodeId := common.Bytes2Hex(response.ElectionNodeId)
 		log.Debug("Received broadcast message: " + nodeId);
 		// just calculate the voted tickets.
-		NextElectionInfo.confirmedTickets[nodeId] ++;
-		NextElectionInfo.confirmedActiveTimes[nodeId] = response.ActiveTime;
+		pm.nextElectionInfo.confirmedTickets[nodeId] ++;
+		pm.nextElectionInfo.confirmedActiveTimes[nodeId] = response.ActiveTime;
 
 		bestNodeId, _, activeTime := pm.getBestNodeInfo();
-		if NextElectionInfo.enodestate == VOTESTATE_SELECTED {
+		if pm.nextElectionInfo.enodestate == VOTESTATE_SELECTED {
 			// check who is the final elected node.
-			if !reflect.DeepEqual(NextElectionInfo.electionNodeIdHash, bestNodeId) {
+			if !reflect.DeepEqual(pm.nextElectionInfo.electionNodeIdHash, bestNodeId) {
 				log.Info(fmt.Sprintf("Switched to the best election node: %v", common.Bytes2Hex(bestNodeId)));
-				NextElectionInfo.electionNodeId = common.Bytes2Hex(bestNodeId);
-				NextElectionInfo.electionNodeIdHash = bestNodeId;
-				NextElectionInfo.activeTime = activeTime;
+				pm.nextElectionInfo.electionNodeId = common.Bytes2Hex(bestNodeId);
+				pm.nextElectionInfo.electionNodeIdHash = bestNodeId;
+				pm.nextElectionInfo.activeTime = activeTime;
 				pm.setNextRoundTimer(activeTime);
 			}
-		} else if NextElectionInfo.enodestate == VOTESTATE_LOOKING { //&& maxTickets > uint32(len(pm.ethManager.peers.peers))
-			NextElectionInfo.enodestate = VOTESTATE_SELECTED;
-			NextElectionInfo.electionNodeId = common.Bytes2Hex(bestNodeId);
-			NextElectionInfo.electionNodeIdHash = bestNodeId;
-			NextElectionInfo.activeTime = activeTime;
+		} else if pm.nextElectionInfo.enodestate == VOTESTATE_LOOKING { //&& maxTickets > uint32(len(pm.ethManager.peers.peers))
+			pm.nextElectionInfo.enodestate = VOTESTATE_SELECTED;
+			pm.nextElectionInfo.electionNodeId = common.Bytes2Hex(bestNodeId);
+			pm.nextElectionInfo.electionNodeIdHash = bestNodeId;
+			pm.nextElectionInfo.activeTime = activeTime;
 			pm.setNextRoundTimer(activeTime);
 		}
 
 		return nil;
 	default:
-		return pm.dposManager.handleMsg(msg, p)
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
 	return nil
 }
 
 func (pm *DVoteProtocolManager) getBestNodeInfo() ([]byte, uint32, uint64) {
-	if len(NextElectionInfo.confirmedTickets) == 0 {
-		return NextElectionInfo.electionNodeIdHash, NextElectionInfo.electionTickets, NextElectionInfo.activeTime;
+	if len(pm.nextElectionInfo.confirmedTickets) == 0 {
+		return pm.nextElectionInfo.electionNodeIdHash, pm.nextElectionInfo.electionTickets, pm.nextElectionInfo.activeTime;
 	}
 	maxTickets, bestNodeId := uint32(0), "";
-	for key, value := range NextElectionInfo.confirmedTickets {
+	for key, value := range pm.nextElectionInfo.confirmedTickets {
 		if maxTickets < value {
 			maxTickets = value;
 			bestNodeId = key;
 			// if there are more than two items with the same tickets, lets handle it in getBestNodeInfo2.
 		}
 	}
-	return common.Hex2Bytes(bestNodeId), maxTickets, NextElectionInfo.confirmedActiveTimes[bestNodeId];
+	return common.Hex2Bytes(bestNodeId), maxTickets, pm.nextElectionInfo.confirmedActiveTimes[bestNodeId];
 }
 
 // get the best node without confliction
 func (pm *DVoteProtocolManager) getBestNodeInfo2() ([]byte, uint32, uint64) {
-	if len(NextElectionInfo.confirmedTickets) == 0 {
-		return NextElectionInfo.electionNodeIdHash, NextElectionInfo.electionTickets, NextElectionInfo.activeTime;
+	if len(pm.nextElectionInfo.confirmedTickets) == 0 {
+		return pm.nextElectionInfo.electionNodeIdHash, pm.nextElectionInfo.electionTickets, pm.nextElectionInfo.activeTime;
 	}
 	maxTickets, bestNodeId := uint32(0), "";
-	for key, value := range NextElectionInfo.confirmedTickets {
+	for key, value := range pm.nextElectionInfo.confirmedTickets {
 		if maxTickets < value {
 			maxTickets = value;
 			bestNodeId = key;
@@ -490,7 +526,7 @@ func (pm *DVoteProtocolManager) getBestNodeInfo2() ([]byte, uint32, uint64) {
 	}
 
 	counter := 0
-	for _, value := range NextElectionInfo.confirmedTickets {
+	for _, value := range pm.nextElectionInfo.confirmedTickets {
 		if maxTickets == value {
 			counter ++;
 		}
@@ -499,40 +535,45 @@ func (pm *DVoteProtocolManager) getBestNodeInfo2() ([]byte, uint32, uint64) {
 	if counter > 1 {
 		return nil, 0, 0
 	}
-	return common.Hex2Bytes(bestNodeId), maxTickets, NextElectionInfo.confirmedActiveTimes[bestNodeId];
+	return common.Hex2Bytes(bestNodeId), maxTickets, pm.nextElectionInfo.confirmedActiveTimes[bestNodeId];
 }
 
 func (pm *DVoteProtocolManager) checkBestNodeConflict() {
-	if len(NextElectionInfo.confirmedTickets) > 1 {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	if pm.stopped() || pm.nextElectionInfo == nil {
+		return;
+	}
+	if len(pm.nextElectionInfo.confirmedTickets) > 1 {
 		_, tickets, _ := pm.getBestNodeInfo2()
 		if tickets == 0 {
 			log.Warn("Selecting of the best node is conflicted, sync from peers again.")
 			// sync from peers.
-			for _, peer := range pm.ethManager.peers.peers {
-				err := peer.SendBestNodeConflict(&BroadcastBestNodeConflict{NextElectionInfo.round,
-					1, NextElectionInfo.activeTime, currNodeIdHash});
+			for _, peer := range pm.ethManager.peers.Peers() {
+				err := peer.SendBestNodeConflict(&BroadcastBestNodeConflict{pm.nextElectionInfo.round,
+					1, pm.nextElectionInfo.activeTime, pm.dposManager.nodeIdHash});
 				if (err != nil) {
 					log.Warn("Error occurred while sending VoteElectionRequest: " + err.Error())
 				}
 			}
-			time.AfterFunc(time.Second * time.Duration(BNConflictInterval), pm.checkBestNodeConflict);
+			pm.clock.AfterFunc(time.Second * time.Duration(BNConflictInterval), pm.checkBestNodeConflict);
 		}
 	}
 
 }
 
 func (pm *DVoteProtocolManager) setNextRoundTimer(bestActiveTime uint64) {
-	leftTime := int64(bestActiveTime) - time.Now().Unix()
+	leftTime := int64(bestActiveTime) - pm.clock.Now().Unix()
 	if leftTime < 1 {
 		log.Warn("Discard this round due to the expiration of the active time. reschedule it.")
-		go pm.scheduleElecting();
+		pm.clock.AfterFunc(0, pm.scheduleElecting);
 		return;
 	}
 	if pm.t1 != nil {
 		pm.t1.Stop() // potentially could be an issue if the timer is unable to be cancelled.
-		log.Debug(fmt.Sprintf("rescheduled for next round %v in %v seconds", NextElectionInfo.round+1, leftTime))
+		log.Debug(fmt.Sprintf("rescheduled for next round %v in %v seconds", pm.nextElectionInfo.round+1, leftTime))
 	} else {
-		log.Debug(fmt.Sprintf("scheduled for next round %v in %v seconds", NextElectionInfo.round+1, leftTime))
+		log.Debug(fmt.Sprintf("scheduled for next round %v in %v seconds", pm.nextElectionInfo.round+1, leftTime))
 	}
-	pm.t1 = time.AfterFunc(time.Second*time.Duration(leftTime), pm.scheduleElecting)
+	pm.t1 = pm.clock.AfterFunc(time.Second*time.Duration(leftTime), pm.scheduleElecting)
 }
\ No newline at end of file
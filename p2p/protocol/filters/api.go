@@ -38,6 +38,8 @@ var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
 )
 
+var errPendingDAppLogs = errors.New("DApp chains have no pending logs")
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -59,6 +61,9 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+
+	dappEventsMu sync.Mutex
+	dappEvents   map[common.Address]*EventSystem // Event systems following the DApp chains
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -69,12 +74,66 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
+
+		dappEvents: make(map[common.Address]*EventSystem),
 	}
 	go api.timeoutLoop()
 
 	return api
 }
 
+// filterBackend returns the backend of the chain the logs of a DApp are filtered
+// from, or the backend of the main chain if no DApp is given, along with the
+// function releasing it.
+func (api *PublicFilterAPI) filterBackend(dappId *common.Address) (Backend, func(), error) {
+	if dappId == nil {
+		return api.backend, func() {}, nil
+	}
+	return api.backend.DAppFilterBackend(*dappId)
+}
+
+// eventSystem returns the event system following the chain the criteria select.
+// The event system of a DApp chain is created on first use, and again after the
+// DApp chain is reattached.
+func (api *PublicFilterAPI) eventSystem(crit FilterCriteria) (*EventSystem, error) {
+	if crit.DAppId == nil {
+		return api.events, nil
+	}
+	pending := rpc.PendingBlockNumber.Int64()
+	if (crit.FromBlock != nil && crit.FromBlock.Int64() == pending) || (crit.ToBlock != nil && crit.ToBlock.Int64() == pending) {
+		return nil, errPendingDAppLogs
+	}
+	backend, release, err := api.backend.DAppFilterBackend(*crit.DAppId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	api.dappEventsMu.Lock()
+	defer api.dappEventsMu.Unlock()
+
+	if events, ok := api.dappEvents[*crit.DAppId]; ok && events.backend.ChainDb() == backend.ChainDb() {
+		return events, nil
+	}
+	events := NewEventSystem(api.mux, backend, api.events.lightMode)
+	api.dappEvents[*crit.DAppId] = events
+	return events, nil
+}
+
+// StopDAppEvents stops the event system following the chain of a detached DApp,
+// uninstalling the filters and subscriptions created on it. The event system is
+// created anew if the DApp chain is reattached.
+func StopDAppEvents(api *PublicFilterAPI, dappId common.Address) {
+	api.dappEventsMu.Lock()
+	events, ok := api.dappEvents[dappId]
+	delete(api.dappEvents, dappId)
+	api.dappEventsMu.Unlock()
+
+	if ok {
+		events.Stop()
+	}
+}
+
 // timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
 // Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
@@ -236,12 +295,16 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
+	events, err := api.eventSystem(crit)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
 	)
 
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	logsSub, err := events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
 	if err != nil {
 		return nil, err
 	}
@@ -260,6 +323,8 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 			case <-notifier.Closed(): // connection dropped
 				logsSub.Unsubscribe()
 				return
+			case <-logsSub.Err(): // DApp chain detached
+				return
 			}
 		}
 	}()
@@ -275,6 +340,7 @@ type FilterCriteria struct {
 	ToBlock   *big.Int
 	Addresses []common.Address
 	Topics    [][]common.Hash
+	DAppId    *common.Address
 }
 
 // NewFilter creates a new filter and returns the filter id. It can be
@@ -289,10 +355,17 @@ type FilterCriteria struct {
 //
 // In case "fromBlock" > "toBlock" an error is returned.
 //
+// If "dappId" is given the filter follows the chain of that DApp, which has no
+// pending logs.
+//
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter
 func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+	events, err := api.eventSystem(crit)
+	if err != nil {
+		return rpc.ID(""), err
+	}
 	logs := make(chan []*types.Log)
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
+	logsSub, err := events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
 	if err != nil {
 		return rpc.ID(""), err
 	}
@@ -334,7 +407,13 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		crit.ToBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
 	}
 	// Create and run the filter to get all the logs
-	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
+	backend, release, err := api.filterBackend(crit.DAppId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	filter := New(backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -382,7 +461,13 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		end = f.crit.ToBlock.Int64()
 	}
 	// Create and run the filter to get all the logs
-	filter := New(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+	backend, release, err := api.filterBackend(f.crit.DAppId)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	filter := New(backend, begin, end, f.crit.Addresses, f.crit.Topics)
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -450,6 +535,7 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		ToBlock   *rpc.BlockNumber `json:"toBlock"`
 		Addresses interface{}      `json:"address"`
 		Topics    []interface{}    `json:"topics"`
+		DAppId    *common.Address  `json:"dappId"`
 	}
 
 	var raw input
@@ -465,6 +551,8 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		args.ToBlock = big.NewInt(raw.ToBlock.Int64())
 	}
 
+	args.DAppId = raw.DAppId
+
 	args.Addresses = []common.Address{}
 
 	if raw.Addresses != nil {
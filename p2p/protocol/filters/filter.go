@@ -43,6 +43,8 @@ type Backend interface {
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	DAppFilterBackend(dappId common.Address) (Backend, func(), error) // Backend of a DApp chain, to be released after use
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -102,12 +104,27 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
-	if f.begin == -1 {
+	// Resolve the finalized tag only if the range actually refers to it
+	var finalized uint64
+	if f.begin == rpc.FinalizedBlockNumber.Int64() || f.end == rpc.FinalizedBlockNumber.Int64() {
+		header, _ := f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+		if header == nil {
+			return nil, nil
+		}
+		finalized = header.Number.Uint64()
+	}
+	switch f.begin {
+	case rpc.LatestBlockNumber.Int64():
 		f.begin = int64(head)
+	case rpc.FinalizedBlockNumber.Int64():
+		f.begin = int64(finalized)
 	}
 	end := uint64(f.end)
-	if f.end == -1 {
+	switch f.end {
+	case rpc.LatestBlockNumber.Int64():
 		end = head
+	case rpc.FinalizedBlockNumber.Int64():
+		end = finalized
 	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
@@ -93,6 +93,8 @@ type EventSystem struct {
 	lastHead  *types.Header
 	install   chan *subscription // install filter for event notification
 	uninstall chan *subscription // remove filter for event notification
+	quit      chan struct{}      // closed when the event system is stopped
+	stopOnce  sync.Once
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -108,6 +110,7 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 		lightMode: lightMode,
 		install:   make(chan *subscription),
 		uninstall: make(chan *subscription),
+		quit:      make(chan struct{}),
 	}
 
 	go m.eventLoop()
@@ -115,6 +118,13 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 	return m
 }
 
+// Stop terminates the work loop of the event system, uninstalling all of its
+// subscriptions. Subscribing to a stopped event system yields subscriptions that
+// are uninstalled right away.
+func (es *EventSystem) Stop() {
+	es.stopOnce.Do(func() { close(es.quit) })
+}
+
 // Subscription is created when the client registers itself for a particular event.
 type Subscription struct {
 	ID        rpc.ID
@@ -140,6 +150,8 @@ func (sub *Subscription) Unsubscribe() {
 			select {
 			case sub.es.uninstall <- sub.f:
 				break uninstallLoop
+			case <-sub.es.quit:
+				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
@@ -155,8 +167,13 @@ func (sub *Subscription) Unsubscribe() {
 
 // subscribe installs the subscription in the event broadcast loop.
 func (es *EventSystem) subscribe(sub *subscription) *Subscription {
-	es.install <- sub
-	<-sub.installed
+	select {
+	case es.install <- sub:
+		<-sub.installed
+	case <-es.quit:
+		close(sub.installed)
+		close(sub.err)
+	}
 	return &Subscription{ID: sub.id, f: sub, es: es}
 }
 
@@ -477,6 +494,17 @@ func (es *EventSystem) eventLoop() {
 			close(f.err)
 
 		// System stopped
+		case <-es.quit:
+			uninstalled := make(map[rpc.ID]bool)
+			for _, subs := range index {
+				for id, f := range subs {
+					if !uninstalled[id] {
+						uninstalled[id] = true
+						close(f.err)
+					}
+				}
+			}
+			return
 		case <-txSub.Err():
 			return
 		case <-rmLogsSub.Err():
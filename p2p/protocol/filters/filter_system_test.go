@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -57,10 +58,14 @@ func (b *testBackend) EventMux() *event.TypeMux {
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var hash common.Hash
 	var num uint64
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		hash = core.GetHeadBlockHash(b.db)
 		num = core.GetBlockNumber(b.db, hash)
-	} else {
+	case rpc.FinalizedBlockNumber:
+		hash = core.GetFinalizedHash(b.db)
+		num = core.GetBlockNumber(b.db, hash)
+	default:
 		num = uint64(blockNr)
 		hash = core.GetCanonicalHash(b.db, num)
 	}
@@ -99,6 +104,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) DAppFilterBackend(dappId common.Address) (Backend, func(), error) {
+	return nil, nil, errors.New("no DApp chains")
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return config.BloomBitsBlocks, b.sections
 }
@@ -569,3 +578,57 @@ func TestPendingLogsSubscription(t *testing.T) {
 		}
 	}
 }
+
+// dappTestBackend is a test backend resolving the chain of a single DApp.
+type dappTestBackend struct {
+	*testBackend
+	dapp *testBackend
+}
+
+func (b *dappTestBackend) DAppFilterBackend(dappId common.Address) (Backend, func(), error) {
+	return b.dapp, func() {}, nil
+}
+
+// TestStopDAppEvents tests that stopping the events of a detached DApp uninstalls
+// its filters and unsubscribes from the DApp chain.
+func TestStopDAppEvents(t *testing.T) {
+	t.Parallel()
+
+	newBackend := func(mux *event.TypeMux) *testBackend {
+		db, _ := store.NewMemDatabase()
+		return &testBackend{mux, db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+	}
+	var (
+		mux     = new(event.TypeMux)
+		dapp    = newBackend(mux)
+		backend = &dappTestBackend{newBackend(mux), dapp}
+		api     = NewPublicFilterAPI(backend, false)
+		dappId  = common.HexToAddress("0xdc")
+	)
+	id, err := api.NewFilter(FilterCriteria{DAppId: &dappId})
+	if err != nil {
+		t.Fatalf("failed to create DApp filter: %v", err)
+	}
+	if _, err := api.GetFilterChanges(id); err != nil {
+		t.Fatalf("DApp filter not installed: %v", err)
+	}
+	StopDAppEvents(api, dappId)
+
+	for i := 0; ; i++ {
+		_, err := api.GetFilterChanges(id)
+		if err != nil && dapp.logsFeed.Send([]*types.Log{}) == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("DApp filter still installed after stopping its events")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Reattaching the DApp follows its chain anew
+	if id, err = api.NewFilter(FilterCriteria{DAppId: &dappId}); err != nil {
+		t.Fatalf("failed to recreate DApp filter: %v", err)
+	}
+	if _, err := api.GetFilterChanges(id); err != nil {
+		t.Fatalf("recreated DApp filter not installed: %v", err)
+	}
+}
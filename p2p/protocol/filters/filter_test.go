@@ -30,6 +30,7 @@ import (
 	"github.com/juchain/go-juchain/core/store"
 	"github.com/juchain/go-juchain/common/event"
 	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -239,4 +240,25 @@ func TestFilters(t *testing.T) {
 	if len(logs) != 0 {
 		t.Error("expected 0 log, got", len(logs))
 	}
+
+	// Finalized ranges resolve against the finalized block, not the head
+	if err := core.WriteFinalizedHash(db, chain[9].Hash()); err != nil {
+		t.Fatal("error writing finalized hash:", err)
+	}
+	filter = New(backend, 0, rpc.FinalizedBlockNumber.Int64(), []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log, got", len(logs))
+	}
+
+	filter = New(backend, rpc.FinalizedBlockNumber.Int64(), -1, []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log, got", len(logs))
+	}
+	if len(logs) > 0 && logs[0].Topics[0] != hash3 {
+		t.Errorf("expected log[0].Topics[0] to be %x, got %x", hash3, logs[0].Topics[0])
+	}
 }
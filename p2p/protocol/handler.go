@@ -29,6 +29,8 @@ import (
 
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/consensus"
+	"github.com/juchain/go-juchain/consensus/dpos"
+	"github.com/juchain/go-juchain/consensus/solo"
 	"github.com/juchain/go-juchain/core"
 	"github.com/juchain/go-juchain/core/types"
 	"github.com/juchain/go-juchain/p2p/protocol/downloader"
@@ -41,7 +43,6 @@ import (
 	"github.com/juchain/go-juchain/config"
 	"github.com/juchain/go-juchain/common/rlp"
 	"github.com/juchain/go-juchain/p2p/node"
-	"bytes"
 )
 
 const (
@@ -73,6 +74,7 @@ type ProtocolManager struct {
 
 	txpool      txPool
 	blockchain  *core.BlockChain
+	dapps       dappChainReader // DApp chains replicated by the node
 	chainconfig *config.ChainConfig
 	maxPeers    int
 
@@ -83,7 +85,9 @@ type ProtocolManager struct {
 
 	SubProtocols []p2p.Protocol
 
-	dposManager *DVoteProtocolManager
+	dposManager   *DVoteProtocolManager  // nil if the chain is not run by the dpos engine
+	cliqueManager *CliqueProtocolManager // nil if the chain is not run by the clique engine
+	evidence      *dpos.EvidencePool     // nil if the chain is not run by the dpos engine
 
 	eventMux      *event.TypeMux
 	txCh          chan core.TxPreEvent
@@ -110,19 +114,26 @@ func NewProtocolManager(eth *JuchainService, config *config.ChainConfig, config2
 		eventMux:    mux,
 		txpool:      txpool,
 		blockchain:  blockchain,
+		dapps:       eth,
 		chainconfig: config,
 		backend:     eth.ApiBackend,
+		evidence:    eth.evidence,
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	};
-	manager0,err0 := NewDVoteProtocolManager(eth, manager, config, config2, mode, networkId, blockchain, engine);
-	if err0 != nil {
-		return nil, err0;
+	// Proof-of-authority chains are sealed by the signers instead of the delegators
+	if signer, ok := engine.(*clique.Clique); ok {
+		manager.cliqueManager = NewCliqueProtocolManager(eth, config, signer)
+	} else {
+		manager0,err0 := NewDVoteProtocolManager(eth, manager, config, config2, mode, networkId, blockchain, engine);
+		if err0 != nil {
+			return nil, err0;
+		}
+		manager.dposManager = manager0;
 	}
-	manager.dposManager = manager0;
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
@@ -139,7 +150,7 @@ func NewProtocolManager(eth *JuchainService, config *config.ChainConfig, config2
 		manager.SubProtocols = append(manager.SubProtocols, p2p.Protocol{
 			Name:    ProtocolName,
 			Version: version,
-			Length:  SYNC_BIGPERIOD_RESPONSE, // the maxinum number of all messages.
+			Length:  VOTE_PreCommit + 1, // the maxinum number of all messages.
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 				peer := manager.newPeer(version, p, rw)
 				select {
@@ -224,13 +235,23 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	go pm.syncer()
 	go pm.txsyncLoop()
 
-	pm.dposManager.Start(maxPeers)
+	if pm.dposManager != nil {
+		pm.dposManager.Start(maxPeers)
+	}
+	if pm.cliqueManager != nil {
+		pm.cliqueManager.Start()
+	}
 }
 
 func (pm *ProtocolManager) Stop() {
 	log.Info("Stopping P2P protocol")
 
-	pm.dposManager.Stop()
+	if pm.dposManager != nil {
+		pm.dposManager.Stop()
+	}
+	if pm.cliqueManager != nil {
+		pm.cliqueManager.Stop()
+	}
 	pm.txSub.Unsubscribe()         // quits txBroadcastLoop
 	pm.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
 
@@ -274,7 +295,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), config.DAppAddresses.List()); err != nil {
 		p.Log().Info("P2P handshake failed", "err", err)
 		return err
 	}
@@ -296,6 +317,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	// after this will be sent via broadcasts.
 	pm.syncTransactions(p)
 
+	// Catch up on the DApp chains replicated by the peer group as well
+	pm.syncDApps(p)
+
 	// main loop. handle incoming messages.
 	for {
 		if err := pm.handleMsg(p); err != nil {
@@ -319,6 +343,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	defer msg.Discard()
 
 	if msg.Code >= VOTE_ElectionNode_Request {
+		// Voting messages are meaningless to chains not run by the delegators
+		if pm.dposManager == nil {
+			return nil
+		}
 		if err := pm.dposManager.handleMsg(&msg, p); err != nil {
 			p.Log().Warn("DPoS message handling failed", "err", err)
 			return err
@@ -416,6 +444,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&headers); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if pm.evidence != nil {
+			for _, header := range headers {
+				pm.evidence.Observe(header)
+			}
+		}
 		// Filter out any explicitly requested headers, deliver the rest to the downloader
 		filter := len(headers) == 1
 		if filter {
@@ -596,9 +629,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		request.Block.ReceivedAt = msg.ReceivedAt
 		request.Block.ReceivedFrom = p
 
-		// if dpos delegator is activated.
-		if GigPeriodInstance != nil && GigPeriodInstance.wasHisTurn(request.Block.Round(), request.Block.PesidentID(), request.Block.Time().Int64()) {
-			return errResp(ErrInvalidTimestamp, "");
+		// Conflicting blocks of a delegator are evidence whether imported or not
+		if pm.evidence != nil {
+			pm.evidence.Observe(request.Block.Header())
 		}
 
 		// Mark the peer as owning the block and schedule it for import
@@ -654,15 +687,69 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		for i, tx := range txs {
 			// Validate and mark the remote transaction
-			if tx == nil || tx.DAppID() == types.EmptyDAppIdHash || tx.RefHashId() == types.EmptyHash {
+			if tx == nil || tx.DAppID() == nil || tx.RefHashId() == nil {
 				return errResp(ErrDecode, "dapp transaction %d is nil", i)
 			}
-			if !config.DAppAddresses.Has(tx.DAppID()) {
-				return errResp(ErrDecode, "dapp transaction %d is not acceptable by current node.", i)
-			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		// Relay the new transactions inside the peer group of their DApps. The pool
+		// rejects unsigned and replayed ones, which are not relayed any further
+		for i, err := range pm.txpool.AddDAppTransactions(txs) {
+			if err != nil {
+				p.Log().Trace("Rejected DApp transaction", "hash", txs[i].Hash(), "err", err)
+				continue
+			}
+			pm.BroadcastDAppTx(txs[i].Hash(), txs[i])
+		}
+
+	case msg.Code == NewDAppBlockMsg:
+		// Retrieve and decode the propagated DApp block
+		var block types.Block
+		if err := msg.Decode(&block); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if block.DAppID() == *types.EmptyDAppIdHash {
+			return errResp(ErrDecode, "main chain block %x propagated as DApp block", block.Hash())
+		}
+		block.ReceivedAt = msg.ReceivedAt
+		block.ReceivedFrom = p
+
+		p.MarkBlock(block.Hash())
+		pm.importDAppBlock(p, &block)
+
+	case msg.Code == GetDAppBlocksMsg:
+		// Decode the DApp block query
+		var query getDAppBlocksData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		// Gather blocks until the fetch or network limits is reached
+		var (
+			bytes  common.StorageSize
+			blocks []*types.Block
+		)
+		if chain := pm.dappChain(query.DAppId); chain != nil {
+			for number := query.Origin; len(blocks) < int(query.Amount) && len(blocks) < maxDAppBlockFetch && bytes < softResponseLimit; number++ {
+				block := chain.GetBlockByNumber(number)
+				if block == nil {
+					break
+				}
+				blocks = append(blocks, block)
+				bytes += block.Size()
+			}
+		}
+		return p.SendDAppBlocks(query.DAppId, blocks)
+
+	case msg.Code == DAppBlocksMsg:
+		// A batch of DApp blocks arrived to one of our previous requests
+		var response dappBlocksData
+		if err := msg.Decode(&response); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, block := range response.Blocks {
+			p.MarkBlock(block.Hash())
+		}
+		pm.deliverDAppBlocks(p, response.DAppId, response.Blocks)
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -674,6 +761,18 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
+
+	// DApp blocks are only propagated inside the peer group of their DApp
+	if dappId := block.DAppID(); dappId != *types.EmptyDAppIdHash {
+		if propagate {
+			peers := pm.peers.DAppPeersWithoutBlock(dappId, hash)
+			for _, peer := range peers {
+				peer.SendNewDAppBlock(block)
+			}
+			log.Trace("Propagated DApp block", "dapp", dappId, "hash", hash, "recipients", len(peers))
+		}
+		return
+	}
 	peers := pm.peers.PeersWithoutBlock(hash)
 
 	// If propagation is requested, send to a subset of the peer
@@ -687,20 +786,12 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			return
 		}
 
-		if !bytes.Equal(block.DAppID().Bytes(), types.EmptyDAppIdHash.Bytes()) {
-			for i := range config.DAppAddresses.Addresse {
-				//TODO: only send to DApp assigned P2P Group.
-				addr := config.DAppAddresses.Addresse[i]
-				log.Trace(addr.String())
-			}
-		} else {
-			// Send the block to a subset of our peers
-			transfer := peers[:int(math.Sqrt(float64(len(peers))))]
-			for _, peer := range transfer {
-				peer.SendNewBlock(block, td)
-			}
-			log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
+		// Send the block to a subset of our peers
+		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
+		for _, peer := range transfer {
+			peer.SendNewBlock(block, td)
 		}
+		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 		return
 	}
 	// Otherwise if the block is indeed in out own chain, announce it
@@ -722,6 +813,22 @@ func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction)
 		peer.SendTransactions(types.Transactions{tx})
 	}
 	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
+
+	// The DApp part of local DApp transactions only goes to the DApp's peer group
+	if dappTx := tx.DAppTx(); dappTx != nil {
+		pm.BroadcastDAppTx(dappTx.Hash(), dappTx)
+	}
+}
+
+// BroadcastDAppTx will propagate a DApp transaction to all peers of its DApp's
+// peer group which are not known to already have the given transaction.
+func (pm *ProtocolManager) BroadcastDAppTx(hash common.Hash, tx *types.Transaction) {
+	dappId := *tx.DAppID()
+	peers := pm.peers.DAppPeersWithoutTx(dappId, hash)
+	for _, peer := range peers {
+		peer.SendDAppTransactions(types.Transactions{tx})
+	}
+	log.Trace("Broadcast DApp transaction", "dapp", dappId, "hash", hash, "recipients", len(peers))
 }
 
 // Mined broadcast loop
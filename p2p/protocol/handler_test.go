@@ -38,10 +38,10 @@ import (
 	"strings"
 	"github.com/juchain/go-juchain/vm/solc/abi"
 	"github.com/juchain/go-juchain/consensus/dpos"
-	"github.com/juchain/go-juchain/consensus"
 	"github.com/juchain/go-juchain/vm/solc"
 	"crypto/ecdsa"
 	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/p2p/node"
 )
 
 // Tests that protocol versions and modes of operations are matched up properly.
@@ -466,7 +466,6 @@ func TestVoteElection(t *testing.T) { testVoteElection(t, OBOD01) }
 
 func testVoteElection(t *testing.T, protocol uint) {
 	log.Root().SetHandler(log.LvlFilterHandler(log.LvlDebug, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
-	TestMode = true;
 	generator := func(i int, block *core.BlockGen) {}
 	// Assemble the testing environment
 	pm, _   := newTestProtocolManagerMust(t, downloader.FullSync, 4, generator, nil, false)
@@ -476,88 +475,106 @@ func testVoteElection(t *testing.T, protocol uint) {
 	defer peer.close()
 	defer pm.Stop();
 
-	NodeAIdHash := common.Hex2Bytes("aaaaa111");
-	NodeBIdHash := common.Hex2Bytes("bbbbb111");
+	// the election messages sent to the peers are not checked, drop them.
+	for _, p := range []*testPeer{peer, peer1} {
+		go func(app *p2p.MsgPipeRW) {
+			for {
+				msg, err := app.ReadMsg()
+				if err != nil {
+					return
+				}
+				msg.Discard()
+			}
+		}(p.app)
+	}
+	NextElectionInfo := func() *ElectionInfo {
+		pm.dposManager.lock.Lock()
+		defer pm.dposManager.lock.Unlock()
+		return pm.dposManager.nextElectionInfo
+	}
+	currNodeIdHash := pm.dposManager.dposManager.nodeIdHash
+	NodeAIdHash := common.Hex2Bytes("aaaaa111aaaaa111");
+	NodeBIdHash := common.Hex2Bytes("bbbbb111bbbbb111");
 
 	pm.dposManager.scheduleElecting()
-	activeTime := NextElectionInfo.activeTime;
+	activeTime := NextElectionInfo().activeTime;
 	//expects I win. simply skip this request
 	p2p.Send(peer.app, VOTE_ElectionNode_Request, &VoteElectionRequest{1,
 		100, activeTime, currNodeIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//expects agreed the request node as the election node
 	p2p.Send(peer.app, VOTE_ElectionNode_Request, &VoteElectionRequest{1,
 		2, activeTime, currNodeIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.round != 1 {
-		t.Errorf("returned %v want     %v", NextElectionInfo.round, 2)
+	if NextElectionInfo().round != 1 {
+		t.Errorf("returned %v want     %v", NextElectionInfo().round, 2)
 	}
-	t.Logf("electionTickets returned %v", NextElectionInfo.electionTickets)
+	t.Logf("electionTickets returned %v", NextElectionInfo().electionTickets)
 
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//I am in agreed state already.
 	p2p.Send(peer1.app, VOTE_ElectionNode_Request, &VoteElectionRequest{1,
 		2, activeTime, NodeAIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.round != 1 {
-		t.Errorf("returned %v want     %v", NextElectionInfo.round, 2)
+	if NextElectionInfo().round != 1 {
+		t.Errorf("returned %v want     %v", NextElectionInfo().round, 2)
 	}
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//Mismatched request.round with less value
 	p2p.Send(peer1.app, VOTE_ElectionNode_Request, &VoteElectionRequest{0,
 		2, activeTime, NodeAIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.round != 1 {
-		t.Errorf("returned %v want     %v", NextElectionInfo.round, 2)
+	if NextElectionInfo().round != 1 {
+		t.Errorf("returned %v want     %v", NextElectionInfo().round, 2)
 	}
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//Mismatched request.round with greater value
 	p2p.Send(peer1.app, VOTE_ElectionNode_Request, &VoteElectionRequest{2,
 		2, activeTime, currNodeIdHash})
-	p2p.Send(peer1.app, VOTE_ElectionNode_Request, &VoteElectionRequest{NextElectionInfo.round - 10,
+	p2p.Send(peer1.app, VOTE_ElectionNode_Request, &VoteElectionRequest{NextElectionInfo().round - 10,
 		2, activeTime, currNodeIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.round != 1 {
-		t.Errorf("returned %v want     %v", NextElectionInfo.round, 2)
+	if NextElectionInfo().round != 1 {
+		t.Errorf("returned %v want     %v", NextElectionInfo().round, 2)
 	}
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//Voted Election Response must not have VOTESTATE_SELECTED state. rejected!
 	p2p.Send(peer.app, VOTE_ElectionNode_Response, &VoteElectionResponse{1,
-		2, NextElectionInfo.activeTime,
+		2, NextElectionInfo().activeTime,
 		VOTESTATE_SELECTED,currNodeIdHash})
 	p2p.Send(peer.app, VOTE_ElectionNode_Response, &VoteElectionResponse{1,
-		2, NextElectionInfo.activeTime,
+		2, NextElectionInfo().activeTime,
 		VOTESTATE_MISMATCHED_ROUND,currNodeIdHash})
 
 	//Confirmed the final election node:
@@ -568,34 +585,34 @@ func testVoteElection(t *testing.T, protocol uint) {
 	p2p.Send(peer.app, VOTE_ElectionNode_Broadcast, &BroadcastVotedElection{1,
 		2, activeTime, VOTESTATE_MISMATCHED_ROUND,NodeAIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
-	if NextElectionInfo.activeTime != activeTime {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().activeTime != activeTime {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
-	// switch to next round.
-	pm.dposManager.scheduleElecting()
-	if NextElectionInfo.round != 2 {
-		t.Errorf("returned %v want     %v", NextElectionInfo.round, 2)
+	// switch to next round once the active time of the best node is reached.
+	pm.dposManager.clock.(*simClock).Run(time.Second * time.Duration(ElectingInterval))
+	if NextElectionInfo().round != 2 {
+		t.Errorf("returned %v want     %v", NextElectionInfo().round, 2)
 	}
-	if NextElectionInfo.enodestate != VOTESTATE_LOOKING {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().enodestate != VOTESTATE_LOOKING {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 
 	//Mismatched request.round
 	p2p.Send(peer.app, VOTE_ElectionNode_Request, &VoteElectionRequest{1,
-		NextElectionInfo.electionTickets, activeTime, currNodeIdHash})
+		NextElectionInfo().electionTickets, activeTime, currNodeIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.enodestate != VOTESTATE_LOOKING {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().enodestate != VOTESTATE_LOOKING {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 	p2p.Send(peer.app, VOTE_ElectionNode_Request, &VoteElectionRequest{3,
-		NextElectionInfo.electionTickets, activeTime,currNodeIdHash})
+		NextElectionInfo().electionTickets, activeTime,currNodeIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.enodestate != VOTESTATE_LOOKING {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_LOOKING)
+	if NextElectionInfo().enodestate != VOTESTATE_LOOKING {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_LOOKING)
 	}
 	p2p.Send(peer.app, VOTE_ElectionNode_Broadcast, &BroadcastVotedElection{2,
 		2, activeTime, VOTESTATE_MISMATCHED_ROUND,currNodeIdHash})
@@ -604,104 +621,94 @@ func testVoteElection(t *testing.T, protocol uint) {
 	p2p.Send(peer.app, VOTE_ElectionNode_Broadcast, &BroadcastVotedElection{2,
 		4, activeTime, VOTESTATE_MISMATCHED_ROUND,NodeBIdHash})
 	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextElectionInfo.enodestate != VOTESTATE_SELECTED {
-		t.Errorf("returned %v want     %v", NextElectionInfo.enodestate, VOTESTATE_SELECTED)
+	if NextElectionInfo().enodestate != VOTESTATE_SELECTED {
+		t.Errorf("returned %v want     %v", NextElectionInfo().enodestate, VOTESTATE_SELECTED)
 	}
 
 	pm.dposManager.scheduleElecting()
-	TestMode = false
 }
 
 func TestDPosDelegator(t *testing.T) {
 	//log.Root().SetHandler(log.LvlFilterHandler(log.LvlDebug, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
 
-	DelegatorsTable = []string{"abcd"}
-	TestMode = true;
 	generator := func(i int, block *core.BlockGen) {}
 	// Assemble the testing environment
 	pm, _   := newTestProtocolManagerMust(t, downloader.FullSync, 4, generator, nil, false)
-	peer, _  := newTestPeer("peer", OBOD01, pm, true)
-	peer1, _  := newTestPeer("peer1", OBOD01, pm, true)
-	defer peer1.close()
-	defer peer.close()
 	defer pm.Stop();
+	currNodeId := pm.dposManager.dposManager.nodeId
 
-	NodeAIdHash := common.Hex2Bytes("aaaaa111");
-	pm.dposManager.dposManager.syncDelegatedNodeSafely()
-
-	p2p.Send(peer.app, SYNC_BIGPERIOD_REQUEST, &SyncBigPeriodRequest{NextGigPeriodInstance.round,
-		NextGigPeriodInstance.activeTime,
-		NextGigPeriodInstance.delegatedNodes,
-		NextGigPeriodInstance.delegatedNodesSign,
-		currNodeIdHash})
-	p2p.Send(peer1.app, SYNC_BIGPERIOD_REQUEST, &SyncBigPeriodRequest{NextGigPeriodInstance.round,
-		NextGigPeriodInstance.activeTime,
-		NextGigPeriodInstance.delegatedNodes,
-		NextGigPeriodInstance.delegatedNodesSign,
-		NodeAIdHash})
-	// mismatch and overflow
-	p2p.Send(peer1.app, SYNC_BIGPERIOD_REQUEST, &SyncBigPeriodRequest{NextGigPeriodInstance.round-10,
-		NextGigPeriodInstance.activeTime,
-		NextGigPeriodInstance.delegatedNodes,
-		NextGigPeriodInstance.delegatedNodesSign,
-		NodeAIdHash})
-	p2p.Send(peer1.app, SYNC_BIGPERIOD_REQUEST, &SyncBigPeriodRequest{NextGigPeriodInstance.round+10,
-		NextGigPeriodInstance.activeTime,
-		NextGigPeriodInstance.delegatedNodes,
-		NextGigPeriodInstance.delegatedNodesSign,
-		NodeAIdHash})
-	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextGigPeriodInstance.state != STATE_CONFIRMED {
-		t.Errorf("returned %v want     %v", NextGigPeriodInstance.state, STATE_CONFIRMED)
-	}
-	if len(NextGigPeriodInstance.delegatedNodes) == 0 {
-		t.Errorf("returned %v want     %v", NextGigPeriodInstance.delegatedNodes, 1)
-	}
-
-	delegatedNodesA := []string{"aaa", "bbbb", "cccc", "ddddd", "eeeeee"}
-	p2p.Send(peer1.app, SYNC_BIGPERIOD_RESPONSE, &SyncBigPeriodResponse{NextGigPeriodInstance.round,
-		NextGigPeriodInstance.activeTime,
-		delegatedNodesA,
-		SignCandidates(delegatedNodesA),
-		STATE_CONFIRMED,
-		NodeAIdHash})
-	p2p.Send(peer1.app, SYNC_BIGPERIOD_RESPONSE, &SyncBigPeriodResponse{NextGigPeriodInstance.round,
-		NextGigPeriodInstance.activeTime,
-		delegatedNodesA,
-		SignCandidates(delegatedNodesA),
-		STATE_CONFIRMED,
-		NodeAIdHash})
-	time.Sleep(time.Millisecond * time.Duration(500))
-	if NextGigPeriodInstance.state != STATE_CONFIRMED {
-		t.Errorf("returned %v want     %v", NextGigPeriodInstance.state, STATE_CONFIRMED)
+	// the delegators are only read from the state of checkpoint blocks.
+	head := pm.blockchain.CurrentHeader()
+	statedb, err := pm.blockchain.StateAt(head.Root)
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	accessor := &DelegatorAccessorTestImpl{currNodeId: currNodeId}
+	delegators, err := accessor.Delegators(pm.blockchain, head, statedb)
+	if err != nil {
+		t.Fatalf("failed to read delegators: %v", err)
+	}
+	if !reflect.DeepEqual(delegators, []string{currNodeId}) {
+		t.Errorf("returned %v want     %v", delegators, []string{currNodeId})
 	}
-	if !reflect.DeepEqual(NextGigPeriodInstance.delegatedNodes, delegatedNodesA) {
-		t.Errorf("returned %v want     %v", NextGigPeriodInstance.delegatedNodes, delegatedNodesA)
+	// the genesis block records no delegator, the election node keeps packaging.
+	if pm.dposManager.isDelegationActivated() {
+		t.Errorf("returned %v want     %v", true, false)
+	}
+	if pm.dposManager.dposManager.isDelegatedNode() {
+		t.Errorf("returned %v want     %v", true, false)
 	}
-	TestMode = false
 }
 
 func TestDPosDelegatorContract(t *testing.T) {
 	log.Root().SetHandler(log.LvlFilterHandler(log.LvlDebug, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
 
-	TestMode = true
 	generator := func(i int, block *core.BlockGen) {}
 	// Assemble the testing environment
 	pm, _   := newTestProtocolManagerMust(t, downloader.FullSync, 1, generator, nil, false)
 	defer pm.Stop();
+	currNodeId := pm.dposManager.dposManager.nodeId
 
-	dappabi, err := abi.JSON(strings.NewReader(core.DPOSBallotABI))
+	dappabi, err := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
 	if err != nil {
-		log.Error("Unable to load DPoS Ballot ABI object!")
-		return;
+		t.Fatalf("failed to load delegator registry ABI: %v", err)
 	}
-	VotingAccessor = &DelegatorAccessorImpl{dappabi: dappabi, blockchain: pm.blockchain, b: pm.backend};
-	DelegatorsTable, DelegatorNodeInfo, err = VotingAccessor.Refresh();
+	head := pm.blockchain.CurrentHeader()
+	statedb, err := pm.blockchain.StateAt(head.Root)
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	// register the current node and bond votes for it through the native registry.
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     core.GetHashFn(head, pm.blockchain),
+		BlockNumber: new(big.Int).Set(head.Number),
+		Time:        new(big.Int).Set(head.Time),
+		GasLimit:    head.GasLimit,
+	}
+	evm := vm.NewEVM(context, statedb, pm.blockchain.Config(), vm.Config{})
+	signature, _ := crypto.Sign(vm.DelegatorRegistrationHash(testBank).Bytes(), testNodeKey)
+	for i, method := range []string{"register", "vote"} {
+		args := []interface{}{currNodeId}
+		if method == "register" {
+			args = append(args, signature)
+		}
+		input, err := dappabi.Pack(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		if _, _, err := evm.Call(vm.AccountRef(testBank), vm.DelegatorRegistryAddress, input, 100000, big.NewInt(int64(i*1000))); err != nil {
+			t.Fatalf("failed to %s delegator: %v", method, err)
+		}
+	}
+	delegators, err := dpos.NewRegistryReader(int(TotalDelegatorNumber)).Delegators(pm.blockchain, head, statedb)
+	if err != nil {
+		t.Fatalf("failed to read delegators: %v", err)
+	}
+	if !reflect.DeepEqual(delegators, []string{currNodeId}) {
+		t.Errorf("returned %v want     %v", delegators, []string{currNodeId})
 	}
-
-	TestMode = false
 }
 
 func TestPackageBlock(t *testing.T) {
@@ -712,8 +719,7 @@ func TestPackageBlock(t *testing.T) {
 	pm, _   := newTestProtocolManagerMust(t, downloader.FullSync, 1, generator, nil, false)
 	defer pm.Stop();
 
-	ElectionInfo0 = &ElectionInfo{electionNodeId: currNodeId}
-	ElectionInfo0.electionNodeId = currNodeId
+	pm.dposManager.electionInfo = &ElectionInfo{electionNodeId: pm.dposManager.dposManager.nodeId}
 
 	for i :=0; i < 100; i++ {
 		pm.dposManager.schedulePackaging()
@@ -722,6 +728,30 @@ func TestPackageBlock(t *testing.T) {
 
 }
 
+// Tests that the height of the last pre-commit vote survives a restart, so a
+// delegator never votes twice at a height.
+func TestPreCommitPersistence(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 2, nil, nil, false)
+	defer pm.Stop()
+
+	manager := pm.dposManager.dposManager
+	if err := core.WriteLastPreCommit(db, 2); err != nil {
+		t.Fatalf("failed to store pre-commit: %v", err)
+	}
+	restarted, err := NewDPoSProtocolManager(manager.eth, pm, pm.chainconfig, &node.Config{P2P: p2p.Config{PrivateKey: testNodeKey}},
+		downloader.FullSync, DefaultConfig.NetworkId, pm.blockchain, manager.engine)
+	if err != nil {
+		t.Fatalf("failed to restart protocol manager: %v", err)
+	}
+	if restarted.lastPreCommit != 2 {
+		t.Fatalf("last pre-commit mismatch: have %d, want 2", restarted.lastPreCommit)
+	}
+	restarted.preCommit(pm.blockchain.CurrentHeader())
+	if number := core.GetLastPreCommit(db); number != 2 {
+		t.Errorf("voted again at height 2: stored pre-commit %d", number)
+	}
+}
+
 func TestMultipleDAppChainsInsert(t *testing.T) {
 	log.Root().SetHandler(log.LvlFilterHandler(log.LvlTrace, log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
 
@@ -732,13 +762,17 @@ func TestMultipleDAppChainsInsert(t *testing.T) {
 
 	gspec   := core.Genesis{
 		Config: config.TestChainConfig,
+		// The only funded account deploys the DApp manager in every genesis, using up its first nonce
 		Alloc: core.GenesisAlloc{
 			dappIdA: {Balance: new(big.Int).SetUint64(2 * config.Ether)},
-			dappIdB: {Balance: new(big.Int).SetUint64(2 * config.Ether)},
 		},
 		GasLimit: 100e6, // 100 M
 	}
-	engine := consensus.CreateFakeEngine()
+	// The local delegator produces the anchors, so it packages their DApp blocks
+	engine := dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, nil)
+	engine.Authorize("testnode1", func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
 	db, _ := store.NewMemDatabase()
 	gspec.MustCommit(db)
 	db1, _ := store.NewMemDatabase()
@@ -762,27 +796,75 @@ func TestMultipleDAppChainsInsert(t *testing.T) {
 		dappIdA: chain1,
 		dappIdB: chain2,
 	}
+	defer func(addresses *config.DAppAddress) { config.DAppAddresses = addresses }(config.DAppAddresses)
+	config.DAppAddresses = &config.DAppAddress{Addresse: []common.Address{dappIdA, dappIdB}}
 	//statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	//statedb.SetBalance(dappIdA, new(big.Int).SetUint64(config.Ether))
 	//statedb.SetBalance(dappIdB, new(big.Int).SetUint64(config.Ether))
 
-	pool := core.NewTxPool(core.DefaultTxPoolConfig, config.TestChainConfig, chain, dappChains)
+	pool := core.NewTxPool(core.DefaultTxPoolConfig, config.TestChainConfig, chain)
 	defer pool.Stop()
 
 	packager := dpos.NewPackager1(config.TestChainConfig, engine, dappIdA, chain, pool, &event.TypeMux{})
 	defer packager.Stop()
 
-	tx0 := dappTransaction(&dappIdA, 0, 100000, key)
-	tx1 := dappTransaction(&dappIdA, 1, 100000, key)
-	tx2 := dappTransaction(&dappIdA, 2, 100000, key)
-	tx3 := dappTransaction(&dappIdA, 3, 100000, key)
+	tx0 := dappTransaction(&dappIdA, 1, 100000, key)
+	tx1 := dappTransaction(&dappIdA, 2, 100000, key)
+	tx2 := dappTransaction(&dappIdA, 3, 100000, key)
+	tx3 := dappTransaction(&dappIdA, 4, 100000, key)
 	if (tx0 == nil) {
 		t.Error("failed to create dapp tx.")
 		return;
 	}
 	pool.AddLocals(types.Transactions{tx0, tx1, tx2, tx3})
 
-	packager.GenerateNewBlock(1, "testnode1")
+	dappPackager := dpos.NewDAppPackager(config.TestChainConfig, engine, chain, dappChains, pool, new(event.TypeMux))
+	defer dappPackager.Stop()
+
+	dappHeads := make(chan core.ChainHeadEvent, 1)
+	sub := chain1.SubscribeChainHeadEvent(dappHeads)
+	defer sub.Unsubscribe()
+
+	block := packager.GenerateNewBlock(1, "testnode1")
+	if block == nil {
+		t.Fatalf("failed to generate main block")
+	}
+	select {
+	case ev := <-dappHeads:
+		dappBlock := ev.Block
+		if dappBlock.DAppID() != dappIdA || dappBlock.Header().DAppMainHash != block.Hash() {
+			t.Errorf("dapp block anchor mismatch: have %x on %x, want %x on %x", dappBlock.DAppID(), dappBlock.Header().DAppMainHash, dappIdA, block.Hash())
+		}
+		if len(dappBlock.Transactions()) != 4 {
+			t.Errorf("dapp block transactions mismatch: have %d, want %d", len(dappBlock.Transactions()), 4)
+		}
+		if receipts := core.GetBlockReceipts(db1, dappBlock.Hash(), dappBlock.NumberU64()); len(receipts) != 4 {
+			t.Errorf("dapp block receipts mismatch: have %d, want %d", len(receipts), 4)
+		}
+		if _, err := chain1.StateAt(dappBlock.Root()); err != nil {
+			t.Errorf("dapp block state unavailable: %v", err)
+		}
+		if err := core.VerifyAnchor(chain, dappBlock); err != nil {
+			t.Errorf("dapp block not anchored: %v", err)
+		}
+		proof, err := core.ProveAnchor(chain, dappBlock, dappBlock.Transactions()[2])
+		if err != nil {
+			t.Fatalf("failed to prove anchor: %v", err)
+		}
+		if err := core.VerifyAnchorProof(proof); err != nil || proof.Header.Hash() != block.Hash() {
+			t.Errorf("anchor proof mismatch: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("dapp block not packaged")
+	}
+	if chain2.CurrentBlock().NumberU64() != 0 {
+		t.Errorf("unrelated dapp chain advanced to #%d", chain2.CurrentBlock().NumberU64())
+	}
+	// Anchors produced by other delegators are packaged by them only
+	foreign := types.NewBlockWithHeader(&types.Header{ParentHash: block.Hash(), Number: big.NewInt(2), PresidentId: "testnode2"})
+	if dappBlock, err := dappPackager.Commit(dappIdA, foreign); dappBlock != nil || err != nil {
+		t.Errorf("packaged foreign anchor: have %v, %v", dappBlock, err)
+	}
 
 	t.Logf("chain.CurrentBlock().Number()= %v", chain.CurrentBlock().Number())
 	t.Logf("chain1.CurrentBlock().Number()= %v", chain1.CurrentBlock().Number())
@@ -792,6 +874,85 @@ func TestMultipleDAppChainsInsert(t *testing.T) {
 	t.Logf("db2.Len() = %v", db2.Len())
 
 }
+// Tests that the DApp packager only drops the pending DApp transactions it
+// included or which can never execute, keeping the ones skipped because the
+// DApp block ran out of gas.
+func TestDAppPackagerSkippedTransactions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dappId := crypto.PubkeyToAddress(key.PublicKey)
+
+	gspec := core.Genesis{
+		Config:   config.TestChainConfig,
+		Alloc:    core.GenesisAlloc{dappId: {Balance: new(big.Int).SetUint64(2 * config.Ether)}},
+		GasLimit: 100e6,
+	}
+	engine := dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, nil)
+	engine.Authorize("testnode1", func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	db, _ := store.NewMemDatabase()
+	gspec.MustCommit(db)
+	dappDb, _ := store.NewMemDatabase()
+	gspec.MustCommit(dappDb)
+
+	// The DApp blocks only fit one of the transactions
+	dappConfig := *config.TestChainConfig
+	dappConfig.DApp = &config.DAppConfig{GasLimit: 120000}
+
+	chain, err := core.NewBlockChain(db, nil, config.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create main chain: %v", err)
+	}
+	dappChain, err := core.NewBlockChain(dappDb, nil, &dappConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create dapp chain: %v", err)
+	}
+	defer func(addresses *config.DAppAddress) { config.DAppAddresses = addresses }(config.DAppAddresses)
+	config.DAppAddresses = &config.DAppAddress{Addresse: []common.Address{dappId}}
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, config.TestChainConfig, chain)
+	defer pool.Stop()
+
+	packager := dpos.NewPackager1(config.TestChainConfig, engine, dappId, chain, pool, &event.TypeMux{})
+	defer packager.Stop()
+
+	dappPackager := dpos.NewDAppPackager(config.TestChainConfig, engine, chain, map[common.Address]*core.BlockChain{dappId: dappChain}, pool, new(event.TypeMux))
+	defer dappPackager.Stop()
+
+	dappHeads := make(chan core.ChainHeadEvent, 1)
+	sub := dappChain.SubscribeChainHeadEvent(dappHeads)
+	defer sub.Unsubscribe()
+
+	// The first transaction is included, the second one runs out of gas and the
+	// third one fails on its nonce, following the skipped one
+	txs := types.Transactions{
+		dappTransaction(&dappId, 1, 100000, key),
+		dappTransaction(&dappId, 2, 100000, key),
+		dappTransaction(&dappId, 3, 100000, key),
+	}
+	if errs := pool.AddLocals(txs); errs[0] != nil || errs[1] != nil || errs[2] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	block := packager.GenerateNewBlock(1, "testnode1")
+	if block == nil {
+		t.Fatalf("failed to generate main block")
+	}
+	select {
+	case ev := <-dappHeads:
+		if included := ev.Block.Transactions(); len(included) != 1 || included[0].Hash() != txs[0].DAppTx().Hash() {
+			t.Fatalf("dapp block transactions mismatch: have %d, want 1", len(included))
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("dapp block not packaged")
+	}
+	pending := pool.DAppTransactions(dappId, block.Transactions())
+	if len(pending) != 1 || pending[0].Hash() != txs[1].DAppTx().Hash() {
+		t.Errorf("pending dapp transactions mismatch: have %d, want the skipped one", len(pending))
+	}
+}
+
 var dappTxData = []byte{1,2,3,4,5,6,7,8,10,4,5,6,7,8,10,1,2,3,4,5,6,7,8,10,4,5,6,7,8,10,1,2,3,4,5,6,7,8,10,4,5,6,7,8,10,1,2,3,4,5,6,7,8,10,4,5,6,7,8,101,2,3,4,5,6,7,8,10,4,5,6,7,8,10};
 func dappTransaction(dapp *common.Address, nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) *types.Transaction {
 	return pricedDappTransaction(dapp, nonce, gaslimit, big.NewInt(1), key)
@@ -799,11 +960,11 @@ func dappTransaction(dapp *common.Address, nonce uint64, gaslimit uint64, key *e
 
 func pricedDappTransaction(dapp *common.Address, nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
 	tx := types.NewDAppTransaction(dapp, nonce, gaslimit, gasprice, dappTxData)
-	tx, _ = types.SignTx(tx, types.NewChainSigner(common.Big1), key)
+	tx, _ = types.SignTx(tx, types.NewDAppSigner(common.Big1), key)
 
-	from, _ := types.Sender(types.NewChainSigner(common.Big1), tx)
-	from1, _ := types.Sender(types.NewChainSigner(common.Big1), tx.DAppTx())
-	from2, _ := types.Sender(types.NewChainSigner(common.Big2), tx.DAppTx())
+	from, _ := types.Sender(types.NewDAppSigner(common.Big1), tx)
+	from1, _ := types.Sender(types.NewDAppSigner(common.Big1), tx.DAppTx())
+	from2, _ := types.Sender(types.NewDAppSigner(common.Big2), tx.DAppTx())
 	if from != from1 || from1 == from2 {
 		return nil; //errors.New("signed error.")
 	}
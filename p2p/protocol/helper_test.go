@@ -26,6 +26,7 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/core"
@@ -48,6 +49,9 @@ import (
 var (
 	testBankKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	testBank       = crypto.PubkeyToAddress(testBankKey.PublicKey)
+
+	// testNodeKey is the node key of the test protocol managers
+	testNodeKey, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
 )
 
 // newTestProtocolManager creates a new protocol manager for testing purposes,
@@ -62,9 +66,9 @@ func newTestProtocolManager(mode downloader.SyncMode, blocks int, generator func
 			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000000000)}},
 		}
 		genesis       = gspec.MustCommit(db)
-		engine        = dpos.New(&config.DPoSConfig{}, db);
+		engine        = dpos.New(&config.DPoSConfig{PoSMode: config.ModeFullFake}, db);
 		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
-		config2       = &node.Config{}
+		config2       = &node.Config{P2P: p2p.Config{PrivateKey: testNodeKey}}
 		eth           = &JuchainService{
 			config:         &DefaultConfig,
 			chainDb:        db,
@@ -83,11 +87,11 @@ func newTestProtocolManager(mode downloader.SyncMode, blocks int, generator func
 			etherbase:      DefaultConfig.Etherbase,
 			bloomRequests:  make(chan chan *bloombits.Retrieval),
 			bloomIndexer:   NewBloomIndexer(db, config.BloomBitsBlocks),
-			txPool:         core.NewTxPool(DefaultConfig.TxPool, config.TestChainConfig, blockchain, nil),
+			txPool:         core.NewTxPool(DefaultConfig.TxPool, config.TestChainConfig, blockchain),
 		}
 	)
 
-	eth.ApiBackend = &EthApiBackend{eth, nil}
+	eth.ApiBackend = &EthApiBackend{eth: eth}
 	gpoconfig := eth.config.GPO
 	if gpoconfig.Default == nil {
 		gpoconfig.Default = eth.config.GasPrice
@@ -102,6 +106,12 @@ func newTestProtocolManager(mode downloader.SyncMode, blocks int, generator func
 	if err != nil {
 		return nil, nil, err
 	}
+	// Drive the election by a simulated clock, so no round starts unless run
+	if pm.dposManager != nil {
+		clock := newSimClock(time.Now())
+		pm.dposManager.clock = clock
+		pm.dposManager.dposManager.clock = clock
+	}
 	//if (started) {
 		pm.Start(1000)
 	//}
@@ -142,6 +152,12 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddDAppTransactions appends a batch of DApp transactions to the pool, and
+// notifies any listeners if the addition channel is non nil
+func (p *testTxPool) AddDAppTransactions(txs []*types.Transaction) []error {
+	return p.AddRemotes(txs)
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	p.lock.RLock()
@@ -162,6 +178,15 @@ func (p *testTxPool) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscr
 	return p.txFeed.Subscribe(ch)
 }
 
+// testDAppChains is a fake, helper set of DApp chains replicated by the node
+// for testing purposes.
+type testDAppChains map[common.Address]*core.BlockChain
+
+// DAppChain retrieves the chain of a DApp, nil if the DApp is not replicated.
+func (c testDAppChains) DAppChain(dappId common.Address) (*core.BlockChain, store.Database) {
+	return c[dappId], nil
+}
+
 // newTestTransaction create a new dummy transaction.
 func newTestTransaction(from *ecdsa.PrivateKey, nonce uint64, datasize int) *types.Transaction {
 	tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), make([]byte, datasize))
@@ -213,16 +238,24 @@ func newTestPeer(name string, version uint, pm *ProtocolManager, shake bool) (*t
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
 func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash) {
+	p.handshakeDApps(t, td, head, genesis, config.DAppAddresses.List())
+}
+
+// handshakeDApps simulates a handshake expecting the same state from the remote
+// side as we are simulating locally, but replicating the given DApp chains.
+func (p *testPeer) handshakeDApps(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash, dapps []common.Address) {
 	msg := &statusData{
 		ProtocolVersion: uint32(p.version),
 		NetworkId:       DefaultConfig.NetworkId,
 		TD:              td,
 		CurrentBlock:    head,
 		GenesisBlock:    genesis,
+		DApps:           config.DAppAddresses.List(),
 	}
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
 	}
+	msg.DApps = dapps
 	if err := p2p.Send(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status send: %v", err)
 	}
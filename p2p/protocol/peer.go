@@ -28,7 +28,6 @@ import (
 	"github.com/juchain/go-juchain/p2p"
 	"github.com/juchain/go-juchain/common/rlp"
 	"gopkg.in/fatih/set.v0"
-	"github.com/juchain/go-juchain/config"
 )
 
 var (
@@ -49,6 +48,7 @@ type PeerInfo struct {
 	Version    uint      `json:"version"`    // JuchainService protocol version negotiated
 	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
 	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	DApps      []common.Address `json:"dapps,omitempty"` // DApp chains replicated by the peer
 }
 
 type peer struct {
@@ -60,9 +60,10 @@ type peer struct {
 	version  uint         // Protocol version negotiated
 	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head  common.Hash
+	td    *big.Int
+	dapps map[common.Address]struct{} // DApp chains replicated by the peer, the peer group it belongs to
+	lock  sync.RWMutex
 
 	knownTxs    *set.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks *set.Set // Set of block hashes known to be known by this peer
@@ -89,6 +90,7 @@ func (p *peer) Info() *PeerInfo {
 		Version:    p.version,
 		Difficulty: td,
 		Head:       hash.Hex(),
+		DApps:      p.DApps(),
 	}
 }
 
@@ -111,6 +113,28 @@ func (p *peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// DApps retrieves the DApp chains replicated by the peer.
+func (p *peer) DApps() []common.Address {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var dapps []common.Address
+	for dappId := range p.dapps {
+		dapps = append(dapps, dappId)
+	}
+	return dapps
+}
+
+// Replicates returns whether the peer belongs to the peer group of a DApp,
+// replicating its chain.
+func (p *peer) Replicates(dappId common.Address) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.dapps[dappId]
+	return ok
+}
+
 // MarkBlock marks a block as known for the peer, ensuring that the block will
 // never be propagated to this particular peer.
 func (p *peer) MarkBlock(hash common.Hash) {
@@ -140,10 +164,12 @@ func (p *peer) SendTransactions(txs types.Transactions) error {
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// SendDAppTransactions sends DApp transactions to a peer of their DApp's peer
+// group and includes the hashes in its transaction hash set for future reference.
 func (p *peer) SendDAppTransactions(txs types.Transactions) error {
 	for _, tx := range txs {
-		if !config.DAppAddresses.HasAssignedNodes(p.ID().String()) {
-			return errors.New("peer " + p.ID().String() + " does not support this dapp transaction " + tx.DAppID().String());
+		if tx.DAppID() == nil || !p.Replicates(*tx.DAppID()) {
+			return fmt.Errorf("peer %s does not replicate DApp of transaction %x", p.id, tx.Hash())
 		}
 		p.knownTxs.Add(tx.Hash())
 	}
@@ -170,6 +196,18 @@ func (p *peer) SendNewBlock(block *types.Block, td *big.Int) error {
 	return p2p.Send(p.rw, NewBlockMsg, []interface{}{block, td})
 }
 
+// SendNewDAppBlock propagates an entire DApp block to a peer of its DApp's
+// peer group.
+func (p *peer) SendNewDAppBlock(block *types.Block) error {
+	p.knownBlocks.Add(block.Hash())
+	return p2p.Send(p.rw, NewDAppBlockMsg, block)
+}
+
+// SendDAppBlocks sends a batch of blocks of a DApp chain to the remote peer.
+func (p *peer) SendDAppBlocks(dappId common.Address, blocks []*types.Block) error {
+	return p2p.Send(p.rw, DAppBlocksMsg, &dappBlocksData{DAppId: dappId, Blocks: blocks})
+}
+
 // SendBlockHeaders sends a batch of block headers to the remote peer.
 func (p *peer) SendBlockHeaders(headers []*types.Header) error {
 	return p2p.Send(p.rw, BlockHeadersMsg, headers)
@@ -239,15 +277,14 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetReceiptsMsg, hashes)
 }
 
-// DPOS messages
-func (p *peer) SendSyncBigPeriodRequest(request *SyncBigPeriodRequest) error {
-	//p.Log().Debug("register as candidate request", "count", len(request))
-	return p2p.Send(p.rw, SYNC_BIGPERIOD_REQUEST, request)
-}
-func (p *peer) SendSyncBigPeriodResponse(response *SyncBigPeriodResponse) error {
-	//p.Log().Debug("register as candidate response", "count", len(response))
-	return p2p.Send(p.rw, SYNC_BIGPERIOD_RESPONSE, response)
+// RequestDAppBlocks fetches a batch of consecutive blocks of a DApp chain,
+// starting at the given number.
+func (p *peer) RequestDAppBlocks(dappId common.Address, origin uint64, amount int) error {
+	p.Log().Debug("Fetching batch of DApp blocks", "dapp", dappId, "count", amount, "fromnum", origin)
+	return p2p.Send(p.rw, GetDAppBlocksMsg, &getDAppBlocksData{DAppId: dappId, Origin: origin, Amount: uint64(amount)})
 }
+
+// DPOS messages
 func (p *peer) SendVoteElectionRequest(request *VoteElectionRequest) error {
 	//p.Log().Debug("register as candidate request", "count", len(request))
 	return p2p.Send(p.rw, VOTE_ElectionNode_Request, request)
@@ -264,9 +301,13 @@ func (p *peer) SendBestNodeConflict(response *BroadcastBestNodeConflict) error {
 	//p.Log().Debug("register as candidate response", "count", len(response))
 	return p2p.Send(p.rw, VOTE_BESTNODE_CONFLICT, response)
 }
+func (p *peer) SendPreCommit(vote *types.PreCommit) error {
+	return p2p.Send(p.rw, VOTE_PreCommit, vote)
+}
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks, and exchanging the DApp
+// chains replicated by both sides.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, dapps []common.Address) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
@@ -278,6 +319,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			TD:              td,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
+			DApps:           dapps,
 		})
 	}()
 	go func() {
@@ -296,6 +338,10 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		}
 	}
 	p.td, p.head = status.TD, status.CurrentBlock
+	p.dapps = make(map[common.Address]struct{}, len(status.DApps))
+	for _, dappId := range status.DApps {
+		p.dapps[dappId] = struct{}{}
+	}
 	return nil
 }
 
@@ -393,6 +439,18 @@ func (ps *peerSet) Len() int {
 	return len(ps.peers)
 }
 
+// Peers retrieves a list of all the registered peers.
+func (ps *peerSet) Peers() []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
 // PeersWithoutBlock retrieves a list of peers that do not have a given block in
 // their set of known hashes.
 func (ps *peerSet) PeersWithoutBlock(hash common.Hash) []*peer {
@@ -423,6 +481,50 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 	return list
 }
 
+// PeersWithDApp retrieves a list of peers in the peer group of a DApp.
+func (ps *peerSet) PeersWithDApp(dappId common.Address) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.Replicates(dappId) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// DAppPeersWithoutBlock retrieves a list of peers in the peer group of a DApp
+// that do not have a given block of its chain in their set of known hashes.
+func (ps *peerSet) DAppPeersWithoutBlock(dappId common.Address, hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.Replicates(dappId) && !p.knownBlocks.Has(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// DAppPeersWithoutTx retrieves a list of peers in the peer group of a DApp that
+// do not have a given transaction of the DApp in their set of known hashes.
+func (ps *peerSet) DAppPeersWithoutTx(dappId common.Address, hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.Replicates(dappId) && !p.knownTxs.Has(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
 	ps.lock.RLock()
@@ -55,6 +55,9 @@ const (
 	NewBlockMsg        = 0x06
 	TxMsg              = 0x07
 	DAppTxMsg          = 0x08
+	NewDAppBlockMsg    = 0x09
+	GetDAppBlocksMsg   = 0x0a
+	DAppBlocksMsg      = 0x0b
 	GetNodeDataMsg     = 0x0d
 	NodeDataMsg        = 0x0e
 	GetReceiptsMsg     = 0x0f
@@ -98,6 +101,10 @@ type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// AddDAppTransactions should add the given DApp transactions to the pending
+	// transactions of their DApp chains.
+	AddDAppTransactions([]*types.Transaction) []error
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -114,6 +121,7 @@ type statusData struct {
 	TD              *big.Int
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	DApps           []common.Address `rlp:"tail"` // DApp chains replicated by the node
 }
 
 // newBlockHashesData is the network packet for the block announcements.
@@ -180,3 +188,16 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getDAppBlocksData represents a query of consecutive blocks of a DApp chain.
+type getDAppBlocksData struct {
+	DAppId common.Address // DApp chain to retrieve the blocks from
+	Origin uint64         // Number of the first block to retrieve
+	Amount uint64         // Maximum number of blocks to retrieve
+}
+
+// dappBlocksData is the network packet for DApp chain block distribution.
+type dappBlocksData struct {
+	DAppId common.Address
+	Blocks []*types.Block
+}
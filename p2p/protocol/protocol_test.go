@@ -18,6 +18,7 @@ package protocol
 
 import (
 	"fmt"
+	"math/big"
 	"sync"
 	"testing"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/juchain/go-juchain/p2p/protocol/downloader"
 	"github.com/juchain/go-juchain/p2p"
 	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
 )
 
 func init() {
@@ -58,15 +60,15 @@ func testStatusMsgErrors(t *testing.T, protocol uint) {
 			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
 		},
 		{
-			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", protocol),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 1)"),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}},
+			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}, nil},
 			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis.Hash().Bytes()[:8]),
 		},
 	}
@@ -175,6 +177,106 @@ func testSendTransactions(t *testing.T, protocol uint) {
 	wg.Wait()
 }
 
+// Tests that DApp transactions and blocks are only gossiped inside the peer
+// group of their DApp, as advertised during the status handshake, and that the
+// peers of the group synchronise the DApp chain from each other.
+func TestDAppPeerGroups(t *testing.T) {
+	defer func(addresses *config.DAppAddress) { config.DAppAddresses = addresses }(config.DAppAddresses)
+	dappId := common.Address{0xda}
+	config.DAppAddresses = &config.DAppAddress{Addresse: []common.Address{dappId}}
+
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, txAdded, true)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	pm.dapps = testDAppChains{dappId: pm.blockchain}
+	defer pm.Stop()
+
+	var (
+		genesis = pm.blockchain.Genesis()
+		head    = pm.blockchain.CurrentHeader()
+		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+	)
+	// Peers of the group are asked for the blocks following the local head
+	member, _ := newTestPeer("member", OBOD01, pm, true)
+	defer member.close()
+	if err := p2p.ExpectMsg(member.app, GetDAppBlocksMsg, &getDAppBlocksData{dappId, 5, maxDAppBlockFetch}); err != nil {
+		t.Fatalf("sync request: %v", err)
+	}
+	outsider, _ := newTestPeer("outsider", OBOD01, pm, false)
+	outsider.handshakeDApps(t, td, head.Hash(), genesis.Hash(), nil)
+	defer outsider.close()
+
+	for pm.peers.Len() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if peer := pm.peers.Peer(member.peer.id); !peer.Replicates(dappId) {
+		t.Errorf("member not in the peer group")
+	}
+	if peer := pm.peers.Peer(outsider.peer.id); peer.Replicates(dappId) {
+		t.Errorf("outsider in the peer group")
+	}
+	// The DApp part of a transaction is only sent to the peer group
+	tx, _ := types.SignTx(types.NewDAppTransaction(&dappId, 0, 100000, big.NewInt(1), []byte{0x01}), types.NewDAppSigner(config.TestChainConfig.ChainId), testAccount)
+
+	var wg sync.WaitGroup
+	expect := func(p *testPeer, code uint64, content interface{}) {
+		defer wg.Done()
+		if err := p2p.ExpectMsg(p.app, code, content); err != nil {
+			t.Errorf("%v: %v", p.Peer, err)
+		}
+	}
+	wg.Add(3)
+	go func() {
+		expect(member, TxMsg, types.Transactions{tx})
+		expect(member, DAppTxMsg, types.Transactions{tx.DAppTx()})
+	}()
+	go expect(outsider, TxMsg, types.Transactions{tx})
+	pm.BroadcastTx(tx.Hash(), tx)
+	wg.Wait()
+
+	if outsider.peer.knownTxs.Has(tx.DAppTx().Hash()) {
+		t.Errorf("DApp transaction sent outside of the peer group")
+	}
+	// DApp transactions of the peer group are added to the pool
+	relayed, _ := types.SignTx(types.NewDAppTransaction(&dappId, 1, 100000, big.NewInt(1), []byte{0x02}), types.NewDAppSigner(config.TestChainConfig.ChainId), testAccount)
+	if err := p2p.Send(member.app, DAppTxMsg, types.Transactions{relayed.DAppTx()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != relayed.DAppTx().Hash() {
+			t.Errorf("added wrong DApp transactions: %v", added)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("no DApp transaction added within 2 seconds")
+	}
+	// DApp blocks are only propagated to the peer group
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5), DAppID: dappId, DAppMainHash: common.Hash{0x01}})
+
+	wg.Add(1)
+	go expect(member, NewDAppBlockMsg, block)
+	pm.BroadcastBlock(block, true)
+	wg.Wait()
+
+	if outsider.peer.knownBlocks.Has(block.Hash()) {
+		t.Errorf("DApp block sent outside of the peer group")
+	}
+	// Blocks of the DApp chain are served to the peer group
+	blocks := []*types.Block{pm.blockchain.GetBlockByNumber(1), pm.blockchain.GetBlockByNumber(2)}
+	if err := p2p.Send(member.app, GetDAppBlocksMsg, &getDAppBlocksData{dappId, 1, 2}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(member.app, DAppBlocksMsg, &dappBlocksData{dappId, blocks}); err != nil {
+		t.Errorf("blocks mismatch: %v", err)
+	}
+	if err := p2p.Send(member.app, GetDAppBlocksMsg, &getDAppBlocksData{common.Address{0x01}, 1, 2}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(member.app, DAppBlocksMsg, &dappBlocksData{DAppId: common.Address{0x01}}); err != nil {
+		t.Errorf("unknown DApp blocks mismatch: %v", err)
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,852 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains a simulated network of delegators, connected in process
+// and driven by a simulated clock, to script the voting and packaging processes
+// of several nodes deterministically.
+
+package protocol
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/event"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core"
+	"github.com/juchain/go-juchain/core/account"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/p2p"
+	"github.com/juchain/go-juchain/p2p/discover"
+	"github.com/juchain/go-juchain/p2p/node"
+	"github.com/juchain/go-juchain/p2p/protocol/downloader"
+	"github.com/juchain/go-juchain/vm/solc"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+const (
+	simPeriod  = 3          // Number of seconds between the slots of the simulated delegators
+	simGenesis = 1500000000 // Timestamp of the simulated genesis block, where the clock starts
+	simTimeout = 20 * time.Second
+)
+
+// simClock is a clock which only moves when told to. The timers falling due are
+// called one after the other on the goroutine moving the clock, in the order of
+// their deadlines, including the ones scheduled by the called functions.
+type simClock struct {
+	now    time.Time
+	timers []*simTimer
+	seq    uint64 // Sequence number of the last timer, breaking ties of deadlines
+
+	lock sync.Mutex
+}
+
+type simTimer struct {
+	clock *simClock
+	at    time.Time
+	seq   uint64
+	fn    func()
+}
+
+func newSimClock(now time.Time) *simClock {
+	return &simClock{now: now}
+}
+
+func (c *simClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *simClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.seq++
+	timer := &simTimer{clock: c, at: c.now.Add(d), seq: c.seq, fn: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (t *simTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Run moves the clock forward by the given duration, calling the timers falling
+// due meanwhile.
+func (c *simClock) Run(d time.Duration) {
+	c.lock.Lock()
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, timer := range c.timers {
+			if timer.at.After(end) {
+				continue
+			}
+			if next < 0 || timer.at.Before(c.timers[next].at) || (timer.at.Equal(c.timers[next].at) && timer.seq < c.timers[next].seq) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		timer := c.timers[next]
+		c.timers = append(c.timers[:next], c.timers[next+1:]...)
+		if timer.at.After(c.now) {
+			c.now = timer.at
+		}
+		c.lock.Unlock()
+		timer.fn()
+		c.lock.Lock()
+	}
+	c.now = end
+	c.lock.Unlock()
+}
+
+// simLink is an in-process connection between two simulated nodes. Contrary to
+// p2p.MsgPipe, messages are buffered as on a socket instead of waiting for the
+// remote side to consume them, so two nodes may message each other while they
+// are handling a message.
+type simLink struct {
+	net    *simNetwork
+	nodes  [2]int
+	queues [2]*simQueue // Messages inbound to either end
+	closed bool
+}
+
+type simQueue struct {
+	msgs []p2p.Msg
+	busy bool       // Whether the last message read is still being handled
+	cond *sync.Cond // Signalled on new messages and on closing the link
+}
+
+// simEnd is one end of a link, transporting the messages of a node.
+type simEnd struct {
+	link *simLink
+	side int
+}
+
+func (e *simEnd) WriteMsg(msg p2p.Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	e.link.net.lock.Lock()
+	defer e.link.net.lock.Unlock()
+
+	if e.link.closed {
+		return p2p.ErrPipeClosed
+	}
+	queue := e.link.queues[1-e.side]
+	queue.msgs = append(queue.msgs, p2p.Msg{Code: msg.Code, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)})
+	queue.cond.Signal()
+	return nil
+}
+
+// ReadMsg waits for the next message inbound to the end. The previous message
+// is considered handled once the node comes back for the next one.
+func (e *simEnd) ReadMsg() (p2p.Msg, error) {
+	e.link.net.lock.Lock()
+	defer e.link.net.lock.Unlock()
+
+	queue := e.link.queues[e.side]
+	queue.busy = false
+	for len(queue.msgs) == 0 && !e.link.closed {
+		queue.cond.Wait()
+	}
+	if e.link.closed {
+		return p2p.Msg{}, p2p.ErrPipeClosed
+	}
+	msg := queue.msgs[0]
+	queue.msgs, queue.busy = queue.msgs[1:], true
+	msg.ReceivedAt = time.Now()
+	return msg, nil
+}
+
+// simNode is a delegator of the simulated network.
+type simNode struct {
+	index  int
+	key    *ecdsa.PrivateKey
+	addr   common.Address // Account of the delegator, funded in the genesis block
+	nodeId string         // Short node id, identifying the delegator
+
+	chain   *core.BlockChain
+	eth     *JuchainService
+	pm      *ProtocolManager
+	stopped bool
+}
+
+// dvote returns the voting manager of the node.
+func (n *simNode) dvote() *DVoteProtocolManager {
+	return n.pm.dposManager
+}
+
+// dpos returns the packaging manager of the node.
+func (n *simNode) dpos() *DPoSProtocolManager {
+	return n.pm.dposManager.dposManager
+}
+
+// simNetwork is a network of delegators in a single process, sharing a simulated
+// clock. Nodes are connected, partitioned and taken offline by the tests, which
+// move the clock over the slots and rounds of the election.
+type simNetwork struct {
+	t      *testing.T
+	clock  *simClock
+	config *config.ChainConfig
+	nodes  []*simNode
+	links  []*simLink
+
+	lock sync.Mutex // Protects the links and the queues of their messages
+}
+
+// newSimNetwork creates a network of the given number of nodes, all of them
+// started but not yet connected. The first delegators nodes are recorded as
+// delegators in the genesis block, without any the nodes elect an election node
+// packaging the blocks until the first checkpoint.
+func newSimNetwork(t *testing.T, nodes int, delegators int, epoch uint64) *simNetwork {
+	net := &simNetwork{
+		t:     t,
+		clock: newSimClock(time.Unix(simGenesis, 0)),
+		config: &config.ChainConfig{
+			ChainId:              config.TestChainConfig.ChainId,
+			EIP158Block:          config.TestChainConfig.EIP158Block,
+			ByzantiumBlock:       config.TestChainConfig.ByzantiumBlock,
+			ConstantinopleBlock:  config.TestChainConfig.ConstantinopleBlock,
+			SystemContractsBlock: config.TestChainConfig.SystemContractsBlock,
+			DPoS:                 &config.DPoSConfig{Period: simPeriod, Epoch: epoch},
+		},
+	}
+	// Derive the keys from the index of the nodes, so the schedules are reproducible
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < nodes; i++ {
+		key, err := crypto.ToECDSA(crypto.Keccak256([]byte{byte(i)}))
+		if err != nil {
+			t.Fatalf("failed to derive key of node %d: %v", i, err)
+		}
+		n := &simNode{
+			index:  i,
+			key:    key,
+			addr:   crypto.PubkeyToAddress(key.PublicKey),
+			nodeId: discover.PubkeyID(&key.PublicKey).TerminalString(),
+		}
+		alloc[n.addr] = core.GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(config.Ether))}
+		net.nodes = append(net.nodes, n)
+	}
+	extra := make([]byte, 32)
+	if delegators > 0 {
+		ids := make([]string, delegators)
+		for i := range ids {
+			ids[i] = net.nodes[i].nodeId
+		}
+		blob, err := rlp.EncodeToBytes(ids)
+		if err != nil {
+			t.Fatalf("failed to encode delegators: %v", err)
+		}
+		extra = append(extra, blob...)
+	}
+	gspec := &core.Genesis{
+		Config:    net.config,
+		Timestamp: simGenesis,
+		ExtraData: append(extra, make([]byte, 65)...),
+		GasLimit:  config.GenesisGasLimit,
+		Alloc:     alloc,
+	}
+	for _, n := range net.nodes {
+		db, _ := store.NewMemDatabase()
+		gspec.MustCommit(db)
+		net.start(n, db)
+	}
+	return net
+}
+
+// start creates the chain and the protocol manager of a node on top of its
+// database holding the genesis block, and starts it.
+func (net *simNetwork) start(n *simNode, db *store.MemDatabase) {
+	evmux := new(event.TypeMux)
+
+	engine := CreateConsensusEngine(nil, net.config, db)
+	chain, err := core.NewBlockChain(db, nil, net.config, engine, vm.Config{})
+	if err != nil {
+		net.t.Fatalf("failed to create chain of node %d: %v", n.index, err)
+	}
+	poolConfig := DefaultConfig.TxPool
+	poolConfig.Journal = ""
+
+	eth := &JuchainService{
+		config:         &DefaultConfig,
+		chainDb:        db,
+		chainConfig:    net.config,
+		blockchain:     chain,
+		dappchains:     make(map[common.Address]*core.BlockChain),
+		eventMux:       evmux,
+		accountManager: account.NewManager(),
+		engine:         engine,
+		networkId:      DefaultConfig.NetworkId,
+		etherbase:      n.addr,
+		txPool:         core.NewTxPool(poolConfig, net.config, chain),
+	}
+	eth.ApiBackend = &EthApiBackend{eth: eth}
+
+	pm, err := NewProtocolManager(eth, net.config, &node.Config{P2P: p2p.Config{PrivateKey: n.key}}, downloader.FullSync, DefaultConfig.NetworkId, evmux, eth.txPool, engine, chain, db)
+	if err != nil {
+		net.t.Fatalf("failed to create protocol manager of node %d: %v", n.index, err)
+	}
+	// Drive the election by the simulated clock, with reproducible tickets
+	pm.dposManager.clock = net.clock
+	pm.dposManager.rand = rand.New(rand.NewSource(int64(n.index)))
+	pm.dposManager.dposManager.clock = net.clock
+
+	n.chain, n.eth, n.pm = chain, eth, pm
+	pm.Start(1000)
+}
+
+// connect links two nodes and waits until both registered the other as a peer.
+func (net *simNetwork) connect(a, b int) {
+	net.lock.Lock()
+	link := &simLink{net: net, nodes: [2]int{a, b}}
+	for i := range link.queues {
+		link.queues[i] = &simQueue{cond: sync.NewCond(&net.lock)}
+	}
+	net.links = append(net.links, link)
+	net.lock.Unlock()
+
+	for side, index := range link.nodes {
+		var (
+			local  = net.nodes[index]
+			remote = net.nodes[link.nodes[1-side]]
+			id     = discover.PubkeyID(&remote.key.PublicKey)
+			rw     = &simEnd{link: link, side: side}
+		)
+		go local.pm.SubProtocols[0].Run(p2p.NewPeer(id, remote.nodeId, nil), rw)
+	}
+	net.waitFor("peers connected", func() bool {
+		return net.nodes[a].pm.peers.Peer(net.nodes[b].nodeId) != nil && net.nodes[b].pm.peers.Peer(net.nodes[a].nodeId) != nil
+	})
+}
+
+// connectAll links every pair of online nodes not linked yet.
+func (net *simNetwork) connectAll() {
+	for a := range net.nodes {
+		for b := a + 1; b < len(net.nodes); b++ {
+			if !net.nodes[a].stopped && !net.nodes[b].stopped && !net.linked(a, b) {
+				net.connect(a, b)
+			}
+		}
+	}
+}
+
+// linked returns whether two nodes are connected.
+func (net *simNetwork) linked(a, b int) bool {
+	net.lock.Lock()
+	defer net.lock.Unlock()
+
+	for _, link := range net.links {
+		if (link.nodes[0] == a && link.nodes[1] == b) || (link.nodes[0] == b && link.nodes[1] == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// disconnect tears down the links matching the filter, waiting until the nodes
+// dropped each other.
+func (net *simNetwork) disconnect(match func(a, b int) bool) {
+	net.lock.Lock()
+	var dropped []*simLink
+	for i := 0; i < len(net.links); i++ {
+		if link := net.links[i]; match(link.nodes[0], link.nodes[1]) {
+			link.closed = true
+			for _, queue := range link.queues {
+				queue.msgs, queue.busy = nil, false
+				queue.cond.Broadcast()
+			}
+			dropped = append(dropped, link)
+			net.links = append(net.links[:i], net.links[i+1:]...)
+			i--
+		}
+	}
+	net.lock.Unlock()
+
+	for _, link := range dropped {
+		a, b := net.nodes[link.nodes[0]], net.nodes[link.nodes[1]]
+		net.waitFor("peers dropped", func() bool {
+			return a.pm.peers.Peer(b.nodeId) == nil && b.pm.peers.Peer(a.nodeId) == nil
+		})
+	}
+}
+
+// partition splits the network into the given groups of nodes, which can't reach
+// each other anymore.
+func (net *simNetwork) partition(groups ...[]int) {
+	group := make(map[int]int)
+	for i, nodes := range groups {
+		for _, index := range nodes {
+			group[index] = i
+		}
+	}
+	net.disconnect(func(a, b int) bool { return group[a] != group[b] })
+}
+
+// heal reconnects every online node and lets the nodes behind synchronise with
+// their best peer, as they would on their next forced sync.
+func (net *simNetwork) heal() {
+	net.connectAll()
+	for _, n := range net.nodes {
+		if !n.stopped {
+			go n.pm.synchronise(n.pm.peers.BestPeer())
+		}
+	}
+	net.settle()
+}
+
+// stop takes a node offline for good.
+func (net *simNetwork) stop(index int) {
+	n := net.nodes[index]
+	net.disconnect(func(a, b int) bool { return a == index || b == index })
+	n.stopped = true
+	n.pm.Stop()
+	n.eth.txPool.Stop()
+	n.chain.Stop()
+}
+
+// close stops every node still online.
+func (net *simNetwork) close() {
+	for i, n := range net.nodes {
+		if !n.stopped {
+			net.stop(i)
+		}
+	}
+}
+
+// idle returns whether every message sent was handled by its recipient.
+func (net *simNetwork) idle() bool {
+	net.lock.Lock()
+	defer net.lock.Unlock()
+
+	for _, link := range net.links {
+		for _, queue := range link.queues {
+			if len(queue.msgs) > 0 || queue.busy {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// group returns the online nodes reachable from the given node.
+func (net *simNetwork) group(index int) []*simNode {
+	var (
+		seen  = map[int]bool{index: true}
+		queue = []int{index}
+		group []*simNode
+	)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		group = append(group, net.nodes[n])
+
+		net.lock.Lock()
+		for _, link := range net.links {
+			for side, a := range link.nodes {
+				if b := link.nodes[1-side]; a == n && !seen[b] {
+					seen[b] = true
+					queue = append(queue, b)
+				}
+			}
+		}
+		net.lock.Unlock()
+	}
+	return group
+}
+
+// settle waits until all messages were handled and the nodes reaching each
+// other agree on their head block.
+func (net *simNetwork) settle() {
+	net.waitFor("network settled", func() bool {
+		if !net.idle() {
+			return false
+		}
+		for i, n := range net.nodes {
+			if n.stopped {
+				continue
+			}
+			head := n.chain.CurrentBlock().Hash()
+			for _, peer := range net.group(i) {
+				if peer.chain.CurrentBlock().Hash() != head {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// run moves the clock forward second by second, letting the network settle
+// after every second.
+func (net *simNetwork) run(d time.Duration) {
+	net.clock.Run(0)
+	net.settle()
+	for ; d > 0; d -= time.Second {
+		net.clock.Run(time.Second)
+		net.settle()
+	}
+}
+
+// runSlots moves the clock over the given number of slots.
+func (net *simNetwork) runSlots(slots int) {
+	net.run(time.Duration(slots*simPeriod) * time.Second)
+}
+
+// waitFor waits until the condition holds, failing the test on timeout.
+func (net *simNetwork) waitFor(what string, cond func() bool) {
+	for deadline := time.Now().Add(simTimeout); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			net.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// producer returns the index of the delegator scheduled for the given round on
+// top of the given parent, -1 if none is.
+func (net *simNetwork) producer(n *simNode, parent *types.Header, round uint64) int {
+	schedule, err := n.dpos().engine.Schedule(n.chain, parent)
+	if err != nil {
+		net.t.Fatalf("failed to retrieve schedule on top of block %d: %v", parent.Number, err)
+	}
+	if len(schedule.Delegators) == 0 {
+		return -1
+	}
+	return net.index(schedule.Producer(round))
+}
+
+// index returns the index of the node with the given short id, -1 if unknown.
+func (net *simNetwork) index(nodeId string) int {
+	for _, n := range net.nodes {
+		if n.nodeId == nodeId {
+			return n.index
+		}
+	}
+	return -1
+}
+
+// checkChain verifies that every block of the canonical chain of the node was
+// sealed by the delegator scheduled for its round, if any, and returns the number
+// of blocks sealed by every node. Without delegators the election node packages
+// the blocks in any round.
+func (net *simNetwork) checkChain(n *simNode) map[int]int {
+	sealed := make(map[int]int)
+	for number := uint64(1); number <= n.chain.CurrentBlock().NumberU64(); number++ {
+		header := n.chain.GetHeaderByNumber(number)
+		parent := n.chain.GetHeaderByNumber(number - 1)
+		if header.Round <= parent.Round {
+			net.t.Errorf("node %d: block %d round %d not after parent round %d", n.index, number, header.Round, parent.Round)
+		}
+		signer, err := n.dpos().engine.Signer(header)
+		if err != nil || signer != header.PresidentId {
+			net.t.Errorf("node %d: block %d signer mismatch: have %s (%v), want %s", n.index, number, signer, err, header.PresidentId)
+		}
+		if producer := net.producer(n, parent, header.Round); producer >= 0 && producer != net.index(signer) {
+			net.t.Errorf("node %d: block %d round %d sealed by node %d, scheduled node %d", n.index, number, header.Round, net.index(signer), producer)
+		}
+		sealed[net.index(signer)]++
+	}
+	return sealed
+}
+
+// waitFinalized waits until every given node finalized the last block it sealed
+// itself. Pre-commit votes for blocks not imported yet are dropped, so only the
+// sealer of a block is sure to collect the votes of all the other delegators.
+func (net *simNetwork) waitFinalized(nodes ...*simNode) {
+	for _, n := range nodes {
+		var last uint64
+		for number := n.chain.CurrentBlock().NumberU64(); number > 0; number-- {
+			if n.chain.GetHeaderByNumber(number).PresidentId == n.nodeId {
+				last = number
+				break
+			}
+		}
+		if last == 0 {
+			net.t.Fatalf("node %d: no block sealed", n.index)
+		}
+		net.waitFor("blocks finalized", func() bool {
+			return n.chain.CurrentFinalizedHeader().Number.Uint64() >= last
+		})
+	}
+}
+
+// Tests that two nodes without any delegators elect the node with the most
+// tickets, which packages the blocks of both while no delegator is recorded.
+func TestSimulatedElection(t *testing.T) {
+	net := newSimNetwork(t, 2, 0, 0)
+	defer net.close()
+
+	net.connect(0, 1)
+	net.run(0)
+
+	var tickets [2]uint32
+	for i, n := range net.nodes {
+		n.dvote().lock.Lock()
+		if info := n.dvote().nextElectionInfo; info == nil || info.round != 1 {
+			t.Fatalf("node %d: election of the first round not started", i)
+		}
+		tickets[i] = n.dvote().nextElectionInfo.electionTickets
+		n.dvote().lock.Unlock()
+	}
+	winner := 0
+	if tickets[1] > tickets[0] {
+		winner = 1
+	}
+	if tickets[0] == tickets[1] {
+		t.Fatalf("tied tickets %v", tickets)
+	}
+	// The first round is confirmed by every node once it begins
+	net.run(time.Duration(ElectingInterval) * time.Second)
+	for i, n := range net.nodes {
+		n.dvote().lock.Lock()
+		info := n.dvote().electionInfo
+		n.dvote().lock.Unlock()
+
+		if info == nil || info.electionNodeId != net.nodes[winner].nodeId {
+			t.Fatalf("node %d: election node mismatch: have %+v, want %s", i, info, net.nodes[winner].nodeId)
+		}
+	}
+	// The election node bootstraps the chain until delegators are recorded
+	net.run(time.Duration(ElectingInterval-1) * time.Second)
+
+	head := net.nodes[0].chain.CurrentBlock()
+	if head.NumberU64() == 0 {
+		t.Fatalf("no block packaged by the election node")
+	}
+	if other := net.nodes[1].chain.CurrentBlock(); other.Hash() != head.Hash() {
+		t.Fatalf("heads mismatch: %d [%x] vs %d [%x]", head.NumberU64(), head.Hash(), other.NumberU64(), other.Hash())
+	}
+	if sealed := net.checkChain(net.nodes[0]); sealed[winner] != int(head.NumberU64()) {
+		t.Errorf("blocks sealed by node %d: have %d, want %d", winner, sealed[winner], head.NumberU64())
+	}
+}
+
+// Tests that the delegators recorded in the genesis block package the blocks in
+// turns, one block every slot, and finalize them.
+func TestSimulatedSlotRotation(t *testing.T) {
+	net := newSimNetwork(t, 4, 4, 0)
+	defer net.close()
+
+	net.connectAll()
+	net.runSlots(12)
+
+	for _, n := range net.nodes {
+		if head := n.chain.CurrentBlock(); head.NumberU64() != 12 {
+			t.Fatalf("node %d: head mismatch: have %d, want %d", n.index, head.NumberU64(), 12)
+		}
+	}
+	sealed := net.checkChain(net.nodes[0])
+	for _, n := range net.nodes {
+		if sealed[n.index] != 3 {
+			t.Errorf("node %d: sealed blocks mismatch: have %d, want %d", n.index, sealed[n.index], 3)
+		}
+		if head := n.chain.GetHeaderByNumber(12); head.Round != 12 {
+			t.Errorf("node %d: slot skipped, head round %d", n.index, head.Round)
+		}
+	}
+	// Every delegator votes for every block, finalizing them
+	net.waitFinalized(net.nodes...)
+}
+
+// Tests that the slots of an offline delegator are skipped, while the remaining
+// delegators keep packaging and finalizing their blocks.
+func TestSimulatedOfflineDelegator(t *testing.T) {
+	net := newSimNetwork(t, 4, 4, 0)
+	defer net.close()
+
+	genesis := net.nodes[0].chain.Genesis().Header()
+	offline := net.producer(net.nodes[0], genesis, 2)
+	net.stop(offline)
+
+	net.connectAll()
+	net.runSlots(12)
+
+	online := net.nodes[(offline+1)%len(net.nodes)]
+	sealed := net.checkChain(online)
+	if sealed[offline] != 0 {
+		t.Errorf("offline node sealed %d blocks", sealed[offline])
+	}
+	if head := online.chain.CurrentBlock(); head.NumberU64() != 9 {
+		t.Errorf("head mismatch: have %d, want %d", head.NumberU64(), 9)
+	}
+	for number := uint64(1); number <= online.chain.CurrentBlock().NumberU64(); number++ {
+		header := online.chain.GetHeaderByNumber(number)
+		parent := online.chain.GetHeaderByNumber(number - 1)
+		for round := parent.Round + 1; round < header.Round; round++ {
+			if producer := net.producer(online, parent, round); producer != offline {
+				t.Errorf("block %d: slot %d of online node %d skipped", number, round, producer)
+			}
+		}
+	}
+	// The votes of the three online delegators still complete a majority
+	var nodes []*simNode
+	for _, n := range net.nodes {
+		if !n.stopped {
+			nodes = append(nodes, n)
+		}
+	}
+	net.waitFinalized(nodes...)
+}
+
+// Tests that a partitioned network keeps packaging on both sides, finalizing
+// only the blocks of the majority, and that the minority reorganises to the
+// chain of the majority once the partition heals.
+func TestSimulatedPartition(t *testing.T) {
+	net := newSimNetwork(t, 4, 4, 0)
+	defer net.close()
+
+	net.connectAll()
+	net.runSlots(4)
+
+	// Cut off the delegator of the fourth slot, which packages every fourth block
+	var (
+		lone     = net.producer(net.nodes[0], net.nodes[0].chain.CurrentHeader(), 4)
+		majority []*simNode
+		group    []int
+	)
+	for _, n := range net.nodes {
+		if n.index != lone {
+			majority = append(majority, n)
+			group = append(group, n.index)
+		}
+	}
+	minority := net.nodes[lone]
+	net.partition(group, []int{lone})
+	net.runSlots(8)
+
+	// Both sides keep packaging in their own slots only
+	if sealed := net.checkChain(majority[0]); sealed[lone] != 1 || majority[0].chain.CurrentBlock().NumberU64() != 10 {
+		t.Errorf("majority chain mismatch: %d blocks, sealed %v", majority[0].chain.CurrentBlock().NumberU64(), sealed)
+	}
+	if sealed := net.checkChain(minority); sealed[lone] != 3 || minority.chain.CurrentBlock().NumberU64() != 6 {
+		t.Errorf("minority chain mismatch: %d blocks, sealed %v", minority.chain.CurrentBlock().NumberU64(), sealed)
+	}
+	// Only the majority completes the votes to finalize its blocks
+	net.waitFinalized(majority...)
+	if number := minority.chain.CurrentFinalizedHeader().Number.Uint64(); number > 4 {
+		t.Errorf("minority finalized block %d", number)
+	}
+	head := majority[0].chain.CurrentBlock()
+
+	net.heal()
+	for _, n := range net.nodes {
+		if n.chain.CurrentBlock().Hash() != head.Hash() {
+			t.Errorf("node %d: head mismatch: have %d [%x], want %d [%x]", n.index, n.chain.CurrentBlock().NumberU64(), n.chain.CurrentBlock().Hash(), head.NumberU64(), head.Hash())
+		}
+	}
+}
+
+// Tests that the delegators voted in the registry during an epoch take over the
+// packaging from the checkpoint on, replacing the delegator which got no votes.
+func TestSimulatedDelegatorElection(t *testing.T) {
+	net := newSimNetwork(t, 4, 3, 6)
+	defer net.close()
+
+	net.connectAll()
+
+	// Nodes 0, 1 and 3 register and vote for themselves, node 2 drops out
+	registry, err := abi.JSON(strings.NewReader(vm.DelegatorRegistryABI))
+	if err != nil {
+		t.Fatalf("failed to load delegator registry ABI: %v", err)
+	}
+	signer := types.NewChainSigner(net.config.ChainId)
+	for _, i := range []int{0, 1, 3} {
+		n := net.nodes[i]
+		nonce := n.eth.txPool.State().GetNonce(n.addr)
+		signature, _ := crypto.Sign(vm.DelegatorRegistrationHash(n.addr).Bytes(), n.key)
+		for j, method := range []string{"register", "vote"} {
+			args := []interface{}{n.nodeId}
+			if method == "register" {
+				args = append(args, signature)
+			}
+			input, err := registry.Pack(method, args...)
+			if err != nil {
+				t.Fatalf("failed to pack %s: %v", method, err)
+			}
+			value := new(big.Int)
+			if method == "vote" {
+				value.Mul(big.NewInt(int64(i+1)), big.NewInt(config.Ether))
+			}
+			tx, _ := types.SignTx(types.NewTransaction(nonce+uint64(j), vm.DelegatorRegistryAddress, value, 200000, big.NewInt(1), input), signer, n.key)
+			for _, peer := range net.nodes {
+				if err := peer.eth.txPool.AddLocal(tx); err != nil {
+					t.Fatalf("node %d: failed to add %s transaction: %v", peer.index, method, err)
+				}
+			}
+		}
+	}
+	net.runSlots(6)
+
+	checkpoint := net.nodes[0].chain.CurrentHeader()
+	if checkpoint.Number.Uint64() != 6 {
+		t.Fatalf("checkpoint mismatch: have block %d, want %d", checkpoint.Number, 6)
+	}
+	schedule, err := net.nodes[0].dpos().engine.Schedule(net.nodes[0].chain, checkpoint)
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule of the next epoch: %v", err)
+	}
+	elected := make(map[string]bool)
+	for _, id := range schedule.Delegators {
+		elected[id] = true
+	}
+	if len(elected) != 3 || !elected[net.nodes[0].nodeId] || !elected[net.nodes[1].nodeId] || !elected[net.nodes[3].nodeId] {
+		t.Fatalf("elected delegators mismatch: %v", schedule.Delegators)
+	}
+	if net.nodes[2].dpos().isDelegatedNode() || !net.nodes[3].dpos().isDelegatedNode() {
+		t.Errorf("delegation of the next epoch mismatch")
+	}
+	net.runSlots(6)
+
+	sealed := net.checkChain(net.nodes[0])
+	for number := uint64(7); number <= 12; number++ {
+		if header := net.nodes[0].chain.GetHeaderByNumber(number); header.PresidentId == net.nodes[2].nodeId {
+			t.Errorf("block %d sealed by the dropped delegator", number)
+		}
+	}
+	if sealed[3] != 2 {
+		t.Errorf("blocks sealed by the elected delegator: have %d, want %d", sealed[3], 2)
+	}
+	for _, n := range net.nodes {
+		if n.chain.CurrentBlock().NumberU64() != 12 {
+			t.Errorf("node %d: head mismatch: have %d, want %d", n.index, n.chain.CurrentBlock().NumberU64(), 12)
+		}
+	}
+}
@@ -33,12 +33,29 @@ import (
 
 // EthClient defines typed wrappers for the Juchain RPC API.
 type EthClient struct {
-	c *Client
+	c      *Client
+	dappId *common.Address // DApp whose chain the requests are resolved against, nil for the main chain
 }
 
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *Client) *EthClient {
-	return &EthClient{c}
+	return &EthClient{c: c}
+}
+
+// DApp returns a client resolving the chain and state requests against the chain
+// of the given DApp instead of the main chain. Both clients share the same RPC
+// client.
+func (ec *EthClient) DApp(dappId common.Address) *EthClient {
+	return &EthClient{c: ec.c, dappId: &dappId}
+}
+
+// callContext performs a chain or state request, appending the DApp of the client
+// to the arguments if one is set.
+func (ec *EthClient) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if ec.dappId != nil {
+		args = append(args, *ec.dappId)
+	}
+	return ec.c.CallContext(ctx, result, method, args...)
 }
 
 func (ec *EthClient) Close() {
@@ -52,7 +69,7 @@ func (ec *EthClient) Close() {
 // Note that loading full blocks requires two requests. Use HeaderByHash
 // if you don't need all transactions or uncle headers.
 func (ec *EthClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return ec.getBlock(ctx, "eth_getBlockByHash", hash, true)
+	return ec.getBlock(ctx, "block_getBlockByHash", hash, true)
 }
 
 // BlockByNumber returns a block from the current canonical chain. If number is nil, the
@@ -61,7 +78,7 @@ func (ec *EthClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.
 // Note that loading full blocks requires two requests. Use HeaderByNumber
 // if you don't need all transactions or uncle headers.
 func (ec *EthClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
-	return ec.getBlock(ctx, "eth_getBlockByNumber", toBlockNumArg(number), true)
+	return ec.getBlock(ctx, "block_getBlockByNumber", toBlockNumArg(number), true)
 }
 
 type rpcBlock struct {
@@ -72,7 +89,7 @@ type rpcBlock struct {
 
 func (ec *EthClient) getBlock(ctx context.Context, method string, args ...interface{}) (*types.Block, error) {
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, method, args...)
+	err := ec.callContext(ctx, &raw, method, args...)
 	if err != nil {
 		return nil, err
 	} else if len(raw) == 0 {
@@ -107,10 +124,13 @@ func (ec *EthClient) getBlock(ctx context.Context, method string, args ...interf
 		reqs := make([]BatchElem, len(body.UncleHashes))
 		for i := range reqs {
 			reqs[i] = BatchElem{
-				Method: "eth_getUncleByBlockHashAndIndex",
+				Method: "block_getUncleByBlockHashAndIndex",
 				Args:   []interface{}{body.Hash, hexutil.EncodeUint64(uint64(i))},
 				Result: &uncles[i],
 			}
+			if ec.dappId != nil {
+				reqs[i].Args = append(reqs[i].Args, *ec.dappId)
+			}
 		}
 		if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
 			return nil, err
@@ -136,7 +156,7 @@ func (ec *EthClient) getBlock(ctx context.Context, method string, args ...interf
 // HeaderByHash returns the block header with the given hash.
 func (ec *EthClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	var head *types.Header
-	err := ec.c.CallContext(ctx, &head, "eth_getBlockByHash", hash, false)
+	err := ec.callContext(ctx, &head, "block_getBlockByHash", hash, false)
 	if err == nil && head == nil {
 		err = juchain.NotFound
 	}
@@ -147,7 +167,7 @@ func (ec *EthClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types
 // nil, the latest known header is returned.
 func (ec *EthClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
 	var head *types.Header
-	err := ec.c.CallContext(ctx, &head, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	err := ec.callContext(ctx, &head, "block_getBlockByNumber", toBlockNumArg(number), false)
 	if err == nil && head == nil {
 		err = juchain.NotFound
 	}
@@ -175,7 +195,7 @@ func (tx *rpcTransaction) UnmarshalJSON(msg []byte) error {
 // TransactionByHash returns the transaction with the given hash.
 func (ec *EthClient) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
 	var json *rpcTransaction
-	err = ec.c.CallContext(ctx, &json, "eth_getTransactionByHash", hash)
+	err = ec.callContext(ctx, &json, "block_getTransactionByHash", hash)
 	if err != nil {
 		return nil, false, err
 	} else if json == nil {
@@ -203,7 +223,7 @@ func (ec *EthClient) TransactionSender(ctx context.Context, tx *types.Transactio
 		Hash common.Hash
 		From common.Address
 	}
-	if err = ec.c.CallContext(ctx, &meta, "eth_getTransactionByBlockHashAndIndex", block, hexutil.Uint64(index)); err != nil {
+	if err = ec.callContext(ctx, &meta, "block_getTransactionByBlockHashAndIndex", block, hexutil.Uint64(index)); err != nil {
 		return common.Address{}, err
 	}
 	if meta.Hash == (common.Hash{}) || meta.Hash != tx.Hash() {
@@ -215,14 +235,14 @@ func (ec *EthClient) TransactionSender(ctx context.Context, tx *types.Transactio
 // TransactionCount returns the total number of transactions in the given block.
 func (ec *EthClient) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
 	var num hexutil.Uint
-	err := ec.c.CallContext(ctx, &num, "eth_getBlockTransactionCountByHash", blockHash)
+	err := ec.callContext(ctx, &num, "block_getBlockTransactionCountByHash", blockHash)
 	return uint(num), err
 }
 
 // TransactionInBlock returns a single transaction at index in the given block.
 func (ec *EthClient) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
 	var json *rpcTransaction
-	err := ec.c.CallContext(ctx, &json, "eth_getTransactionByBlockHashAndIndex", blockHash, hexutil.Uint64(index))
+	err := ec.callContext(ctx, &json, "block_getTransactionByBlockHashAndIndex", blockHash, hexutil.Uint64(index))
 	if err == nil {
 		if json == nil {
 			return nil, juchain.NotFound
@@ -238,7 +258,7 @@ func (ec *EthClient) TransactionInBlock(ctx context.Context, blockHash common.Ha
 // Note that the receipt is not available for pending transactions.
 func (ec *EthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	var r *types.Receipt
-	err := ec.c.CallContext(ctx, &r, "eth_getTransactionReceipt", txHash)
+	err := ec.callContext(ctx, &r, "block_getTransactionReceipt", txHash)
 	if err == nil {
 		if r == nil {
 			return nil, juchain.NotFound
@@ -251,6 +271,9 @@ func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
 	}
+	if number.Cmp(big.NewInt(FinalizedBlockNumber.Int64())) == 0 {
+		return "finalized"
+	}
 	return hexutil.EncodeBig(number)
 }
 
@@ -266,7 +289,7 @@ type rpcProgress struct {
 // no sync currently running, it returns nil.
 func (ec *EthClient) SyncProgress(ctx context.Context) (*juchain.SyncProgress, error) {
 	var raw json.RawMessage
-	if err := ec.c.CallContext(ctx, &raw, "eth_syncing"); err != nil {
+	if err := ec.c.CallContext(ctx, &raw, "block_syncing"); err != nil {
 		return nil, err
 	}
 	// Handle the possible response types
@@ -290,7 +313,7 @@ func (ec *EthClient) SyncProgress(ctx context.Context) (*juchain.SyncProgress, e
 // SubscribeNewHead subscribes to notifications about the current blockchain head
 // on the given channel.
 func (ec *EthClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (juchain.Subscription, error) {
-	return ec.c.EthSubscribe(ctx, ch, "newHeads")
+	return ec.c.Subscribe(ctx, "block", ch, "newHeads")
 }
 
 // State Access
@@ -312,7 +335,7 @@ func (ec *EthClient) NetworkID(ctx context.Context) (*big.Int, error) {
 // The block number can be nil, in which case the balance is taken from the latest known block.
 func (ec *EthClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
 	var result hexutil.Big
-	err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, toBlockNumArg(blockNumber))
+	err := ec.callContext(ctx, &result, "block_getBalance", account, toBlockNumArg(blockNumber))
 	return (*big.Int)(&result), err
 }
 
@@ -320,7 +343,7 @@ func (ec *EthClient) BalanceAt(ctx context.Context, account common.Address, bloc
 // The block number can be nil, in which case the value is taken from the latest known block.
 func (ec *EthClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getStorageAt", account, key, toBlockNumArg(blockNumber))
+	err := ec.callContext(ctx, &result, "block_getStorageAt", account, key, toBlockNumArg(blockNumber))
 	return result, err
 }
 
@@ -328,7 +351,7 @@ func (ec *EthClient) StorageAt(ctx context.Context, account common.Address, key
 // The block number can be nil, in which case the code is taken from the latest known block.
 func (ec *EthClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getCode", account, toBlockNumArg(blockNumber))
+	err := ec.callContext(ctx, &result, "block_getCode", account, toBlockNumArg(blockNumber))
 	return result, err
 }
 
@@ -336,22 +359,31 @@ func (ec *EthClient) CodeAt(ctx context.Context, account common.Address, blockNu
 // The block number can be nil, in which case the nonce is taken from the latest known block.
 func (ec *EthClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "eth_getTransactionCount", account, toBlockNumArg(blockNumber))
+	err := ec.callContext(ctx, &result, "block_getTransactionCount", account, toBlockNumArg(blockNumber))
 	return uint64(result), err
 }
 
 // Filters
 
-// FilterLogs executes a filter query.
+// FilterLogs executes a filter query. Queries without a DApp are resolved against
+// the DApp of the client.
 func (ec *EthClient) FilterLogs(ctx context.Context, q juchain.FilterQuery) ([]types.Log, error) {
 	var result []types.Log
-	err := ec.c.CallContext(ctx, &result, "eth_getLogs", toFilterArg(q))
+	err := ec.c.CallContext(ctx, &result, "block_getLogs", toFilterArg(ec.filterQuery(q)))
 	return result, err
 }
 
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
+// Queries without a DApp are resolved against the DApp of the client.
 func (ec *EthClient) SubscribeFilterLogs(ctx context.Context, q juchain.FilterQuery, ch chan<- types.Log) (juchain.Subscription, error) {
-	return ec.c.EthSubscribe(ctx, ch, "logs", toFilterArg(q))
+	return ec.c.Subscribe(ctx, "block", ch, "logs", toFilterArg(ec.filterQuery(q)))
+}
+
+func (ec *EthClient) filterQuery(q juchain.FilterQuery) juchain.FilterQuery {
+	if q.DAppId == nil {
+		q.DAppId = ec.dappId
+	}
+	return q
 }
 
 func toFilterArg(q juchain.FilterQuery) interface{} {
@@ -364,6 +396,9 @@ func toFilterArg(q juchain.FilterQuery) interface{} {
 	if q.FromBlock == nil {
 		arg["fromBlock"] = "0x0"
 	}
+	if q.DAppId != nil {
+		arg["dappId"] = q.DAppId
+	}
 	return arg
 }
 
@@ -372,21 +407,21 @@ func toFilterArg(q juchain.FilterQuery) interface{} {
 // PendingBalanceAt returns the wei balance of the given account in the pending state.
 func (ec *EthClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
 	var result hexutil.Big
-	err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, "pending")
+	err := ec.callContext(ctx, &result, "block_getBalance", account, "pending")
 	return (*big.Int)(&result), err
 }
 
 // PendingStorageAt returns the value of key in the contract storage of the given account in the pending state.
 func (ec *EthClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getStorageAt", account, key, "pending")
+	err := ec.callContext(ctx, &result, "block_getStorageAt", account, key, "pending")
 	return result, err
 }
 
 // PendingCodeAt returns the contract code of the given account in the pending state.
 func (ec *EthClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getCode", account, "pending")
+	err := ec.callContext(ctx, &result, "block_getCode", account, "pending")
 	return result, err
 }
 
@@ -394,14 +429,14 @@ func (ec *EthClient) PendingCodeAt(ctx context.Context, account common.Address)
 // This is the nonce that should be used for the next transaction.
 func (ec *EthClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "eth_getTransactionCount", account, "pending")
+	err := ec.callContext(ctx, &result, "block_getTransactionCount", account, "pending")
 	return uint64(result), err
 }
 
 // PendingTransactionCount returns the total number of transactions in the pending state.
 func (ec *EthClient) PendingTransactionCount(ctx context.Context) (uint, error) {
 	var num hexutil.Uint
-	err := ec.c.CallContext(ctx, &num, "eth_getBlockTransactionCountByNumber", "pending")
+	err := ec.callContext(ctx, &num, "block_getBlockTransactionCountByNumber", "pending")
 	return uint(num), err
 }
 
@@ -417,7 +452,7 @@ func (ec *EthClient) PendingTransactionCount(ctx context.Context) (uint, error)
 // blocks might not be available.
 func (ec *EthClient) CallContract(ctx context.Context, msg juchain.CallMsg, blockNumber *big.Int) ([]byte, error) {
 	var hex hexutil.Bytes
-	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+	err := ec.callContext(ctx, &hex, "block_call", toCallArg(msg), toBlockNumArg(blockNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -428,7 +463,7 @@ func (ec *EthClient) CallContract(ctx context.Context, msg juchain.CallMsg, bloc
 // The state seen by the contract call is the pending state.
 func (ec *EthClient) PendingCallContract(ctx context.Context, msg juchain.CallMsg) ([]byte, error) {
 	var hex hexutil.Bytes
-	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), "pending")
+	err := ec.callContext(ctx, &hex, "block_call", toCallArg(msg), "pending")
 	if err != nil {
 		return nil, err
 	}
@@ -439,7 +474,7 @@ func (ec *EthClient) PendingCallContract(ctx context.Context, msg juchain.CallMs
 // execution of a transaction.
 func (ec *EthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	var hex hexutil.Big
-	if err := ec.c.CallContext(ctx, &hex, "eth_gasPrice"); err != nil {
+	if err := ec.c.CallContext(ctx, &hex, "block_gasPrice"); err != nil {
 		return nil, err
 	}
 	return (*big.Int)(&hex), nil
@@ -451,7 +486,7 @@ func (ec *EthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 // but it should provide a basis for setting a reasonable default.
 func (ec *EthClient) EstimateGas(ctx context.Context, msg juchain.CallMsg) (uint64, error) {
 	var hex hexutil.Uint64
-	err := ec.c.CallContext(ctx, &hex, "eth_estimateGas", toCallArg(msg))
+	err := ec.c.CallContext(ctx, &hex, "block_estimateGas", toCallArg(msg))
 	if err != nil {
 		return 0, err
 	}
@@ -467,7 +502,7 @@ func (ec *EthClient) SendTransaction(ctx context.Context, tx *types.Transaction)
 	if err != nil {
 		return err
 	}
-	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
+	return ec.c.CallContext(ctx, nil, "block_sendRawTransaction", common.ToHex(data))
 }
 
 func toCallArg(msg juchain.CallMsg) interface{} {
@@ -17,7 +17,14 @@
 package rpc
 
 import (
+	"context"
+	"math/big"
+	"testing"
+
 	"github.com/juchain/go-juchain"
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/core/types"
 )
 
 // Verify that EthClient implements the Juchain interfaces.
@@ -34,3 +41,60 @@ var (
 	// _ = juchain.PendingStateEventer(&EthClient{})
 	_ = juchain.PendingContractCaller(&EthClient{})
 )
+
+// DAppChainService mimics the chain APIs of a node, recording the DApp the
+// requests are resolved against.
+type DAppChainService struct {
+	dappId *common.Address
+	logs   map[string]interface{}
+}
+
+func (s *DAppChainService) GetBalance(address common.Address, blockNr BlockNumber, dappId *common.Address) *hexutil.Big {
+	s.dappId = dappId
+	if dappId != nil {
+		return (*hexutil.Big)(big.NewInt(2))
+	}
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (s *DAppChainService) GetLogs(crit map[string]interface{}) []*types.Log {
+	s.logs = crit
+	return []*types.Log{}
+}
+
+// Tests that the requests of a DApp client reach the chain APIs in the namespace
+// they are registered under, carrying the DApp.
+func TestEthClientDAppRoundTrip(t *testing.T) {
+	service := new(DAppChainService)
+	server := NewServer()
+	if err := server.RegisterName("block", service); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	ec := NewClient(client)
+	dappId := common.HexToAddress("0xdc")
+
+	balance, err := ec.BalanceAt(context.Background(), common.Address{}, nil)
+	if err != nil {
+		t.Fatalf("main chain balance failed: %v", err)
+	}
+	if balance.Int64() != 1 || service.dappId != nil {
+		t.Fatalf("main chain balance resolved against DApp %v: have %v", service.dappId, balance)
+	}
+	balance, err = ec.DApp(dappId).BalanceAt(context.Background(), common.Address{}, nil)
+	if err != nil {
+		t.Fatalf("DApp balance failed: %v", err)
+	}
+	if balance.Int64() != 2 || service.dappId == nil || *service.dappId != dappId {
+		t.Fatalf("DApp balance resolved against DApp %v: have %v", service.dappId, balance)
+	}
+	if _, err := ec.DApp(dappId).FilterLogs(context.Background(), juchain.FilterQuery{}); err != nil {
+		t.Fatalf("DApp log filter failed: %v", err)
+	}
+	if have, _ := service.logs["dappId"].(string); common.HexToAddress(have) != dappId {
+		t.Fatalf("DApp log filter mismatch: have %v, want %v", service.logs["dappId"], dappId.Hex())
+	}
+}
@@ -461,6 +461,9 @@ func (fb *filterBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscr
 }
 
 func (fb *filterBackend) BloomStatus() (uint64, uint64) { return 4096, 0 }
+func (fb *filterBackend) DAppFilterBackend(dappId common.Address) (filters.Backend, func(), error) {
+	return nil, nil, errors.New("DApp chains not supported")
+}
 func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
 	panic("not supported")
 }
@@ -0,0 +1,392 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// DAppBridgeAddress is the address of the native bridge moving value between the
+// main chain and the DApp chains.
+var DAppBridgeAddress = common.BytesToAddress([]byte{1, 1})
+
+// DAppBridgeABI is the ABI of the native DApp bridge. Value is locked for a DApp
+// on the main chain and burned on the DApp chain, the other methods are queries.
+const DAppBridgeABI = `[
+	{"constant":false,"inputs":[{"name":"dapp","type":"address"},{"name":"recipient","type":"address"}],"name":"lock","outputs":[],"payable":true,"type":"function"},
+	{"constant":false,"inputs":[{"name":"recipient","type":"address"}],"name":"burn","outputs":[],"payable":true,"type":"function"},
+	{"constant":false,"inputs":[{"name":"proof","type":"bytes"}],"name":"release","outputs":[],"payable":false,"type":"function"},
+	{"constant":true,"inputs":[{"name":"id","type":"bytes32"}],"name":"deposit","outputs":[{"name":"dapp","type":"address"},{"name":"sender","type":"address"},{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"},{"name":"number","type":"uint256"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"dapp","type":"address"}],"name":"locked","outputs":[{"name":"amount","type":"uint256"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"hash","type":"bytes32"}],"name":"released","outputs":[{"name":"number","type":"uint256"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"id","type":"bytes32"}],"name":"minted","outputs":[{"name":"number","type":"uint256"}],"type":"function"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"id","type":"bytes32"},{"indexed":true,"name":"dapp","type":"address"},{"indexed":false,"name":"recipient","type":"address"},{"indexed":false,"name":"amount","type":"uint256"}],"name":"Locked","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"sender","type":"address"},{"indexed":false,"name":"recipient","type":"address"},{"indexed":false,"name":"amount","type":"uint256"}],"name":"Burned","type":"event"},
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"hash","type":"bytes32"},{"indexed":false,"name":"recipient","type":"address"},{"indexed":false,"name":"amount","type":"uint256"}],"name":"Released","type":"event"}
+]`
+
+var bridgeABI abi.ABI
+
+func init() {
+	var err error
+	if bridgeABI, err = abi.JSON(strings.NewReader(DAppBridgeABI)); err != nil {
+		panic(err)
+	}
+}
+
+// Storage layout of the DApp bridge, everything is kept under the hash of a
+// prefix and its key. The deposits and releases are kept on the main chain, the
+// mints on the DApp chains.
+var (
+	bridgeCountPrefix     = []byte("n") // bridgeCountPrefix + DApp id -> number of deposits
+	bridgeListPrefix      = []byte("l") // bridgeListPrefix + DApp id + index -> deposit id
+	bridgeDAppPrefix      = []byte("d") // bridgeDAppPrefix + deposit id -> DApp id
+	bridgeSenderPrefix    = []byte("s") // bridgeSenderPrefix + deposit id -> account locking the value
+	bridgeRecipientPrefix = []byte("r") // bridgeRecipientPrefix + deposit id -> account credited on the DApp chain
+	bridgeAmountPrefix    = []byte("a") // bridgeAmountPrefix + deposit id -> value locked
+	bridgeNumberPrefix    = []byte("b") // bridgeNumberPrefix + deposit id -> main chain block locking the value
+	bridgeLockedPrefix    = []byte("v") // bridgeLockedPrefix + DApp id -> value locked and not released yet
+	bridgeReleasedPrefix  = []byte("x") // bridgeReleasedPrefix + burn hash -> main chain block releasing the value
+	bridgeMintCountPrefix = []byte("c") // bridgeMintCountPrefix + DApp id -> number of deposits minted
+	bridgeMintedPrefix    = []byte("m") // bridgeMintedPrefix + deposit id -> DApp block minting the deposit
+)
+
+// BridgeDeposit is value locked on the main chain to be minted on a DApp chain.
+type BridgeDeposit struct {
+	Id        common.Hash    `json:"id"`        // Id of the deposit
+	DAppId    common.Address `json:"dappId"`    // DApp the value is locked for
+	Sender    common.Address `json:"sender"`    // Account locking the value
+	Recipient common.Address `json:"recipient"` // Account credited on the DApp chain
+	Amount    *big.Int       `json:"amount"`    // Value locked
+	Number    uint64         `json:"number"`    // Main chain block locking the value
+}
+
+// bridgeGet retrieves a value of the DApp bridge.
+func bridgeGet(db StateDB, prefix []byte, key []byte) common.Hash {
+	return db.GetState(DAppBridgeAddress, registrySlot(prefix, key))
+}
+
+// bridgeSet stores a value in the DApp bridge, making sure the bridge account is
+// not empty. Otherwise its storage would be dropped together with the empty
+// accounts at the end of the transaction.
+func bridgeSet(db StateDB, prefix []byte, key []byte, value common.Hash) {
+	if db.GetNonce(DAppBridgeAddress) == 0 {
+		db.SetNonce(DAppBridgeAddress, 1)
+	}
+	db.SetState(DAppBridgeAddress, registrySlot(prefix, key), value)
+}
+
+// depositKey returns the key of the deposit of the given index within a DApp.
+func depositKey(dappId common.Address, index uint64) []byte {
+	return append(dappId.Bytes(), common.BigToHash(new(big.Int).SetUint64(index)).Bytes()...)
+}
+
+// GetBridgeDeposit retrieves a deposit from the DApp bridge of the main chain, or
+// nil if no such deposit was made.
+func GetBridgeDeposit(db StateDB, id common.Hash) *BridgeDeposit {
+	dapp := bridgeGet(db, bridgeDAppPrefix, id[:])
+	if dapp == (common.Hash{}) {
+		return nil
+	}
+	sender := bridgeGet(db, bridgeSenderPrefix, id[:])
+	recipient := bridgeGet(db, bridgeRecipientPrefix, id[:])
+	return &BridgeDeposit{
+		Id:        id,
+		DAppId:    common.BytesToAddress(dapp[:]),
+		Sender:    common.BytesToAddress(sender[:]),
+		Recipient: common.BytesToAddress(recipient[:]),
+		Amount:    bridgeGet(db, bridgeAmountPrefix, id[:]).Big(),
+		Number:    bridgeGet(db, bridgeNumberPrefix, id[:]).Big().Uint64(),
+	}
+}
+
+// BridgeDeposits retrieves the deposits made for a DApp on the main chain,
+// starting at the given index.
+func BridgeDeposits(db StateDB, dappId common.Address, from uint64) []*BridgeDeposit {
+	count := bridgeGet(db, bridgeCountPrefix, dappId[:]).Big().Uint64()
+
+	var deposits []*BridgeDeposit
+	for i := from; i < count; i++ {
+		deposits = append(deposits, GetBridgeDeposit(db, bridgeGet(db, bridgeListPrefix, depositKey(dappId, i))))
+	}
+	return deposits
+}
+
+// LockedValue returns the value locked for a DApp on the main chain which was
+// not released yet.
+func LockedValue(db StateDB, dappId common.Address) *big.Int {
+	return bridgeGet(db, bridgeLockedPrefix, dappId[:]).Big()
+}
+
+// ReleasedBurn returns the number of the main chain block which released the
+// value burned by the DApp transaction of the given hash, or zero if it was not
+// released.
+func ReleasedBurn(db StateDB, hash common.Hash) uint64 {
+	return bridgeGet(db, bridgeReleasedPrefix, hash[:]).Big().Uint64()
+}
+
+// MintedDeposits returns the number of deposits of a DApp minted on its chain.
+func MintedDeposits(db StateDB, dappId common.Address) uint64 {
+	return bridgeGet(db, bridgeMintCountPrefix, dappId[:]).Big().Uint64()
+}
+
+// DepositMinted returns the number of the DApp block which minted the deposit of
+// the given id, or zero if it was not minted.
+func DepositMinted(db StateDB, id common.Hash) uint64 {
+	return bridgeGet(db, bridgeMintedPrefix, id[:]).Big().Uint64()
+}
+
+// MintDeposit credits the value of a deposit to its recipient on the DApp chain,
+// in the DApp block of the given number. Deposits have to be minted in order.
+func MintDeposit(db StateDB, deposit *BridgeDeposit, number uint64) {
+	minted := MintedDeposits(db, deposit.DAppId)
+
+	bridgeSet(db, bridgeMintCountPrefix, deposit.DAppId[:], common.BigToHash(new(big.Int).SetUint64(minted+1)))
+	bridgeSet(db, bridgeMintedPrefix, deposit.Id[:], common.BigToHash(new(big.Int).SetUint64(number)))
+	db.AddBalance(deposit.Recipient, deposit.Amount)
+}
+
+// BurnRecipient returns the account of the main chain receiving the value burned
+// by a DApp transaction, and whether the transaction burns any value at all.
+func BurnRecipient(tx *types.Transaction) (common.Address, bool) {
+	if tx.To() == nil || *tx.To() != DAppBridgeAddress || tx.Value().Sign() <= 0 {
+		return common.Address{}, false
+	}
+	input := tx.Data()
+	if len(input) < 4 {
+		return common.Address{}, false
+	}
+	method, err := bridgeABI.MethodById(input)
+	if err != nil || method.Name != "burn" {
+		return common.Address{}, false
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return common.Address{}, false
+	}
+	return args[0].(common.Address), true
+}
+
+// dappBridge implemented as a native system contract. Value locked for a DApp on
+// the main chain stays with the bridge and is minted on the DApp chain by the
+// blocks anchored after it, once final. Value burned on the DApp chain is released
+// on the main chain by proving the receipt of the burning transaction in a DApp
+// block sealed for a final anchor.
+//
+// The main chain does not follow the state of the DApp chains: a release trusts
+// the receipt root of the sealed DApp block, and is bounded by the value locked
+// for the DApp.
+type dappBridge struct{}
+
+func (c *dappBridge) Run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errExecutionReverted
+	}
+	method, err := bridgeABI.MethodById(input)
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	if contract.Value().Sign() != 0 && method.Name != "lock" && method.Name != "burn" {
+		return nil, errExecutionReverted
+	}
+	if !method.Const && evm.interpreter.readOnly {
+		return nil, errWriteProtection
+	}
+	var (
+		db    = evm.StateDB
+		dappy = evm.ChainConfig().DAppId != nil
+	)
+	switch method.Name {
+	case "lock":
+		if !contract.UseGas(config.BridgeLockGas) {
+			return nil, ErrOutOfGas
+		}
+		if dappy {
+			return nil, errExecutionReverted
+		}
+		return nil, c.lock(evm, contract.Caller(), args[0].(common.Address), args[1].(common.Address), contract.Value())
+
+	case "burn":
+		if !contract.UseGas(config.BridgeBurnGas) {
+			return nil, ErrOutOfGas
+		}
+		if !dappy {
+			return nil, errExecutionReverted
+		}
+		return nil, c.burn(evm, contract.Caller(), args[0].(common.Address), contract.Value())
+
+	case "release":
+		if !contract.UseGas(config.BridgeReleaseGas) {
+			return nil, ErrOutOfGas
+		}
+		if dappy {
+			return nil, errExecutionReverted
+		}
+		return nil, c.release(evm, args[0].([]byte))
+
+	case "deposit":
+		if !contract.UseGas(config.BridgeQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		deposit := GetBridgeDeposit(db, common.Hash(args[0].([32]byte)))
+		if deposit == nil {
+			deposit = &BridgeDeposit{Amount: new(big.Int)}
+		}
+		return method.Outputs.Pack(deposit.DAppId, deposit.Sender, deposit.Recipient, deposit.Amount, new(big.Int).SetUint64(deposit.Number))
+
+	case "locked":
+		if !contract.UseGas(config.BridgeQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		return method.Outputs.Pack(LockedValue(db, args[0].(common.Address)))
+
+	case "released":
+		if !contract.UseGas(config.BridgeQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		return method.Outputs.Pack(new(big.Int).SetUint64(ReleasedBurn(db, common.Hash(args[0].([32]byte)))))
+
+	case "minted":
+		if !contract.UseGas(config.BridgeQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		return method.Outputs.Pack(new(big.Int).SetUint64(DepositMinted(db, common.Hash(args[0].([32]byte)))))
+	}
+	return nil, errExecutionReverted
+}
+
+// lock keeps the value sent by the caller with the bridge and records it as a
+// deposit to be minted for the recipient on the chain of the DApp.
+func (c *dappBridge) lock(evm *EVM, caller common.Address, dappId common.Address, recipient common.Address, value *big.Int) error {
+	if value.Sign() == 0 || dappId == (common.Address{}) {
+		return errExecutionReverted
+	}
+	db := evm.StateDB
+
+	count := bridgeGet(db, bridgeCountPrefix, dappId[:]).Big().Uint64()
+	key := depositKey(dappId, count)
+	id := crypto.Keccak256Hash(key)
+
+	bridgeSet(db, bridgeListPrefix, key, id)
+	bridgeSet(db, bridgeCountPrefix, dappId[:], common.BigToHash(new(big.Int).SetUint64(count+1)))
+	bridgeSet(db, bridgeDAppPrefix, id[:], dappId.Hash())
+	bridgeSet(db, bridgeSenderPrefix, id[:], caller.Hash())
+	bridgeSet(db, bridgeRecipientPrefix, id[:], recipient.Hash())
+	bridgeSet(db, bridgeAmountPrefix, id[:], common.BigToHash(value))
+	bridgeSet(db, bridgeNumberPrefix, id[:], common.BigToHash(evm.BlockNumber))
+
+	locked := LockedValue(db, dappId)
+	bridgeSet(db, bridgeLockedPrefix, dappId[:], common.BigToHash(locked.Add(locked, value)))
+
+	c.log(evm, "Locked", []common.Hash{id, dappId.Hash()}, recipient.Hash(), common.BigToHash(value))
+	return nil
+}
+
+// burn destroys the value sent by the caller on the DApp chain, to be released
+// for the recipient on the main chain.
+func (c *dappBridge) burn(evm *EVM, caller common.Address, recipient common.Address, value *big.Int) error {
+	if value.Sign() == 0 {
+		return errExecutionReverted
+	}
+	evm.StateDB.SubBalance(DAppBridgeAddress, value)
+
+	c.log(evm, "Burned", []common.Hash{caller.Hash()}, recipient.Hash(), common.BigToHash(value))
+	return nil
+}
+
+// release verifies the proof of a burn anchored in a final main chain block, and
+// pays the burned value out of the value locked for the DApp. The receipt of the
+// burn has to show its successful execution on the DApp chain. Every burn may only
+// be released once.
+func (c *dappBridge) release(evm *EVM, proof []byte) error {
+	if evm.VerifyRelease == nil {
+		return errExecutionReverted
+	}
+	tx, receipt, err := evm.VerifyRelease(proof)
+	if err != nil {
+		return errExecutionReverted
+	}
+	recipient, ok := BurnRecipient(tx)
+	if !ok {
+		return errExecutionReverted
+	}
+	var (
+		db     = evm.StateDB
+		dappId = *tx.DAppID()
+		hash   = tx.Hash()
+		amount = tx.Value()
+	)
+	if !burned(receipt, recipient, amount) {
+		return errExecutionReverted
+	}
+	if ReleasedBurn(db, hash) != 0 {
+		return errExecutionReverted
+	}
+	locked := LockedValue(db, dappId)
+	if locked.Cmp(amount) < 0 {
+		return errExecutionReverted
+	}
+	bridgeSet(db, bridgeLockedPrefix, dappId[:], common.BigToHash(locked.Sub(locked, amount)))
+	bridgeSet(db, bridgeReleasedPrefix, hash[:], common.BigToHash(evm.BlockNumber))
+
+	db.SubBalance(DAppBridgeAddress, amount)
+	db.AddBalance(recipient, amount)
+
+	c.log(evm, "Released", []common.Hash{hash}, recipient.Hash(), common.BigToHash(amount))
+	return nil
+}
+
+// burned returns whether the receipt is the one of a successful burn of the given
+// amount for the recipient, logged by the bridge.
+func burned(receipt *types.Receipt, recipient common.Address, amount *big.Int) bool {
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		return false
+	}
+	data := append(recipient.Hash().Bytes(), common.BigToHash(amount).Bytes()...)
+	for _, log := range receipt.Logs {
+		if log.Address == DAppBridgeAddress && len(log.Topics) > 0 && log.Topics[0] == bridgeABI.Events["Burned"].Id() && bytes.Equal(log.Data, data) {
+			return true
+		}
+	}
+	return false
+}
+
+// log emits an event of the bridge with the given indexed topics and data words.
+func (c *dappBridge) log(evm *EVM, name string, topics []common.Hash, words ...common.Hash) {
+	var data []byte
+	for _, word := range words {
+		data = append(data, word[:]...)
+	}
+	evm.StateDB.AddLog(&types.Log{
+		Address:     DAppBridgeAddress,
+		Topics:      append([]common.Hash{bridgeABI.Events[name].Id()}, topics...),
+		Data:        data,
+		BlockNumber: evm.BlockNumber.Uint64(),
+	})
+}
@@ -0,0 +1,144 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
+)
+
+func newBridgeEVM(statedb *state.StateDB, chainConfig *config.ChainConfig, release ReleaseFunc) *EVM {
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		VerifyRelease: release,
+		BlockNumber:   big.NewInt(5),
+	}
+	return NewEVM(ctx, statedb, chainConfig, Config{})
+}
+
+func TestDAppBridge(t *testing.T) {
+	var (
+		dappId    = common.BytesToAddress([]byte{0xda})
+		sender    = common.BytesToAddress([]byte{1})
+		recipient = common.BytesToAddress([]byte{2})
+	)
+	db, _ := store.NewMemDatabase()
+	mainState, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	mainState.AddBalance(sender, big.NewInt(1000))
+	dappState, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// The burn proven by the release, anchored in a final block
+	burnInput, _ := bridgeABI.Pack("burn", sender)
+	burn := types.NewDAppCall(&dappId, 0, DAppBridgeAddress, big.NewInt(300), 100000, new(big.Int), burnInput).DAppTx()
+	var burnLogs []*types.Log
+	release := func(proof []byte) (*types.Transaction, *types.Receipt, error) {
+		switch string(proof) {
+		case "final":
+			return burn, &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: burnLogs}, nil
+		case "failed":
+			return burn, &types.Receipt{Status: types.ReceiptStatusFailed, Logs: burnLogs}, nil
+		case "unlogged":
+			return burn, &types.Receipt{Status: types.ReceiptStatusSuccessful}, nil
+		}
+		return nil, nil, errors.New("not final")
+	}
+	mainEVM := newBridgeEVM(mainState, config.TestChainConfig, release)
+	dappEVM := newBridgeEVM(dappState, config.TestChainConfig.DAppChainConfig(dappId), nil)
+
+	call := func(evm *EVM, from common.Address, value int64, method string, args ...interface{}) ([]byte, error) {
+		input, err := bridgeABI.Pack(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		ret, _, err := evm.Call(AccountRef(from), DAppBridgeAddress, input, 1000000, big.NewInt(value))
+		return ret, err
+	}
+	// Lock value for the DApp on the main chain only
+	if _, err := call(dappEVM, sender, 0, "lock", dappId, recipient); err != errExecutionReverted {
+		t.Errorf("empty lock: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(mainEVM, sender, 400, "lock", dappId, recipient); err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+	if balance := mainState.GetBalance(sender); balance.Cmp(big.NewInt(600)) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 600", balance)
+	}
+	if locked := LockedValue(mainState, dappId); locked.Cmp(big.NewInt(400)) != 0 {
+		t.Errorf("locked value mismatch: have %v, want 400", locked)
+	}
+	deposits := BridgeDeposits(mainState, dappId, 0)
+	if len(deposits) != 1 {
+		t.Fatalf("deposit count mismatch: have %d, want 1", len(deposits))
+	}
+	if d := deposits[0]; d.DAppId != dappId || d.Sender != sender || d.Recipient != recipient || d.Amount.Cmp(big.NewInt(400)) != 0 || d.Number != 5 {
+		t.Errorf("deposit mismatch: have %+v", d)
+	}
+	ret, err := call(mainEVM, sender, 0, "deposit", deposits[0].Id)
+	if err != nil {
+		t.Fatalf("failed to query deposit: %v", err)
+	}
+	var deposit struct {
+		Dapp, Sender, Recipient common.Address
+		Amount, Number          *big.Int
+	}
+	if err := bridgeABI.Unpack(&deposit, "deposit", ret); err != nil {
+		t.Fatalf("failed to unpack deposit: %v", err)
+	}
+	if deposit.Dapp != dappId || deposit.Recipient != recipient || deposit.Amount.Cmp(big.NewInt(400)) != 0 {
+		t.Errorf("queried deposit mismatch: have %+v", deposit)
+	}
+	// Mint the deposit on the DApp chain and burn part of it
+	MintDeposit(dappState, deposits[0], 1)
+	if minted := MintedDeposits(dappState, dappId); minted != 1 {
+		t.Errorf("minted deposits mismatch: have %d, want 1", minted)
+	}
+	if number := DepositMinted(dappState, deposits[0].Id); number != 1 {
+		t.Errorf("minting block mismatch: have %d, want 1", number)
+	}
+	if _, err := call(mainEVM, sender, 300, "burn", sender); err != errExecutionReverted {
+		t.Errorf("burn on main chain: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(dappEVM, recipient, 300, "burn", sender); err != nil {
+		t.Fatalf("failed to burn: %v", err)
+	}
+	if balance := dappState.GetBalance(recipient); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 100", balance)
+	}
+	if balance := dappState.GetBalance(DAppBridgeAddress); balance.Sign() != 0 {
+		t.Errorf("burned value kept: have %v, want 0", balance)
+	}
+	burnLogs = dappState.Logs()
+
+	// Release the burn on the main chain, once, if it succeeded on the DApp chain
+	for _, proof := range []string{"pending", "failed", "unlogged"} {
+		if _, err := call(mainEVM, sender, 0, "release", []byte(proof)); err != errExecutionReverted {
+			t.Errorf("%s release: have %v, want %v", proof, err, errExecutionReverted)
+		}
+	}
+	if _, err := call(mainEVM, sender, 0, "release", []byte("final")); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if balance := mainState.GetBalance(sender); balance.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("released balance mismatch: have %v, want 900", balance)
+	}
+	if locked := LockedValue(mainState, dappId); locked.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("locked value mismatch: have %v, want 100", locked)
+	}
+	if number := ReleasedBurn(mainState, burn.Hash()); number != 5 {
+		t.Errorf("releasing block mismatch: have %d, want 5", number)
+	}
+	if _, err := call(mainEVM, sender, 0, "release", []byte("final")); err != errExecutionReverted {
+		t.Errorf("double release: have %v, want %v", err, errExecutionReverted)
+	}
+}
@@ -17,15 +17,22 @@
 package vm
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"math/big"
+	"sort"
+	"strings"
 
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/common/math"
 	"github.com/juchain/go-juchain/common/crypto"
 	"github.com/juchain/go-juchain/common/crypto/bn256"
+	"github.com/juchain/go-juchain/common/rlp"
 	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/types"
+	"github.com/juchain/go-juchain/vm/solc/abi"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -377,3 +384,797 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+// SystemContract is the interface for native Go contracts which keep their data
+// in the storage of their own account. Contrary to precompiled contracts their
+// gas cost depends on the state, so they charge the gas by themselves.
+type SystemContract interface {
+	Run(evm *EVM, contract *Contract, input []byte) ([]byte, error) // Run runs the system contract
+}
+
+// DelegatorRegistryAddress is the address of the native delegator registry.
+var DelegatorRegistryAddress = common.BytesToAddress([]byte{1, 0})
+
+// SystemContracts contains the native system contracts of the chain from the
+// system contracts fork on, before the DApp context fork. No system contract runs
+// before the system contracts fork.
+var SystemContracts = map[common.Address]SystemContract{
+	DelegatorRegistryAddress: &delegatorRegistry{},
+	DAppBridgeAddress:        &dappBridge{},
+}
+
+// SystemContractsDAppContext contains the native system contracts of the chain
+// from the DApp context fork on.
+var SystemContractsDAppContext = map[common.Address]SystemContract{
+	DelegatorRegistryAddress: &delegatorRegistry{},
+	DAppBridgeAddress:        &dappBridge{},
+	DAppContextAddress:       &dappContext{},
+}
+
+// DelegatorRegistryABI is the ABI of the native delegator registry. The ids of
+// the candidates are the short node ids of the delegators, returned as bytes32.
+// Registering a candidate requires the signature of DelegatorRegistrationHash
+// by the node key of the candidate.
+const DelegatorRegistryABI = `[
+	{"constant":false,"inputs":[{"name":"id","type":"string"},{"name":"signature","type":"bytes"}],"name":"register","outputs":[],"payable":false,"type":"function"},
+	{"constant":false,"inputs":[{"name":"id","type":"string"}],"name":"vote","outputs":[],"payable":true,"type":"function"},
+	{"constant":false,"inputs":[],"name":"unvote","outputs":[],"payable":false,"type":"function"},
+	{"constant":false,"inputs":[],"name":"withdraw","outputs":[],"payable":false,"type":"function"},
+	{"constant":false,"inputs":[{"name":"first","type":"bytes"},{"name":"second","type":"bytes"}],"name":"slash","outputs":[],"payable":false,"type":"function"},
+	{"constant":false,"inputs":[{"name":"first","type":"bytes"},{"name":"second","type":"bytes"}],"name":"slashPreCommit","outputs":[],"payable":false,"type":"function"},
+	{"constant":false,"inputs":[{"name":"id","type":"string"}],"name":"unjail","outputs":[],"payable":false,"type":"function"},
+	{"constant":true,"inputs":[{"name":"id","type":"string"}],"name":"candidate","outputs":[{"name":"owner","type":"address"},{"name":"votes","type":"uint256"},{"name":"jailed","type":"bool"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"voter","type":"address"}],"name":"stake","outputs":[{"name":"id","type":"bytes32"},{"name":"bonded","type":"uint256"},{"name":"unbonding","type":"uint256"},{"name":"release","type":"uint256"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"n","type":"uint256"}],"name":"delegators","outputs":[{"name":"ids","type":"bytes32[]"},{"name":"votes","type":"uint256[]"}],"type":"function"}
+]`
+
+var registryABI abi.ABI
+
+func init() {
+	var err error
+	if registryABI, err = abi.JSON(strings.NewReader(DelegatorRegistryABI)); err != nil {
+		panic(err)
+	}
+}
+
+// Storage layout of the delegator registry. The number of candidates is kept in
+// the zero slot, everything else under the hash of a prefix and its key.
+var (
+	registryCountSlot = common.Hash{}
+
+	registryListPrefix        = []byte("l") // registryListPrefix + index -> candidate id
+	registryOwnerPrefix       = []byte("o") // registryOwnerPrefix + candidate id -> owner
+	registryVotesPrefix       = []byte("v") // registryVotesPrefix + candidate id -> bonded votes
+	registryTotalSharesPrefix = []byte("h") // registryTotalSharesPrefix + candidate id -> shares of all voters
+	registryPoolPrefix        = []byte("a") // registryPoolPrefix + candidate id -> votes unbonding from the candidate
+	registryPoolSharesPrefix  = []byte("g") // registryPoolSharesPrefix + candidate id -> unbonding shares of all voters
+	registryGenerationPrefix  = []byte("y") // registryGenerationPrefix + candidate id -> number of times all shares were voided
+	registryJailedPrefix      = []byte("j") // registryJailedPrefix + candidate id -> release epoch of the jailed candidate
+	registryMissedPrefix      = []byte("m") // registryMissedPrefix + candidate id -> missed slots
+	registryMissedEpochPrefix = []byte("n") // registryMissedEpochPrefix + candidate id -> epoch of the missed slots + 1
+	registryBallotPrefix      = []byte("b") // registryBallotPrefix + voter -> candidate id
+	registrySharesPrefix      = []byte("s") // registrySharesPrefix + voter -> shares of the candidate votes
+	registrySharesGenPrefix   = []byte("q") // registrySharesGenPrefix + voter -> generation of the candidate the shares belong to
+	registryUnbondingPrefix   = []byte("u") // registryUnbondingPrefix + voter -> shares of the votes unbonding from the candidate
+	registryUnbondFromPrefix  = []byte("f") // registryUnbondFromPrefix + voter -> candidate id the votes unbond from
+	registryUnbondGenPrefix   = []byte("z") // registryUnbondGenPrefix + voter -> generation of the candidate the unbonding shares belong to
+	registryReleasePrefix     = []byte("r") // registryReleasePrefix + voter -> release epoch of the unbonding votes
+	registryEvidencePrefix    = []byte("e") // registryEvidencePrefix + evidence hash -> evidence used flag
+	registryCommissionPrefix  = []byte("c") // registryCommissionPrefix + candidate id -> commission earned
+	registryRewardsPrefix     = []byte("w") // registryRewardsPrefix + candidate id -> rewards bonded for the voters
+	registryPayoutPrefix      = []byte("p") // registryPayoutPrefix + field index -> reward distribution of the latest block
+	registryProducedPrefix    = []byte("d") // registryProducedPrefix + candidate id + epoch -> slots produced within the epoch
+	registrySkippedPrefix     = []byte("k") // registrySkippedPrefix + candidate id + epoch -> slots missed within the epoch
+	registryAllProducedPrefix = []byte("t") // registryAllProducedPrefix + candidate id -> slots produced ever
+	registryAllSkippedPrefix  = []byte("x") // registryAllSkippedPrefix + candidate id -> slots missed ever
+
+	// registryJailedForGood is the release epoch of candidates which may never
+	// be released again.
+	registryJailedForGood = common.BytesToHash(bytes.Repeat([]byte{0xff}, common.HashLength))
+)
+
+// DelegatorCandidate is a node registered in the delegator registry.
+type DelegatorCandidate struct {
+	Id     string         `json:"id"`     // Short node id of the candidate
+	Owner  common.Address `json:"owner"`  // Account which registered the candidate
+	Votes  *big.Int       `json:"votes"`  // Votes bonded for the candidate
+	Jailed bool           `json:"jailed"` // Whether the candidate was excluded from being scheduled
+
+	Commission *big.Int `json:"commission"` // Commission earned by producing blocks
+	Rewards    *big.Int `json:"rewards"`    // Block rewards bonded for the voters of the candidate
+}
+
+// DelegatorStake is the stake an account bonded in the delegator registry.
+type DelegatorStake struct {
+	Candidate string   `json:"candidate"` // Candidate the votes are bonded for
+	Bonded    *big.Int `json:"bonded"`    // Bonded votes, reduced by the slashing of the candidate
+	Unbonding *big.Int `json:"unbonding"` // Withdrawn votes waiting for their release
+	Release   uint64   `json:"release"`   // Epoch the unbonding votes are released at
+}
+
+// DelegatorReward is the distribution of the emission of a block.
+type DelegatorReward struct {
+	Delegator  string   `json:"delegator"`  // Delegator which produced the block
+	Emission   *big.Int `json:"emission"`   // Total emission of the block
+	Treasury   *big.Int `json:"treasury"`   // Share paid to the treasury
+	Commission *big.Int `json:"commission"` // Share paid to the owner account of the delegator
+	Voters     *big.Int `json:"voters"`     // Share bonded pro rata for the voters of the delegator
+}
+
+// DelegatorLiveness is the record of the slots a delegator produced and missed.
+type DelegatorLiveness struct {
+	Epoch         uint64 `json:"epoch"`         // Epoch the epoch counters belong to
+	EpochProduced uint64 `json:"epochProduced"` // Slots produced within the epoch
+	EpochMissed   uint64 `json:"epochMissed"`   // Slots missed within the epoch
+	Produced      uint64 `json:"produced"`      // Slots produced ever
+	Missed        uint64 `json:"missed"`        // Slots missed ever
+	Jailed        bool   `json:"jailed"`        // Whether the delegator is excluded from being scheduled
+}
+
+// registrySlot returns the storage slot of the given key under the prefix.
+func registrySlot(prefix []byte, key []byte) common.Hash {
+	return crypto.Keccak256Hash(prefix, key)
+}
+
+// registryGet retrieves a value of the delegator registry.
+func registryGet(db StateDB, prefix []byte, key []byte) common.Hash {
+	return db.GetState(DelegatorRegistryAddress, registrySlot(prefix, key))
+}
+
+// registrySet stores a value in the delegator registry, making sure the registry
+// account is not empty. Otherwise its storage would be dropped together with the
+// empty accounts at the end of the transaction.
+func registrySet(db StateDB, prefix []byte, key []byte, value common.Hash) {
+	if db.GetNonce(DelegatorRegistryAddress) == 0 {
+		db.SetNonce(DelegatorRegistryAddress, 1)
+	}
+	db.SetState(DelegatorRegistryAddress, registrySlot(prefix, key), value)
+}
+
+// candidateKey converts a candidate id into its left aligned bytes32 form. Ids
+// must be between 1 and 32 bytes long and may not contain zero bytes.
+func candidateKey(id string) (common.Hash, bool) {
+	if len(id) == 0 || len(id) > common.HashLength || strings.IndexByte(id, 0) >= 0 {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(common.RightPadBytes([]byte(id), common.HashLength)), true
+}
+
+// candidateId converts the bytes32 form of a candidate id back into a string.
+func candidateId(key common.Hash) string {
+	return string(bytes.TrimRight(key[:], "\x00"))
+}
+
+// DelegatorRegistrationHash returns the hash a node signs with its node key to
+// register itself as a candidate owned by the given account. Binding the owner
+// keeps others from replaying the signature to claim the node for themselves.
+func DelegatorRegistrationHash(owner common.Address) common.Hash {
+	return crypto.Keccak256Hash(DelegatorRegistryAddress.Bytes(), owner.Bytes())
+}
+
+// registrationSigner recovers the short node id of the node key which signed
+// the registration of the given owner.
+func registrationSigner(owner common.Address, signature []byte) (string, bool) {
+	if len(signature) != 65 {
+		return "", false
+	}
+	pubkey, err := crypto.Ecrecover(DelegatorRegistrationHash(owner).Bytes(), signature)
+	if err != nil {
+		return "", false
+	}
+	// The short node id is the hex form of the first 8 bytes of the node id,
+	// which is the uncompressed public key without its format prefix
+	return hex.EncodeToString(pubkey[1:9]), true
+}
+
+// registered returns whether a candidate of the given key was registered.
+func registered(db StateDB, key common.Hash) bool {
+	return registryGet(db, registryOwnerPrefix, key[:]) != (common.Hash{})
+}
+
+// readCandidate assembles a registered candidate from the registry.
+func readCandidate(db StateDB, key common.Hash) *DelegatorCandidate {
+	owner := registryGet(db, registryOwnerPrefix, key[:])
+	return &DelegatorCandidate{
+		Id:     candidateId(key),
+		Owner:  common.BytesToAddress(owner[:]),
+		Votes:  registryGet(db, registryVotesPrefix, key[:]).Big(),
+		Jailed: registryGet(db, registryJailedPrefix, key[:]) != (common.Hash{}),
+
+		Commission: registryGet(db, registryCommissionPrefix, key[:]).Big(),
+		Rewards:    registryGet(db, registryRewardsPrefix, key[:]).Big(),
+	}
+}
+
+// bondedVotes converts the given shares of a candidate into votes.
+func bondedVotes(db StateDB, key common.Hash, shares *big.Int) *big.Int {
+	total := registryGet(db, registryTotalSharesPrefix, key[:]).Big()
+	if total.Sign() == 0 {
+		return new(big.Int)
+	}
+	votes := registryGet(db, registryVotesPrefix, key[:]).Big()
+	return votes.Div(votes.Mul(votes, shares), total)
+}
+
+// voterShares returns the shares of the votes the voter bonded for a candidate.
+// Shares of an earlier generation of the candidate were voided by slashing it
+// to zero and are worth nothing.
+func voterShares(db StateDB, voter common.Address, key common.Hash) *big.Int {
+	if registryGet(db, registrySharesGenPrefix, voter[:]) != registryGet(db, registryGenerationPrefix, key[:]) {
+		return new(big.Int)
+	}
+	return registryGet(db, registrySharesPrefix, voter[:]).Big()
+}
+
+// unbondingVotes returns the candidate the votes of the voter are unbonding from,
+// along with the shares the voter holds of its unbonding votes and their value.
+func unbondingVotes(db StateDB, voter common.Address) (common.Hash, *big.Int, *big.Int) {
+	key := registryGet(db, registryUnbondFromPrefix, voter[:])
+	if key == (common.Hash{}) || registryGet(db, registryUnbondGenPrefix, voter[:]) != registryGet(db, registryGenerationPrefix, key[:]) {
+		return key, new(big.Int), new(big.Int)
+	}
+	shares := registryGet(db, registryUnbondingPrefix, voter[:]).Big()
+	total := registryGet(db, registryPoolSharesPrefix, key[:]).Big()
+	if total.Sign() == 0 {
+		return key, new(big.Int), new(big.Int)
+	}
+	pool := registryGet(db, registryPoolPrefix, key[:]).Big()
+	return key, shares, pool.Div(pool.Mul(pool, shares), total)
+}
+
+// GetDelegatorCandidate retrieves a candidate from the delegator registry, or
+// nil if no such candidate was registered.
+func GetDelegatorCandidate(db StateDB, id string) *DelegatorCandidate {
+	key, ok := candidateKey(id)
+	if !ok || !registered(db, key) {
+		return nil
+	}
+	return readCandidate(db, key)
+}
+
+// GetDelegatorStake retrieves the stake the given account bonded in the delegator
+// registry.
+func GetDelegatorStake(db StateDB, voter common.Address) *DelegatorStake {
+	_, _, unbonding := unbondingVotes(db, voter)
+	stake := &DelegatorStake{
+		Bonded:    new(big.Int),
+		Unbonding: unbonding,
+		Release:   registryGet(db, registryReleasePrefix, voter[:]).Big().Uint64(),
+	}
+	if key := registryGet(db, registryBallotPrefix, voter[:]); key != (common.Hash{}) {
+		stake.Candidate = candidateId(key)
+		stake.Bonded = bondedVotes(db, key, voterShares(db, voter, key))
+	}
+	return stake
+}
+
+// TopDelegators returns at most n candidates of the delegator registry which are
+// not jailed and have votes bonded, ordered by their votes and then by their ids.
+func TopDelegators(db StateDB, n int) []*DelegatorCandidate {
+	count := db.GetState(DelegatorRegistryAddress, registryCountSlot).Big().Uint64()
+
+	candidates := make([]*DelegatorCandidate, 0, count)
+	for i := uint64(0); i < count; i++ {
+		key := registryGet(db, registryListPrefix, new(big.Int).SetUint64(i).Bytes())
+		if candidate := readCandidate(db, key); candidate.Votes.Sign() > 0 && !candidate.Jailed {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if c := candidates[i].Votes.Cmp(candidates[j].Votes); c != 0 {
+			return c > 0
+		}
+		return candidates[i].Id < candidates[j].Id
+	})
+	if n >= 0 && len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// SlashDelegator burns the given per mille of the votes bonded for a candidate
+// and of the votes unbonding from it, optionally jailing it for good. The slashed
+// amount is returned.
+func SlashDelegator(db StateDB, id string, permille uint64, jail bool) *big.Int {
+	key, ok := candidateKey(id)
+	if !ok || !registered(db, key) {
+		return new(big.Int)
+	}
+	votes := registryGet(db, registryVotesPrefix, key[:]).Big()
+	pool := registryGet(db, registryPoolPrefix, key[:]).Big()
+
+	penalty := new(big.Int)
+	for _, amount := range []*big.Int{votes, pool} {
+		slashed := new(big.Int).Mul(amount, new(big.Int).SetUint64(permille))
+		if slashed.Div(slashed, big.NewInt(1000)); slashed.Cmp(amount) > 0 {
+			slashed.Set(amount)
+		}
+		amount.Sub(amount, slashed)
+		penalty.Add(penalty, slashed)
+	}
+	registrySet(db, registryVotesPrefix, key[:], common.BigToHash(votes))
+	registrySet(db, registryPoolPrefix, key[:], common.BigToHash(pool))
+	db.SubBalance(DelegatorRegistryAddress, penalty)
+
+	// Nothing is left for the shares of the voters, void them so they don't
+	// dilute the votes bonded for the candidate afterwards
+	if votes.Sign() == 0 && pool.Sign() == 0 {
+		generation := registryGet(db, registryGenerationPrefix, key[:]).Big()
+		registrySet(db, registryGenerationPrefix, key[:], common.BigToHash(generation.Add(generation, common.Big1)))
+		registrySet(db, registryTotalSharesPrefix, key[:], common.Hash{})
+		registrySet(db, registryPoolSharesPrefix, key[:], common.Hash{})
+	}
+
+	if jail {
+		registrySet(db, registryJailedPrefix, key[:], registryJailedForGood)
+	}
+	return penalty
+}
+
+// EvidenceHash returns the hash double signing evidence is recorded under. It is
+// keyed on the slot both headers were sealed for, so a delegator is slashed at
+// most once per slot, whichever and however many conflicting headers it sealed.
+func EvidenceHash(first, second *types.Header) common.Hash {
+	return crypto.Keccak256Hash([]byte("doublesign"), []byte(first.PresidentId), first.DAppID.Bytes(), new(big.Int).SetUint64(first.Round).Bytes())
+}
+
+// EvidenceUsed returns whether the double signing evidence of both headers was
+// already used to slash a delegator.
+func EvidenceUsed(db StateDB, first, second *types.Header) bool {
+	return registryGet(db, registryEvidencePrefix, EvidenceHash(first, second).Bytes()) != (common.Hash{})
+}
+
+// PreCommitEvidenceHash returns the hash conflicting pre-commit evidence is
+// recorded under. A delegator is slashed at most once per voted height, however
+// many conflicting votes it cast.
+func PreCommitEvidenceHash(first, second *types.PreCommit) common.Hash {
+	return crypto.Keccak256Hash([]byte("precommit"), []byte(first.Delegator), new(big.Int).SetUint64(first.Number).Bytes())
+}
+
+// PreCommitEvidenceUsed returns whether the evidence of both conflicting votes
+// was already used to slash a delegator.
+func PreCommitEvidenceUsed(db StateDB, first, second *types.PreCommit) bool {
+	return registryGet(db, registryEvidencePrefix, PreCommitEvidenceHash(first, second).Bytes()) != (common.Hash{})
+}
+
+// PackSlash assembles the input of a registry call slashing the delegator which
+// sealed both headers.
+func PackSlash(first, second *types.Header) ([]byte, error) {
+	blobs := make([][]byte, 2)
+	for i, header := range []*types.Header{first, second} {
+		blob, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return nil, err
+		}
+		blobs[i] = blob
+	}
+	return registryABI.Pack("slash", blobs[0], blobs[1])
+}
+
+// PackSlashPreCommit assembles the input of a registry call slashing the
+// delegator which cast both conflicting pre-commit votes.
+func PackSlashPreCommit(first, second *types.PreCommit) ([]byte, error) {
+	blobs := make([][]byte, 2)
+	for i, vote := range []*types.PreCommit{first, second} {
+		blob, err := rlp.EncodeToBytes(vote)
+		if err != nil {
+			return nil, err
+		}
+		blobs[i] = blob
+	}
+	return registryABI.Pack("slashPreCommit", blobs[0], blobs[1])
+}
+
+// JailDelegator excludes a candidate from being scheduled until its owner
+// releases it at the given epoch. Longer running jails are kept.
+func JailDelegator(db StateDB, id string, release uint64) {
+	key, ok := candidateKey(id)
+	if !ok || !registered(db, key) {
+		return
+	}
+	jailed := registryGet(db, registryJailedPrefix, key[:])
+	if jailed == registryJailedForGood || jailed.Big().Uint64() >= release {
+		return
+	}
+	registrySet(db, registryJailedPrefix, key[:], common.BigToHash(new(big.Int).SetUint64(release)))
+}
+
+// DistributeReward bonds the voter share of a block reward for the voters of the
+// producing delegator, growing their stakes pro rata, and records the reward as
+// the one of the current block. The treasury share and the commission have to
+// be credited by the caller.
+func DistributeReward(db StateDB, reward *DelegatorReward) {
+	key, ok := candidateKey(reward.Delegator)
+	if ok && registered(db, key) {
+		if reward.Voters.Sign() > 0 {
+			votes := registryGet(db, registryVotesPrefix, key[:]).Big()
+			rewards := registryGet(db, registryRewardsPrefix, key[:]).Big()
+
+			registrySet(db, registryVotesPrefix, key[:], common.BigToHash(votes.Add(votes, reward.Voters)))
+			registrySet(db, registryRewardsPrefix, key[:], common.BigToHash(rewards.Add(rewards, reward.Voters)))
+			db.AddBalance(DelegatorRegistryAddress, reward.Voters)
+		}
+		if reward.Commission.Sign() > 0 {
+			commission := registryGet(db, registryCommissionPrefix, key[:]).Big()
+			registrySet(db, registryCommissionPrefix, key[:], common.BigToHash(commission.Add(commission, reward.Commission)))
+		}
+	}
+	fields := []common.Hash{key, common.BigToHash(reward.Emission), common.BigToHash(reward.Treasury), common.BigToHash(reward.Commission), common.BigToHash(reward.Voters)}
+	for i, field := range fields {
+		registrySet(db, registryPayoutPrefix, []byte{byte(i)}, field)
+	}
+}
+
+// GetBlockReward retrieves the reward distribution of the block the given state
+// belongs to.
+func GetBlockReward(db StateDB) *DelegatorReward {
+	fields := make([]common.Hash, 5)
+	for i := range fields {
+		fields[i] = registryGet(db, registryPayoutPrefix, []byte{byte(i)})
+	}
+	return &DelegatorReward{
+		Delegator:  candidateId(fields[0]),
+		Emission:   fields[1].Big(),
+		Treasury:   fields[2].Big(),
+		Commission: fields[3].Big(),
+		Voters:     fields[4].Big(),
+	}
+}
+
+// MissedSlots returns the number of slots a candidate missed within the given epoch.
+func MissedSlots(db StateDB, id string, epoch uint64) uint64 {
+	key, ok := candidateKey(id)
+	if !ok || registryGet(db, registryMissedEpochPrefix, key[:]).Big().Uint64() != epoch+1 {
+		return 0
+	}
+	return registryGet(db, registryMissedPrefix, key[:]).Big().Uint64()
+}
+
+// SetMissedSlots stores the number of slots a candidate missed within the given
+// epoch, dropping the count of any previous epoch.
+func SetMissedSlots(db StateDB, id string, epoch uint64, missed uint64) {
+	key, ok := candidateKey(id)
+	if !ok {
+		return
+	}
+	registrySet(db, registryMissedEpochPrefix, key[:], common.BigToHash(new(big.Int).SetUint64(epoch+1)))
+	registrySet(db, registryMissedPrefix, key[:], common.BigToHash(new(big.Int).SetUint64(missed)))
+}
+
+// livenessKey returns the key of the liveness counters of a candidate within
+// the given epoch.
+func livenessKey(key common.Hash, epoch uint64) []byte {
+	return append(key[:], new(big.Int).SetUint64(epoch).Bytes()...)
+}
+
+// RecordSlots adds to the number of slots a candidate produced and missed
+// within the given epoch.
+func RecordSlots(db StateDB, id string, epoch uint64, produced uint64, missed uint64) {
+	key, ok := candidateKey(id)
+	if !ok || !registered(db, key) {
+		return
+	}
+	counters := []struct {
+		prefix []byte
+		key    []byte
+		add    uint64
+	}{
+		{registryProducedPrefix, livenessKey(key, epoch), produced},
+		{registrySkippedPrefix, livenessKey(key, epoch), missed},
+		{registryAllProducedPrefix, key[:], produced},
+		{registryAllSkippedPrefix, key[:], missed},
+	}
+	for _, counter := range counters {
+		if counter.add > 0 {
+			count := registryGet(db, counter.prefix, counter.key).Big()
+			registrySet(db, counter.prefix, counter.key, common.BigToHash(count.Add(count, new(big.Int).SetUint64(counter.add))))
+		}
+	}
+}
+
+// GetDelegatorLiveness retrieves the slots a candidate produced and missed,
+// within the given epoch and ever, or nil if no such candidate was registered.
+func GetDelegatorLiveness(db StateDB, id string, epoch uint64) *DelegatorLiveness {
+	key, ok := candidateKey(id)
+	if !ok || !registered(db, key) {
+		return nil
+	}
+	return &DelegatorLiveness{
+		Epoch:         epoch,
+		EpochProduced: registryGet(db, registryProducedPrefix, livenessKey(key, epoch)).Big().Uint64(),
+		EpochMissed:   registryGet(db, registrySkippedPrefix, livenessKey(key, epoch)).Big().Uint64(),
+		Produced:      registryGet(db, registryAllProducedPrefix, key[:]).Big().Uint64(),
+		Missed:        registryGet(db, registryAllSkippedPrefix, key[:]).Big().Uint64(),
+		Jailed:        registryGet(db, registryJailedPrefix, key[:]) != (common.Hash{}),
+	}
+}
+
+// delegatorRegistry implemented as a native system contract. Every node may be
+// registered as a candidate once. Accounts vote for a candidate by bonding their
+// balance, which is locked until an unbonding period passed after the votes were
+// withdrawn, and is slashed along with the candidate until then. Candidates caught double signing are slashed and jailed for good,
+// candidates jailed for missing their slots may be released by their owner.
+type delegatorRegistry struct{}
+
+func (c *delegatorRegistry) Run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errExecutionReverted
+	}
+	method, err := registryABI.MethodById(input)
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	if contract.Value().Sign() != 0 && method.Name != "vote" {
+		return nil, errExecutionReverted
+	}
+	if !method.Const && evm.interpreter.readOnly {
+		return nil, errWriteProtection
+	}
+	var (
+		db    = evm.StateDB
+		dpos  = evm.ChainConfig().DPoS
+		epoch = evm.BlockNumber.Uint64() / dpos.EpochLength()
+	)
+	switch method.Name {
+	case "register":
+		if !contract.UseGas(config.DelegatorRegisterGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.register(db, contract.Caller(), args[0].(string), args[1].([]byte))
+
+	case "vote":
+		if !contract.UseGas(config.DelegatorVoteGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.vote(db, contract.Caller(), args[0].(string), contract.Value())
+
+	case "unvote":
+		if !contract.UseGas(config.DelegatorUnvoteGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.unvote(db, contract.Caller(), epoch+dpos.UnbondingEpochs())
+
+	case "withdraw":
+		if !contract.UseGas(config.DelegatorWithdrawGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.withdraw(db, contract.Caller(), epoch)
+
+	case "slash":
+		if !contract.UseGas(config.DelegatorSlashGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.slash(evm, args[0].([]byte), args[1].([]byte), dpos.DoubleSignPenalty())
+
+	case "slashPreCommit":
+		if !contract.UseGas(config.DelegatorSlashGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.slashPreCommit(evm, args[0].([]byte), args[1].([]byte), dpos.DoubleSignPenalty())
+
+	case "unjail":
+		if !contract.UseGas(config.DelegatorUnjailGas) {
+			return nil, ErrOutOfGas
+		}
+		return nil, c.unjail(db, contract.Caller(), args[0].(string), epoch)
+
+	case "candidate":
+		if !contract.UseGas(config.DelegatorQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		candidate := GetDelegatorCandidate(db, args[0].(string))
+		if candidate == nil {
+			candidate = &DelegatorCandidate{Votes: new(big.Int)}
+		}
+		return method.Outputs.Pack(candidate.Owner, candidate.Votes, candidate.Jailed)
+
+	case "stake":
+		if !contract.UseGas(config.DelegatorQueryGas) {
+			return nil, ErrOutOfGas
+		}
+		stake := GetDelegatorStake(db, args[0].(common.Address))
+		key, _ := candidateKey(stake.Candidate)
+		return method.Outputs.Pack([32]byte(key), stake.Bonded, stake.Unbonding, new(big.Int).SetUint64(stake.Release))
+
+	case "delegators":
+		count := db.GetState(DelegatorRegistryAddress, registryCountSlot).Big().Uint64()
+		if !contract.UseGas(config.DelegatorQueryGas + count*config.DelegatorListPerItemGas) {
+			return nil, ErrOutOfGas
+		}
+		n := args[0].(*big.Int)
+		if !n.IsInt64() || n.Int64() > int64(count) {
+			n = new(big.Int).SetUint64(count)
+		}
+		candidates := TopDelegators(db, int(n.Int64()))
+
+		ids, votes := make([][32]byte, len(candidates)), make([]*big.Int, len(candidates))
+		for i, candidate := range candidates {
+			key, _ := candidateKey(candidate.Id)
+			ids[i], votes[i] = key, candidate.Votes
+		}
+		return method.Outputs.Pack(ids, votes)
+	}
+	return nil, errExecutionReverted
+}
+
+// register adds the node of the given id to the candidates, owned by the caller.
+// The node has to prove the ownership of its id by signing the registration of
+// the caller with its node key, otherwise anyone could claim the id of another
+// node before it registered itself.
+func (c *delegatorRegistry) register(db StateDB, caller common.Address, id string, signature []byte) error {
+	key, ok := candidateKey(id)
+	if !ok || registered(db, key) {
+		return errExecutionReverted
+	}
+	if signer, ok := registrationSigner(caller, signature); !ok || signer != id {
+		return errExecutionReverted
+	}
+	count := db.GetState(DelegatorRegistryAddress, registryCountSlot).Big()
+
+	registrySet(db, registryListPrefix, count.Bytes(), key)
+	registrySet(db, registryOwnerPrefix, key[:], caller.Hash())
+	db.SetState(DelegatorRegistryAddress, registryCountSlot, common.BigToHash(count.Add(count, common.Big1)))
+	return nil
+}
+
+// vote bonds the value sent by the caller as votes for the given candidate. The
+// caller may add to the votes of its candidate, but has to withdraw them before
+// voting for another one.
+func (c *delegatorRegistry) vote(db StateDB, caller common.Address, id string, value *big.Int) error {
+	key, ok := candidateKey(id)
+	if !ok || value.Sign() == 0 || !registered(db, key) || registryGet(db, registryJailedPrefix, key[:]) != (common.Hash{}) {
+		return errExecutionReverted
+	}
+	if previous := registryGet(db, registryBallotPrefix, caller[:]); previous != (common.Hash{}) && previous != key {
+		return errExecutionReverted
+	}
+	// Votes are tracked as shares of the candidate, so that slashing the
+	// candidate hits all of its voters pro rata
+	votes := registryGet(db, registryVotesPrefix, key[:]).Big()
+	total := registryGet(db, registryTotalSharesPrefix, key[:]).Big()
+
+	shares := new(big.Int).Set(value)
+	if total.Sign() > 0 && votes.Sign() > 0 {
+		shares.Div(shares.Mul(shares, total), votes)
+	}
+	owned := voterShares(db, caller, key)
+
+	registrySet(db, registryVotesPrefix, key[:], common.BigToHash(votes.Add(votes, value)))
+	registrySet(db, registryTotalSharesPrefix, key[:], common.BigToHash(total.Add(total, shares)))
+	registrySet(db, registrySharesPrefix, caller[:], common.BigToHash(owned.Add(owned, shares)))
+	registrySet(db, registrySharesGenPrefix, caller[:], registryGet(db, registryGenerationPrefix, key[:]))
+	registrySet(db, registryBallotPrefix, caller[:], key)
+	return nil
+}
+
+// unvote withdraws all votes of the caller, which stay bonded until the given
+// release epoch. Until then they are slashed along with the candidate, so an
+// account may only unbond from one candidate at a time.
+func (c *delegatorRegistry) unvote(db StateDB, caller common.Address, release uint64) error {
+	key := registryGet(db, registryBallotPrefix, caller[:])
+	if key == (common.Hash{}) {
+		return errExecutionReverted
+	}
+	from, unbonding, _ := unbondingVotes(db, caller)
+	if unbonding.Sign() > 0 && from != key {
+		return errExecutionReverted
+	}
+	shares := voterShares(db, caller, key)
+	amount := bondedVotes(db, key, shares)
+
+	votes := registryGet(db, registryVotesPrefix, key[:]).Big()
+	total := registryGet(db, registryTotalSharesPrefix, key[:]).Big()
+
+	registrySet(db, registryVotesPrefix, key[:], common.BigToHash(votes.Sub(votes, amount)))
+	registrySet(db, registryTotalSharesPrefix, key[:], common.BigToHash(total.Sub(total, shares)))
+	registrySet(db, registrySharesPrefix, caller[:], common.Hash{})
+	registrySet(db, registryBallotPrefix, caller[:], common.Hash{})
+
+	// The withdrawn votes join the unbonding votes of the candidate as shares,
+	// the same way bonded votes do
+	pool := registryGet(db, registryPoolPrefix, key[:]).Big()
+	poolShares := registryGet(db, registryPoolSharesPrefix, key[:]).Big()
+
+	joined := new(big.Int).Set(amount)
+	if poolShares.Sign() > 0 && pool.Sign() > 0 {
+		joined.Div(joined.Mul(joined, poolShares), pool)
+	}
+	registrySet(db, registryPoolPrefix, key[:], common.BigToHash(pool.Add(pool, amount)))
+	registrySet(db, registryPoolSharesPrefix, key[:], common.BigToHash(poolShares.Add(poolShares, joined)))
+	registrySet(db, registryUnbondingPrefix, caller[:], common.BigToHash(unbonding.Add(unbonding, joined)))
+	registrySet(db, registryUnbondFromPrefix, caller[:], key)
+	registrySet(db, registryUnbondGenPrefix, caller[:], registryGet(db, registryGenerationPrefix, key[:]))
+	registrySet(db, registryReleasePrefix, caller[:], common.BigToHash(new(big.Int).SetUint64(release)))
+	return nil
+}
+
+// withdraw releases the unbonded votes of the caller back to its balance, once
+// the release epoch is reached.
+func (c *delegatorRegistry) withdraw(db StateDB, caller common.Address, epoch uint64) error {
+	key, shares, amount := unbondingVotes(db, caller)
+	if amount.Sign() == 0 || registryGet(db, registryReleasePrefix, caller[:]).Big().Uint64() > epoch {
+		return errExecutionReverted
+	}
+	pool := registryGet(db, registryPoolPrefix, key[:]).Big()
+	poolShares := registryGet(db, registryPoolSharesPrefix, key[:]).Big()
+
+	registrySet(db, registryPoolPrefix, key[:], common.BigToHash(pool.Sub(pool, amount)))
+	registrySet(db, registryPoolSharesPrefix, key[:], common.BigToHash(poolShares.Sub(poolShares, shares)))
+	registrySet(db, registryUnbondingPrefix, caller[:], common.Hash{})
+	registrySet(db, registryUnbondFromPrefix, caller[:], common.Hash{})
+	registrySet(db, registryUnbondGenPrefix, caller[:], common.Hash{})
+	registrySet(db, registryReleasePrefix, caller[:], common.Hash{})
+
+	db.SubBalance(DelegatorRegistryAddress, amount)
+	db.AddBalance(caller, amount)
+	return nil
+}
+
+// slash verifies the evidence of two headers sealed by the same delegator for
+// the same slot, and slashes and jails the offending delegator. Every piece of
+// evidence may only be used once.
+func (c *delegatorRegistry) slash(evm *EVM, first, second []byte, permille uint64) error {
+	if evm.VerifyDoubleSign == nil {
+		return errExecutionReverted
+	}
+	var headers [2]types.Header
+	if rlp.DecodeBytes(first, &headers[0]) != nil || rlp.DecodeBytes(second, &headers[1]) != nil {
+		return errExecutionReverted
+	}
+	id, err := evm.VerifyDoubleSign(&headers[0], &headers[1])
+	if err != nil {
+		return errExecutionReverted
+	}
+	evidence := EvidenceHash(&headers[0], &headers[1]).Bytes()
+	if registryGet(evm.StateDB, registryEvidencePrefix, evidence) != (common.Hash{}) {
+		return errExecutionReverted
+	}
+	registrySet(evm.StateDB, registryEvidencePrefix, evidence, common.BigToHash(common.Big1))
+
+	SlashDelegator(evm.StateDB, id, permille, true)
+	return nil
+}
+
+// slashPreCommit verifies the evidence of two pre-commit votes cast by the same
+// delegator for different blocks of the same height, and slashes and jails the
+// offending delegator. Every piece of evidence may only be used once.
+func (c *delegatorRegistry) slashPreCommit(evm *EVM, first, second []byte, permille uint64) error {
+	if evm.VerifyDoublePreCommit == nil {
+		return errExecutionReverted
+	}
+	var votes [2]types.PreCommit
+	if rlp.DecodeBytes(first, &votes[0]) != nil || rlp.DecodeBytes(second, &votes[1]) != nil {
+		return errExecutionReverted
+	}
+	id, err := evm.VerifyDoublePreCommit(&votes[0], &votes[1])
+	if err != nil {
+		return errExecutionReverted
+	}
+	evidence := PreCommitEvidenceHash(&votes[0], &votes[1]).Bytes()
+	if registryGet(evm.StateDB, registryEvidencePrefix, evidence) != (common.Hash{}) {
+		return errExecutionReverted
+	}
+	registrySet(evm.StateDB, registryEvidencePrefix, evidence, common.BigToHash(common.Big1))
+
+	SlashDelegator(evm.StateDB, id, permille, true)
+	return nil
+}
+
+// unjail releases a candidate jailed for missing its slots, once the release
+// epoch is reached. Only the owner of the candidate may release it.
+func (c *delegatorRegistry) unjail(db StateDB, caller common.Address, id string, epoch uint64) error {
+	key, ok := candidateKey(id)
+	if !ok || registryGet(db, registryOwnerPrefix, key[:]) != caller.Hash() {
+		return errExecutionReverted
+	}
+	jailed := registryGet(db, registryJailedPrefix, key[:])
+	if jailed == (common.Hash{}) || jailed == registryJailedForGood || jailed.Big().Uint64() > epoch {
+		return errExecutionReverted
+	}
+	registrySet(db, registryJailedPrefix, key[:], common.Hash{})
+	return nil
+}
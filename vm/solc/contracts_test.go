@@ -1,11 +1,19 @@
 package vm
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/crypto"
+	"github.com/juchain/go-juchain/common/rlp"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+	"github.com/juchain/go-juchain/core/types"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -465,3 +473,454 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// testerCandidate derives the node key of a delegator candidate from a seed and
+// returns it together with its short node id.
+func testerCandidate(seed byte) (*ecdsa.PrivateKey, string) {
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte{seed}))
+	return key, testerCandidateId(key)
+}
+
+// testerCandidateId returns the short node id of the given node key.
+func testerCandidateId(key *ecdsa.PrivateKey) string {
+	return fmt.Sprintf("%x", crypto.FromECDSAPub(&key.PublicKey)[1:9])
+}
+
+// testerRegistration signs the registration of a candidate owned by the given
+// account with the node key of the candidate.
+func testerRegistration(owner common.Address, key *ecdsa.PrivateKey) []byte {
+	signature, _ := crypto.Sign(DelegatorRegistrationHash(owner).Bytes(), key)
+	return signature
+}
+
+// Tests that candidates can be registered and voted for with bonded balance in
+// the native delegator registry, and that the top delegators are ordered by the
+// votes bonded for them.
+func TestDelegatorRegistry(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for i := byte(1); i <= 5; i++ {
+		statedb.AddBalance(common.BytesToAddress([]byte{i}), big.NewInt(1000))
+	}
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb, config.TestChainConfig, Config{})
+
+	var (
+		keyA, idA = testerCandidate(1)
+		keyB, idB = testerCandidate(2)
+		keyC, idC = testerCandidate(3)
+		keyD, idD = testerCandidate(4)
+	)
+	call := func(from byte, value int64, method string, args ...interface{}) ([]byte, error) {
+		input, err := registryABI.Pack(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		ret, _, err := evm.Call(AccountRef(common.BytesToAddress([]byte{from})), DelegatorRegistryAddress, input, 1000000, big.NewInt(value))
+		return ret, err
+	}
+	list := func() []string {
+		ret, err := call(9, 0, "delegators", big.NewInt(31))
+		if err != nil {
+			t.Fatalf("failed to list delegators: %v", err)
+		}
+		var list struct {
+			Ids   [][32]byte
+			Votes []*big.Int
+		}
+		if err := registryABI.Unpack(&list, "delegators", ret); err != nil {
+			t.Fatalf("failed to unpack delegators: %v", err)
+		}
+		ids := make([]string, len(list.Ids))
+		for i, id := range list.Ids {
+			ids[i] = candidateId(id)
+		}
+		return ids
+	}
+	for i, key := range []*ecdsa.PrivateKey{keyA, keyB, keyC} {
+		owner := common.BytesToAddress([]byte{byte(i + 1)})
+		if _, err := call(byte(i+1), 0, "register", testerCandidateId(key), testerRegistration(owner, key)); err != nil {
+			t.Fatalf("failed to register %s: %v", testerCandidateId(key), err)
+		}
+	}
+	if _, err := call(1, 0, "register", idA, testerRegistration(common.BytesToAddress([]byte{1}), keyA)); err != errExecutionReverted {
+		t.Errorf("duplicate registration: have %v, want %v", err, errExecutionReverted)
+	}
+	// Registrations must be signed by the node key for the registering account
+	if _, err := call(5, 0, "register", idD, testerRegistration(common.BytesToAddress([]byte{4}), keyD)); err != errExecutionReverted {
+		t.Errorf("replayed registration: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(5, 0, "register", idD, testerRegistration(common.BytesToAddress([]byte{5}), keyA)); err != errExecutionReverted {
+		t.Errorf("foreign node key: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(5, 0, "register", idD, []byte{}); err != errExecutionReverted {
+		t.Errorf("unsigned registration: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(1, 10, "vote", idD); err != errExecutionReverted {
+		t.Errorf("vote for unknown candidate: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(1, 0, "vote", idA); err != errExecutionReverted {
+		t.Errorf("vote without bond: have %v, want %v", err, errExecutionReverted)
+	}
+	// Bond votes, a voter may only add to the votes of its own candidate
+	votes := []struct {
+		voter byte
+		value int64
+		id    string
+	}{{1, 100, idC}, {2, 50, idC}, {3, 120, idB}, {4, 5, idA}, {4, 5, idA}}
+	for _, vote := range votes {
+		if _, err := call(vote.voter, vote.value, "vote", vote.id); err != nil {
+			t.Fatalf("voter %d failed to vote for %s: %v", vote.voter, vote.id, err)
+		}
+	}
+	if _, err := call(4, 5, "vote", idB); err != errExecutionReverted {
+		t.Errorf("vote for second candidate: have %v, want %v", err, errExecutionReverted)
+	}
+	if balance := statedb.GetBalance(DelegatorRegistryAddress); balance.Int64() != 280 {
+		t.Errorf("bonded balance mismatch: have %v, want %v", balance, 280)
+	}
+	if want := []string{idC, idB, idA}; !reflect.DeepEqual(list(), want) {
+		t.Errorf("delegators mismatch: have %v, want %v", list(), want)
+	}
+	// Slashing hits all voters of a candidate pro rata
+	if penalty := SlashDelegator(statedb, idC, 100, false); penalty.Int64() != 15 {
+		t.Errorf("penalty mismatch: have %v, want %v", penalty, 15)
+	}
+	if stake := GetDelegatorStake(statedb, common.BytesToAddress([]byte{1})); stake.Bonded.Int64() != 90 || stake.Candidate != idC {
+		t.Errorf("stake mismatch: have %+v", stake)
+	}
+	// Withdrawn votes are released only after the unbonding period
+	if _, err := call(1, 0, "unvote"); err != nil {
+		t.Fatalf("failed to withdraw votes: %v", err)
+	}
+	if _, err := call(1, 0, "unvote"); err != errExecutionReverted {
+		t.Errorf("double withdrawal: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, err := call(1, 0, "withdraw"); err != errExecutionReverted {
+		t.Errorf("early release: have %v, want %v", err, errExecutionReverted)
+	}
+	dpos := config.TestChainConfig.DPoS
+	evm.BlockNumber = new(big.Int).SetUint64(dpos.UnbondingEpochs() * dpos.EpochLength())
+	if _, err := call(1, 0, "withdraw"); err != nil {
+		t.Fatalf("failed to release votes: %v", err)
+	}
+	if balance := statedb.GetBalance(common.BytesToAddress([]byte{1})); balance.Int64() != 990 {
+		t.Errorf("released balance mismatch: have %v, want %v", balance, 990)
+	}
+	// Jailed candidates and candidates without votes must not be listed
+	if _, err := call(3, 0, "unvote"); err != nil {
+		t.Fatalf("failed to withdraw votes: %v", err)
+	}
+	SlashDelegator(statedb, idA, 0, true)
+	if want := []string{idC}; !reflect.DeepEqual(list(), want) {
+		t.Errorf("delegators mismatch: have %v, want %v", list(), want)
+	}
+	if top := TopDelegators(statedb, 1); len(top) != 1 || top[0].Votes.Int64() != 45 {
+		t.Errorf("top delegator mismatch: have %v", top)
+	}
+	// Writes must be rejected within static calls
+	input, _ := registryABI.Pack("unvote")
+	if _, _, err := evm.StaticCall(AccountRef(common.BytesToAddress([]byte{2})), DelegatorRegistryAddress, input, 1000000); err != errWriteProtection {
+		t.Errorf("static withdrawal: have %v, want %v", err, errWriteProtection)
+	}
+}
+
+// Tests that double signing evidence slashes and jails the offending delegator
+// exactly once.
+func TestDelegatorRegistrySlashing(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.AddBalance(common.BytesToAddress([]byte{1}), big.NewInt(1000))
+
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb, config.TestChainConfig, Config{})
+	sender := AccountRef(common.BytesToAddress([]byte{1}))
+	key, id := testerCandidate(1)
+
+	register, _ := registryABI.Pack("register", id, testerRegistration(sender.Address(), key))
+	vote, _ := registryABI.Pack("vote", id)
+	for i, input := range [][]byte{register, vote} {
+		if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, big.NewInt(int64(i*400))); err != nil {
+			t.Fatalf("failed to register and vote: %v", err)
+		}
+	}
+	first, _ := rlp.EncodeToBytes(&types.Header{Number: big.NewInt(1), Round: 7})
+	second, _ := rlp.EncodeToBytes(&types.Header{Number: big.NewInt(2), Round: 7})
+	input, _ := registryABI.Pack("slash", first, second)
+
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("slashing without verifier: have %v, want %v", err, errExecutionReverted)
+	}
+	evm.VerifyDoubleSign = func(first, second *types.Header) (string, error) {
+		return id, nil
+	}
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to slash: %v", err)
+	}
+	candidate := GetDelegatorCandidate(statedb, id)
+	if want := 400 - 400*int64(config.DefaultDoubleSignSlash)/1000; candidate.Votes.Int64() != want || !candidate.Jailed {
+		t.Errorf("slashed candidate mismatch: have %+v, want %d votes jailed", candidate, want)
+	}
+	// The same evidence in reverse order must not be accepted again
+	input, _ = registryABI.Pack("slash", second, first)
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("replayed evidence: have %v, want %v", err, errExecutionReverted)
+	}
+	// Neither may another conflicting header of the same slot, nor a re-sealed one
+	third, _ := rlp.EncodeToBytes(&types.Header{Number: big.NewInt(3), Round: 7})
+	resealed, _ := rlp.EncodeToBytes(&types.Header{Number: big.NewInt(1), Round: 7, Extra: []byte{1}})
+	for _, pair := range [][2][]byte{{first, third}, {resealed, second}} {
+		input, _ = registryABI.Pack("slash", pair[0], pair[1])
+		if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+			t.Errorf("evidence of a slashed slot: have %v, want %v", err, errExecutionReverted)
+		}
+	}
+	// Candidates caught double signing may never be released
+	JailDelegator(statedb, id, 1)
+	input, _ = registryABI.Pack("unjail", id)
+	evm.BlockNumber = big.NewInt(int64(100 * config.DefaultDPoSEpoch))
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("released double signer: have %v, want %v", err, errExecutionReverted)
+	}
+}
+
+// Tests that slashing hits the votes unbonding from a candidate as well, and that
+// the shares voided by slashing a candidate to zero don't dilute new votes.
+func TestDelegatorRegistrySlashingStake(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for i := byte(1); i <= 3; i++ {
+		statedb.AddBalance(common.BytesToAddress([]byte{i}), big.NewInt(1000))
+	}
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb, config.TestChainConfig, Config{})
+	key, id := testerCandidate(1)
+
+	call := func(from byte, value int64, method string, args ...interface{}) {
+		input, err := registryABI.Pack(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		if _, _, err := evm.Call(AccountRef(common.BytesToAddress([]byte{from})), DelegatorRegistryAddress, input, 1000000, big.NewInt(value)); err != nil {
+			t.Fatalf("account %d: failed to %s: %v", from, method, err)
+		}
+	}
+	stake := func(from byte) *DelegatorStake {
+		return GetDelegatorStake(statedb, common.BytesToAddress([]byte{from}))
+	}
+	call(1, 0, "register", id, testerRegistration(common.BytesToAddress([]byte{1}), key))
+	call(1, 400, "vote", id)
+	call(2, 400, "vote", id)
+	call(2, 0, "unvote")
+
+	// Unbonding votes are slashed along with the bonded ones
+	if slashed := SlashDelegator(statedb, id, 500, false); slashed.Int64() != 400 {
+		t.Errorf("slashed amount mismatch: have %v, want %d", slashed, 400)
+	}
+	if bonded := stake(1).Bonded; bonded.Int64() != 200 {
+		t.Errorf("bonded votes mismatch: have %v, want %d", bonded, 200)
+	}
+	if unbonding := stake(2).Unbonding; unbonding.Int64() != 200 {
+		t.Errorf("unbonding votes mismatch: have %v, want %d", unbonding, 200)
+	}
+	evm.BlockNumber = big.NewInt(int64((config.DefaultUnbondingEpochs + 1) * config.DefaultDPoSEpoch))
+	call(2, 0, "withdraw")
+	if balance := statedb.GetBalance(common.BytesToAddress([]byte{2})); balance.Int64() != 800 {
+		t.Errorf("withdrawn balance mismatch: have %v, want %d", balance, 800)
+	}
+	// Slashing the candidate to zero voids the shares of its voters
+	SlashDelegator(statedb, id, 1000, false)
+	call(3, 300, "vote", id)
+
+	if bonded := stake(3).Bonded; bonded.Int64() != 300 {
+		t.Errorf("new votes mismatch: have %v, want %d", bonded, 300)
+	}
+	if bonded := stake(1).Bonded; bonded.Sign() != 0 {
+		t.Errorf("voided votes mismatch: have %v, want 0", bonded)
+	}
+	call(1, 0, "unvote")
+	call(3, 0, "unvote")
+	if unbonding := stake(3).Unbonding; unbonding.Int64() != 300 {
+		t.Errorf("new unbonding votes mismatch: have %v, want %d", unbonding, 300)
+	}
+	if balance := statedb.GetBalance(DelegatorRegistryAddress); balance.Int64() != 300 {
+		t.Errorf("registry balance mismatch: have %v, want %d", balance, 300)
+	}
+}
+
+// Tests that conflicting pre-commit votes slash and jail the offending delegator
+// once per voted height.
+func TestDelegatorRegistryPreCommitSlashing(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.AddBalance(common.BytesToAddress([]byte{1}), big.NewInt(1000))
+
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb, config.TestChainConfig, Config{})
+	sender := AccountRef(common.BytesToAddress([]byte{1}))
+	key, id := testerCandidate(1)
+
+	register, _ := registryABI.Pack("register", id, testerRegistration(sender.Address(), key))
+	vote, _ := registryABI.Pack("vote", id)
+	for i, input := range [][]byte{register, vote} {
+		if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, big.NewInt(int64(i*400))); err != nil {
+			t.Fatalf("failed to register and vote: %v", err)
+		}
+	}
+	first, _ := rlp.EncodeToBytes(&types.PreCommit{Number: 5, Hash: common.Hash{1}, Delegator: id})
+	second, _ := rlp.EncodeToBytes(&types.PreCommit{Number: 5, Hash: common.Hash{2}, Delegator: id})
+	input, _ := registryABI.Pack("slashPreCommit", first, second)
+
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("slashing without verifier: have %v, want %v", err, errExecutionReverted)
+	}
+	evm.VerifyDoublePreCommit = func(first, second *types.PreCommit) (string, error) {
+		return id, nil
+	}
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to slash: %v", err)
+	}
+	candidate := GetDelegatorCandidate(statedb, id)
+	if want := 400 - 400*int64(config.DefaultDoubleSignSlash)/1000; candidate.Votes.Int64() != want || !candidate.Jailed {
+		t.Errorf("slashed candidate mismatch: have %+v, want %d votes jailed", candidate, want)
+	}
+	// Another conflicting vote of the same height must not be accepted again
+	third, _ := rlp.EncodeToBytes(&types.PreCommit{Number: 5, Hash: common.Hash{3}, Delegator: id})
+	input, _ = registryABI.Pack("slashPreCommit", first, third)
+	if _, _, err := evm.Call(sender, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("replayed evidence: have %v, want %v", err, errExecutionReverted)
+	}
+}
+
+// Tests that candidates jailed for missing their slots may be released by their
+// owner once the release epoch is reached, and that their liveness is tracked.
+func TestDelegatorRegistryUnjail(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	evm := NewEVM(Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}, statedb, config.TestChainConfig, Config{})
+	owner, other := AccountRef(common.BytesToAddress([]byte{1})), AccountRef(common.BytesToAddress([]byte{2}))
+
+	key, id := testerCandidate(1)
+
+	input, _ := registryABI.Pack("register", id, testerRegistration(owner.Address(), key))
+	if _, _, err := evm.Call(owner, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	RecordSlots(statedb, id, 0, 3, 1)
+	RecordSlots(statedb, id, 1, 0, 2)
+
+	want := &DelegatorLiveness{Epoch: 1, EpochMissed: 2, Produced: 3, Missed: 3}
+	if liveness := GetDelegatorLiveness(statedb, id, 1); !reflect.DeepEqual(liveness, want) {
+		t.Errorf("liveness mismatch: have %+v, want %+v", liveness, want)
+	}
+	// Jail the candidate until the second epoch, shorter jails must not release it earlier
+	JailDelegator(statedb, id, 2)
+	JailDelegator(statedb, id, 1)
+
+	input, _ = registryABI.Pack("unjail", id)
+	evm.BlockNumber = big.NewInt(int64(config.DefaultDPoSEpoch))
+	if _, _, err := evm.Call(owner, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("early release: have %v, want %v", err, errExecutionReverted)
+	}
+	evm.BlockNumber = big.NewInt(int64(2 * config.DefaultDPoSEpoch))
+	if _, _, err := evm.Call(other, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("release by foreign account: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, _, err := evm.Call(owner, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if candidate := GetDelegatorCandidate(statedb, id); candidate.Jailed {
+		t.Errorf("released candidate still jailed")
+	}
+	if _, _, err := evm.Call(owner, DelegatorRegistryAddress, input, 1000000, new(big.Int)); err != errExecutionReverted {
+		t.Errorf("release of free candidate: have %v, want %v", err, errExecutionReverted)
+	}
+}
+
+// Tests that the delegator registry and DApp bridge only run from the system
+// contracts fork block on, before which their addresses are ordinary accounts.
+func TestSystemContractsFork(t *testing.T) {
+	chainConfig := *config.TestChainConfig
+	chainConfig.SystemContractsBlock = big.NewInt(10)
+	chainConfig.DAppSignBlock, chainConfig.DAppContextBlock = nil, nil
+
+	dappId := common.BytesToAddress([]byte{0xda})
+	sender := common.BytesToAddress([]byte{1})
+
+	listInput, _ := registryABI.Pack("delegators", big.NewInt(31))
+	lockInput, _ := bridgeABI.Pack("lock", dappId, sender)
+	for _, tt := range []struct {
+		number int64
+		active bool
+	}{{9, false}, {10, true}} {
+		db, _ := store.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.AddBalance(sender, big.NewInt(1000))
+		ctx := Context{
+			CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+				return db.GetBalance(addr).Cmp(amount) >= 0
+			},
+			Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+				db.SubBalance(sender, amount)
+				db.AddBalance(recipient, amount)
+			},
+			BlockNumber: big.NewInt(tt.number),
+		}
+		evm := NewEVM(ctx, statedb, &chainConfig, Config{})
+
+		ret, _, err := evm.StaticCall(AccountRef(sender), DelegatorRegistryAddress, listInput, 100000)
+		if err != nil {
+			t.Fatalf("block %d: failed to list delegators: %v", tt.number, err)
+		}
+		if active := len(ret) > 0; active != tt.active {
+			t.Errorf("block %d: registry activity mismatch: have %v, want %v", tt.number, active, tt.active)
+		}
+		if _, _, err := evm.Call(AccountRef(sender), DAppBridgeAddress, lockInput, 100000, big.NewInt(100)); err != nil {
+			t.Fatalf("block %d: failed to lock value: %v", tt.number, err)
+		}
+		if active := LockedValue(statedb, dappId).Sign() > 0; active != tt.active {
+			t.Errorf("block %d: bridge activity mismatch: have %v, want %v", tt.number, active, tt.active)
+		}
+	}
+}
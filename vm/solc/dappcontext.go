@@ -0,0 +1,92 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/vm/solc/abi"
+)
+
+// DAppContextAddress is the address of the native contract exposing the DApp
+// and delegated proof-of-stake fields of the current block to contracts.
+var DAppContextAddress = common.BytesToAddress([]byte{1, 2})
+
+// DAppContextABI is the ABI of the native DApp context. The president is the
+// short node id of the delegator sealing the block, returned as bytes32 like the
+// ids of the delegator registry. On the main chain the DApp and its anchor are
+// zero, on the DApp chains the round and president are the ones of the anchor.
+const DAppContextABI = `[
+	{"constant":true,"inputs":[],"name":"dappId","outputs":[{"name":"dapp","type":"address"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"dappMainHash","outputs":[{"name":"hash","type":"bytes32"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"round","outputs":[{"name":"round","type":"uint256"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"presidentId","outputs":[{"name":"id","type":"bytes32"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"context","outputs":[{"name":"dapp","type":"address"},{"name":"hash","type":"bytes32"},{"name":"round","type":"uint256"},{"name":"id","type":"bytes32"}],"type":"function"}
+]`
+
+var dappContextABI abi.ABI
+
+func init() {
+	var err error
+	if dappContextABI, err = abi.JSON(strings.NewReader(DAppContextABI)); err != nil {
+		panic(err)
+	}
+}
+
+// dappContext implemented as a native system contract. It keeps no storage and
+// only reads the context of the EVM, which is populated from the block header.
+type dappContext struct{}
+
+func (c *dappContext) Run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	if len(input) < 4 || contract.Value().Sign() != 0 {
+		return nil, errExecutionReverted
+	}
+	method, err := dappContextABI.MethodById(input)
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	if !contract.UseGas(config.DAppContextGas) {
+		return nil, ErrOutOfGas
+	}
+	var (
+		round     = new(big.Int).SetUint64(evm.Round)
+		president [32]byte
+	)
+	if key, ok := candidateKey(evm.PresidentId); ok {
+		president = key
+	}
+	switch method.Name {
+	case "dappId":
+		return method.Outputs.Pack(evm.DAppID)
+
+	case "dappMainHash":
+		return method.Outputs.Pack([32]byte(evm.DAppMainHash))
+
+	case "round":
+		return method.Outputs.Pack(round)
+
+	case "presidentId":
+		return method.Outputs.Pack(president)
+
+	case "context":
+		return method.Outputs.Pack(evm.DAppID, [32]byte(evm.DAppMainHash), round, president)
+	}
+	return nil, errExecutionReverted
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The go-juchain Authors
+// This file is part of the go-juchain library.
+//
+// The go-juchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-juchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-juchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+)
+
+func TestDAppContext(t *testing.T) {
+	var (
+		dappId   = common.BytesToAddress([]byte{0xda})
+		mainHash = common.HexToHash("0xa1b2")
+		caller   = common.BytesToAddress([]byte{1})
+		reader   = common.BytesToAddress([]byte("reader"))
+	)
+	db, _ := store.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	ctx := Context{
+		CanTransfer:  func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:     func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber:  big.NewInt(5),
+		DAppID:       dappId,
+		DAppMainHash: mainHash,
+		Round:        42,
+		PresidentId:  "delegator1",
+	}
+	evm := NewEVM(ctx, statedb, config.TestChainConfig.DAppChainConfig(dappId), Config{})
+
+	ret, _, err := evm.StaticCall(AccountRef(caller), DAppContextAddress, dappContextABI.Methods["context"].Id(), 100000)
+	if err != nil {
+		t.Fatalf("failed to query context: %v", err)
+	}
+	var context struct {
+		Dapp  common.Address
+		Hash  [32]byte
+		Round *big.Int
+		Id    [32]byte
+	}
+	if err := dappContextABI.Unpack(&context, "context", ret); err != nil {
+		t.Fatalf("failed to unpack context: %v", err)
+	}
+	president, _ := candidateKey("delegator1")
+	if context.Dapp != dappId || context.Hash != mainHash || context.Round.Uint64() != 42 || context.Id != president {
+		t.Errorf("context mismatch: have %+v", context)
+	}
+	// Value may not be sent to the context
+	if _, _, err := evm.Call(AccountRef(caller), DAppContextAddress, dappContextABI.Methods["round"].Id(), 100000, big.NewInt(1)); err != errExecutionReverted {
+		t.Errorf("paid query: have %v, want %v", err, errExecutionReverted)
+	}
+	// Contracts read the context by calling it, here shifting the selector of
+	// dappId() into place and returning the result of the static call
+	code := append([]byte{byte(PUSH4)}, dappContextABI.Methods["dappId"].Id()...)
+	code = append(code, common.Hex2Bytes("60e01b60005260206000600460006101025afa5060206000f3")...)
+	statedb.SetCode(reader, code)
+
+	ret, _, err = evm.Call(AccountRef(caller), reader, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to read the context from a contract: %v", err)
+	}
+	if have := common.BytesToAddress(ret); have != dappId {
+		t.Errorf("DApp id read by contract mismatch: have %x, want %x", have, dappId)
+	}
+}
+
+// Tests that the DApp context is only exposed from its fork block on, before
+// which its address is an ordinary empty account.
+func TestDAppContextFork(t *testing.T) {
+	chainConfig := *config.TestChainConfig
+	chainConfig.DAppContextBlock = big.NewInt(10)
+
+	input := dappContextABI.Methods["round"].Id()
+	for _, tt := range []struct {
+		number int64
+		round  uint64
+	}{{9, 0}, {10, 42}} {
+		db, _ := store.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+		ctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(tt.number),
+			Round:       42,
+		}
+		evm := NewEVM(ctx, statedb, &chainConfig, Config{})
+
+		ret, _, err := evm.StaticCall(AccountRef(common.Address{}), DAppContextAddress, input, 100000)
+		if err != nil {
+			t.Fatalf("block %d: failed to query round: %v", tt.number, err)
+		}
+		if round := new(big.Int).SetBytes(ret).Uint64(); round != tt.round || (tt.round == 0 && len(ret) != 0) {
+			t.Errorf("block %d: round mismatch: have %x, want %d", tt.number, ret, tt.round)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/juchain/go-juchain/common"
 	"github.com/juchain/go-juchain/common/crypto"
 	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/types"
 )
 
 // emptyCodeHash is used by create to ensure deployment is disallowed to already
@@ -35,11 +36,36 @@ type (
 	// GetHashFunc returns the nth block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// DoubleSignFunc returns the delegator which sealed both given headers for
+	// the same slot, or an error if they are no evidence of double signing.
+	DoubleSignFunc func(*types.Header, *types.Header) (string, error)
+	// DoublePreCommitFunc returns the delegator which cast both given pre-commit
+	// votes for different blocks of the same height, or an error if they are no
+	// evidence of double voting.
+	DoublePreCommitFunc func(*types.PreCommit, *types.PreCommit) (string, error)
+	// ReleaseFunc returns the DApp transaction proven to be anchored in a final
+	// block of the main chain by the given proof, along with its receipt proven by
+	// the DApp block executing it, or an error if the proof is invalid.
+	ReleaseFunc func([]byte) (*types.Transaction, *types.Receipt, error)
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, snapshot int, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
+		var systemContracts map[common.Address]SystemContract
+		switch {
+		case evm.chainRules.IsDAppContext:
+			systemContracts = SystemContractsDAppContext
+		case evm.chainRules.IsSystemContracts:
+			systemContracts = SystemContracts
+		}
+		if p := systemContracts[*contract.CodeAddr]; p != nil {
+			// System contracts only ever operate on the storage of their own account
+			if contract.Address() != *contract.CodeAddr {
+				return nil, errExecutionReverted
+			}
+			return p.Run(evm, contract, input)
+		}
 		precompiles := PrecompiledContractsHomestead
 		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 			precompiles = PrecompiledContractsByzantium
@@ -61,6 +87,12 @@ type Context struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// VerifyDoubleSign verifies the evidence of slashing transactions
+	VerifyDoubleSign DoubleSignFunc
+	// VerifyDoublePreCommit verifies the evidence of pre-commit slashing transactions
+	VerifyDoublePreCommit DoublePreCommitFunc
+	// VerifyRelease verifies the burns released by the DApp bridge
+	VerifyRelease ReleaseFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -72,6 +104,12 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+
+	// DApp information, provided by the DApp context contract
+	DAppID       common.Address // DApp the block belongs to, zero on the main chain
+	DAppMainHash common.Hash    // Main chain block the DApp block is anchored to
+	Round        uint64         // DPoS round of the block
+	PresidentId  string         // Delegator sealing the block
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -158,7 +196,14 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 			precompiles = PrecompiledContractsByzantium
 		}
-		if precompiles[addr] == nil && value.Sign() == 0 {
+		var systemContracts map[common.Address]SystemContract
+		switch {
+		case evm.chainRules.IsDAppContext:
+			systemContracts = SystemContractsDAppContext
+		case evm.chainRules.IsSystemContracts:
+			systemContracts = SystemContracts
+		}
+		if precompiles[addr] == nil && systemContracts[addr] == nil && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
@@ -301,9 +346,8 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	return ret, contract.Gas, err
 }
 
-// Create creates a new contract using code as deployment code.
-func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
-
+// create creates a new contract at the given address using code as deployment code.
+func (evm *EVM) create(caller ContractRef, code []byte, codeHash common.Hash, gas uint64, value *big.Int, contractAddr common.Address) ([]byte, common.Address, uint64, error) {
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(config.CallCreateDepth) {
@@ -312,11 +356,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
-	// Ensure there's no existing contract already at the designated address
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 	if evm.StateDB.GetNonce(contractAddr) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
@@ -331,12 +374,12 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	// E The contract is a scoped evmironment for this execution context
 	// only.
 	contract := NewContract(caller, AccountRef(contractAddr), value, gas)
-	contract.SetCallCode(&contractAddr, crypto.Keccak256Hash(code), code)
+	contract.SetCallCode(&contractAddr, codeHash, code)
 
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, contractAddr, gas, nil
 	}
-	ret, err = run(evm, snapshot, contract, nil)
+	ret, err := run(evm, snapshot, contract, nil)
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := len(ret) > config.MaxCodeSize
 	// if the contract creation ran successfully and no errors were returned
@@ -368,6 +411,22 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// Create creates a new contract using code as deployment code.
+func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	return evm.create(caller, code, crypto.Keccak256Hash(code), gas, value, contractAddr)
+}
+
+// Create2 creates a new contract using code as deployment code.
+//
+// The different between Create2 with Create is Create2 uses sha3(0xff ++ msg.sender ++ salt ++ sha3(init_code))[12:]
+// instead of the usual sender-and-nonce-hash as the address where the contract is initialized at.
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	codeHash := crypto.Keccak256Hash(code)
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), codeHash.Bytes())
+	return evm.create(caller, code, codeHash, gas, endowment, contractAddr)
+}
+
 // ChainConfig returns the evmironment's chain configuration
 func (evm *EVM) ChainConfig() *config.ChainConfig { return evm.chainConfig }
 
@@ -120,6 +120,9 @@ func gasSStore(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 		y, x = stack.Back(1), stack.Back(0)
 		val  = evm.StateDB.GetState(contract.Address(), common.BigToHash(x))
 	)
+	if evm.chainRules.IsConstantinople {
+		return gasSStoreNet(evm, contract, common.BigToHash(x), val, common.BigToHash(y))
+	}
 	// This checks for 3 scenario's and calculates gas accordingly
 	// 1. From a zero-value address to a non-zero value         (NEW VALUE)
 	// 2. From a non-zero value address to a zero-value address (DELETE)
@@ -137,6 +140,59 @@ func gasSStore(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	}
 }
 
+// gasSStoreNet meters SSTORE by the net change of the slot within the current
+// transaction (EIP-1283), only charging the full price for the first write. A
+// call with no more gas left than the stipend of a value transfer may not write
+// at all (EIP-2200), net metering would let it modify the storage of a reentered
+// contract otherwise:
+//
+// 0. If gas left is less than or equal to 2300, fail.
+// 1. If current value equals new value (this is a no-op), 200 gas is deducted.
+// 2. If current value does not equal new value
+//   2.1. If original value equals current value (this storage slot has not been changed by the current execution context)
+//     2.1.1. If original value is 0, 20000 gas is deducted.
+//     2.1.2. Otherwise, 5000 gas is deducted. If new value is 0, add 15000 gas to refund counter.
+//   2.2. If original value does not equal current value (this storage slot is dirty), 200 gas is deducted. Apply both of the following clauses.
+//     2.2.1. If original value is not 0
+//       2.2.1.1. If current value is 0 (also means that new value is not 0), remove 15000 gas from refund counter.
+//       2.2.1.2. If new value is 0 (also means that current value is not 0), add 15000 gas to refund counter.
+//     2.2.2. If original value equals new value (this storage slot is reset)
+//       2.2.2.1. If original value is 0, add 19800 gas to refund counter.
+//       2.2.2.2. Otherwise, add 4800 gas to refund counter.
+func gasSStoreNet(evm *EVM, contract *Contract, key, current, value common.Hash) (uint64, error) {
+	if contract.Gas <= config.SstoreSentryGas { // reentrancy sentry (0)
+		return 0, errSstoreSentry
+	}
+	if current == value { // noop (1)
+		return config.NetSstoreNoopGas, nil
+	}
+	original := evm.StateDB.GetCommittedState(contract.Address(), key)
+	if original == current {
+		if original == (common.Hash{}) { // create slot (2.1.1)
+			return config.NetSstoreInitGas, nil
+		}
+		if value == (common.Hash{}) { // delete slot (2.1.2b)
+			evm.StateDB.AddRefund(config.NetSstoreClearRefund)
+		}
+		return config.NetSstoreCleanGas, nil // write existing slot (2.1.2)
+	}
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) { // recreate slot (2.2.1.1)
+			evm.StateDB.SubRefund(config.NetSstoreClearRefund)
+		} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
+			evm.StateDB.AddRefund(config.NetSstoreClearRefund)
+		}
+	}
+	if original == value {
+		if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
+			evm.StateDB.AddRefund(config.NetSstoreResetClearRefund)
+		} else { // reset to original existing slot (2.2.2.2)
+			evm.StateDB.AddRefund(config.NetSstoreResetRefund)
+		}
+	}
+	return config.NetSstoreDirtyGas, nil
+}
+
 func makeGasLog(n uint64) gasFunc {
 	return func(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 		requestedSize, overflow := bigUint64(stack.Back(1))
@@ -289,6 +345,29 @@ func gasCreate(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	return gas, nil
 }
 
+func gasCreate2(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var overflow bool
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if gas, overflow = math.SafeAdd(gas, config.Create2Gas); overflow {
+		return 0, errGasUintOverflow
+	}
+	// The init code is hashed to derive the contract address
+	wordGas, overflow := bigUint64(stack.Back(2))
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), config.Sha3WordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
 func gasBalance(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.Balance, nil
 }
@@ -297,6 +376,10 @@ func gasExtCodeSize(gt config.GasTable, evm *EVM, contract *Contract, stack *Sta
 	return gt.ExtcodeSize, nil
 }
 
+func gasExtCodeHash(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gt.ExtcodeHash, nil
+}
+
 func gasSLoad(gt config.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.SLoad, nil
 }
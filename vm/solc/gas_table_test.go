@@ -16,7 +16,16 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/juchain/go-juchain/common"
+	"github.com/juchain/go-juchain/common/hexutil"
+	"github.com/juchain/go-juchain/config"
+	"github.com/juchain/go-juchain/core/state"
+	"github.com/juchain/go-juchain/core/store"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +43,99 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+var eip1283Tests = []struct {
+	original byte
+	gaspool  uint64
+	input    string
+	used     uint64
+	refund   uint64
+}{
+	{0, 1000000, "0x60006000556000600055", 412, 0},
+	{0, 1000000, "0x60006000556001600055", 20212, 0},
+	{0, 1000000, "0x60016000556000600055", 20212, 19800},
+	{0, 1000000, "0x60016000556002600055", 20212, 0},
+	{0, 1000000, "0x60016000556001600055", 20212, 0},
+	{1, 1000000, "0x60006000556000600055", 5212, 15000},
+	{1, 1000000, "0x60006000556001600055", 5212, 4800},
+	{1, 1000000, "0x60006000556002600055", 5212, 0},
+	{1, 1000000, "0x60026000556000600055", 5212, 15000},
+	{1, 1000000, "0x60026000556003600055", 5212, 0},
+	{1, 1000000, "0x60026000556001600055", 5212, 4800},
+	{1, 1000000, "0x60026000556002600055", 5212, 0},
+	{1, 1000000, "0x60016000556000600055", 5212, 15000},
+	{1, 1000000, "0x60016000556002600055", 5212, 0},
+	{1, 1000000, "0x60016000556001600055", 412, 0},
+	{0, 1000000, "0x600160005560006000556001600055", 40218, 19800},
+	{1, 1000000, "0x600060005560016000556000600055", 10218, 19800},
+}
+
+// Tests that SSTORE is metered by the net storage change of a transaction
+// once Constantinople is active, with the test vectors of EIP-1283.
+func TestEIP1283(t *testing.T) {
+	for i, tt := range eip1283Tests {
+		address := common.BytesToAddress([]byte("contract"))
+
+		db, _ := store.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.input))
+		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{tt.original}))
+		statedb.Finalise(true) // Push the state into the "original" slot
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int),
+		}
+		vmenv := NewEVM(vmctx, statedb, config.TestChainConfig, Config{})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if err != nil {
+			t.Errorf("test %d: execution failed: %v", i, err)
+		}
+		if used := tt.gaspool - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := vmenv.StateDB.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
+
+// Tests that a net-metered SSTORE fails if no more gas than the stipend of a
+// value transfer is left, even if it would only cost the no-op price (EIP-2200).
+func TestSstoreSentry(t *testing.T) {
+	tests := []struct {
+		gaspool uint64
+		used    uint64
+		err     error
+	}{
+		{2306, 2306, ErrOutOfGas}, // 2300 gas left at the SSTORE
+		{2307, 206, nil},          // 2301 gas left at the SSTORE
+	}
+	for i, tt := range tests {
+		address := common.BytesToAddress([]byte("contract"))
+
+		db, _ := store.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode("0x6000600055"))
+		statedb.Finalise(true)
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int),
+		}
+		vmenv := NewEVM(vmctx, statedb, config.TestChainConfig, Config{})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if used := tt.gaspool - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+	}
+}